@@ -0,0 +1,37 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+)
+
+// actorKey is the context key of the actor of a request.
+type actorKey struct{}
+
+// NewActorContext returns a copy of ctx attributed to actor. The Album
+// changes made by AlbumStorage implementations with it are recorded as made
+// by actor.
+func NewActorContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor ctx is attributed to, or an empty string
+// if it is not attributed to any.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// attributeToActor returns an http.Handler that passes requests to next
+// attributed to the subject of their Principal. Unauthenticated requests are
+// passed unattributed.
+func attributeToActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := auth.FromContext(r.Context()); ok {
+			r = r.WithContext(NewActorContext(r.Context(), p.Subject))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
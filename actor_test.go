@@ -0,0 +1,42 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+)
+
+func TestAttributeToActor(t *testing.T) {
+	tests := map[string]struct {
+		principal *auth.Principal
+		actorWant string
+	}{
+		"unauthenticated": {
+			actorWant: "",
+		},
+		"authenticated": {
+			principal: &auth.Principal{Subject: "jtohru"},
+			actorWant: "jtohru",
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var actor string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actor = ActorFromContext(r.Context())
+			})
+			req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+			if test.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), *test.principal))
+			}
+
+			attributeToActor(next).ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, test.actorWant, actor)
+		})
+	}
+}
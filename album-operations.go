@@ -0,0 +1,64 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The album operations below are shared by the HTTP handlers and the gRPC
+// service, which only translate their requests and responses, so that both
+// APIs create, update and paginate albums the same way.
+
+// newAlbum returns the album identified by id created by req at now, in the
+// catalog of the tenant of ctx and by its actor.
+func newAlbum(ctx context.Context, id uuid.UUID, req request, now time.Time) Album {
+	return Album{
+		ID:                  id,
+		Title:               req.Title,
+		Artist:              req.Artist,
+		Price:               req.price(),
+		CreatedAt:           now,
+		UpdatedAt:           now,
+		Version:             1,
+		TenantID:            TenantFromContext(ctx),
+		CreatedBy:           ActorFromContext(ctx),
+		UpdatedBy:           ActorFromContext(ctx),
+		ReleaseDate:         req.ReleaseDate,
+		OriginalReleaseYear: req.OriginalReleaseYear,
+	}
+}
+
+// updateAlbum returns alb updated by req at now by the actor of ctx. If req
+// has a version, the update is based on it.
+func updateAlbum(ctx context.Context, alb Album, req request, now time.Time) Album {
+	alb.Title = req.Title
+	alb.Artist = req.Artist
+	alb.Price = req.price()
+	alb.ReleaseDate = req.ReleaseDate
+	alb.OriginalReleaseYear = req.OriginalReleaseYear
+	alb.UpdatedAt = now
+	alb.UpdatedBy = ActorFromContext(ctx)
+	if req.Version != 0 {
+		alb.Version = req.Version
+	}
+	return alb
+}
+
+// albumsPage returns the offset and the limit of the page of albums numbered
+// pageNumber of pageSize albums, or an error describing why the page is
+// invalid.
+func albumsPage(pageSize, pageNumber int) (offset, limit int, err error) {
+	switch {
+	case pageSize < 1:
+		return 0, 0, errors.New("page size is less than 1")
+	case pageSize > maxAlbumsPageSize:
+		return 0, 0, fmt.Errorf("page size is greater than %d", maxAlbumsPageSize)
+	case pageNumber < 1:
+		return 0, 0, errors.New("page number is less than 1")
+	}
+	return pageSize * (pageNumber - 1), pageSize, nil
+}
@@ -0,0 +1,35 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlbumsPage(t *testing.T) {
+	tests := map[string]struct {
+		pageSize, pageNumber  int
+		wantOffset, wantLimit int
+		wantErr               string
+	}{
+		"first page":            {pageSize: 10, pageNumber: 1, wantOffset: 0, wantLimit: 10},
+		"third page":            {pageSize: 10, pageNumber: 3, wantOffset: 20, wantLimit: 10},
+		"largest page":          {pageSize: 50, pageNumber: 2, wantOffset: 50, wantLimit: 50},
+		"page size too small":   {pageSize: 0, pageNumber: 1, wantErr: "page size is less than 1"},
+		"page size too large":   {pageSize: 51, pageNumber: 1, wantErr: "page size is greater than 50"},
+		"page number too small": {pageSize: 10, pageNumber: 0, wantErr: "page number is less than 1"},
+	}
+	for testName, tc := range tests {
+		t.Run(testName, func(t *testing.T) {
+			offset, limit, err := albumsPage(tc.pageSize, tc.pageNumber)
+
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantOffset, offset)
+			assert.Equal(t, tc.wantLimit, limit)
+		})
+	}
+}
@@ -4,14 +4,51 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/civil"
+	"github.com/jhtohru/go-album-catalog/money"
 )
 
 // Album represents data about a music album.
 type Album struct {
-	ID        uuid.UUID `json:"id"`
-	Title     string    `json:"title"`
-	Artist    string    `json:"artist"`
-	Price     int       `json:"price"`
+	ID        uuid.UUID   `json:"id"`
+	Title     string      `json:"title"`
+	Artist    string      `json:"artist"`
+	Price     money.Money `json:"price"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// Version is incremented every time the album is updated.
+	Version int `json:"version"`
+	// TenantID is the tenant whose catalog the album belongs to, empty for the
+	// default one. Albums are always stored into the catalog of the tenant of
+	// the context they are stored with.
+	TenantID string `json:"tenant_id,omitempty"`
+	// CreatedBy and UpdatedBy are who created and last updated the album, if
+	// known.
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// Artwork is the key of the cover art of the album in the BlobStorage,
+	// empty if it has none.
+	Artwork string `json:"artwork,omitempty"`
+	// ReleaseDate is the date the album was released on, nil if unknown.
+	ReleaseDate *civil.Date `json:"release_date,omitempty"`
+	// OriginalReleaseYear is the year the album was originally released in,
+	// before any reissue, zero if unknown.
+	OriginalReleaseYear int `json:"original_release_year,omitempty"`
+}
+
+// albumFields are the JSON field names of an Album.
+var albumFields = []string{"id", "title", "artist", "price", "created_at", "updated_at", "version", "tenant_id", "created_by", "updated_by", "artwork", "release_date", "original_release_year"}
+
+// AlbumAuditEntry records a single change of an Album.
+type AlbumAuditEntry struct {
+	// Action is either "insert", "update" or "delete".
+	Action string `json:"action"`
+	// Actor is who changed the Album, if known.
+	Actor string `json:"actor,omitempty"`
+	// Before is the Album state before the change, nil if it was inserted.
+	Before *Album `json:"before"`
+	// After is the Album state after the change, nil if it was deleted.
+	After     *Album    `json:"after"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 }
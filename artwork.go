@@ -0,0 +1,149 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+// ErrArtworkNotFound is returned by an ArtworkFetcher when no cover art of an
+// album is found by the sources of its metadata.
+var ErrArtworkNotFound = errors.New("artwork not found")
+
+// ErrArtworkSourceFailed is returned by an ArtworkFetcher, wrapping the error
+// of the request, when the cover art of an album fails to be fetched.
+var ErrArtworkSourceFailed = errors.New("artwork source failed")
+
+// maxArtworkSize is the maximum size of the cover art fetched.
+const maxArtworkSize = 10 << 20
+
+// errCoverArtMissing is returned by fetchCoverArt when the source has no cover
+// art at the requested URL.
+var errCoverArtMissing = errors.New("cover art missing")
+
+// ArtworkFetcher fetches the cover art of albums from the cover art URLs of
+// their metadata, such as the Discogs or the Cover Art Archive ones, storing
+// it into a BlobStorage and recording it as the Artwork of the album.
+type ArtworkFetcher struct {
+	albumStorage    AlbumStorage
+	metadataStorage MetadataStorage
+	blobs           BlobStorage
+	client          *http.Client
+	clock           clock.Clock
+}
+
+// NewArtworkFetcher returns a new ArtworkFetcher that fetches the cover art
+// found in metadataStorage through client, storing it into blobs and recording
+// it on the albums of albumStorage.
+func NewArtworkFetcher(
+	albumStorage AlbumStorage,
+	metadataStorage MetadataStorage,
+	blobs BlobStorage,
+	client *http.Client,
+) *ArtworkFetcher {
+	return &ArtworkFetcher{
+		albumStorage:    albumStorage,
+		metadataStorage: metadataStorage,
+		blobs:           blobs,
+		client:          client,
+		clock:           clock.System,
+	}
+}
+
+// artworkKey returns the key of the cover art of the album identified by
+// albumID in the BlobStorage.
+func artworkKey(albumID uuid.UUID) string {
+	return "artwork/" + albumID.String()
+}
+
+// Fetch fetches the cover art of the album identified by albumID from the
+// cover art URL of its metadata, trying each source in order until one has
+// it, and returns the album with the cover art recorded as its Artwork. It
+// returns ErrArtworkNotFound if no source has it, an error wrapping
+// ErrArtworkSourceFailed if a source fails, and the errors of
+// AlbumStorage.UpdateFunc.
+func (f *ArtworkFetcher) Fetch(ctx context.Context, albumID uuid.UUID) (Album, error) {
+	mds, err := f.metadataStorage.FindAll(ctx, albumID)
+	if err != nil {
+		return Album{}, err
+	}
+	key := artworkKey(albumID)
+	for _, md := range mds {
+		if md.CoverArtURL == "" {
+			continue
+		}
+		err := f.fetchCoverArt(ctx, md.CoverArtURL, key)
+		if errors.Is(err, errCoverArtMissing) {
+			continue
+		}
+		if err != nil {
+			return Album{}, fmt.Errorf("%w: fetching %s cover art: %w", ErrArtworkSourceFailed, md.Source, err)
+		}
+		return f.albumStorage.UpdateFunc(ctx, albumID, func(alb Album) Album {
+			alb.Artwork = key
+			alb.UpdatedAt = f.clock.Now().UTC()
+			alb.UpdatedBy = ActorFromContext(ctx)
+			return alb
+		})
+	}
+	return Album{}, ErrArtworkNotFound
+}
+
+// fetchCoverArt downloads the image at url, storing it as the blob of key.
+func (f *ArtworkFetcher) fetchCoverArt(ctx context.Context, url, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "image/*")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errCoverArtMissing
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return fmt.Errorf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	if resp.ContentLength > maxArtworkSize {
+		return fmt.Errorf("cover art is larger than %d bytes", maxArtworkSize)
+	}
+	// Read the cover art up to one byte past the maximum size, so that larger
+	// ones of unknown length are not stored.
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxArtworkSize+1))
+	if err != nil {
+		return err
+	}
+	if len(content) > maxArtworkSize {
+		return fmt.Errorf("cover art is larger than %d bytes", maxArtworkSize)
+	}
+	return f.blobs.Put(ctx, key, mediaType, bytes.NewReader(content))
+}
+
+// Artwork returns the cover art recorded as the Artwork of alb, or
+// ErrArtworkNotFound if it has none.
+func (f *ArtworkFetcher) Artwork(ctx context.Context, alb Album) (Blob, error) {
+	if alb.Artwork == "" {
+		return Blob{}, ErrArtworkNotFound
+	}
+	blob, err := f.blobs.Get(ctx, alb.Artwork)
+	if errors.Is(err, ErrBlobNotFound) {
+		return Blob{}, ErrArtworkNotFound
+	}
+	return blob, err
+}
@@ -0,0 +1,197 @@
+// Package auth authenticates the callers of the album catalog API by the JWTs
+// they send as bearer tokens.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Subject identifies the caller.
+	Subject string
+	// Roles are the roles granted to the caller.
+	Roles []string
+	// Tenant is the tenant whose catalog the caller operates on, empty for
+	// the default one.
+	Tenant string
+}
+
+// The roles granted to callers, each one granting the permissions of the
+// roles before it.
+const (
+	// RoleReader allows reading albums.
+	RoleReader = "reader"
+	// RoleEditor allows creating and updating albums.
+	RoleEditor = "editor"
+	// RoleAdmin allows deleting albums and administering the catalog.
+	RoleAdmin = "admin"
+)
+
+// roleRanks ranks the roles by the permissions they grant.
+var roleRanks = map[string]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// HasRole reports whether p was granted role or a role granting its
+// permissions, such as RoleAdmin for RoleEditor.
+func (p Principal) HasRole(role string) bool {
+	want, ok := roleRanks[role]
+	if !ok {
+		return false
+	}
+	for _, r := range p.Roles {
+		if roleRanks[r] >= want {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey is the context key of the Principal of a request.
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the Principal carried by ctx, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// ErrInvalidToken is returned when a token cannot be verified.
+var ErrInvalidToken = errors.New("invalid token")
+
+// VerifierOptions are the claims a Verifier requires tokens to have. The
+// claims left empty are not checked.
+type VerifierOptions struct {
+	Issuer   string
+	Audience string
+}
+
+// Verifier verifies JWTs and extracts the Principal they were issued to.
+type Verifier struct {
+	keyfunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+// claims are the claims of the tokens verified by a Verifier.
+type claims struct {
+	jwt.RegisteredClaims
+	Roles  []string `json:"roles"`
+	Tenant string   `json:"tenant"`
+}
+
+// newVerifier returns a new Verifier of tokens signed with method, whose
+// verification key is found by keyfunc.
+func newVerifier(method jwt.SigningMethod, keyfunc jwt.Keyfunc, opts VerifierOptions) *Verifier {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{method.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	return &Verifier{
+		keyfunc: keyfunc,
+		parser:  jwt.NewParser(parserOpts...),
+	}
+}
+
+// NewHS256Verifier returns a new Verifier of the tokens signed with HS256 and
+// secret.
+func NewHS256Verifier(secret []byte, opts VerifierOptions) *Verifier {
+	return newVerifier(jwt.SigningMethodHS256, func(*jwt.Token) (any, error) {
+		return secret, nil
+	}, opts)
+}
+
+// Verify verifies token, returning the Principal it was issued to, whose
+// roles are the ones of its "roles" claim and whose tenant is the one of its
+// "tenant" claim. It returns an error wrapping
+// ErrInvalidToken if token is not valid.
+func (v *Verifier) Verify(token string) (Principal, error) {
+	c, err := v.claims(token)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: c.Subject, Roles: c.Roles, Tenant: c.Tenant}, nil
+}
+
+// claims verifies token, returning its claims.
+func (v *Verifier) claims(token string) (claims, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(token, &c, v.keyfunc); err != nil {
+		return claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return c, nil
+}
+
+// Middleware returns an http.Handler that authenticates the requests bearing
+// a token verified by v, passing them to next with their Principal in their
+// context. Requests without a token are passed to next unauthenticated, while
+// requests with an invalid token are responded with 401 Unauthorized.
+func Middleware(v *Verifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			unauthorized(w, "malformed authorization header")
+			return
+		}
+		p, err := v.Verify(token)
+		if err != nil {
+			unauthorized(w, "invalid token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+	})
+}
+
+// RequireRole returns an http.Handler that passes to next the requests
+// authenticated as a Principal having role. Unauthenticated requests are
+// responded with 401 Unauthorized, while requests of a Principal lacking role
+// are responded with 403 Forbidden.
+func RequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := FromContext(r.Context())
+		if !ok {
+			unauthorized(w, "authentication required")
+			return
+		}
+		if !p.HasRole(role) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"message": "missing role " + role})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unauthorized responds with 401 Unauthorized and msg as its message.
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"message": msg})
+}
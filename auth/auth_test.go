@@ -0,0 +1,259 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// sign returns a token of claims signed with method and key.
+func sign(t *testing.T, method jwt.SigningMethod, key any, header map[string]any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	for name, value := range header {
+		token.Header[name] = value
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	verifier := auth.NewHS256Verifier(secret, auth.VerifierOptions{Issuer: "catalog-tests"})
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := map[string]struct {
+		token         string
+		principalWant auth.Principal
+		errWant       error
+	}{
+		"happy path": {
+			token: sign(t, jwt.SigningMethodHS256, secret, nil, jwt.MapClaims{
+				"sub": "jtohru", "iss": "catalog-tests", "exp": exp, "roles": []string{"editor"},
+			}),
+			principalWant: auth.Principal{Subject: "jtohru", Roles: []string{"editor"}},
+		},
+		"wrong secret": {
+			token: sign(t, jwt.SigningMethodHS256, []byte("wrong"), nil, jwt.MapClaims{
+				"sub": "jtohru", "iss": "catalog-tests", "exp": exp,
+			}),
+			errWant: auth.ErrInvalidToken,
+		},
+		"expired": {
+			token: sign(t, jwt.SigningMethodHS256, secret, nil, jwt.MapClaims{
+				"sub": "jtohru", "iss": "catalog-tests", "exp": time.Now().Add(-time.Hour).Unix(),
+			}),
+			errWant: auth.ErrInvalidToken,
+		},
+		"no expiration": {
+			token: sign(t, jwt.SigningMethodHS256, secret, nil, jwt.MapClaims{
+				"sub": "jtohru", "iss": "catalog-tests",
+			}),
+			errWant: auth.ErrInvalidToken,
+		},
+		"wrong issuer": {
+			token: sign(t, jwt.SigningMethodHS256, secret, nil, jwt.MapClaims{
+				"sub": "jtohru", "iss": "someone-else", "exp": exp,
+			}),
+			errWant: auth.ErrInvalidToken,
+		},
+		"unexpected signing method": {
+			token: sign(t, jwt.SigningMethodHS512, secret, nil, jwt.MapClaims{
+				"sub": "jtohru", "iss": "catalog-tests", "exp": exp,
+			}),
+			errWant: auth.ErrInvalidToken,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			p, err := verifier.Verify(test.token)
+
+			assert.Equal(t, test.principalWant, p)
+			assert.ErrorIs(t, err, test.errWant)
+		})
+	}
+}
+
+func TestNewJWKSVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwksServer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	verifier, err := auth.NewJWKSVerifier(ctx, jwksServer.URL, time.Hour, auth.VerifierOptions{}, func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	t.Run("happy path", func(t *testing.T) {
+		token := sign(t, jwt.SigningMethodRS256, key, map[string]any{"kid": "key-1"}, jwt.MapClaims{
+			"sub": "jtohru", "exp": exp, "roles": []string{"admin"},
+		})
+
+		p, err := verifier.Verify(token)
+
+		assert.Nil(t, err)
+		assert.Equal(t, auth.Principal{Subject: "jtohru", Roles: []string{"admin"}}, p)
+	})
+
+	t.Run("unknown key id", func(t *testing.T) {
+		token := sign(t, jwt.SigningMethodRS256, key, map[string]any{"kid": "key-2"}, jwt.MapClaims{
+			"sub": "jtohru", "exp": exp,
+		})
+
+		_, err := verifier.Verify(token)
+
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	verifier := auth.NewHS256Verifier(secret, auth.VerifierOptions{})
+	validToken := sign(t, jwt.SigningMethodHS256, secret, nil, jwt.MapClaims{
+		"sub": "jtohru", "exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"reader"},
+	})
+	tests := map[string]struct {
+		authorization    string
+		statusCodeWant   int
+		principalWant    *auth.Principal
+		responseBodyWant string
+	}{
+		"no token": {
+			statusCodeWant: http.StatusOK,
+		},
+		"malformed authorization header": {
+			authorization:    "Basic am9objpwYXNz",
+			statusCodeWant:   http.StatusUnauthorized,
+			responseBodyWant: `{"message": "malformed authorization header"}`,
+		},
+		"invalid token": {
+			authorization:    "Bearer not-a-token",
+			statusCodeWant:   http.StatusUnauthorized,
+			responseBodyWant: `{"message": "invalid token"}`,
+		},
+		"happy path": {
+			authorization:  "Bearer " + validToken,
+			statusCodeWant: http.StatusOK,
+			principalWant:  &auth.Principal{Subject: "jtohru", Roles: []string{"reader"}},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var principal *auth.Principal
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if p, ok := auth.FromContext(r.Context()); ok {
+					principal = &p
+				}
+			})
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			auth.Middleware(verifier, next).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.Equal(t, test.principalWant, principal)
+			if test.responseBodyWant != "" {
+				assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrincipal_HasRole(t *testing.T) {
+	tests := map[string]struct {
+		roles []string
+		role  string
+		want  bool
+	}{
+		"granted role":           {roles: []string{"editor"}, role: "editor", want: true},
+		"granted by higher role": {roles: []string{"admin"}, role: "reader", want: true},
+		"lower role only":        {roles: []string{"reader"}, role: "editor", want: false},
+		"unknown granted role":   {roles: []string{"superuser"}, role: "reader", want: false},
+		"unknown required role":  {roles: []string{"admin"}, role: "superuser", want: false},
+		"no roles":               {role: "reader", want: false},
+		"one of many roles":      {roles: []string{"auditor", "editor"}, role: "editor", want: true},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			p := auth.Principal{Subject: "jtohru", Roles: test.roles}
+
+			assert.Equal(t, test.want, p.HasRole(test.role))
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := map[string]struct {
+		principal        *auth.Principal
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"unauthenticated": {
+			statusCodeWant:   http.StatusUnauthorized,
+			responseBodyWant: `{"message": "authentication required"}`,
+		},
+		"missing role": {
+			principal:        &auth.Principal{Subject: "jtohru", Roles: []string{"reader"}},
+			statusCodeWant:   http.StatusForbidden,
+			responseBodyWant: `{"message": "missing role editor"}`,
+		},
+		"happy path": {
+			principal:      &auth.Principal{Subject: "jtohru", Roles: []string{"admin"}},
+			statusCodeWant: http.StatusNoContent,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/albums", nil)
+			if test.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), *test.principal))
+			}
+
+			auth.RequireRole(auth.RoleEditor, next).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			if test.responseBodyWant != "" {
+				assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			}
+		})
+	}
+}
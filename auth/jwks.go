@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwks is a JSON Web Key Set.
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk is a JSON Web Key. Only the fields of RSA public keys are decoded.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// publicKey returns the RSA public key of k.
+func (k jwk) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("decoding modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("decoding exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// keySet holds the RSA public keys of a JWKS URL by their key IDs.
+type keySet struct {
+	url    string
+	client *http.Client
+	mu     sync.RWMutex
+	keys   map[string]*rsa.PublicKey
+}
+
+// refresh replaces the keys of s with the ones served at its URL.
+func (s *keySet) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching jwks: unexpected status %s", resp.Status)
+	}
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding jwks: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("decoding jwk %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// keyfunc returns the key of s whose ID is the "kid" header of token.
+func (s *keySet) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown key id")
+	}
+	return key, nil
+}
+
+// NewJWKSVerifier returns a new Verifier of the tokens signed with RS256 and
+// any of the keys of the JSON Web Key Set served at url. The keys are fetched
+// at once, failing if they cannot be, and then refreshed once every
+// refreshInterval until ctx is done. Refresh failures are reported to onError
+// and keep the previous keys.
+func NewJWKSVerifier(
+	ctx context.Context,
+	url string,
+	refreshInterval time.Duration,
+	opts VerifierOptions,
+	onError func(error),
+) (*Verifier, error) {
+	set := &keySet{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := set.refresh(ctx); err != nil {
+		return nil, err
+	}
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := set.refresh(ctx); err != nil {
+					onError(err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return newVerifier(jwt.SigningMethodRS256, set.keyfunc, opts), nil
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig configures an OIDCProvider.
+type OIDCConfig struct {
+	// IssuerURL is the URL of the OpenID Connect issuer, whose configuration
+	// is discovered at IssuerURL/.well-known/openid-configuration.
+	IssuerURL string
+	// ClientID identifies the application to the issuer. It is also the
+	// audience required of the verified tokens.
+	ClientID string
+	// ClientSecret authenticates the application to the issuer. It may be
+	// empty for public clients, which rely on PKCE alone.
+	ClientSecret string
+	// RedirectURL is the URL the issuer redirects to after a login, which
+	// must be served by the CallbackHandler.
+	RedirectURL string
+}
+
+// discovery is the OpenID Connect provider metadata.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider logs users in with an OpenID Connect issuer through the
+// authorization code flow with PKCE, and verifies the ID tokens it issues.
+type OIDCProvider struct {
+	oauth2   oauth2.Config
+	verifier *Verifier
+}
+
+// NewOIDCProvider returns a new OIDCProvider of the issuer configured by cfg,
+// discovering its endpoints at once. The keys of the issuer are refreshed as
+// NewJWKSVerifier does.
+func NewOIDCProvider(
+	ctx context.Context,
+	cfg OIDCConfig,
+	refreshInterval time.Duration,
+	onError func(error),
+) (*OIDCProvider, error) {
+	url := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("discovering oidc issuer: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovering oidc issuer: unexpected status %s", resp.Status)
+	}
+	var d discovery
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, fmt.Errorf("decoding oidc discovery: %w", err)
+	}
+	verifier, err := NewJWKSVerifier(ctx, d.JWKSURI, refreshInterval, VerifierOptions{
+		Issuer:   d.Issuer,
+		Audience: cfg.ClientID,
+	}, onError)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCProvider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  d.AuthorizationEndpoint,
+				TokenURL: d.TokenEndpoint,
+			},
+			Scopes: []string{"openid", "profile", "email"},
+		},
+		verifier: verifier,
+	}, nil
+}
+
+// Verifier returns the Verifier of the ID tokens issued to the application.
+func (p *OIDCProvider) Verifier() *Verifier {
+	return p.verifier
+}
+
+// The names of the cookies holding the state of a login until its callback.
+const (
+	stateCookie    = "oidc_state"
+	verifierCookie = "oidc_verifier"
+)
+
+// LoginHandler returns an http.Handler that starts a login by redirecting to
+// the authorization endpoint of the issuer.
+func (p *OIDCProvider) LoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := oauth2.GenerateVerifier()
+		verifier := oauth2.GenerateVerifier()
+		setLoginCookie(w, stateCookie, state, 10*60)
+		setLoginCookie(w, verifierCookie, verifier, 10*60)
+		url := p.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+		http.Redirect(w, r, url, http.StatusFound)
+	})
+}
+
+// loginResponse is the response to a successful login.
+type loginResponse struct {
+	IDToken   string    `json:"id_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CallbackHandler returns an http.Handler that finishes a login by exchanging
+// the authorization code the issuer redirected with for an ID token, which it
+// responds with to be sent as bearer token to the API.
+func (p *OIDCProvider) CallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := r.Cookie(stateCookie)
+		if err != nil || state.Value != r.URL.Query().Get("state") {
+			unauthorized(w, "invalid login state")
+			return
+		}
+		verifier, err := r.Cookie(verifierCookie)
+		if err != nil {
+			unauthorized(w, "invalid login state")
+			return
+		}
+		setLoginCookie(w, stateCookie, "", -1)
+		setLoginCookie(w, verifierCookie, "", -1)
+		if errMsg := r.URL.Query().Get("error"); errMsg != "" {
+			unauthorized(w, "login failed: "+errMsg)
+			return
+		}
+		token, err := p.oauth2.Exchange(r.Context(), r.URL.Query().Get("code"), oauth2.VerifierOption(verifier.Value))
+		if err != nil {
+			unauthorized(w, "exchanging authorization code failed")
+			return
+		}
+		idToken, ok := token.Extra("id_token").(string)
+		if !ok {
+			unauthorized(w, "no id token issued")
+			return
+		}
+		claims, err := p.verifier.claims(idToken)
+		if err != nil {
+			unauthorized(w, "invalid token")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(loginResponse{
+			IDToken:   idToken,
+			ExpiresAt: claims.ExpiresAt.UTC(),
+		})
+	})
+}
+
+// setLoginCookie sets the login cookie named name to value for maxAge
+// seconds, or deletes it if maxAge is negative.
+func setLoginCookie(w http.ResponseWriter, name, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
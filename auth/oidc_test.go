@@ -0,0 +1,146 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+)
+
+// newIssuer starts a fake OpenID Connect issuer whose token endpoint issues
+// ID tokens to clientID for the authorization code "code".
+func newIssuer(t *testing.T, clientID string) *httptest.Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	issuer := httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "code" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idToken := sign(t, jwt.SigningMethodRS256, key, map[string]any{"kid": "key-1"}, jwt.MapClaims{
+			"iss": issuer.URL,
+			"aud": clientID,
+			"sub": "jtohru",
+			"exp": time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	return issuer
+}
+
+func TestOIDCProvider(t *testing.T) {
+	issuer := newIssuer(t, "catalog")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider, err := auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+		IssuerURL:   issuer.URL,
+		ClientID:    "catalog",
+		RedirectURL: "https://catalog.example.com/auth/callback",
+	}, time.Hour, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// login starts a login, returning its redirect URL and cookies.
+	login := func(t *testing.T) (*url.URL, []*http.Cookie) {
+		rec := httptest.NewRecorder()
+		provider.LoginHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+		location, err := url.Parse(rec.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return location, rec.Result().Cookies()
+	}
+
+	t.Run("login redirect", func(t *testing.T) {
+		location, cookies := login(t)
+
+		query := location.Query()
+		assert.Equal(t, issuer.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+		assert.Equal(t, "catalog", query.Get("client_id"))
+		assert.Equal(t, "S256", query.Get("code_challenge_method"))
+		assert.NotEmpty(t, query.Get("code_challenge"))
+		assert.NotEmpty(t, query.Get("state"))
+		assert.Len(t, cookies, 2)
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		_, cookies := login(t)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/auth/callback?code=code&state=forged", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		provider.CallbackHandler().ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Result().StatusCode)
+		assert.JSONEq(t, `{"message": "invalid login state"}`, rec.Body.String())
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		location, cookies := login(t)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/auth/callback?code=code&state="+location.Query().Get("state"), nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		provider.CallbackHandler().ServeHTTP(rec, req)
+
+		var resp struct {
+			IDToken   string    `json:"id_token"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+		assert.Equal(t, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), resp.ExpiresAt)
+		p, err := provider.Verifier().Verify(resp.IDToken)
+		assert.Nil(t, err)
+		assert.Equal(t, "jtohru", p.Subject)
+	})
+}
@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrBlobNotFound is returned by a BlobStorage when there is no blob of a key.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Blob is a binary object kept by a BlobStorage.
+type Blob struct {
+	ContentType string
+	Size        int64
+	// Body reads the content of the blob. It must be closed by the caller.
+	Body io.ReadCloser
+}
+
+// BlobStorage represents a storage of binary objects, such as the cover art of
+// the albums, identified by keys.
+//
+// Implementations must be safe for concurrent use.
+type BlobStorage interface {
+	// Put stores the content read from body as the blob of key, of
+	// contentType, replacing the previous one, if any.
+	Put(ctx context.Context, key, contentType string, body io.Reader) error
+	// Get returns the blob of key, or ErrBlobNotFound if there is none.
+	Get(ctx context.Context, key string) (Blob, error)
+}
+
+// MemoryBlobStorage is a BlobStorage that keeps the blobs in memory, so they
+// are lost when the instance stops and are not shared with other instances.
+type MemoryBlobStorage struct {
+	mu    sync.RWMutex
+	blobs map[string]memoryBlob
+}
+
+type memoryBlob struct {
+	contentType string
+	content     []byte
+}
+
+// NewMemoryBlobStorage returns a new empty MemoryBlobStorage.
+func NewMemoryBlobStorage() *MemoryBlobStorage {
+	return &MemoryBlobStorage{blobs: make(map[string]memoryBlob)}
+}
+
+func (s *MemoryBlobStorage) Put(_ context.Context, key, contentType string, body io.Reader) error {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = memoryBlob{contentType: contentType, content: content}
+	return nil
+}
+
+func (s *MemoryBlobStorage) Get(_ context.Context, key string) (Blob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	blob, ok := s.blobs[key]
+	if !ok {
+		return Blob{}, ErrBlobNotFound
+	}
+	return Blob{
+		ContentType: blob.contentType,
+		Size:        int64(len(blob.content)),
+		Body:        io.NopCloser(bytes.NewReader(blob.content)),
+	}, nil
+}
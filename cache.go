@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+// CachedAlbumStorage is an AlbumStorage that keeps the most recently found
+// Albums in memory. The cached Albums are evicted by their ID whenever the
+// underlying storage is written to through it. Writes made to the underlying
+// storage by anyone else are only seen once the cached Albums expire.
+type CachedAlbumStorage struct {
+	AlbumStorage
+
+	size  int
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	lru     *list.List // of *cacheEntry, most recently used first
+	// generation is incremented on every eviction, so that a FindOne racing
+	// with a write does not cache the Album read before the write.
+	generation uint64
+	hits       uint64
+	misses     uint64
+}
+
+type cacheEntry struct {
+	alb       Album
+	expiresAt time.Time
+}
+
+// CacheStats are the hit and miss counters of a CachedAlbumStorage.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// NewCachedAlbumStorage returns a new CachedAlbumStorage that caches up to size
+// Albums found in storage for ttl each.
+func NewCachedAlbumStorage(storage AlbumStorage, size int, ttl time.Duration) *CachedAlbumStorage {
+	return &CachedAlbumStorage{
+		AlbumStorage: storage,
+		size:         size,
+		ttl:          ttl,
+		clock:        clock.System,
+		entries:      make(map[uuid.UUID]*list.Element, size),
+		lru:          list.New(),
+	}
+}
+
+// Stats returns the hit and miss counters of FindOne.
+func (s *CachedAlbumStorage) Stats() CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return CacheStats{Hits: s.hits, Misses: s.misses}
+}
+
+func (s *CachedAlbumStorage) FindOne(ctx context.Context, id uuid.UUID) (Album, error) {
+	s.mu.Lock()
+	if elem, ok := s.entries[id]; ok {
+		entry := elem.Value.(*cacheEntry)
+		switch {
+		case entry.alb.TenantID != TenantFromContext(ctx):
+			// The Album is left to the storage, which does not find it for
+			// other tenants.
+		case s.clock.Now().Before(entry.expiresAt):
+			s.lru.MoveToFront(elem)
+			s.hits++
+			s.mu.Unlock()
+			return entry.alb, nil
+		default:
+			s.remove(elem)
+		}
+	}
+	s.misses++
+	generation := s.generation
+	s.mu.Unlock()
+
+	alb, err := s.AlbumStorage.FindOne(ctx, id)
+	if err != nil {
+		return Album{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation == generation {
+		s.add(alb)
+	}
+	return alb, nil
+}
+
+func (s *CachedAlbumStorage) Update(ctx context.Context, alb Album) error {
+	defer s.evict(alb.ID)
+	return s.AlbumStorage.Update(ctx, alb)
+}
+
+func (s *CachedAlbumStorage) UpdateFunc(ctx context.Context, id uuid.UUID, update func(Album) Album) (Album, error) {
+	defer s.evict(id)
+	return s.AlbumStorage.UpdateFunc(ctx, id, update)
+}
+
+func (s *CachedAlbumStorage) Upsert(ctx context.Context, alb Album) (Album, bool, error) {
+	defer s.evict(alb.ID)
+	return s.AlbumStorage.Upsert(ctx, alb)
+}
+
+func (s *CachedAlbumStorage) Remove(ctx context.Context, id uuid.UUID) error {
+	defer s.evict(id)
+	return s.AlbumStorage.Remove(ctx, id)
+}
+
+func (s *CachedAlbumStorage) RemoveReturning(ctx context.Context, id uuid.UUID) (Album, error) {
+	defer s.evict(id)
+	return s.AlbumStorage.RemoveReturning(ctx, id)
+}
+
+// evict evicts the cached Album whose ID is equal to id, if any.
+func (s *CachedAlbumStorage) evict(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[id]; ok {
+		s.remove(elem)
+	}
+	s.generation++
+}
+
+// add caches alb, evicting the least recently used Album if the cache is full.
+// It must be called with s.mu held.
+func (s *CachedAlbumStorage) add(alb Album) {
+	if s.size <= 0 {
+		return
+	}
+	if elem, ok := s.entries[alb.ID]; ok {
+		s.remove(elem)
+	}
+	if s.lru.Len() >= s.size {
+		s.remove(s.lru.Back())
+	}
+	s.entries[alb.ID] = s.lru.PushFront(&cacheEntry{
+		alb:       alb,
+		expiresAt: s.clock.Now().Add(s.ttl),
+	})
+}
+
+// remove removes elem from the cache. It must be called with s.mu held.
+func (s *CachedAlbumStorage) remove(elem *list.Element) {
+	entry := s.lru.Remove(elem).(*cacheEntry)
+	delete(s.entries, entry.alb.ID)
+}
@@ -0,0 +1,163 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+func TestCachedAlbumStorage_FindOne(t *testing.T) {
+	// newCache returns a CachedAlbumStorage of size over a storage holding albs
+	// and the number of times the storage FindOne was called.
+	newCache := func(size int, albs ...Album) (*CachedAlbumStorage, *int) {
+		var calls int
+		storage := &storageSpy{}
+		storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+			calls++
+			for _, alb := range albs {
+				if alb.ID == id && alb.TenantID == TenantFromContext(ctx) {
+					return alb, nil
+				}
+			}
+			return Album{}, ErrAlbumNotFound
+		}
+		return NewCachedAlbumStorage(storage, size, time.Minute), &calls
+	}
+
+	t.Run("album not found", func(t *testing.T) {
+		cache, calls := newCache(10)
+		id := uuid.New()
+
+		for range 2 {
+			alb, err := cache.FindOne(context.Background(), id)
+
+			assert.Empty(t, alb)
+			assert.ErrorIs(t, err, ErrAlbumNotFound)
+		}
+		assert.Equal(t, 2, *calls)
+		assert.Equal(t, CacheStats{Misses: 2}, cache.Stats())
+	})
+
+	t.Run("cache hit", func(t *testing.T) {
+		want := randomAlbum()
+		cache, calls := newCache(10, want)
+
+		for range 3 {
+			alb, err := cache.FindOne(context.Background(), want.ID)
+
+			assert.Nil(t, err)
+			assert.Equal(t, want, alb)
+		}
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 1}, cache.Stats())
+	})
+
+	t.Run("album of another tenant", func(t *testing.T) {
+		want := randomAlbum()
+		want.TenantID = "acme"
+		cache, calls := newCache(10, want)
+		cache.FindOne(NewTenantContext(context.Background(), "acme"), want.ID)
+
+		alb, err := cache.FindOne(NewTenantContext(context.Background(), "globex"), want.ID)
+
+		assert.Empty(t, alb)
+		assert.ErrorIs(t, err, ErrAlbumNotFound)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("expired album", func(t *testing.T) {
+		want := randomAlbum()
+		cache, calls := newCache(10, want)
+		fake := clock.NewFake(time.Now())
+		cache.clock = fake
+		cache.FindOne(context.Background(), want.ID)
+		fake.Advance(time.Minute)
+
+		alb, err := cache.FindOne(context.Background(), want.ID)
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, alb)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("least recently used album evicted", func(t *testing.T) {
+		albs := randomAlbums(3)
+		cache, calls := newCache(2, albs...)
+		cache.FindOne(context.Background(), albs[0].ID)
+		cache.FindOne(context.Background(), albs[1].ID)
+		cache.FindOne(context.Background(), albs[0].ID)
+		cache.FindOne(context.Background(), albs[2].ID) // evicts albs[1]
+
+		cache.FindOne(context.Background(), albs[0].ID)
+		cache.FindOne(context.Background(), albs[1].ID)
+
+		assert.Equal(t, 4, *calls)
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 4}, cache.Stats())
+	})
+}
+
+func TestCachedAlbumStorage_writes(t *testing.T) {
+	tests := map[string]func(cache *CachedAlbumStorage, alb Album) error{
+		"Update": func(cache *CachedAlbumStorage, alb Album) error {
+			return cache.Update(context.Background(), alb)
+		},
+		"UpdateFunc": func(cache *CachedAlbumStorage, alb Album) error {
+			_, err := cache.UpdateFunc(context.Background(), alb.ID, func(alb Album) Album { return alb })
+			return err
+		},
+		"Upsert": func(cache *CachedAlbumStorage, alb Album) error {
+			_, _, err := cache.Upsert(context.Background(), alb)
+			return err
+		},
+		"Remove": func(cache *CachedAlbumStorage, alb Album) error {
+			return cache.Remove(context.Background(), alb.ID)
+		},
+		"RemoveReturning": func(cache *CachedAlbumStorage, alb Album) error {
+			_, err := cache.RemoveReturning(context.Background(), alb.ID)
+			return err
+		},
+	}
+	for testName, write := range tests {
+		t.Run(testName, func(t *testing.T) {
+			for _, writeErr := range []error{nil, fmt.Errorf("unexpected write error")} {
+				alb := randomAlbum()
+				var calls int
+				storage := &storageSpy{
+					findOne: func(context.Context, uuid.UUID) (Album, error) {
+						calls++
+						return alb, nil
+					},
+					update: func(context.Context, Album) error {
+						return writeErr
+					},
+					updateFunc: func(context.Context, uuid.UUID, func(Album) Album) (Album, error) {
+						return alb, writeErr
+					},
+					upsert: func(context.Context, Album) (Album, bool, error) {
+						return alb, false, writeErr
+					},
+					remove: func(context.Context, uuid.UUID) error {
+						return writeErr
+					},
+					removeReturning: func(context.Context, uuid.UUID) (Album, error) {
+						return alb, writeErr
+					},
+				}
+				cache := NewCachedAlbumStorage(storage, 10, time.Minute)
+				cache.FindOne(context.Background(), alb.ID)
+
+				err := write(cache, alb)
+				cache.FindOne(context.Background(), alb.ID)
+
+				assert.Equal(t, writeErr, err)
+				assert.Equal(t, 2, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// Package catalogpb contains the protobuf messages and the gRPC service of the
+// album catalog, generated from album.proto.
+package catalogpb
+
+//go:generate buf generate
@@ -0,0 +1,69 @@
+// Package civil provides the Date the albums of the catalog are released on,
+// a calendar date without a time of day or a time zone.
+package civil
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout is the time layout of the text representation of a Date.
+const dateLayout = "2006-01-02"
+
+// Date is a calendar date. Its text and JSON representation is the ISO 8601
+// one, such as "1997-05-21".
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the Date t is on, in the location of t.
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a Date from its text representation.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("parsing date: %w", err)
+	}
+	return DateOf(t), nil
+}
+
+// String returns the text representation of d.
+func (d Date) String() string {
+	return d.In(time.UTC).Format(dateLayout)
+}
+
+// In returns the time d starts at in loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Before reports whether d is before other.
+func (d Date) Before(other Date) bool {
+	return d.In(time.UTC).Before(other.In(time.UTC))
+}
+
+// After reports whether d is after other.
+func (d Date) After(other Date) bool {
+	return other.Before(d)
+}
+
+// MarshalText encodes d into its text representation.
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes d from its text representation.
+func (d *Date) UnmarshalText(data []byte) error {
+	parsed, err := ParseDate(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,62 @@
+package civil_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/civil"
+)
+
+func TestDate_JSON(t *testing.T) {
+	data, err := json.Marshal(civil.Date{Year: 1997, Month: time.May, Day: 21})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `"1997-05-21"`, string(data))
+
+	tests := map[string]struct {
+		data     string
+		dateWant civil.Date
+		wantErr  bool
+	}{
+		"date":          {data: `"1997-05-21"`, dateWant: civil.Date{Year: 1997, Month: time.May, Day: 21}},
+		"time":          {data: `"1997-05-21T00:00:00Z"`, wantErr: true},
+		"invalid day":   {data: `"1997-02-30"`, wantErr: true},
+		"not a string":  {data: `19970521`, wantErr: true},
+		"empty string":  {data: `""`, wantErr: true},
+		"single digits": {data: `"1997-5-21"`, wantErr: true},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var d civil.Date
+
+			err := json.Unmarshal([]byte(test.data), &d)
+
+			if test.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.dateWant, d)
+		})
+	}
+}
+
+func TestDateOf(t *testing.T) {
+	loc := time.FixedZone("UTC-3", -3*60*60)
+	tm := time.Date(1997, time.May, 21, 23, 0, 0, 0, loc)
+
+	assert.Equal(t, civil.Date{Year: 1997, Month: time.May, Day: 21}, civil.DateOf(tm))
+	assert.Equal(t, civil.Date{Year: 1997, Month: time.May, Day: 22}, civil.DateOf(tm.UTC()))
+}
+
+func TestDate_Before(t *testing.T) {
+	d := civil.Date{Year: 1997, Month: time.May, Day: 21}
+
+	assert.True(t, d.Before(civil.Date{Year: 1997, Month: time.May, Day: 22}))
+	assert.False(t, d.Before(d))
+	assert.True(t, d.After(civil.Date{Year: 1996, Month: time.December, Day: 31}))
+	assert.False(t, d.After(d))
+}
@@ -0,0 +1,303 @@
+// Package client is a client of the album catalog HTTP API.
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// Album is an album of the catalog.
+type Album struct {
+	ID        uuid.UUID   `json:"id"`
+	Title     string      `json:"title"`
+	Artist    string      `json:"artist"`
+	Price     money.Money `json:"price"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// Version is incremented every time the album is updated.
+	Version   int    `json:"version"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+	Artwork   string `json:"artwork,omitempty"`
+}
+
+// AlbumInput is the data an album is created or updated with.
+type AlbumInput struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	// Price is priced in the default currency of the catalog if it has no
+	// currency.
+	Price money.Money `json:"price"`
+	// Version is the album version an update is based on, failing it with a
+	// conflict if the album was updated since. Zero means the update is not
+	// checked against the stored version.
+	Version int `json:"version,omitempty"`
+}
+
+// Error is an error response of the API.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	Message    string `json:"message"`
+	// Problems are the problems of each invalid field of the request, if
+	// any.
+	Problems map[string]string `json:"problems"`
+	// RetryAfter is how long the API asks to wait before retrying the
+	// request, by the Retry-After header of the response, if any.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// Error makes Error implement error.
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return e.Message
+}
+
+// API is the album catalog API, which Client implements through HTTP. Code
+// depending on API rather than on Client can be tested with a fake of it,
+// such as the one of package clienttest.
+type API interface {
+	ListAlbums(ctx context.Context, pageSize, pageNumber int) ([]Album, error)
+	ListAll(ctx context.Context, filter Filter) iter.Seq2[Album, error]
+	CreateAlbum(ctx context.Context, in AlbumInput) (Album, error)
+	GetAlbum(ctx context.Context, id uuid.UUID) (Album, error)
+	UpdateAlbum(ctx context.Context, id uuid.UUID, in AlbumInput) (Album, error)
+	DeleteAlbum(ctx context.Context, id uuid.UUID) (Album, error)
+}
+
+// Client is a client of the album catalog HTTP API. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	// retry is how the requests that fail transiently are retried, which
+	// they are not by default.
+	retry RetryPolicy
+}
+
+// New returns a new Client of the API at baseURL that authenticates with the
+// bearer token apiKey, if not empty, requesting it through client.
+func New(baseURL, apiKey string, client *http.Client, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: apiKey, client: client}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// MaxPageSize is the greatest number of albums the API lists at once.
+const MaxPageSize = 50
+
+// maxRateLimitWaits is how many times in a row ListAll waits for the rate
+// limit of the API to let a page be listed before giving up.
+const maxRateLimitWaits = 5
+
+// Filter selects the albums listed by ListAll.
+type Filter struct {
+	// PageSize is how many albums are requested at once. Zero means
+	// MaxPageSize.
+	PageSize int
+	// Fields are the fields the albums are restricted to, such as "id" and
+	// "title". Empty means every field.
+	Fields []string
+}
+
+// ListAlbums returns the albums of the page pageNumber, starting from 1, of
+// the catalog split into pages of pageSize albums.
+func (c *Client) ListAlbums(ctx context.Context, pageSize, pageNumber int) ([]Album, error) {
+	return c.listAlbums(ctx, pageSize, pageNumber, nil)
+}
+
+// ListAll returns an iterator over every album of the catalog selected by
+// filter, which lists them page by page as it is ranged over. When the rate
+// limit of the API is exceeded, the page is requested again after the time
+// asked by the API, of at least a second. The iteration stops after the first
+// error.
+func (c *Client) ListAll(ctx context.Context, filter Filter) iter.Seq2[Album, error] {
+	pageSize := cmp.Or(filter.PageSize, MaxPageSize)
+	return func(yield func(Album, error) bool) {
+		for pageNumber := 1; ; pageNumber++ {
+			albs, err := c.listAlbums(ctx, pageSize, pageNumber, filter.Fields)
+			for waits := 0; waits < maxRateLimitWaits; waits++ {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+					break
+				}
+				if err = sleep(ctx, max(apiErr.RetryAfter, time.Second)); err != nil {
+					break
+				}
+				albs, err = c.listAlbums(ctx, pageSize, pageNumber, filter.Fields)
+			}
+			if err != nil {
+				yield(Album{}, err)
+				return
+			}
+			for _, alb := range albs {
+				if !yield(alb, nil) {
+					return
+				}
+			}
+			if len(albs) < pageSize {
+				return
+			}
+		}
+	}
+}
+
+// listAlbums returns the albums of the page pageNumber of the catalog split
+// into pages of pageSize albums, restricted to fields if not empty.
+func (c *Client) listAlbums(ctx context.Context, pageSize, pageNumber int, fields []string) ([]Album, error) {
+	q := url.Values{
+		"page_size":   []string{strconv.Itoa(pageSize)},
+		"page_number": []string{strconv.Itoa(pageNumber)},
+	}
+	if len(fields) > 0 {
+		q.Set("fields", strings.Join(fields, ","))
+	}
+	var albs []Album
+	if err := c.do(ctx, http.MethodGet, "/albums?"+q.Encode(), nil, &albs); err != nil {
+		return nil, err
+	}
+	return albs, nil
+}
+
+// CreateAlbum creates an album with in.
+func (c *Client) CreateAlbum(ctx context.Context, in AlbumInput) (Album, error) {
+	var alb Album
+	err := c.do(ctx, http.MethodPost, "/albums", in, &alb)
+	return alb, err
+}
+
+// GetAlbum returns the album identified by id.
+func (c *Client) GetAlbum(ctx context.Context, id uuid.UUID) (Album, error) {
+	var alb Album
+	err := c.do(ctx, http.MethodGet, "/albums/"+id.String(), nil, &alb)
+	return alb, err
+}
+
+// UpdateAlbum updates the album identified by id with in, returning the
+// updated album.
+func (c *Client) UpdateAlbum(ctx context.Context, id uuid.UUID, in AlbumInput) (Album, error) {
+	var alb Album
+	err := c.do(ctx, http.MethodPut, "/albums/"+id.String(), in, &alb)
+	return alb, err
+}
+
+// DeleteAlbum deletes the album identified by id, returning the deleted
+// album.
+func (c *Client) DeleteAlbum(ctx context.Context, id uuid.UUID) (Album, error) {
+	var alb Album
+	err := c.do(ctx, http.MethodDelete, "/albums/"+id.String(), nil, &alb)
+	return alb, err
+}
+
+// do requests path with method, sending in as the JSON request body if not
+// nil and decoding the JSON response body into out. A response with a status
+// code other than 2xx is returned as an *Error. The request is retried as the
+// RetryPolicy of c describes.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encoding json: %w", err)
+		}
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := c.send(ctx, method, path, body, out)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		wait, ok := c.retry.backoff(method, attempt, time.Since(start), err)
+		if !ok {
+			return err
+		}
+		if c.retry.OnRetry != nil {
+			c.retry.OnRetry(method, path, attempt, err, wait)
+		}
+		if sleep(ctx, wait) != nil {
+			return err
+		}
+	}
+}
+
+// send sends a single request to path with method and the JSON request body
+// body, if not nil, decoding the JSON response body into out.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		// The body of an error response is decoded at best effort, as it
+		// may not come from the API, such as one of a proxy.
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(apiErr)
+		return apiErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding json: %w", err)
+	}
+	return nil
+}
+
+// parseRetryAfter returns the time to wait asked by the Retry-After header
+// value v, either a number of seconds or an HTTP date, or zero if it is not
+// valid or is in the past.
+func parseRetryAfter(v string) time.Duration {
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// sleep waits for d, or returns the error of ctx if it is done before.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
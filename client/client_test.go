@@ -0,0 +1,218 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/client"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+func TestClientListAlbums(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/albums", r.URL.Path)
+		assert.Equal(t, "20", r.URL.Query().Get("page_size"))
+		assert.Equal(t, "2", r.URL.Query().Get("page_number"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Write([]byte(`[{
+			"id": "5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d",
+			"title": "Sobrevivendo no Inferno",
+			"artist": "Racionais MC's",
+			"price": {"amount": 4999, "currency": "BRL"},
+			"created_at": "2024-08-01T10:00:00Z",
+			"updated_at": "2024-08-02T10:00:00Z",
+			"version": 2
+		}]`))
+	}))
+	defer api.Close()
+	c := client.New(api.URL+"/", "secret", api.Client())
+
+	albs, err := c.ListAlbums(context.Background(), 20, 2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []client.Album{
+		{
+			ID:        uuid.MustParse("5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d"),
+			Title:     "Sobrevivendo no Inferno",
+			Artist:    "Racionais MC's",
+			Price:     money.New(4999, "BRL"),
+			CreatedAt: time.Date(2024, 8, 1, 10, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2024, 8, 2, 10, 0, 0, 0, time.UTC),
+			Version:   2,
+		},
+	}, albs)
+}
+
+func TestClientListAll(t *testing.T) {
+	ids := make([]uuid.UUID, 5)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	requests := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "2", r.URL.Query().Get("page_size"))
+		assert.Equal(t, "id,title", r.URL.Query().Get("fields"))
+		if requests == 2 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message": "rate limit exceeded"}`))
+			return
+		}
+		pageNumber, err := strconv.Atoi(r.URL.Query().Get("page_number"))
+		assert.Nil(t, err)
+		page := []map[string]string{}
+		for _, id := range ids[min((pageNumber-1)*2, len(ids)):min(pageNumber*2, len(ids))] {
+			page = append(page, map[string]string{"id": id.String(), "title": "Nevermind"})
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer api.Close()
+	c := client.New(api.URL, "", api.Client())
+
+	var got []uuid.UUID
+	for alb, err := range c.ListAll(context.Background(), client.Filter{PageSize: 2, Fields: []string{"id", "title"}}) {
+		assert.Nil(t, err)
+		assert.Equal(t, "Nevermind", alb.Title)
+		got = append(got, alb.ID)
+	}
+
+	assert.Equal(t, ids, got)
+	// The rate limited page is requested again.
+	assert.Equal(t, 4, requests)
+}
+
+func TestClientListAll_error(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+	c := client.New(api.URL, "", api.Client())
+
+	var errs []error
+	for _, err := range c.ListAll(context.Background(), client.Filter{}) {
+		errs = append(errs, err)
+	}
+
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "unexpected status 500 Internal Server Error")
+}
+
+func TestClientCreateAlbum(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/albums", r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Empty(t, r.Header.Get("Authorization"))
+			var in map[string]any
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&in))
+			assert.Equal(t, map[string]any{"title": "Nevermind", "artist": "Nirvana", "price": map[string]any{"amount": float64(2999), "currency": "USD"}}, in)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d", "title": "Nevermind", "artist": "Nirvana", "price": {"amount": 2999, "currency": "USD"}, "version": 1}`))
+		}))
+		defer api.Close()
+		c := client.New(api.URL, "", api.Client())
+
+		alb, err := c.CreateAlbum(context.Background(), client.AlbumInput{Title: "Nevermind", Artist: "Nirvana", Price: money.New(2999, "USD")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, client.Album{
+			ID:      uuid.MustParse("5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d"),
+			Title:   "Nevermind",
+			Artist:  "Nirvana",
+			Price:   money.New(2999, "USD"),
+			Version: 1,
+		}, alb)
+	})
+
+	t.Run("api error", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message": "album already exists", "problems": {"title": "is already used by another album of the same artist"}}`))
+		}))
+		defer api.Close()
+		c := client.New(api.URL, "", api.Client())
+
+		_, err := c.CreateAlbum(context.Background(), client.AlbumInput{Title: "Nevermind", Artist: "Nirvana", Price: money.New(2999, "USD")})
+
+		assert.Equal(t, &client.Error{
+			StatusCode: http.StatusConflict,
+			Message:    "album already exists",
+			Problems:   map[string]string{"title": "is already used by another album of the same artist"},
+		}, err)
+	})
+}
+
+func TestClientGetAlbum(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		id := uuid.New()
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/albums/"+id.String(), r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "album not found"}`))
+		}))
+		defer api.Close()
+		c := client.New(api.URL, "", api.Client())
+
+		_, err := c.GetAlbum(context.Background(), id)
+
+		assert.EqualError(t, err, "album not found")
+	})
+
+	t.Run("error response without body", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer api.Close()
+		c := client.New(api.URL, "", api.Client())
+
+		_, err := c.GetAlbum(context.Background(), uuid.New())
+
+		assert.EqualError(t, err, "unexpected status 502 Bad Gateway")
+	})
+}
+
+func TestClientUpdateAlbum(t *testing.T) {
+	id := uuid.New()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/albums/"+id.String(), r.URL.Path)
+		var in map[string]any
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&in))
+		assert.Equal(t, map[string]any{"title": "Nevermind", "artist": "Nirvana", "price": map[string]any{"amount": float64(3999), "currency": "USD"}, "version": float64(1)}, in)
+		w.Write([]byte(`{"id": "` + id.String() + `", "title": "Nevermind", "artist": "Nirvana", "price": {"amount": 3999, "currency": "USD"}, "version": 2}`))
+	}))
+	defer api.Close()
+	c := client.New(api.URL, "", api.Client())
+
+	alb, err := c.UpdateAlbum(context.Background(), id, client.AlbumInput{Title: "Nevermind", Artist: "Nirvana", Price: money.New(3999, "USD"), Version: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, client.Album{ID: id, Title: "Nevermind", Artist: "Nirvana", Price: money.New(3999, "USD"), Version: 2}, alb)
+}
+
+func TestClientDeleteAlbum(t *testing.T) {
+	id := uuid.New()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/albums/"+id.String(), r.URL.Path)
+		w.Write([]byte(`{"id": "` + id.String() + `", "title": "Nevermind", "artist": "Nirvana", "price": {"amount": 3999, "currency": "USD"}, "version": 2}`))
+	}))
+	defer api.Close()
+	c := client.New(api.URL, "", api.Client())
+
+	alb, err := c.DeleteAlbum(context.Background(), id)
+
+	assert.Nil(t, err)
+	assert.Equal(t, client.Album{ID: id, Title: "Nevermind", Artist: "Nirvana", Price: money.New(3999, "USD"), Version: 2}, alb)
+}
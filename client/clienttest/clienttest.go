@@ -0,0 +1,366 @@
+// Package clienttest provides fakes of the album catalog API, for testing the
+// code using package client without a running catalog.
+package clienttest
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/client"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// albumFields are the JSON field names of a client.Album.
+var albumFields = []string{"id", "title", "artist", "price", "created_at", "updated_at", "version", "tenant_id", "created_by", "updated_by", "artwork"}
+
+// Fake is an in-memory fake of the album catalog API, implementing
+// client.API. It validates, stores and lists the albums as the API does,
+// failing with the *client.Error the API responds with. It is safe for
+// concurrent use.
+type Fake struct {
+	mu     sync.Mutex
+	albums map[uuid.UUID]client.Album
+	now    func() time.Time
+}
+
+// NewFake returns a new Fake storing albs.
+func NewFake(albs ...client.Album) *Fake {
+	f := &Fake{albums: make(map[uuid.UUID]client.Album, len(albs)), now: time.Now}
+	for _, alb := range albs {
+		f.albums[alb.ID] = alb
+	}
+	return f
+}
+
+// Albums returns the albums stored by f, in the order the API lists them.
+func (f *Fake) Albums() []client.Album {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	albs := make([]client.Album, 0, len(f.albums))
+	for _, alb := range f.albums {
+		albs = append(albs, alb)
+	}
+	slices.SortFunc(albs, func(a, b client.Album) int {
+		return cmp.Or(
+			strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)),
+			strings.Compare(a.ID.String(), b.ID.String()),
+		)
+	})
+	return albs
+}
+
+// ListAlbums makes Fake implement client.API.
+func (f *Fake) ListAlbums(ctx context.Context, pageSize, pageNumber int) ([]client.Album, error) {
+	switch {
+	case pageSize < 1:
+		return nil, apiError(http.StatusBadRequest, "page size is less than 1", nil)
+	case pageSize > client.MaxPageSize:
+		return nil, apiError(http.StatusBadRequest, fmt.Sprintf("page size is greater than %d", client.MaxPageSize), nil)
+	case pageNumber < 1:
+		return nil, apiError(http.StatusBadRequest, "page number is less than 1", nil)
+	}
+	albs := f.Albums()
+	start := min(pageSize*(pageNumber-1), len(albs))
+	return albs[start:min(start+pageSize, len(albs))], nil
+}
+
+// ListAll makes Fake implement client.API.
+func (f *Fake) ListAll(ctx context.Context, filter client.Filter) iter.Seq2[client.Album, error] {
+	return func(yield func(client.Album, error) bool) {
+		for _, field := range filter.Fields {
+			if !slices.Contains(albumFields, field) {
+				yield(client.Album{}, apiError(http.StatusBadRequest, fmt.Sprintf("unknown field %q", field), nil))
+				return
+			}
+		}
+		for _, alb := range f.Albums() {
+			if len(filter.Fields) > 0 {
+				data, _ := json.Marshal(project(alb, filter.Fields))
+				alb = client.Album{}
+				json.Unmarshal(data, &alb)
+			}
+			if !yield(alb, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CreateAlbum makes Fake implement client.API.
+func (f *Fake) CreateAlbum(ctx context.Context, in client.AlbumInput) (client.Album, error) {
+	if err := validate(in); err != nil {
+		return client.Album{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkUnique(uuid.Nil, in); err != nil {
+		return client.Album{}, err
+	}
+	now := f.now().UTC()
+	alb := client.Album{
+		ID:        uuid.New(),
+		Title:     in.Title,
+		Artist:    in.Artist,
+		Price:     price(in),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+	f.albums[alb.ID] = alb
+	return alb, nil
+}
+
+// GetAlbum makes Fake implement client.API.
+func (f *Fake) GetAlbum(ctx context.Context, id uuid.UUID) (client.Album, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	alb, ok := f.albums[id]
+	if !ok {
+		return client.Album{}, errAlbumNotFound()
+	}
+	return alb, nil
+}
+
+// UpdateAlbum makes Fake implement client.API.
+func (f *Fake) UpdateAlbum(ctx context.Context, id uuid.UUID, in client.AlbumInput) (client.Album, error) {
+	if err := validate(in); err != nil {
+		return client.Album{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	alb, ok := f.albums[id]
+	if !ok {
+		return client.Album{}, errAlbumNotFound()
+	}
+	if in.Version != 0 && in.Version != alb.Version {
+		return client.Album{}, apiError(http.StatusConflict, "album version conflict", nil)
+	}
+	if err := f.checkUnique(id, in); err != nil {
+		return client.Album{}, err
+	}
+	alb.Title = in.Title
+	alb.Artist = in.Artist
+	alb.Price = price(in)
+	alb.UpdatedAt = f.now().UTC()
+	alb.Version++
+	f.albums[id] = alb
+	return alb, nil
+}
+
+// DeleteAlbum makes Fake implement client.API.
+func (f *Fake) DeleteAlbum(ctx context.Context, id uuid.UUID) (client.Album, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	alb, ok := f.albums[id]
+	if !ok {
+		return client.Album{}, errAlbumNotFound()
+	}
+	delete(f.albums, id)
+	return alb, nil
+}
+
+// checkUnique returns the error of the API if the artist and title of in are
+// used by an album other than the one identified by id.
+func (f *Fake) checkUnique(id uuid.UUID, in client.AlbumInput) error {
+	for _, alb := range f.albums {
+		if alb.ID != id && strings.EqualFold(alb.Artist, in.Artist) && strings.EqualFold(alb.Title, in.Title) {
+			return apiError(http.StatusConflict, "album already exists", map[string]string{
+				"title": "is already used by another album of the same artist",
+			})
+		}
+	}
+	return nil
+}
+
+// validate returns the error of the API if in is not valid.
+func validate(in client.AlbumInput) error {
+	problems := make(map[string]string)
+	if in.Title == "" {
+		problems["title"] = "is empty"
+	}
+	if in.Artist == "" {
+		problems["artist"] = "is empty"
+	}
+	switch {
+	case in.Price.AmountMinor <= 0:
+		problems["price"] = "is not greater than zero"
+	case !money.ValidCurrency(price(in).Currency):
+		problems["price"] = "has an invalid currency"
+	}
+	if len(problems) > 0 {
+		return apiError(http.StatusBadRequest, "invalid request body", problems)
+	}
+	return nil
+}
+
+// price returns the price of in, in US dollars, the default currency of the
+// API, if it has no currency.
+func price(in client.AlbumInput) money.Money {
+	if in.Price.Currency == "" {
+		return money.New(in.Price.AmountMinor, "USD")
+	}
+	return in.Price
+}
+
+// errAlbumNotFound returns the error of the API for an album not found.
+func errAlbumNotFound() error {
+	return apiError(http.StatusNotFound, "album not found", nil)
+}
+
+// apiError returns the *client.Error of a response of the API with
+// statusCode, msg and problems.
+func apiError(statusCode int, msg string, problems map[string]string) error {
+	return &client.Error{StatusCode: statusCode, Message: msg, Problems: problems}
+}
+
+// project returns the JSON object representation of alb restricted to
+// fields.
+func project(alb client.Album, fields []string) map[string]json.RawMessage {
+	data, _ := json.Marshal(alb)
+	var obj map[string]json.RawMessage
+	json.Unmarshal(data, &obj)
+	projection := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := obj[field]; ok {
+			projection[field] = value
+		}
+	}
+	return projection
+}
+
+// NewServer returns a new started httptest.Server serving the album
+// endpoints of the API, storing the albums in f. It responds with the status
+// codes and error bodies of the API, so that a client.Client of its URL
+// behaves as one of a running catalog. The caller should Close it when
+// finished.
+func NewServer(f *Fake) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /albums", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		pageSize, err := strconv.Atoi(q.Get("page_size"))
+		if err != nil {
+			respondError(w, apiError(http.StatusBadRequest, "page size is not a valid number", nil))
+			return
+		}
+		pageNumber, err := strconv.Atoi(q.Get("page_number"))
+		if err != nil {
+			respondError(w, apiError(http.StatusBadRequest, "page number is not a valid number", nil))
+			return
+		}
+		var fields []string
+		if q.Has("fields") {
+			for _, field := range strings.Split(q.Get("fields"), ",") {
+				field = strings.TrimSpace(field)
+				if !slices.Contains(albumFields, field) {
+					respondError(w, apiError(http.StatusBadRequest, fmt.Sprintf("unknown field %q", field), nil))
+					return
+				}
+				fields = append(fields, field)
+			}
+		}
+		albs, err := f.ListAlbums(r.Context(), pageSize, pageNumber)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		if fields == nil {
+			respond(w, http.StatusOK, albs)
+			return
+		}
+		projections := make([]map[string]json.RawMessage, len(albs))
+		for i, alb := range albs {
+			projections[i] = project(alb, fields)
+		}
+		respond(w, http.StatusOK, projections)
+	})
+	mux.HandleFunc("POST /albums", func(w http.ResponseWriter, r *http.Request) {
+		var in client.AlbumInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondError(w, apiError(http.StatusBadRequest, "malformed request body", nil))
+			return
+		}
+		alb, err := f.CreateAlbum(r.Context(), in)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, http.StatusCreated, alb)
+	})
+	mux.HandleFunc("GET /albums/{album_id}", withAlbumID(func(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+		alb, err := f.GetAlbum(r.Context(), id)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, http.StatusOK, alb)
+	}))
+	mux.HandleFunc("PUT /albums/{album_id}", withAlbumID(func(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+		var in client.AlbumInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondError(w, apiError(http.StatusBadRequest, "malformed request body", nil))
+			return
+		}
+		alb, err := f.UpdateAlbum(r.Context(), id, in)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, http.StatusOK, alb)
+	}))
+	mux.HandleFunc("DELETE /albums/{album_id}", withAlbumID(func(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+		alb, err := f.DeleteAlbum(r.Context(), id)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respond(w, http.StatusOK, alb)
+	}))
+	return httptest.NewServer(mux)
+}
+
+// withAlbumID returns an http.HandlerFunc calling handler with the album ID
+// of the path of the request, responding with the error of the API if it is
+// malformed.
+func withAlbumID(handler func(w http.ResponseWriter, r *http.Request, id uuid.UUID)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			respondError(w, apiError(http.StatusBadRequest, "malformed album id", nil))
+			return
+		}
+		handler(w, r, id)
+	}
+}
+
+// respond responds with statusCode and v as the JSON body, as the API does.
+func respond(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// respondError responds with the *client.Error err as the API does.
+func respondError(w http.ResponseWriter, err error) {
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		apiErr = &client.Error{StatusCode: http.StatusInternalServerError, Message: "internal error"}
+	}
+	body := struct {
+		Message  string            `json:"message"`
+		Problems map[string]string `json:"problems,omitempty"`
+	}{apiErr.Message, apiErr.Problems}
+	respond(w, apiErr.StatusCode, body)
+}
@@ -0,0 +1,106 @@
+package clienttest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jhtohru/go-album-catalog/client"
+	"github.com/jhtohru/go-album-catalog/client/clienttest"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// testAPI tests that api behaves as the album catalog API.
+func testAPI(t *testing.T, api client.API) {
+	ctx := context.Background()
+
+	created, err := api.CreateAlbum(ctx, client.AlbumInput{Title: "Nevermind", Artist: "Nirvana", Price: money.New(2999, "USD")})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.Equal(t, 1, created.Version)
+	_, err = api.CreateAlbum(ctx, client.AlbumInput{Title: "In Utero", Artist: "Nirvana", Price: money.New(3499, "EUR")})
+	require.NoError(t, err)
+
+	_, err = api.CreateAlbum(ctx, client.AlbumInput{Title: "nevermind", Artist: "NIRVANA", Price: money.New(999, "USD")})
+	assertAPIError(t, err, http.StatusConflict, "album already exists", map[string]string{
+		"title": "is already used by another album of the same artist",
+	})
+	_, err = api.CreateAlbum(ctx, client.AlbumInput{Title: "Bleach"})
+	assertAPIError(t, err, http.StatusBadRequest, "invalid request body", map[string]string{
+		"artist": "is empty",
+		"price":  "is not greater than zero",
+	})
+
+	got, err := api.GetAlbum(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, "Nevermind", got.Title)
+	_, err = api.GetAlbum(ctx, uuid.New())
+	assertAPIError(t, err, http.StatusNotFound, "album not found", nil)
+
+	// The albums are listed by title.
+	page, err := api.ListAlbums(ctx, 1, 2)
+	assert.Nil(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, created.ID, page[0].ID)
+	_, err = api.ListAlbums(ctx, 51, 1)
+	assertAPIError(t, err, http.StatusBadRequest, "page size is greater than 50", nil)
+	var titles []string
+	for alb, err := range api.ListAll(ctx, client.Filter{PageSize: 1, Fields: []string{"title"}}) {
+		assert.Nil(t, err)
+		assert.Equal(t, uuid.Nil, alb.ID)
+		titles = append(titles, alb.Title)
+	}
+	assert.Equal(t, []string{"In Utero", "Nevermind"}, titles)
+
+	updated, err := api.UpdateAlbum(ctx, created.ID, client.AlbumInput{Title: "Nevermind", Artist: "Nirvana", Price: money.Money{AmountMinor: 3999}, Version: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(3999, "USD"), updated.Price, "a price without a currency is in the default one")
+	assert.Equal(t, 2, updated.Version)
+	_, err = api.UpdateAlbum(ctx, created.ID, client.AlbumInput{Title: "Nevermind", Artist: "Nirvana", Price: money.New(4999, "USD"), Version: 1})
+	assertAPIError(t, err, http.StatusConflict, "album version conflict", nil)
+	_, err = api.UpdateAlbum(ctx, created.ID, client.AlbumInput{Title: "In Utero", Artist: "Nirvana", Price: money.New(4999, "USD")})
+	assertAPIError(t, err, http.StatusConflict, "album already exists", map[string]string{
+		"title": "is already used by another album of the same artist",
+	})
+
+	deleted, err := api.DeleteAlbum(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, updated, deleted)
+	_, err = api.DeleteAlbum(ctx, created.ID)
+	assertAPIError(t, err, http.StatusNotFound, "album not found", nil)
+}
+
+// assertAPIError asserts that err is a *client.Error with statusCode, msg and
+// problems.
+func assertAPIError(t *testing.T, err error, statusCode int, msg string, problems map[string]string) {
+	t.Helper()
+	var apiErr *client.Error
+	if !assert.ErrorAs(t, err, &apiErr) {
+		return
+	}
+	assert.Equal(t, statusCode, apiErr.StatusCode)
+	assert.Equal(t, msg, apiErr.Message)
+	assert.Equal(t, problems, apiErr.Problems)
+}
+
+func TestFake(t *testing.T) {
+	testAPI(t, clienttest.NewFake())
+}
+
+func TestNewServer(t *testing.T) {
+	fake := clienttest.NewFake()
+	server := clienttest.NewServer(fake)
+	defer server.Close()
+
+	testAPI(t, client.New(server.URL, "", server.Client()))
+
+	// The albums are stored into fake.
+	albs := fake.Albums()
+	require.Len(t, albs, 1)
+	assert.Equal(t, "In Utero", albs[0].Title)
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultRetryPolicy is a RetryPolicy suited to most clients.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	MaxElapsedTime: 30 * time.Second,
+}
+
+// RetryPolicy is how a Client retries the requests that fail transiently.
+// Every request is retried when responded with 429 Too Many Requests or 503
+// Service Unavailable, which the API responds before acting on it, and the
+// idempotent ones are also retried when they fail to be sent or responded,
+// or are responded with 502 Bad Gateway or 504 Gateway Timeout.
+//
+// The wait before each retry starts from InitialBackoff, doubles after each
+// one up to MaxBackoff, and is jittered to between half of it and it. The
+// wait asked by the Retry-After header of the response is honored if longer.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a request is sent at most, including
+	// the first one. Less than 2 means the requests are not retried.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// MaxElapsedTime is how long after a request was first sent it is no
+	// longer retried, including the wait before the retry. Zero means no
+	// limit.
+	MaxElapsedTime time.Duration
+	// OnRetry, if not nil, is called before each retry of the request
+	// with method to path, with the attempt that failed, starting from 1,
+	// its error and the wait before the retry, such as to log it.
+	OnRetry func(method, path string, attempt int, err error, wait time.Duration)
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithRetryPolicy makes the Client retry the requests that fail transiently
+// as policy describes.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// backoff returns how long to wait before retrying the request with method,
+// sent elapsed ago, whose attempt failed with err, or false if it is not to
+// be retried.
+func (p RetryPolicy) backoff(method string, attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || !retryable(method, err) {
+		return 0, false
+	}
+	wait := min(p.InitialBackoff<<(attempt-1), p.MaxBackoff)
+	if wait > 0 {
+		wait = wait/2 + rand.N(wait/2+1)
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		wait = max(wait, apiErr.RetryAfter)
+	}
+	if p.MaxElapsedTime > 0 && elapsed+wait > p.MaxElapsedTime {
+		return 0, false
+	}
+	return wait, true
+}
+
+// retryable reports whether the request with method that failed with err can
+// be retried.
+func retryable(method string, err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return true
+		case http.StatusBadGateway, http.StatusGatewayTimeout:
+			return idempotent(method)
+		default:
+			return false
+		}
+	}
+	// The errors of sending the request, or of receiving its response, are
+	// the *url.Error ones returned by the http.Client.
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && idempotent(method)
+}
+
+// idempotent reports whether the requests with method are idempotent, so
+// sending them again has no other effect than sending them once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
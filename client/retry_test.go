@@ -0,0 +1,147 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/client"
+)
+
+func TestClientRetryPolicy(t *testing.T) {
+	type retry struct {
+		attempt int
+		status  int
+	}
+	policy := client.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}
+	for name, test := range map[string]struct {
+		method       string
+		statuses     []int
+		requestsWant int
+		statusWant   int
+		retriesWant  []retry
+	}{
+		"retried until responded": {
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			requestsWant: 3,
+			statusWant:   http.StatusOK,
+			retriesWant:  []retry{{1, http.StatusServiceUnavailable}, {2, http.StatusBadGateway}},
+		},
+		"retried up to the max attempts": {
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusOK},
+			requestsWant: 3,
+			statusWant:   http.StatusTooManyRequests,
+			retriesWant:  []retry{{1, http.StatusTooManyRequests}, {2, http.StatusTooManyRequests}},
+		},
+		"non idempotent request retried when rate limited": {
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			requestsWant: 2,
+			statusWant:   http.StatusOK,
+			retriesWant:  []retry{{1, http.StatusTooManyRequests}},
+		},
+		"non idempotent request not retried on a bad gateway": {
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusBadGateway, http.StatusOK},
+			requestsWant: 1,
+			statusWant:   http.StatusBadGateway,
+		},
+		"client error not retried": {
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			requestsWant: 1,
+			statusWant:   http.StatusNotFound,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			requests := 0
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := test.statuses[requests]
+				requests++
+				w.WriteHeader(status)
+				w.Write([]byte(`{"id": "5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d"}`))
+			}))
+			defer api.Close()
+			var retries []retry
+			policy := policy
+			policy.OnRetry = func(method, path string, attempt int, err error, wait time.Duration) {
+				assert.Equal(t, test.method, method)
+				assert.LessOrEqual(t, wait, time.Millisecond)
+				retries = append(retries, retry{attempt, err.(*client.Error).StatusCode})
+			}
+			c := client.New(api.URL, "", api.Client(), client.WithRetryPolicy(policy))
+
+			var err error
+			if test.method == http.MethodPost {
+				_, err = c.CreateAlbum(context.Background(), client.AlbumInput{Title: "Nevermind"})
+			} else {
+				_, err = c.GetAlbum(context.Background(), uuid.New())
+			}
+
+			assert.Equal(t, test.requestsWant, requests)
+			if test.statusWant == http.StatusOK {
+				assert.Nil(t, err)
+			} else {
+				assert.Equal(t, test.statusWant, err.(*client.Error).StatusCode)
+			}
+			assert.Equal(t, test.retriesWant, retries)
+		})
+	}
+}
+
+func TestClientRetryPolicy_retryAfter(t *testing.T) {
+	requests := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer api.Close()
+	var waits []time.Duration
+	c := client.New(api.URL, "", api.Client(), client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		MaxElapsedTime: time.Hour,
+		OnRetry: func(method, path string, attempt int, err error, wait time.Duration) {
+			waits = append(waits, wait)
+		},
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetAlbum(ctx, uuid.New())
+
+	// The retry waits as asked by the API, so the request is given up when
+	// its context is done.
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, []time.Duration{time.Minute}, waits)
+	assert.Equal(t, http.StatusServiceUnavailable, err.(*client.Error).StatusCode)
+
+	// The retry is not made if the wait exceeds the max elapsed time.
+	requests, waits = 0, nil
+	c = client.New(api.URL, "", api.Client(), client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts:    3,
+		MaxElapsedTime: 30 * time.Second,
+		OnRetry: func(method, path string, attempt int, err error, wait time.Duration) {
+			waits = append(waits, wait)
+		},
+	}))
+
+	_, err = c.GetAlbum(context.Background(), uuid.New())
+
+	assert.Equal(t, 1, requests)
+	assert.Empty(t, waits)
+	assert.Equal(t, http.StatusServiceUnavailable, err.(*client.Error).StatusCode)
+}
@@ -0,0 +1,81 @@
+// Package clock provides the Clock the album catalog tells the time by, so
+// that the code telling it can be tested with a Fake one.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+}
+
+// System is the Clock telling the time of the system, by time.Now.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// Func is an adapter to allow the use of ordinary functions returning the
+// current time, such as time.Now, as Clocks.
+type Func func() time.Time
+
+// Now makes Func implement Clock.
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// Since makes Func implement Clock.
+func (f Func) Since(t time.Time) time.Duration {
+	return f().Sub(t)
+}
+
+// Fake is a Clock frozen at a time until it is advanced or set, for testing
+// the code telling the time. It is safe for concurrent use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a new Fake frozen at now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now makes Fake implement Clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since makes Fake implement Clock.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Advance advances f by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set sets f to now.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
@@ -0,0 +1,37 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 9, 3, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+
+	assert.Equal(t, start, fake.Now())
+	assert.Equal(t, start, fake.Now(), "a fake clock is frozen")
+
+	fake.Advance(90 * time.Second)
+
+	assert.Equal(t, start.Add(90*time.Second), fake.Now())
+	assert.Equal(t, 90*time.Second, fake.Since(start))
+
+	later := time.Date(2024, 9, 4, 0, 0, 0, 0, time.UTC)
+	fake.Set(later)
+
+	assert.Equal(t, later, fake.Now())
+	assert.Equal(t, 12*time.Hour, fake.Since(start))
+}
+
+func TestFunc(t *testing.T) {
+	now := time.Date(2024, 9, 3, 12, 0, 0, 0, time.UTC)
+	c := clock.Func(func() time.Time { return now })
+
+	assert.Equal(t, now, c.Now())
+	assert.Equal(t, time.Hour, c.Since(now.Add(-time.Hour)))
+}
@@ -0,0 +1,247 @@
+// Command albumctl manages the albums of an album catalog through its HTTP
+// API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/client"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// usage describes the commands of albumctl.
+const usage = `Usage: albumctl <command> [flags] [args]
+
+Commands:
+  list                 list a page of albums
+  create               create an album
+  get <album id>       show an album
+  update <album id>    update an album
+  delete <album id>    delete an album
+
+Run albumctl <command> -h for the flags of a command.`
+
+// run runs the command named by the first of args, writing its output to
+// stdout.
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	var command func(context.Context, *client.Client, *flag.FlagSet, *printer) error
+	var fields *albumFlags
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "list":
+		pageSize := flags.Int("page-size", 20, "number of albums of a page")
+		pageNumber := flags.Int("page", 1, "number of the page to list, starting from 1")
+		command = func(ctx context.Context, c *client.Client, flags *flag.FlagSet, p *printer) error {
+			albs, err := c.ListAlbums(ctx, *pageSize, *pageNumber)
+			if err != nil {
+				return err
+			}
+			return p.print(albs...)
+		}
+	case "create":
+		fields = registerAlbumFlags(flags)
+		command = func(ctx context.Context, c *client.Client, flags *flag.FlagSet, p *printer) error {
+			alb, err := c.CreateAlbum(ctx, client.AlbumInput{Title: fields.title, Artist: fields.artist, Price: money.New(fields.price, fields.currency)})
+			if err != nil {
+				return err
+			}
+			return p.print(alb)
+		}
+	case "get":
+		command = func(ctx context.Context, c *client.Client, flags *flag.FlagSet, p *printer) error {
+			id, err := albumID(flags)
+			if err != nil {
+				return err
+			}
+			alb, err := c.GetAlbum(ctx, id)
+			if err != nil {
+				return err
+			}
+			return p.print(alb)
+		}
+	case "update":
+		fields = registerAlbumFlags(flags)
+		command = func(ctx context.Context, c *client.Client, flags *flag.FlagSet, p *printer) error {
+			id, err := albumID(flags)
+			if err != nil {
+				return err
+			}
+			// Fill the fields left unset in with the current album, which the
+			// update is based on so it does not overwrite a concurrent one.
+			alb, err := c.GetAlbum(ctx, id)
+			if err != nil {
+				return err
+			}
+			in := client.AlbumInput{Title: alb.Title, Artist: alb.Artist, Price: alb.Price, Version: alb.Version}
+			flags.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "title":
+					in.Title = fields.title
+				case "artist":
+					in.Artist = fields.artist
+				case "price":
+					in.Price.AmountMinor = fields.price
+				case "currency":
+					in.Price.Currency = fields.currency
+				}
+			})
+			alb, err = c.UpdateAlbum(ctx, id, in)
+			if err != nil {
+				return err
+			}
+			return p.print(alb)
+		}
+	case "delete":
+		command = func(ctx context.Context, c *client.Client, flags *flag.FlagSet, p *printer) error {
+			id, err := albumID(flags)
+			if err != nil {
+				return err
+			}
+			alb, err := c.DeleteAlbum(ctx, id)
+			if err != nil {
+				return err
+			}
+			return p.print(alb)
+		}
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+	var (
+		baseURL = flags.String("url", envOr("ALBUMCTL_URL", "http://localhost:8080"), "base URL of the album catalog API ($ALBUMCTL_URL)")
+		apiKey  = flags.String("api-key", os.Getenv("ALBUMCTL_API_KEY"), "API key sent as the bearer token of the requests ($ALBUMCTL_API_KEY)")
+		output  = flags.String("output", "table", "output format, table or json")
+		timeout = flags.Duration("timeout", 30*time.Second, "timeout of each request")
+		retries = flags.Int("retries", client.DefaultRetryPolicy.MaxAttempts-1, "maximum number of retries of the requests that fail transiently")
+	)
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	retry := client.DefaultRetryPolicy
+	retry.MaxAttempts = *retries + 1
+	retry.OnRetry = func(method, path string, attempt int, err error, wait time.Duration) {
+		fmt.Fprintf(os.Stderr, "%s %s failed: %v, retrying in %s\n", method, path, err, wait.Round(time.Millisecond))
+	}
+	c := client.New(*baseURL, *apiKey, &http.Client{Timeout: *timeout}, client.WithRetryPolicy(retry))
+	err := command(ctx, c, flags, &printer{w: stdout, json: *output == "json", list: args[0] == "list"})
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && len(apiErr.Problems) > 0 {
+		return problemsError(apiErr)
+	}
+	return err
+}
+
+// albumFlags are the album fields set by the flags of the create and update
+// commands.
+type albumFlags struct {
+	title    string
+	artist   string
+	price    int64
+	currency string
+}
+
+// registerAlbumFlags registers the flags of the album fields into flags.
+func registerAlbumFlags(flags *flag.FlagSet) *albumFlags {
+	var fields albumFlags
+	flags.StringVar(&fields.title, "title", "", "title of the album")
+	flags.StringVar(&fields.artist, "artist", "", "artist of the album")
+	flags.Int64Var(&fields.price, "price", 0, "price of the album, in the minor units of its currency, such as cents")
+	flags.StringVar(&fields.currency, "currency", "", "currency of the price of the album, the default one of the catalog if empty")
+	return &fields
+}
+
+// albumID parses the album id given as the only non-flag argument of flags.
+func albumID(flags *flag.FlagSet) (uuid.UUID, error) {
+	if flags.NArg() != 1 {
+		return uuid.UUID{}, errors.New("missing album id")
+	}
+	id, err := uuid.Parse(flags.Arg(0))
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("malformed album id %q", flags.Arg(0))
+	}
+	return id, nil
+}
+
+// problemsError returns an error describing apiErr along with its problems,
+// one per line.
+func problemsError(apiErr *client.Error) error {
+	fields := make([]string, 0, len(apiErr.Problems))
+	for field := range apiErr.Problems {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	msg := apiErr.Message
+	for _, field := range fields {
+		msg += fmt.Sprintf("\n  %s %s", field, apiErr.Problems[field])
+	}
+	return errors.New(msg)
+}
+
+// envOr returns the value of the environment variable key, or def if it is
+// empty.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// printer prints albums either as a table or as JSON.
+type printer struct {
+	w    io.Writer
+	json bool
+	// list is whether the albums are printed as a JSON array even if there
+	// is a single one.
+	list bool
+}
+
+// print prints albs.
+func (p *printer) print(albs ...client.Album) error {
+	if p.json {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		if !p.list && len(albs) == 1 {
+			return enc.Encode(albs[0])
+		}
+		if albs == nil {
+			albs = []client.Album{}
+		}
+		return enc.Encode(albs)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tARTIST\tTITLE\tPRICE\tVERSION\tUPDATED AT")
+	for _, alb := range albs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", alb.ID, alb.Artist, alb.Title, alb.Price, alb.Version, alb.UpdatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
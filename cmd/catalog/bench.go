@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/client"
+	"github.com/jhtohru/go-album-catalog/internal/random"
+)
+
+// benchOp is an operation of the traffic generated by the bench command,
+// chosen with a probability proportional to its weight.
+type benchOp struct {
+	name   string
+	weight int
+	run    func(b *benchTarget, ctx context.Context) error
+}
+
+// benchOps are the operations of the traffic generated by the bench command,
+// mostly reads, as the traffic of a catalog is.
+var benchOps = []benchOp{
+	{"list", 30, (*benchTarget).list},
+	{"get", 45, (*benchTarget).get},
+	{"create", 12, (*benchTarget).create},
+	{"update", 8, (*benchTarget).update},
+	{"delete", 5, (*benchTarget).delete},
+}
+
+// errNoBenchAlbum is the error of the operations skipped because there is no
+// album to operate on yet.
+var errNoBenchAlbum = errors.New("no album to operate on")
+
+// benchTarget is the catalog the bench command generates traffic to. It
+// keeps the IDs of the albums listed, to get them, and of the albums it
+// created, which are the only ones it updates and deletes.
+type benchTarget struct {
+	client *client.Client
+
+	mu      sync.Mutex
+	seen    []uuid.UUID
+	created []uuid.UUID
+}
+
+// list lists a random page of the first ten.
+func (b *benchTarget) list(ctx context.Context) error {
+	albs, err := b.client.ListAlbums(ctx, 20, rand.IntN(10)+1)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, alb := range albs {
+		if len(b.seen) < 1000 {
+			b.seen = append(b.seen, alb.ID)
+		} else {
+			b.seen[rand.IntN(len(b.seen))] = alb.ID
+		}
+	}
+	return nil
+}
+
+// get gets an album listed or created before.
+func (b *benchTarget) get(ctx context.Context) error {
+	b.mu.Lock()
+	ids := b.seen
+	if len(ids) == 0 || (len(b.created) > 0 && rand.IntN(2) == 0) {
+		ids = b.created
+	}
+	if len(ids) == 0 {
+		b.mu.Unlock()
+		return errNoBenchAlbum
+	}
+	id := ids[rand.IntN(len(ids))]
+	b.mu.Unlock()
+	_, err := b.client.GetAlbum(ctx, id)
+	return ignoreNotFound(err)
+}
+
+// create creates a plausible album, whose title is made unique so as not to
+// conflict with the albums of the catalog.
+func (b *benchTarget) create(ctx context.Context) error {
+	alb, err := b.client.CreateAlbum(ctx, client.AlbumInput{
+		Title:  random.AlbumTitle() + " (bench " + random.String(8) + ")",
+		Artist: random.Artist(),
+		Price:  random.Price(),
+	})
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.created = append(b.created, alb.ID)
+	return nil
+}
+
+// update changes the price of an album created before.
+func (b *benchTarget) update(ctx context.Context) error {
+	id, ok := b.pickCreated(false)
+	if !ok {
+		return errNoBenchAlbum
+	}
+	alb, err := b.client.GetAlbum(ctx, id)
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+	_, err = b.client.UpdateAlbum(ctx, id, client.AlbumInput{
+		Title:  alb.Title,
+		Artist: alb.Artist,
+		Price:  random.Price(),
+	})
+	return ignoreNotFound(err)
+}
+
+// delete deletes an album created before.
+func (b *benchTarget) delete(ctx context.Context) error {
+	id, ok := b.pickCreated(true)
+	if !ok {
+		return errNoBenchAlbum
+	}
+	_, err := b.client.DeleteAlbum(ctx, id)
+	return err
+}
+
+// pickCreated returns the ID of a random album created before, forgetting it
+// if remove is true, or false if there is none.
+func (b *benchTarget) pickCreated(remove bool) (uuid.UUID, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.created) == 0 {
+		return uuid.Nil, false
+	}
+	i := rand.IntN(len(b.created))
+	id := b.created[i]
+	if remove {
+		b.created = slices.Delete(b.created, i, i+1)
+	}
+	return id, true
+}
+
+// ignoreNotFound returns err, or nil if it is a not found response, as the
+// albums operated on may be deleted concurrently by another operation, which
+// is not a failure of the target.
+func ignoreNotFound(err error) error {
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// benchStats are the latencies and errors of the operations run by the bench
+// command, by operation name. It is safe for concurrent use.
+type benchStats struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	// dropped counts the operations not run because too many were in flight.
+	dropped int
+}
+
+// record records an operation named name that took latency and failed with
+// err, if not nil.
+func (s *benchStats) record(name string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[name] = append(s.latencies[name], latency)
+	if err != nil {
+		s.errors[name]++
+	}
+}
+
+// print writes s to w as a table of the request count, error rate and
+// latency percentiles of each operation, for a run that lasted elapsed.
+func (s *benchStats) print(w io.Writer, elapsed time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\trequests\terrors\terror rate\tp50\tp90\tp99\tmax\t")
+	var all []time.Duration
+	allErrors := 0
+	row := func(name string, latencies []time.Duration, errs int) {
+		slices.Sort(latencies)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f%%\t%s\t%s\t%s\t%s\t\n",
+			name, len(latencies), errs, 100*float64(errs)/float64(len(latencies)),
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), latencies[len(latencies)-1])
+	}
+	for _, op := range benchOps {
+		latencies := s.latencies[op.name]
+		if len(latencies) == 0 {
+			continue
+		}
+		row(op.name, latencies, s.errors[op.name])
+		all = append(all, latencies...)
+		allErrors += s.errors[op.name]
+	}
+	if len(all) == 0 {
+		fmt.Fprintln(tw, "no requests\t\t\t\t\t\t\t\t")
+	} else {
+		row("total", all, allErrors)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d requests in %s, %.1f requests/s, %d dropped\n",
+		len(all), elapsed.Round(time.Millisecond), float64(len(all))/elapsed.Seconds(), s.dropped)
+	return err
+}
+
+// percentile returns the p-th percentile of the sorted latencies, which must
+// not be empty, by the nearest-rank method.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(latencies))))
+	return latencies[max(rank, 1)-1]
+}
+
+// bench generates CRUD traffic to a running album catalog at a constant rate
+// and reports the latency percentiles and error rate of each operation.
+func bench(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: catalog bench [flags]")
+		flags.PrintDefaults()
+	}
+	var (
+		target      = flags.String("target", "http://localhost:8080", "URL of the album catalog")
+		apiKey      = flags.String("api-key", os.Getenv("BENCH_API_KEY"), "API key sent as the bearer token, defaults to the BENCH_API_KEY environment variable")
+		rps         = flags.Float64("rps", 100, "requests per second")
+		duration    = flags.Duration("duration", 30*time.Second, "duration of the run")
+		concurrency = flags.Int("concurrency", 64, "maximum number of requests in flight, the ones over it are dropped")
+		timeout     = flags.Duration("timeout", 10*time.Second, "timeout of each request")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *rps <= 0 {
+		return errors.New("rps must be positive")
+	}
+	if *duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+	if *concurrency < 1 {
+		return errors.New("concurrency must be positive")
+	}
+	httpClient := &http.Client{
+		Timeout:   *timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+	}
+	b := &benchTarget{client: client.New(*target, *apiKey, httpClient)}
+	if err := b.list(ctx); err != nil {
+		return fmt.Errorf("listing albums of %s: %w", *target, err)
+	}
+
+	// The run is stopped early on an interrupt, still reporting the
+	// operations run until then.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	totalWeight := 0
+	for _, op := range benchOps {
+		totalWeight += op.weight
+	}
+	stats := &benchStats{latencies: make(map[string][]time.Duration), errors: make(map[string]int)}
+	inFlight := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *rps))
+	defer ticker.Stop()
+	fmt.Fprintf(os.Stderr, "sending %g requests/s to %s for %s\n", *rps, *target, *duration)
+	start := time.Now()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			stats.mu.Lock()
+			stats.dropped++
+			stats.mu.Unlock()
+			continue
+		}
+		n := rand.IntN(totalWeight)
+		op := benchOps[0]
+		for _, op = range benchOps {
+			if n < op.weight {
+				break
+			}
+			n -= op.weight
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			// The requests in flight at the end of the run are let
+			// complete rather than canceled, so they are not reported
+			// as errors.
+			opStart := time.Now()
+			err := op.run(b, context.WithoutCancel(ctx))
+			if errors.Is(err, errNoBenchAlbum) {
+				return
+			}
+			stats.record(op.name, time.Since(opStart), err)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// The albums created and not deleted are cleaned up.
+	for _, id := range b.created {
+		if _, err := b.client.DeleteAlbum(context.Background(), id); err != nil {
+			fmt.Fprintf(os.Stderr, "deleting album %s: %v\n", id, err)
+		}
+	}
+
+	return stats.print(os.Stdout, elapsed)
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nats-io/nats.go"
+	"gopkg.in/yaml.v3"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// config is the configuration of the album catalog server.
+//
+// Each setting is named by a command line flag, such as -server-port, and by
+// the environment variable of the same name in upper snake case, such as
+// SERVER_PORT, and can also be set by the key of the same name in a YAML or
+// TOML config file. Flags take precedence over environment variables, which
+// take precedence over the config file, which takes precedence over the
+// defaults. Environment variables set to the empty string are ignored.
+type config struct {
+	ServerHost              string
+	ServerPort              string
+	ListenAddrs             []string
+	AdminAddrs              []string
+	ReadHeaderTimeout       time.Duration
+	ReadTimeout             time.Duration
+	WriteTimeout            time.Duration
+	IdleTimeout             time.Duration
+	H2C                     bool
+	DSN                     string
+	DBDriver                string
+	DBMaxOpenConns          int
+	DBMaxIdleConns          int
+	DBConnMaxLifetime       time.Duration
+	DBStatsInterval         time.Duration
+	MigrateDB               bool
+	CheckSchema             bool
+	SandboxSchema           string
+	SandboxResetInterval    time.Duration
+	StrictQueryParams       bool
+	RequestTimeout          time.Duration
+	TitleMaxLength          int
+	ArtistMaxLength         int
+	MaxPrice                int
+	AllowZeroPrice          bool
+	DefaultCurrency         string
+	RequireCurrency         bool
+	CacheSize               int
+	CacheTTL                time.Duration
+	EventPublisher          string
+	OutboxRelayInterval     time.Duration
+	OTelTracesExporter      string
+	AccessLogSkipPaths      []string
+	SlowQueryThreshold      time.Duration
+	MetricsAddr             string
+	SentryDSN               string
+	JWTHS256Secret          string
+	JWTJWKSURL              string
+	JWTJWKSRefreshInterval  time.Duration
+	JWTIssuer               string
+	JWTAudience             string
+	OIDCIssuerURL           string
+	OIDCClientID            string
+	OIDCClientSecret        string
+	OIDCRedirectURL         string
+	RateLimit               float64
+	RateLimitBurst          int
+	TLSCertFile             string
+	TLSKeyFile              string
+	TLSAutocertHosts        []string
+	TLSAutocertCacheDir     string
+	TLSRedirectAddr         string
+	GRPCAddr                string
+	Webhooks                bool
+	WebhookDeliveryInterval time.Duration
+	LiveUpdates             bool
+	KafkaBrokers            []string
+	KafkaTopic              string
+	KafkaFormat             string
+	NATSURL                 string
+	NATSSubjectPrefix       string
+	DiscogsToken            string
+	DiscogsRateLimit        float64
+	MetadataCacheSize       int
+	MetadataCacheTTL        time.Duration
+	MusicBrainzLookup       bool
+	DrainDelay              time.Duration
+	DrainTimeout            time.Duration
+
+	// sources are where each setting was set from, by name: "flag", "env" or
+	// the path of the config file. The settings left to their defaults are
+	// absent.
+	sources map[string]string
+}
+
+// secretSettings are the names of the settings printed redacted.
+var secretSettings = map[string]bool{
+	"sentry-dsn":         true,
+	"jwt-hs256-secret":   true,
+	"oidc-client-secret": true,
+	"discogs-token":      true,
+}
+
+// register defines the settings of cfg as flags of fs, set to their defaults.
+func (cfg *config) register(fs *flag.FlagSet) {
+	str := func(p *string, name, value, usage string) {
+		fs.StringVar(p, name, value, usage+" ($"+envName(name)+")")
+	}
+	boolean := func(p *bool, name, usage string) {
+		fs.BoolVar(p, name, false, usage+" ($"+envName(name)+")")
+	}
+	integer := func(p *int, name string, value int, usage string) {
+		fs.IntVar(p, name, value, usage+" ($"+envName(name)+")")
+	}
+	float := func(p *float64, name string, value float64, usage string) {
+		fs.Float64Var(p, name, value, usage+" ($"+envName(name)+")")
+	}
+	duration := func(p *time.Duration, name string, value time.Duration, usage string) {
+		fs.DurationVar(p, name, value, usage+" ($"+envName(name)+")")
+	}
+	list := func(p *[]string, name, usage string) {
+		fs.Var((*listValue)(p), name, usage+", comma separated ($"+envName(name)+")")
+	}
+
+	str(&cfg.ServerHost, "server-host", "", "host the HTTP server listens on")
+	str(&cfg.ServerPort, "server-port", "8080", "port the HTTP server listens on")
+	list(&cfg.ListenAddrs, "listen-addrs", "addresses the HTTP server listens on instead of server-host and server-port: host:port, unix:PATH, or systemd or systemd:NAME for the sockets passed by systemd")
+	list(&cfg.AdminAddrs, "admin-addrs", "addresses /readyz and /version are served on apart from the API, if any, as listen-addrs")
+	duration(&cfg.ReadHeaderTimeout, "read-header-timeout", 5*time.Second, "time the HTTP servers wait for the headers of a request")
+	duration(&cfg.ReadTimeout, "read-timeout", 30*time.Second, "time the HTTP servers wait for a whole request, 0 for no timeout")
+	duration(&cfg.WriteTimeout, "write-timeout", time.Minute, "time the HTTP servers take to write a response, 0 for no timeout")
+	duration(&cfg.IdleTimeout, "idle-timeout", 2*time.Minute, "time the HTTP servers keep idle connections open for")
+	boolean(&cfg.H2C, "h2c", "serve HTTP/2 over plain HTTP connections")
+	str(&cfg.DSN, "dsn", "", "postgres dsn, required")
+	str(&cfg.DBDriver, "db-driver", "pq", `postgres driver of the album storage, "pq" or "pgx"`)
+	integer(&cfg.DBMaxOpenConns, "db-max-open-conns", 0, "maximum number of open database connections, 0 for unlimited")
+	integer(&cfg.DBMaxIdleConns, "db-max-idle-conns", 0, "maximum number of idle database connections, 0 for the driver default")
+	duration(&cfg.DBConnMaxLifetime, "db-conn-max-lifetime", 0, "maximum lifetime of database connections, 0 for unlimited")
+	duration(&cfg.DBStatsInterval, "db-stats-interval", 15*time.Second, "interval the database connection pool metrics are collected at")
+	boolean(&cfg.MigrateDB, "migrate-db", "migrate the database on startup")
+	boolean(&cfg.CheckSchema, "check-schema", "check the database schema on startup")
+	str(&cfg.SandboxSchema, "sandbox-schema", "", "schema of the sandbox albums, if serving a sandbox")
+	duration(&cfg.SandboxResetInterval, "sandbox-reset-interval", time.Hour, "interval the sandbox albums are reset at")
+	boolean(&cfg.StrictQueryParams, "strict-query-params", "reject requests with unknown query parameters")
+	duration(&cfg.RequestTimeout, "request-timeout", 30*time.Second, "time requests are handled for before timing out, 0 for no timeout")
+	integer(&cfg.TitleMaxLength, "title-max-length", catalog.DefaultAlbumRules.TitleMaxLength, "maximum length of the album titles, in characters")
+	integer(&cfg.ArtistMaxLength, "artist-max-length", catalog.DefaultAlbumRules.ArtistMaxLength, "maximum length of the album artists, in characters")
+	integer(&cfg.MaxPrice, "max-price", 0, "maximum price of the albums, in the minor units of their currency, 0 for unlimited")
+	boolean(&cfg.AllowZeroPrice, "allow-zero-price", "allow free albums, such as promotional ones")
+	str(&cfg.DefaultCurrency, "default-currency", catalog.DefaultAlbumRules.DefaultCurrency, "currency of the album prices requested without one, such as the legacy integer ones")
+	boolean(&cfg.RequireCurrency, "require-currency", "reject the album prices requested without a currency, such as the legacy integer ones")
+	integer(&cfg.CacheSize, "cache-size", 0, "number of albums cached in memory, 0 to disable the cache")
+	duration(&cfg.CacheTTL, "cache-ttl", time.Minute, "time albums are cached for")
+	str(&cfg.EventPublisher, "event-publisher", "discard", `publisher of the album events, "discard", "log", "kafka" or "nats"`)
+	duration(&cfg.OutboxRelayInterval, "outbox-relay-interval", time.Second, "interval the album events are relayed from the outbox at")
+	str(&cfg.OTelTracesExporter, "otel-traces-exporter", "none", `exporter of the traces, "none" or "otlp"`)
+	list(&cfg.AccessLogSkipPaths, "access-log-skip-paths", "paths whose requests are not logged")
+	duration(&cfg.SlowQueryThreshold, "slow-query-threshold", 0, "duration of the queries logged as slow, 0 to disable the log")
+	str(&cfg.MetricsAddr, "metrics-addr", "", "address the Prometheus metrics are served on, if any")
+	str(&cfg.SentryDSN, "sentry-dsn", "", "sentry dsn the server errors are reported to, if any")
+	str(&cfg.JWTHS256Secret, "jwt-hs256-secret", "", "secret of the HS256 signed tokens")
+	str(&cfg.JWTJWKSURL, "jwt-jwks-url", "", "URL of the JWKS of the RS256 or ES256 signed tokens")
+	duration(&cfg.JWTJWKSRefreshInterval, "jwt-jwks-refresh-interval", time.Hour, "interval the JWKS is refreshed at")
+	str(&cfg.JWTIssuer, "jwt-issuer", "", "required issuer of the tokens, if any")
+	str(&cfg.JWTAudience, "jwt-audience", "", "required audience of the tokens, if any")
+	str(&cfg.OIDCIssuerURL, "oidc-issuer-url", "", "URL of the OpenID Connect issuer, if any")
+	str(&cfg.OIDCClientID, "oidc-client-id", "", "OpenID Connect client ID")
+	str(&cfg.OIDCClientSecret, "oidc-client-secret", "", "OpenID Connect client secret")
+	str(&cfg.OIDCRedirectURL, "oidc-redirect-url", "", "OpenID Connect redirect URL")
+	float(&cfg.RateLimit, "rate-limit", 0, "requests per second allowed to each client, 0 to disable rate limiting")
+	integer(&cfg.RateLimitBurst, "rate-limit-burst", 0, "requests each client can burst, 0 for the rate limit rounded up")
+	str(&cfg.TLSCertFile, "tls-cert-file", "", "TLS certificate file")
+	str(&cfg.TLSKeyFile, "tls-key-file", "", "TLS key file")
+	list(&cfg.TLSAutocertHosts, "tls-autocert-hosts", "hosts whose certificates are obtained from Let's Encrypt")
+	str(&cfg.TLSAutocertCacheDir, "tls-autocert-cache-dir", "autocert-cache", "directory the obtained certificates are cached in")
+	str(&cfg.TLSRedirectAddr, "tls-redirect-addr", "", "address HTTP requests are redirected to HTTPS on, if any")
+	str(&cfg.GRPCAddr, "grpc-addr", "", "address the gRPC API is served on, if any")
+	boolean(&cfg.Webhooks, "webhooks", "deliver the album events to webhooks")
+	duration(&cfg.WebhookDeliveryInterval, "webhook-delivery-interval", 5*time.Second, "interval the webhook deliveries are delivered at")
+	boolean(&cfg.LiveUpdates, "live-updates", "push the album events to WebSocket clients")
+	list(&cfg.KafkaBrokers, "kafka-brokers", "Kafka brokers")
+	str(&cfg.KafkaTopic, "kafka-topic", "album-events", "Kafka topic of the album events")
+	str(&cfg.KafkaFormat, "kafka-format", "json", `format of the Kafka messages, "json" or "avro"`)
+	str(&cfg.NATSURL, "nats-url", nats.DefaultURL, "NATS server URL")
+	str(&cfg.NATSSubjectPrefix, "nats-subject-prefix", "catalog.events", "prefix of the NATS subjects of the album events")
+	str(&cfg.DiscogsToken, "discogs-token", "", "Discogs personal access token, enabling the metadata enrichment")
+	float(&cfg.DiscogsRateLimit, "discogs-rate-limit", 1, "requests per second to Discogs")
+	integer(&cfg.MetadataCacheSize, "metadata-cache-size", 1000, "number of metadata searches cached in memory")
+	duration(&cfg.MetadataCacheTTL, "metadata-cache-ttl", 24*time.Hour, "time metadata searches are cached for")
+	boolean(&cfg.MusicBrainzLookup, "musicbrainz-lookup", "look up album releases in MusicBrainz")
+	duration(&cfg.DrainDelay, "drain-delay", 0, "time new requests are still accepted for after /readyz reports shutting down")
+	duration(&cfg.DrainTimeout, "drain-timeout", catalog.DefaultDrainTimeout, "time in-flight requests are waited for on shutdown")
+}
+
+// envName returns the name of the environment variable of the setting named
+// name, such as SERVER_PORT for server-port.
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// listValue is a flag.Value of a comma separated list of strings.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	*l = nil
+	if s != "" {
+		*l = strings.Split(s, ",")
+	}
+	return nil
+}
+
+func (l *listValue) Get() any {
+	if *l == nil {
+		return []string{}
+	}
+	return []string(*l)
+}
+
+// loadConfig loads the configuration of the server from the command line
+// flags in args, the environment variables and the config file named by the
+// -config flag or the CONFIG_FILE environment variable, if any, and validates
+// it. It also reports whether the -print-config flag is set.
+func loadConfig(args []string) (cfg config, printConfig bool, err error) {
+	fs := flag.NewFlagSet("catalog", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: catalog [flags]")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&printConfig, "print-config", false, "print the configuration, secrets redacted, and exit")
+	cfg, err = parseConfig(fs, args)
+	if err != nil {
+		return config{}, false, err
+	}
+	if err := cfg.validate(); err != nil {
+		return config{}, false, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, printConfig, nil
+}
+
+// parseConfig loads the configuration of the server as loadConfig does,
+// defining its settings and the -config flag into fs, but without validating
+// it.
+func parseConfig(fs *flag.FlagSet, args []string) (cfg config, err error) {
+	cfg.register(fs)
+	var configFile string
+	fs.StringVar(&configFile, "config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file, by its .yaml, .yml or .toml extension ($CONFIG_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+	if fs.NArg() > 0 {
+		return config{}, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	cfg.sources = make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		cfg.sources[f.Name] = "flag"
+	})
+	isSetting := func(name string) bool {
+		return name != "config" && name != "print-config"
+	}
+	var errs []error
+	set := func(name, value, source string) {
+		if err := fs.Set(name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q for %s from %s: %w", value, name, source, err))
+			return
+		}
+		cfg.sources[name] = source
+	}
+	if configFile != "" {
+		values, err := readConfigFile(configFile)
+		if err != nil {
+			return config{}, err
+		}
+		for name, value := range values {
+			if fs.Lookup(name) == nil || !isSetting(name) {
+				errs = append(errs, fmt.Errorf("unknown setting %q in %s", name, configFile))
+				continue
+			}
+			if cfg.sources[name] != "flag" {
+				set(name, value, configFile)
+			}
+		}
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if !isSetting(f.Name) || cfg.sources[f.Name] == "flag" {
+			return
+		}
+		if value := os.Getenv(envName(f.Name)); value != "" {
+			set(f.Name, value, "env")
+		}
+	})
+	if len(errs) > 0 {
+		return config{}, fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// readConfigFile reads the settings of the YAML or TOML config file at path,
+// formatting their values as their flags are. Lists are formatted as comma
+// separated.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var raw map[string]any
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unknown config file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("setting %q of config file is not a scalar or a list", name)
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// validate checks that the settings of cfg are set, valid and consistent,
+// reporting every problem found.
+func (cfg *config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s is %q, not one of %q", name, value, allowed))
+	}
+	positive := func(name string, d time.Duration) {
+		check(d > 0, "%s is not positive", name)
+	}
+
+	check(cfg.DSN != "", "dsn is not set")
+	port, err := strconv.Atoi(cfg.ServerPort)
+	check(err == nil && port >= 0 && port <= math.MaxUint16, "server-port %q is not a port number", cfg.ServerPort)
+	for _, addr := range slices.Concat(cfg.ListenAddrs, cfg.AdminAddrs) {
+		_, _, err := parseListenAddr(addr)
+		check(err == nil, "%v", err)
+	}
+	positive("read-header-timeout", cfg.ReadHeaderTimeout)
+	check(cfg.ReadTimeout >= 0, "read-timeout is negative")
+	check(cfg.WriteTimeout >= 0, "write-timeout is negative")
+	positive("idle-timeout", cfg.IdleTimeout)
+	check(!cfg.H2C || (cfg.TLSCertFile == "" && len(cfg.TLSAutocertHosts) == 0), "both h2c and tls are set")
+	oneOf("db-driver", cfg.DBDriver, "pq", "pgx")
+	check(cfg.DBMaxOpenConns >= 0, "db-max-open-conns is negative")
+	check(cfg.DBMaxIdleConns >= 0, "db-max-idle-conns is negative")
+	check(cfg.DBConnMaxLifetime >= 0, "db-conn-max-lifetime is negative")
+	positive("db-stats-interval", cfg.DBStatsInterval)
+	positive("sandbox-reset-interval", cfg.SandboxResetInterval)
+	check(cfg.RequestTimeout >= 0, "request-timeout is negative")
+	check(cfg.TitleMaxLength >= 1 && cfg.TitleMaxLength <= catalog.DefaultAlbumRules.TitleMaxLength, "title-max-length is not between 1 and %d", catalog.DefaultAlbumRules.TitleMaxLength)
+	check(cfg.ArtistMaxLength >= 1 && cfg.ArtistMaxLength <= catalog.DefaultAlbumRules.ArtistMaxLength, "artist-max-length is not between 1 and %d", catalog.DefaultAlbumRules.ArtistMaxLength)
+	check(cfg.MaxPrice >= 0, "max-price is negative")
+	check(money.ValidCurrency(cfg.DefaultCurrency), "default-currency %q is not an ISO 4217 currency code", cfg.DefaultCurrency)
+	check(cfg.CacheSize >= 0, "cache-size is negative")
+	positive("cache-ttl", cfg.CacheTTL)
+	oneOf("event-publisher", cfg.EventPublisher, "discard", "log", "kafka", "nats")
+	check(cfg.EventPublisher != "kafka" || len(cfg.KafkaBrokers) > 0, "kafka-brokers are not set")
+	oneOf("kafka-format", cfg.KafkaFormat, "json", "avro")
+	positive("outbox-relay-interval", cfg.OutboxRelayInterval)
+	oneOf("otel-traces-exporter", cfg.OTelTracesExporter, "none", "otlp")
+	check(cfg.SlowQueryThreshold >= 0, "slow-query-threshold is negative")
+	check(cfg.JWTHS256Secret == "" || cfg.JWTJWKSURL == "", "both jwt-hs256-secret and jwt-jwks-url are set")
+	check(cfg.OIDCIssuerURL == "" || (cfg.JWTHS256Secret == "" && cfg.JWTJWKSURL == ""), "both oidc-issuer-url and a jwt verifier are set")
+	positive("jwt-jwks-refresh-interval", cfg.JWTJWKSRefreshInterval)
+	check(cfg.RateLimit >= 0, "rate-limit is negative")
+	check(cfg.RateLimitBurst >= 0, "rate-limit-burst is negative")
+	check((cfg.TLSCertFile == "") == (cfg.TLSKeyFile == ""), "only one of tls-cert-file and tls-key-file is set")
+	check(cfg.TLSCertFile == "" || len(cfg.TLSAutocertHosts) == 0, "both tls-cert-file and tls-autocert-hosts are set")
+	positive("webhook-delivery-interval", cfg.WebhookDeliveryInterval)
+	check(cfg.DiscogsRateLimit > 0, "discogs-rate-limit is not positive")
+	check(cfg.MetadataCacheSize >= 0, "metadata-cache-size is negative")
+	positive("metadata-cache-ttl", cfg.MetadataCacheTTL)
+	check(cfg.DrainDelay >= 0, "drain-delay is negative")
+	positive("drain-timeout", cfg.DrainTimeout)
+	return errors.Join(errs...)
+}
+
+// print writes cfg to w as a YAML config file, commenting where each setting
+// was set from. The secrets, and the password of the dsn, are redacted.
+func (cfg *config) print(w io.Writer) error {
+	// Registering the settings sets them to their defaults, so the values of
+	// cfg are copied into the registered ones afterwards.
+	fs := flag.NewFlagSet("catalog", flag.ContinueOnError)
+	var printed config
+	printed.register(fs)
+	printed = *cfg
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		value := f.Value.(flag.Getter).Get()
+		switch v := value.(type) {
+		case time.Duration:
+			value = v.String()
+		case string:
+			if secretSettings[f.Name] && v != "" {
+				value = "REDACTED"
+			} else if f.Name == "dsn" {
+				value = redactDSN(v)
+			}
+		}
+		var data []byte
+		data, err = json.Marshal(value)
+		if err != nil {
+			return
+		}
+		source := "default"
+		if s, ok := cfg.sources[f.Name]; ok {
+			source = s
+		}
+		_, err = fmt.Fprintf(w, "%s: %s # %s\n", f.Name, data, source)
+	})
+	return err
+}
+
+// dsnPassword matches the password of a key/value postgres dsn.
+var dsnPassword = regexp.MustCompile(`password=('(?:[^'\\]|\\.)*'|\S*)`)
+
+// redactDSN returns dsn with its password redacted.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "password=xxxxx")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+)
+
+// doctorTimeout is how long the doctor command waits for the database to
+// answer each of its checks.
+const doctorTimeout = 5 * time.Second
+
+// The outcomes of a doctor check.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "FAIL"
+	checkSkip = "skip"
+)
+
+// doctorReport prints the outcomes of the doctor checks as a report,
+// counting the failed ones.
+type doctorReport struct {
+	w      io.Writer
+	failed int
+}
+
+// add prints the outcome of the check named name, along with the lines of
+// detail.
+func (r *doctorReport) add(outcome, name, detail string) {
+	if outcome == checkFail {
+		r.failed++
+	}
+	lines := strings.Split(detail, "\n")
+	fmt.Fprintf(r.w, "%-4s  %-24s  %s\n", outcome, name, lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(r.w, "%-4s  %-24s  %s\n", "", "", line)
+	}
+}
+
+// doctor checks that the server configured by args, as the server is, can
+// start, printing a report of every check and failing if any of them failed.
+func doctor(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: catalog doctor [server flags]")
+		fs.PrintDefaults()
+	}
+	cfg, err := parseConfig(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	report := &doctorReport{w: os.Stdout}
+
+	if err := cfg.validate(); err != nil {
+		report.add(checkFail, "config", err.Error())
+	} else {
+		report.add(checkOK, "config", "settings are set, valid and consistent")
+	}
+	checkDatabase(ctx, report, cfg)
+	if len(cfg.ListenAddrs) == 0 {
+		checkAddr(report, "server address", net.JoinHostPort(cfg.ServerHost, cfg.ServerPort))
+	}
+	for _, addr := range cfg.ListenAddrs {
+		checkAddr(report, "listen address", addr)
+	}
+	for _, addr := range cfg.AdminAddrs {
+		checkAddr(report, "admin address", addr)
+	}
+	for _, addr := range []struct{ name, addr string }{
+		{"metrics address", cfg.MetricsAddr},
+		{"grpc address", cfg.GRPCAddr},
+		{"tls redirect address", cfg.TLSRedirectAddr},
+	} {
+		if addr.addr != "" {
+			checkAddr(report, addr.name, addr.addr)
+		}
+	}
+
+	if report.failed > 0 {
+		return fmt.Errorf("failed checks: %d", report.failed)
+	}
+	return nil
+}
+
+// checkDatabase checks that the database of cfg is reachable and migrated.
+func checkDatabase(ctx context.Context, report *doctorReport, cfg config) {
+	if cfg.DSN == "" {
+		report.add(checkSkip, "database", "dsn is not set")
+		report.add(checkSkip, "migrations", "dsn is not set")
+		return
+	}
+	db, err := catalog.OpenDB(cfg.DSN, catalog.DBOptions{})
+	if err != nil {
+		report.add(checkFail, "database", err.Error())
+		report.add(checkSkip, "migrations", "database is unreachable")
+		return
+	}
+	defer db.Close()
+	pingCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		report.add(checkFail, "database", fmt.Sprintf("connecting to %s: %v", redactDSN(cfg.DSN), err))
+		report.add(checkSkip, "migrations", "database is unreachable")
+		return
+	}
+	report.add(checkOK, "database", "connected to "+redactDSN(cfg.DSN))
+
+	schemaCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	err = catalog.CheckSchema(schemaCtx, db)
+	var drift *catalog.SchemaDriftError
+	switch {
+	case err == nil:
+		report.add(checkOK, "migrations", "database is migrated to the latest migration")
+	case errors.As(err, &drift) && cfg.MigrateDB:
+		// The server migrates the database on start, which fixes a database
+		// that is only behind.
+		report.add(checkWarn, "migrations", strings.Join(append(drift.Problems, "migrate-db is set, so the database is migrated on start"), "\n"))
+	case errors.As(err, &drift):
+		report.add(checkFail, "migrations", strings.Join(drift.Problems, "\n"))
+	default:
+		report.add(checkFail, "migrations", err.Error())
+	}
+}
+
+// checkAddr checks that the listen address addr, named name, can be listened
+// on. The sockets passed by systemd are only passed to the server, so they
+// are not checked.
+func checkAddr(report *doctorReport, name, addr string) {
+	network, address, err := parseListenAddr(addr)
+	if err != nil {
+		report.add(checkFail, name, err.Error())
+		return
+	}
+	switch network {
+	case "systemd":
+		report.add(checkSkip, name, addr+" is passed by systemd on start")
+		return
+	case "unix":
+		// The socket of a running server is replaced on start.
+		if fi, err := os.Lstat(address); err == nil && fi.Mode()&fs.ModeSocket != 0 {
+			report.add(checkOK, name, addr+" is a socket replaced on start")
+			return
+		}
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		report.add(checkFail, name, err.Error())
+		return
+	}
+	l.Close()
+	report.add(checkOK, name, addr+" is available")
+}
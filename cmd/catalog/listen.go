@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The prefix of the listen addresses of Unix domain sockets, such as
+// unix:/run/catalog.sock, and the listen address of the sockets passed by
+// systemd, optionally followed by the name of the sockets, such as
+// systemd:catalog-admin. The other listen addresses are TCP ones, such as
+// :8080.
+const (
+	unixAddrPrefix = "unix:"
+	systemdAddr    = "systemd"
+)
+
+// listenFDsStart is the first file descriptor of the sockets passed by
+// systemd.
+const listenFDsStart = 3
+
+// parseListenAddr returns the network and address of the listen address
+// addr, where the network is "unix", "tcp" or "systemd", whose address is
+// the name of the sockets, if any.
+func parseListenAddr(addr string) (network, address string, err error) {
+	switch {
+	case strings.HasPrefix(addr, unixAddrPrefix):
+		path := strings.TrimPrefix(addr, unixAddrPrefix)
+		if path == "" {
+			return "", "", fmt.Errorf("listen address %q has no socket path", addr)
+		}
+		return "unix", path, nil
+	case addr == systemdAddr:
+		return "systemd", "", nil
+	case strings.HasPrefix(addr, systemdAddr+":"):
+		return "systemd", strings.TrimPrefix(addr, systemdAddr+":"), nil
+	default:
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return "", "", fmt.Errorf("listen address %q: %w", addr, err)
+		}
+		return "tcp", addr, nil
+	}
+}
+
+// listenerOpener opens the listeners of listen addresses.
+type listenerOpener struct {
+	// systemd are the listeners of the sockets passed by systemd, by name,
+	// which are taken from it as they are opened.
+	systemd     map[string][]net.Listener
+	systemdRead bool
+}
+
+// open opens the listeners of the listen addresses addrs.
+func (o *listenerOpener) open(addrs []string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, addr := range addrs {
+		ls, err := o.openAddr(addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ls...)
+	}
+	return listeners, nil
+}
+
+// openAddr opens the listeners of the listen address addr: every socket
+// passed by systemd not opened yet, or the ones named as addr names them, or
+// else a single listener.
+func (o *listenerOpener) openAddr(addr string) ([]net.Listener, error) {
+	network, address, err := parseListenAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch network {
+	case "unix":
+		// The socket left by a server that did not remove it is replaced.
+		if fi, err := os.Lstat(address); err == nil && fi.Mode()&fs.ModeSocket != 0 {
+			if err := os.Remove(address); err != nil {
+				return nil, fmt.Errorf("removing stale socket: %w", err)
+			}
+		}
+	case "systemd":
+		if !o.systemdRead {
+			o.systemdRead = true
+			o.systemd, err = systemdListeners()
+			if err != nil {
+				return nil, err
+			}
+		}
+		var listeners []net.Listener
+		if address == "" {
+			for name, ls := range o.systemd {
+				listeners = append(listeners, ls...)
+				delete(o.systemd, name)
+			}
+		} else {
+			listeners = o.systemd[address]
+			delete(o.systemd, address)
+		}
+		if len(listeners) == 0 {
+			return nil, fmt.Errorf("no sockets passed by systemd left for listen address %q", addr)
+		}
+		return listeners, nil
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	return []net.Listener{l}, nil
+}
+
+// systemdListeners returns the listeners of the sockets passed by systemd
+// socket activation, by the names set by the FileDescriptorName= of their
+// socket units, which default to the names of the units. The environment
+// variables describing the sockets are unset, so that they are not inherited
+// by child processes.
+func systemdListeners() (map[string][]net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, errors.New("no sockets are passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", os.Getenv("LISTEN_FDS"))
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for _, env := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		os.Unsetenv(env)
+	}
+	listeners := make(map[string][]net.Listener, n)
+	for i := range n {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		// The listener uses a duplicate of the file descriptor, so the
+		// file is closed either way.
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("using socket %q passed by systemd: %w", name, err)
+		}
+		listeners[name] = append(listeners[name], l)
+	}
+	return listeners, nil
+}
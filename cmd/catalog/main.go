@@ -3,84 +3,434 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/getsentry/sentry-go"
 	"github.com/google/uuid"
-	"github.com/pressly/goose/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lib/pq"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	catalog "github.com/jhtohru/go-album-catalog"
-	"github.com/jhtohru/go-album-catalog/internal/runutil"
+	"github.com/jhtohru/go-album-catalog/auth"
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/discogs"
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/health"
+	"github.com/jhtohru/go-album-catalog/kafkapub"
+	"github.com/jhtohru/go-album-catalog/musicbrainz"
+	"github.com/jhtohru/go-album-catalog/natspub"
+	"github.com/jhtohru/go-album-catalog/sentryreport"
 )
 
 func main() {
-	if err := run(context.Background()); err != nil {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func run(ctx context.Context) error {
-	var (
-		host          = os.Getenv("SERVER_HOST")
-		port          = runutil.GetenvDefault("SERVER_PORT", "8080")
-		dsn           = runutil.MustGetenv("DSN")
-		willMigrateDB = runutil.GetenvBool("MIGRATE_DB")
-	)
-	if dsn == "" {
-		return fmt.Errorf("postgres dsn is not set")
+// run runs the subcommand named by the first of args, or the album catalog
+// server configured by args if there is none.
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return serve(ctx, args)
+	}
+	switch args[0] {
+	case "mockserve":
+		return mockserve(ctx, args[1:])
+	case "migrate":
+		return migrate(ctx, args[1:])
+	case "seed":
+		return seed(ctx, args[1:])
+	case "doctor":
+		return doctor(ctx, args[1:])
+	case "bench":
+		return bench(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// serve runs the album catalog server configured by args, as described by
+// config.
+func serve(ctx context.Context, args []string) error {
+	cfg, printConfig, err := loadConfig(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if printConfig {
+		return cfg.print(os.Stdout)
 	}
-	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	// The background workers are stopped once the servers are shut down by
+	// catalog.Run, which handles the signals.
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	db, err := sql.Open("postgres", dsn)
+	shutdownTracing, err := setupTracing(ctx, cfg.OTelTracesExporter)
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Printf("Error shutting down tracing: %v\n", err)
+		}
+	}()
+	dbOpts := catalog.DBOptions{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	}
+	db, err := catalog.OpenDB(cfg.DSN, dbOpts)
 	if err != nil {
 		return fmt.Errorf("connecting to database: %w", err)
 	}
-	if willMigrateDB {
-		if err := goose.Up(db, "migrations"); err != nil {
+	if cfg.MigrateDB {
+		if err := catalog.MigrateDB(ctx, db); err != nil {
 			return fmt.Errorf("migrating database: %w", err)
 		}
 	}
-	albumStorage := catalog.NewPostgresAlbumStorage(db)
+	if cfg.CheckSchema {
+		if err := catalog.CheckSchema(ctx, db); err != nil {
+			return fmt.Errorf("checking database schema: %w", err)
+		}
+	}
 	logHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{AddSource: true})
 	logger := slog.New(logHandler)
-	srv := catalog.NewServer(
-		albumStorage,
-		logger,
-		catalog.Validate,
-		uuid.New,
-		time.Now,
+	var storageOpts []catalog.PostgresOption
+	if cfg.SlowQueryThreshold > 0 {
+		storageOpts = append(storageOpts, catalog.WithSlowQueryLog(logger, cfg.SlowQueryThreshold))
+	}
+	readiness := health.NewChecker(5 * time.Second)
+	readiness.Register("postgres", db.PingContext)
+	dbs := map[string]*sql.DB{"main": db}
+	var albumStorage catalog.AlbumStorage
+	switch cfg.DBDriver {
+	case "pq":
+		albumStorage = catalog.NewPostgresAlbumStorage(db, storageOpts...)
+	case "pgx":
+		poolConfig, err := pgxpool.ParseConfig(cfg.DSN)
+		if err != nil {
+			return fmt.Errorf("parsing postgres dsn: %w", err)
+		}
+		if dbOpts.MaxOpenConns > 0 {
+			poolConfig.MaxConns = int32(dbOpts.MaxOpenConns)
+		}
+		if dbOpts.ConnMaxLifetime > 0 {
+			poolConfig.MaxConnLifetime = dbOpts.ConnMaxLifetime
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			return fmt.Errorf("connecting to database: %w", err)
+		}
+		defer pool.Close()
+		albumStorage = catalog.NewPgxAlbumStorage(pool, storageOpts...)
+		readiness.Register("postgres_pool", pool.Ping)
+	default:
+		return fmt.Errorf("unknown database driver %q", cfg.DBDriver)
+	}
+	if cfg.SandboxSchema != "" {
+		if err := catalog.ResetSandbox(ctx, db, cfg.SandboxSchema); err != nil {
+			return fmt.Errorf("resetting sandbox: %w", err)
+		}
+		sandboxDSN, err := withSearchPath(cfg.DSN, cfg.SandboxSchema)
+		if err != nil {
+			return fmt.Errorf("parsing postgres dsn: %w", err)
+		}
+		sandboxDB, err := catalog.OpenDB(sandboxDSN, dbOpts)
+		if err != nil {
+			return fmt.Errorf("connecting to sandbox database: %w", err)
+		}
+		albumStorage = catalog.NewPostgresAlbumStorage(sandboxDB, storageOpts...)
+		readiness.Register("sandbox_postgres", sandboxDB.PingContext)
+		dbs["sandbox"] = sandboxDB
+		go catalog.RunSandboxResets(ctx, db, cfg.SandboxSchema, cfg.SandboxResetInterval, func(err error) {
+			logger.Error("resetting sandbox", "error", err)
+		})
+	}
+	var publisher catalog.EventPublisher
+	switch cfg.EventPublisher {
+	case "discard":
+		publisher = catalog.EventPublisherFunc(func(context.Context, events.Envelope) error {
+			return nil
+		})
+	case "log":
+		publisher = catalog.EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+			logger.InfoContext(ctx, "album event", "event", env)
+			return nil
+		})
+	case "kafka":
+		writer := kafkapub.NewWriter(cfg.KafkaBrokers, cfg.KafkaTopic)
+		defer writer.Close()
+		publisher, err = kafkapub.New(writer, kafkapub.Format(cfg.KafkaFormat))
+		if err != nil {
+			return err
+		}
+	case "nats":
+		nc, err := nats.Connect(cfg.NATSURL)
+		if err != nil {
+			return fmt.Errorf("connecting to nats: %w", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			return fmt.Errorf("creating jetstream context: %w", err)
+		}
+		publisher = natspub.New(js, cfg.NATSSubjectPrefix)
+	default:
+		return fmt.Errorf("unknown event publisher %q", cfg.EventPublisher)
+	}
+	var webhookStorage catalog.WebhookStorage
+	if cfg.Webhooks {
+		webhookStorage = catalog.NewPostgresWebhookStorage(db)
+		// Queue the webhook deliveries of every event before publishing it,
+		// which is idempotent, so that no event is missed by the webhooks if
+		// publishing it fails.
+		publisher = catalog.MultiEventPublisher(catalog.NewWebhookPublisher(db), publisher)
+		client := &http.Client{Timeout: 10 * time.Second}
+		go catalog.RunWebhookDelivery(ctx, db, client, cfg.WebhookDeliveryInterval, func(err error) {
+			logger.Error("delivering webhooks", "error", err)
+		})
+	}
+	var bus *catalog.EventBus
+	if cfg.LiveUpdates {
+		bus = catalog.NewEventBus()
+		publisher = catalog.MultiEventPublisher(publisher, bus)
+	}
+	metadataClient := &http.Client{Timeout: 10 * time.Second}
+	var lookup catalog.MetadataProvider
+	if cfg.MusicBrainzLookup {
+		// MusicBrainz allows a single request per second to each client.
+		lookup = musicbrainz.New(musicbrainz.DefaultBaseURL, metadataClient)
+		lookup = catalog.NewRateLimitedMetadataProvider(lookup, catalog.NewMemoryRateLimiter(1, 1))
+		lookup = catalog.NewCachedMetadataProvider(lookup, cfg.MetadataCacheSize, cfg.MetadataCacheTTL)
+	}
+	var enricher *catalog.MetadataEnricher
+	metadataStorage := catalog.NewPostgresMetadataStorage(db)
+	switch {
+	case cfg.DiscogsToken != "":
+		var provider catalog.MetadataProvider = discogs.New(discogs.DefaultBaseURL, cfg.DiscogsToken, metadataClient)
+		provider = catalog.NewRateLimitedMetadataProvider(provider, catalog.NewMemoryRateLimiter(cfg.DiscogsRateLimit, 1))
+		provider = catalog.NewCachedMetadataProvider(provider, cfg.MetadataCacheSize, cfg.MetadataCacheTTL)
+		enricher = catalog.NewMetadataEnricher(provider, metadataStorage)
+	case lookup != nil:
+		enricher = catalog.NewMetadataEnricher(lookup, metadataStorage)
+	}
+	go catalog.RunOutboxRelay(ctx, db, publisher, cfg.OutboxRelayInterval, func(err error) {
+		logger.Error("relaying outbox", "error", err)
+	})
+	if cfg.CacheSize > 0 {
+		albumStorage = catalog.NewCachedAlbumStorage(albumStorage, cfg.CacheSize, cfg.CacheTTL)
+	}
+	var httpMetrics *catalog.HTTPMetrics
+	if cfg.MetricsAddr != "" {
+		registry := prometheus.NewRegistry()
+		httpMetrics = catalog.NewHTTPMetrics(registry)
+		go serveMetrics(ctx, cfg.MetricsAddr, registry, dbs, cfg.DBStatsInterval)
+	}
+	var reporter catalog.ErrorReporter
+	if cfg.SentryDSN != "" {
+		if err := sentry.Init(sentry.ClientOptions{Dsn: cfg.SentryDSN}); err != nil {
+			return fmt.Errorf("setting up sentry: %w", err)
+		}
+		defer sentry.Flush(5 * time.Second)
+		reporter = sentryreport.New(sentry.CurrentHub())
+	}
+	verifierOpts := auth.VerifierOptions{Issuer: cfg.JWTIssuer, Audience: cfg.JWTAudience}
+	var (
+		verifier *auth.Verifier
+		oidc     *auth.OIDCProvider
 	)
-	httpServer := &http.Server{
-		Addr:    net.JoinHostPort(host, port),
-		Handler: srv,
+	switch {
+	case cfg.OIDCIssuerURL != "":
+		oidc, err = auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+		}, cfg.JWTJWKSRefreshInterval, func(err error) {
+			logger.Error("refreshing jwks", "error", err)
+		})
+		if err != nil {
+			return fmt.Errorf("setting up oidc provider: %w", err)
+		}
+		verifier = oidc.Verifier()
+	case cfg.JWTHS256Secret != "":
+		verifier = auth.NewHS256Verifier([]byte(cfg.JWTHS256Secret), verifierOpts)
+	case cfg.JWTJWKSURL != "":
+		verifier, err = auth.NewJWKSVerifier(ctx, cfg.JWTJWKSURL, cfg.JWTJWKSRefreshInterval, verifierOpts, func(err error) {
+			logger.Error("refreshing jwks", "error", err)
+		})
+		if err != nil {
+			return fmt.Errorf("setting up jwt verifier: %w", err)
+		}
 	}
-	go func() {
-		log.Printf("listening on %s\n", httpServer.Addr)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Error listening and serving: %v\n", err)
+	var limiter catalog.RateLimiter
+	if cfg.RateLimit > 0 {
+		burst := cfg.RateLimitBurst
+		if burst == 0 {
+			burst = int(math.Ceil(cfg.RateLimit))
 		}
-	}()
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		<-ctx.Done()
-		shutdownCtx := context.Background()
-		shutdownCtx, cancel := context.WithTimeout(shutdownCtx, 10*time.Second)
-		defer cancel()
-		if err := httpServer.Shutdown(shutdownCtx); err != nil {
-			log.Printf("Error shutting down the http server: %v\n", err)
+		limiter = catalog.NewMemoryRateLimiter(cfg.RateLimit, burst)
+	}
+	var artwork *catalog.ArtworkFetcher
+	if enricher != nil {
+		artwork = catalog.NewArtworkFetcher(albumStorage, metadataStorage, catalog.NewMemoryBlobStorage(), metadataClient)
+	}
+	albumRules := catalog.AlbumRules{
+		TitleMaxLength:  cfg.TitleMaxLength,
+		ArtistMaxLength: cfg.ArtistMaxLength,
+		MaxPrice:        cfg.MaxPrice,
+		AllowZeroPrice:  cfg.AllowZeroPrice,
+		DefaultCurrency: cfg.DefaultCurrency,
+	}
+	if cfg.RequireCurrency {
+		albumRules.DefaultCurrency = ""
+	}
+	opts := []catalog.ServerOption{
+		catalog.WithLogger(logger),
+		catalog.WithAlbumRules(albumRules),
+		catalog.WithRequestTimeout(cfg.RequestTimeout),
+		catalog.WithAccessLogSkipPaths(cfg.AccessLogSkipPaths...),
+		catalog.WithWebhooks(webhookStorage),
+		catalog.WithErrorReporter(reporter),
+		catalog.WithMetrics(httpMetrics),
+		catalog.WithVerifier(verifier),
+		catalog.WithRateLimiter(limiter),
+		catalog.WithLiveUpdates(bus),
+		catalog.WithMetadataEnricher(enricher),
+		catalog.WithReleaseLookup(lookup),
+		catalog.WithArtwork(artwork),
+		catalog.WithPrices(catalog.NewPostgresPriceStorage(db)),
+		catalog.WithGenres(catalog.NewPostgresGenreStorage(db)),
+	}
+	// The readiness is served by the admin server instead of the API one,
+	// if any.
+	if len(cfg.AdminAddrs) == 0 {
+		opts = append(opts, catalog.WithReadiness(readiness))
+	}
+	if cfg.StrictQueryParams {
+		opts = append(opts, catalog.WithStrictQueryParams())
+	}
+	srv := catalog.NewServer(albumStorage, opts...)
+	if oidc != nil {
+		mux := http.NewServeMux()
+		mux.Handle("GET /auth/login", oidc.LoginHandler())
+		mux.Handle("GET /auth/callback", oidc.CallbackHandler())
+		mux.Handle("/", srv)
+		srv = mux
+	}
+	// The HTTP requests are redirected to the port of the first TCP listen
+	// address, if any.
+	addr := net.JoinHostPort(cfg.ServerHost, cfg.ServerPort)
+	for _, a := range cfg.ListenAddrs {
+		if network, _, _ := parseListenAddr(a); network == "tcp" {
+			addr = a
+			break
 		}
-	}()
-	wg.Wait()
+	}
+	if cfg.H2C {
+		srv = h2c.NewHandler(srv, &http2.Server{IdleTimeout: cfg.IdleTimeout})
+	}
+	httpServer := newHTTPServer(addr, srv, cfg)
+	redirect, err := setupTLS(httpServer, tlsSettings{
+		certFile:         cfg.TLSCertFile,
+		keyFile:          cfg.TLSKeyFile,
+		autocertHosts:    cfg.TLSAutocertHosts,
+		autocertCacheDir: cfg.TLSAutocertCacheDir,
+	})
+	if err != nil {
+		return fmt.Errorf("setting up tls: %w", err)
+	}
+	servers := []*http.Server{httpServer}
+	if redirect != nil && cfg.TLSRedirectAddr != "" {
+		servers = append(servers, newHTTPServer(cfg.TLSRedirectAddr, redirect, cfg))
+	}
+	var opener listenerOpener
+	listeners := make(map[*http.Server][]net.Listener)
+	if len(cfg.ListenAddrs) > 0 {
+		if listeners[httpServer], err = opener.open(cfg.ListenAddrs); err != nil {
+			return err
+		}
+	}
+	if len(cfg.AdminAddrs) > 0 {
+		adminServer := newHTTPServer("", catalog.NewAdminServer(readiness), cfg)
+		if listeners[adminServer], err = opener.open(cfg.AdminAddrs); err != nil {
+			return err
+		}
+		servers = append(servers, adminServer)
+	}
+	runCfg := catalog.Config{
+		Servers:      servers,
+		Listeners:    listeners,
+		Readiness:    readiness,
+		DrainDelay:   cfg.DrainDelay,
+		DrainTimeout: cfg.DrainTimeout,
+		Logger:       logger,
+	}
+	if cfg.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			return fmt.Errorf("listening on grpc address: %w", err)
+		}
+		var opts []grpc.ServerOption
+		if httpServer.TLSConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(httpServer.TLSConfig)))
+		}
+		runCfg.GRPCServer = catalog.NewGRPCServer(
+			albumStorage,
+			logger,
+			catalog.Validate,
+			albumRules,
+			uuid.New,
+			clock.System,
+			verifier,
+			opts...,
+		)
+		runCfg.GRPCListener = lis
+	}
+
+	return catalog.Run(ctx, runCfg)
+}
+
+// newHTTPServer returns a new http.Server serving handler on addr, timing out
+// slow clients as set by cfg.
+func newHTTPServer(addr string, handler http.Handler, cfg config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
 
-	return nil
+// withSearchPath returns dsn with its search path set to schema.
+func withSearchPath(dsn, schema string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return "", err
+		}
+	}
+	return dsn + " search_path=" + schema, nil
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serveMetrics serves the Prometheus metrics of registry at /metrics on addr
+// until ctx is done, collecting into it the connection pool stats of dbs, by
+// their names, once every interval. The metrics are served in the OpenMetrics
+// format if requested, so that their exemplars are exposed.
+func serveMetrics(ctx context.Context, addr string, registry *prometheus.Registry, dbs map[string]*sql.DB, interval time.Duration) {
+	go newDBStatsCollector(registry).run(ctx, dbs, interval)
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	metricsServer := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down the metrics server: %v\n", err)
+		}
+	}()
+	log.Printf("serving metrics on %s\n", metricsServer.Addr)
+	if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Error serving metrics: %v\n", err)
+	}
+}
+
+// dbStatsCollector exports the connection pool statistics of databases as
+// Prometheus gauges labeled with the database name.
+type dbStatsCollector struct {
+	maxOpenConns *prometheus.GaugeVec
+	openConns    *prometheus.GaugeVec
+	inUseConns   *prometheus.GaugeVec
+	idleConns    *prometheus.GaugeVec
+	waitCount    *prometheus.GaugeVec
+	waitDuration *prometheus.GaugeVec
+}
+
+// newDBStatsCollector returns a new dbStatsCollector whose gauges are
+// registered into registerer.
+func newDBStatsCollector(registerer prometheus.Registerer) *dbStatsCollector {
+	newGauge := func(name, help string) *prometheus.GaugeVec {
+		gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "catalog",
+			Subsystem: "db",
+			Name:      name,
+			Help:      help,
+		}, []string{"db"})
+		registerer.MustRegister(gauge)
+		return gauge
+	}
+	return &dbStatsCollector{
+		maxOpenConns: newGauge("max_open_connections", "Maximum number of open connections to the database."),
+		openConns:    newGauge("open_connections", "Number of established connections, both in use and idle."),
+		inUseConns:   newGauge("in_use_connections", "Number of connections currently in use."),
+		idleConns:    newGauge("idle_connections", "Number of idle connections."),
+		waitCount:    newGauge("wait_count", "Total number of connections waited for."),
+		waitDuration: newGauge("wait_duration_seconds", "Total time blocked waiting for a new connection."),
+	}
+}
+
+// collect sets the gauges of the database named name to its current stats.
+func (c *dbStatsCollector) collect(name string, db *sql.DB) {
+	stats := db.Stats()
+	c.maxOpenConns.WithLabelValues(name).Set(float64(stats.MaxOpenConnections))
+	c.openConns.WithLabelValues(name).Set(float64(stats.OpenConnections))
+	c.inUseConns.WithLabelValues(name).Set(float64(stats.InUse))
+	c.idleConns.WithLabelValues(name).Set(float64(stats.Idle))
+	c.waitCount.WithLabelValues(name).Set(float64(stats.WaitCount))
+	c.waitDuration.WithLabelValues(name).Set(stats.WaitDuration.Seconds())
+}
+
+// run collects the stats of dbs, by their names, once every interval until
+// ctx is done.
+func (c *dbStatsCollector) run(ctx context.Context, dbs map[string]*sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for name, db := range dbs {
+			c.collect(name, db)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
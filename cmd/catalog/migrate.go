@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+)
+
+// migrate runs the migration command named by the first of the non-flag args.
+func migrate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: catalog migrate [flags] up|down|status|create <name>|backfill-release-years")
+		flags.PrintDefaults()
+	}
+	var (
+		dsn       = flags.String("dsn", os.Getenv("DSN"), "postgres dsn, defaults to the DSN environment variable")
+		dir       = flags.String("dir", "migrations", "directory the create command writes migrations to")
+		batchSize = flags.Int("batch-size", 1000, "albums the backfill-release-years command updates at a time")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing migration command")
+	}
+	command := flags.Arg(0)
+	if command == "create" {
+		if flags.NArg() != 2 {
+			return errors.New("missing migration name")
+		}
+		return goose.Create(nil, *dir, flags.Arg(1), "sql")
+	}
+	if *dsn == "" {
+		return errors.New("postgres dsn is not set")
+	}
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+	if command == "backfill-release-years" {
+		if *batchSize < 1 {
+			return errors.New("batch size must be positive")
+		}
+		n, err := catalog.BackfillOriginalReleaseYears(ctx, db, *batchSize)
+		fmt.Printf("backfilled the original release year of %d albums\n", n)
+		if err != nil {
+			return fmt.Errorf("backfilling original release years: %w", err)
+		}
+		return nil
+	}
+	provider, err := catalog.NewMigrationProvider(db)
+	if err != nil {
+		return fmt.Errorf("loading migrations: %w", err)
+	}
+	switch command {
+	case "up":
+		results, err := provider.Up(ctx)
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if err != nil {
+			return fmt.Errorf("migrating database up: %w", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		if result != nil {
+			fmt.Println(result)
+		}
+		if err != nil {
+			return fmt.Errorf("migrating database down: %w", err)
+		}
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("getting migration status: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tSOURCE")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Source.Version, status.State, appliedAt, status.Source.Path)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migration command %q", command)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/internal/mockserver"
+)
+
+// mockserve runs a server that responds with the examples of the OpenAPI
+// Specification document instead of real data.
+func mockserve(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("mockserve", flag.ContinueOnError)
+	var (
+		addr     = flags.String("addr", ":8080", "address to listen on")
+		specPath = flags.String("spec", "docs/oas.yaml", "path to the OpenAPI Specification document")
+		latency  = flags.Duration("latency", 0, "latency injected into every response")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	spec, err := os.ReadFile(*specPath)
+	if err != nil {
+		return fmt.Errorf("reading OpenAPI Specification document: %w", err)
+	}
+	handler, err := mockserver.New(spec, *latency)
+	if err != nil {
+		return fmt.Errorf("building mock server: %w", err)
+	}
+	httpServer := &http.Server{
+		Addr:    *addr,
+		Handler: handler,
+	}
+	log.Printf("serving mocked responses on %s\n", httpServer.Addr)
+	return catalog.Run(ctx, catalog.Config{Servers: []*http.Server{httpServer}})
+}
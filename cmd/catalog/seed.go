@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/internal/random"
+)
+
+// seedBatchSize is how many albums the seed command inserts at once.
+const seedBatchSize = 100
+
+// seed populates the database with plausible sample albums.
+func seed(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: catalog seed [flags]")
+		flags.PrintDefaults()
+	}
+	var (
+		dsn    = flags.String("dsn", os.Getenv("DSN"), "postgres dsn, defaults to the DSN environment variable")
+		count  = flags.Int("count", 500, "number of albums to insert")
+		tenant = flags.String("tenant", "", "tenant whose catalog the albums are inserted into, empty for the default one")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dsn == "" {
+		return errors.New("postgres dsn is not set")
+	}
+	if *count < 1 {
+		return errors.New("count must be positive")
+	}
+	db, err := catalog.OpenDB(*dsn, catalog.DBOptions{})
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+	ctx = catalog.NewActorContext(catalog.NewTenantContext(ctx, *tenant), "seed")
+
+	albs := sampleAlbums(*count, time.Now())
+	inserted := 0
+	for start := 0; start < len(albs); start += seedBatchSize {
+		batch := albs[start:min(start+seedBatchSize, len(albs))]
+		n, err := insertSampleAlbums(ctx, storage, batch)
+		inserted += n
+		if err != nil {
+			return fmt.Errorf("inserting albums: %w", err)
+		}
+	}
+	fmt.Printf("inserted %d albums, skipped %d already existing\n", inserted, len(albs)-inserted)
+
+	return nil
+}
+
+// sampleAlbums returns n plausible albums with distinct artists and titles,
+// created during the year before now.
+func sampleAlbums(n int, now time.Time) []catalog.Album {
+	albs := make([]catalog.Album, 0, n)
+	seen := make(map[string]bool, n)
+	for len(albs) < n {
+		artist, title := random.Artist(), random.AlbumTitle()
+		key := strings.ToLower(artist) + "\x00" + strings.ToLower(title)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		createdAt := random.TimeBetween(now.AddDate(-1, 0, 0), now).UTC().Truncate(time.Microsecond)
+		albs = append(albs, catalog.Album{
+			ID:        uuid.New(),
+			Title:     title,
+			Artist:    artist,
+			Price:     random.Price(),
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Version:   1,
+			CreatedBy: "seed",
+			UpdatedBy: "seed",
+		})
+	}
+
+	return albs
+}
+
+// insertSampleAlbums inserts albs into storage at once, falling back to
+// inserting them one by one, skipping the ones already in the storage, if
+// any of them is. It returns how many albums were inserted.
+func insertSampleAlbums(ctx context.Context, storage catalog.AlbumStorage, albs []catalog.Album) (int, error) {
+	err := storage.InsertBatch(ctx, albs)
+	if err == nil {
+		return len(albs), nil
+	}
+	if !errors.Is(err, catalog.ErrAlbumAlreadyExists) {
+		return 0, err
+	}
+	inserted := 0
+	for _, alb := range albs {
+		err := storage.Insert(ctx, alb)
+		if errors.Is(err, catalog.ErrAlbumAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return inserted, err
+		}
+		inserted++
+	}
+
+	return inserted, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsSettings are the settings of the TLS the server is served over.
+type tlsSettings struct {
+	// certFile and keyFile are the PEM files of the certificate and its key.
+	certFile string
+	keyFile  string
+	// autocertHosts are the hosts whose certificates are obtained from Let's
+	// Encrypt, cached into autocertCacheDir.
+	autocertHosts    []string
+	autocertCacheDir string
+}
+
+// setupTLS sets up httpServer to be served over TLS with the certificate of
+// the files of s, or with the certificates obtained from Let's Encrypt for
+// the autocert hosts of s. It returns the handler redirecting plain HTTP
+// requests to httpServer, which also answers the ACME HTTP challenges in
+// autocert mode, or nil if s sets up no TLS.
+func setupTLS(httpServer *http.Server, s tlsSettings) (http.Handler, error) {
+	_, port, err := net.SplitHostPort(httpServer.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing server address: %w", err)
+	}
+	redirect := redirectToHTTPS(port)
+	switch {
+	case s.certFile != "" && len(s.autocertHosts) > 0:
+		return nil, fmt.Errorf("both a tls certificate and autocert hosts are set")
+	case s.certFile != "":
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading tls certificate: %w", err)
+		}
+		httpServer.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		return redirect, nil
+	case len(s.autocertHosts) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(s.autocertHosts...),
+			Cache:      autocert.DirCache(s.autocertCacheDir),
+		}
+		httpServer.TLSConfig = m.TLSConfig()
+		httpServer.TLSConfig.MinVersion = tls.VersionTLS12
+		return m.HTTPHandler(redirect), nil
+	default:
+		return nil, nil
+	}
+}
+
+// redirectToHTTPS returns an http.Handler that permanently redirects requests
+// to the same URL over HTTPS on port.
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
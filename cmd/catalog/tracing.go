@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// setupTracing sets up the global OpenTelemetry tracer provider to export
+// spans with exporter, which is either "none" or "otlp". The OTLP exporter is
+// configured by the standard OTEL_EXPORTER_OTLP_* environment variables and
+// the traced service by OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES. It
+// returns a function that flushes the pending spans and shuts the provider
+// down.
+func setupTracing(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	switch exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", exporter)
+	}
+	spanExporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating otlp exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return provider.Shutdown, nil
+}
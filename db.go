@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DBOptions are the connection pool settings of a database opened by OpenDB.
+// The zero value of each field keeps the database/sql default.
+type DBOptions struct {
+	// MaxOpenConns is the maximum number of open connections to the
+	// database.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections kept open.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum amount of time a connection may be
+	// reused.
+	ConnMaxLifetime time.Duration
+}
+
+// OpenDB opens the Postgres database described by dsn with lib/pq, setting up
+// its connection pool with opts. As sql.Open, it does not connect to the
+// database.
+func OpenDB(dsn string, opts DBOptions) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	return db, nil
+}
@@ -0,0 +1,29 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenDB(t *testing.T) {
+	t.Run("default settings", func(t *testing.T) {
+		db, err := OpenDB("host=localhost", DBOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		assert.Equal(t, 0, db.Stats().MaxOpenConnections)
+	})
+
+	t.Run("max open connections", func(t *testing.T) {
+		db, err := OpenDB("host=localhost", DBOptions{MaxOpenConns: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		assert.Equal(t, 7, db.Stats().MaxOpenConnections)
+	})
+}
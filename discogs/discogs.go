@@ -0,0 +1,116 @@
+// Package discogs provides a catalog.MetadataProvider that searches for album
+// releases in the Discogs database through its API.
+//
+// See https://www.discogs.com/developers for the API and its rate limits.
+package discogs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+)
+
+// DefaultBaseURL is the base URL of the Discogs API.
+const DefaultBaseURL = "https://api.discogs.com"
+
+// userAgent identifies the requests to the Discogs API, which requires one.
+const userAgent = "go-album-catalog/1.0 +https://github.com/jhtohru/go-album-catalog"
+
+// maxResults is the maximum number of releases returned by a search.
+const maxResults = 10
+
+// Client is a catalog.MetadataProvider that searches for releases through the
+// Discogs API. It is safe for concurrent use.
+type Client struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// New returns a new Client that authenticates to the Discogs API at baseURL,
+// usually DefaultBaseURL, with the personal access token token, requesting it
+// through client.
+func New(baseURL, token string, client *http.Client) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, client: client}
+}
+
+// Name makes Client implement catalog.MetadataProvider.
+func (c *Client) Name() string {
+	return "discogs"
+}
+
+// searchResponse is the response of the database search endpoint.
+type searchResponse struct {
+	Results []struct {
+		ID int64 `json:"id"`
+		// Title is the artist and the title of the release, separated by
+		// " - ".
+		Title      string   `json:"title"`
+		Year       string   `json:"year"`
+		Format     []string `json:"format"`
+		Genre      []string `json:"genre"`
+		CoverImage string   `json:"cover_image"`
+	} `json:"results"`
+}
+
+// SearchReleases makes Client implement catalog.MetadataProvider.
+func (c *Client) SearchReleases(ctx context.Context, artist, title string) ([]catalog.Release, error) {
+	q := url.Values{
+		"type":          []string{"release"},
+		"artist":        []string{artist},
+		"release_title": []string{title},
+		"per_page":      []string{strconv.Itoa(maxResults)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/database/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Discogs token="+c.token)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/vnd.discogs.v2.discogs+json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var body searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding json: %w", err)
+	}
+	releases := make([]catalog.Release, len(body.Results))
+	for i, result := range body.Results {
+		releaseArtist, releaseTitle, ok := strings.Cut(result.Title, " - ")
+		if !ok {
+			releaseArtist, releaseTitle = "", result.Title
+		}
+		releases[i] = catalog.Release{
+			ID:          strconv.FormatInt(result.ID, 10),
+			Title:       releaseTitle,
+			Artist:      releaseArtist,
+			Date:        result.Year,
+			Formats:     nonNil(result.Format),
+			Genres:      nonNil(result.Genre),
+			CoverArtURL: result.CoverImage,
+		}
+	}
+	return releases, nil
+}
+
+// nonNil returns strs, or an empty slice if it is nil.
+func nonNil(strs []string) []string {
+	if strs == nil {
+		return []string{}
+	}
+	return strs
+}
@@ -0,0 +1,75 @@
+package discogs_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/discogs"
+)
+
+func TestClientSearchReleases(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/database/search", r.URL.Path)
+			assert.Equal(t, "release", r.URL.Query().Get("type"))
+			assert.Equal(t, "Racionais MC's", r.URL.Query().Get("artist"))
+			assert.Equal(t, "Sobrevivendo no Inferno", r.URL.Query().Get("release_title"))
+			assert.Equal(t, "Discogs token=secret", r.Header.Get("Authorization"))
+			assert.NotEmpty(t, r.Header.Get("User-Agent"))
+			w.Write([]byte(`{
+				"pagination": {"items": 2},
+				"results": [
+					{
+						"id": 1234,
+						"title": "Racionais MC's - Sobrevivendo no Inferno",
+						"year": "1997",
+						"format": ["CD", "Album"],
+						"genre": ["Hip Hop"],
+						"cover_image": "https://i.discogs.com/1234.jpg"
+					},
+					{"id": 5678, "title": "Sobrevivendo no Inferno"}
+				]
+			}`))
+		}))
+		defer api.Close()
+		client := discogs.New(api.URL, "secret", api.Client())
+
+		releases, err := client.SearchReleases(context.Background(), "Racionais MC's", "Sobrevivendo no Inferno")
+
+		assert.Nil(t, err)
+		assert.Equal(t, []catalog.Release{
+			{
+				ID:          "1234",
+				Title:       "Sobrevivendo no Inferno",
+				Artist:      "Racionais MC's",
+				Date:        "1997",
+				Formats:     []string{"CD", "Album"},
+				Genres:      []string{"Hip Hop"},
+				CoverArtURL: "https://i.discogs.com/1234.jpg",
+			},
+			{
+				ID:      "5678",
+				Title:   "Sobrevivendo no Inferno",
+				Formats: []string{},
+				Genres:  []string{},
+			},
+		}, releases)
+	})
+
+	t.Run("unexpected status", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer api.Close()
+		client := discogs.New(api.URL, "secret", api.Client())
+
+		_, err := client.SearchReleases(context.Background(), "Racionais MC's", "Sobrevivendo no Inferno")
+
+		assert.EqualError(t, err, "unexpected status 429 Too Many Requests")
+	})
+}
@@ -0,0 +1,61 @@
+package catalog
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+// eventBusBuffer is the number of events buffered for each subscriber of an
+// EventBus.
+const eventBusBuffer = 64
+
+// EventBus is an EventPublisher that broadcasts the events published to it to
+// its subscribers in the same process, such as the clients of live update
+// endpoints. It is safe for concurrent use.
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[chan events.Envelope]struct{}
+}
+
+// NewEventBus returns a new EventBus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[chan events.Envelope]struct{})}
+}
+
+// Publish makes EventBus implement EventPublisher. It never blocks: the
+// subscribers that fell so far behind that their buffer is full are
+// unsubscribed, closing their channel, instead of missing the event silently.
+func (b *EventBus) Publish(_ context.Context, env events.Envelope) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- env:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to the events published to b from now on, returning
+// the channel they are received from and a function that unsubscribes. The
+// channel is closed once unsubscribed.
+func (b *EventBus) Subscribe() (<-chan events.Envelope, func()) {
+	ch := make(chan events.Envelope, eventBusBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
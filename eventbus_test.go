@@ -0,0 +1,51 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	envs1, unsubscribe1 := bus.Subscribe()
+	envs2, unsubscribe2 := bus.Subscribe()
+	env := events.Envelope{ID: uuid.New(), Type: events.TypeAlbumCreated}
+
+	err := bus.Publish(context.Background(), env)
+
+	assert.Nil(t, err)
+	assert.Equal(t, env, <-envs1)
+	assert.Equal(t, env, <-envs2)
+
+	// Unsubscribed channels are closed and receive no more events.
+	unsubscribe1()
+	unsubscribe1()
+	_, ok := <-envs1
+	assert.False(t, ok)
+	assert.Nil(t, bus.Publish(context.Background(), env))
+	assert.Equal(t, env, <-envs2)
+	unsubscribe2()
+}
+
+func TestEventBus_slowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	envs, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	for range eventBusBuffer + 1 {
+		bus.Publish(context.Background(), events.Envelope{ID: uuid.New()})
+	}
+
+	// The subscriber receives the buffered events and then its channel is
+	// closed.
+	var received int
+	for range envs {
+		received++
+	}
+	assert.Equal(t, eventBusBuffer, received)
+}
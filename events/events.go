@@ -0,0 +1,173 @@
+// Package events defines the album domain events and their JSON encoding,
+// shared by every feature that propagates album changes.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/civil"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// SchemaVersion is the version of the event payload schemas. It is increased
+// whenever a payload changes in a way that is not backward compatible: version
+// 2 priced the albums in money.Money instead of bare integers, which are still
+// decoded from version 1 payloads.
+const SchemaVersion = 2
+
+// Type identifies the kind of an Event.
+type Type string
+
+// The types of the album domain events.
+const (
+	TypeAlbumCreated Type = "album.created"
+	TypeAlbumUpdated Type = "album.updated"
+	TypeAlbumDeleted Type = "album.deleted"
+)
+
+// ErrUnknownType is returned when decoding an Envelope of an unknown Type.
+var ErrUnknownType = errors.New("unknown event type")
+
+// ErrUnsupportedVersion is returned when decoding an Envelope whose schema
+// version is greater than SchemaVersion.
+var ErrUnsupportedVersion = errors.New("unsupported event schema version")
+
+// Event is an album domain event.
+type Event interface {
+	// Type returns the Type of the event.
+	Type() Type
+}
+
+// Album is the album state carried by the events.
+type Album struct {
+	ID        uuid.UUID   `json:"id"`
+	Title     string      `json:"title"`
+	Artist    string      `json:"artist"`
+	Price     money.Money `json:"price"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Version   int         `json:"version"`
+	// TenantID is the tenant whose catalog the album belongs to, empty for
+	// the default one.
+	TenantID string `json:"tenant_id,omitempty"`
+	// CreatedBy and UpdatedBy are who created and last updated the album, if
+	// known.
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// Artwork is the key of the cover art of the album in the blob storage
+	// of the catalog, empty if it has none.
+	Artwork string `json:"artwork,omitempty"`
+	// ReleaseDate is the date the album was released on, nil if unknown.
+	ReleaseDate *civil.Date `json:"release_date,omitempty"`
+	// OriginalReleaseYear is the year the album was originally released in,
+	// before any reissue, zero if unknown.
+	OriginalReleaseYear int `json:"original_release_year,omitempty"`
+}
+
+// AlbumCreated is the event of an album being created.
+type AlbumCreated struct {
+	Album Album `json:"album"`
+}
+
+// Type makes AlbumCreated implement Event.
+func (AlbumCreated) Type() Type { return TypeAlbumCreated }
+
+// AlbumUpdated is the event of an album being updated.
+type AlbumUpdated struct {
+	Album   Album    `json:"album"`
+	Changes []Change `json:"changes"`
+}
+
+// Type makes AlbumUpdated implement Event.
+func (AlbumUpdated) Type() Type { return TypeAlbumUpdated }
+
+// AlbumDeleted is the event of an album being deleted.
+type AlbumDeleted struct {
+	Album Album `json:"album"`
+}
+
+// Type makes AlbumDeleted implement Event.
+func (AlbumDeleted) Type() Type { return TypeAlbumDeleted }
+
+// Change is the change of a single album field, named by its JSON name.
+type Change struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// Diff returns the changes of the fields of old into new, in field order. The
+// update time, the updater and the version are not reported as changes.
+func Diff(old, new Album) []Change {
+	var changes []Change
+	add := func(field string, oldValue, newValue any) {
+		oldJSON, _ := json.Marshal(oldValue)
+		newJSON, _ := json.Marshal(newValue)
+		if string(oldJSON) != string(newJSON) {
+			changes = append(changes, Change{Field: field, Old: oldJSON, New: newJSON})
+		}
+	}
+	add("title", old.Title, new.Title)
+	add("artist", old.Artist, new.Artist)
+	add("price", old.Price, new.Price)
+	add("created_at", old.CreatedAt, new.CreatedAt)
+	add("artwork", old.Artwork, new.Artwork)
+	add("release_date", old.ReleaseDate, new.ReleaseDate)
+	add("original_release_year", old.OriginalReleaseYear, new.OriginalReleaseYear)
+	return changes
+}
+
+// Envelope is the JSON representation of an Event.
+type Envelope struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          Type            `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Wrap returns the Envelope of e identified by id that occurred at occurredAt.
+func Wrap(id uuid.UUID, occurredAt time.Time, e Event) (Envelope, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("encoding %s event: %w", e.Type(), err)
+	}
+	return Envelope{
+		ID:            id,
+		Type:          e.Type(),
+		SchemaVersion: SchemaVersion,
+		OccurredAt:    occurredAt,
+		Data:          data,
+	}, nil
+}
+
+// Unwrap returns the Event carried by env. It returns ErrUnknownType if the
+// type of env is unknown, or ErrUnsupportedVersion if its schema version is
+// greater than SchemaVersion.
+func Unwrap(env Envelope) (Event, error) {
+	if env.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, env.SchemaVersion)
+	}
+	switch env.Type {
+	case TypeAlbumCreated:
+		return unwrap[AlbumCreated](env)
+	case TypeAlbumUpdated:
+		return unwrap[AlbumUpdated](env)
+	case TypeAlbumDeleted:
+		return unwrap[AlbumDeleted](env)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownType, env.Type)
+}
+
+func unwrap[E Event](env Envelope) (Event, error) {
+	var e E
+	if err := json.Unmarshal(env.Data, &e); err != nil {
+		return nil, fmt.Errorf("decoding %s event: %w", env.Type, err)
+	}
+	return e, nil
+}
@@ -0,0 +1,112 @@
+package events_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+func TestDiff(t *testing.T) {
+	old := events.Album{
+		ID:        uuid.New(),
+		Title:     "Anathema",
+		Artist:    "Judgement",
+		Price:     money.New(1234, "USD"),
+		CreatedAt: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+		Version:   1,
+	}
+	new := old
+	new.Title = "Babylon By Gus Vol.1 - O Ano do Macaco"
+	new.Price = money.New(12345, "USD")
+	new.Artwork = "artwork/anathema"
+	new.UpdatedAt = time.Date(2024, 8, 2, 0, 0, 0, 0, time.UTC)
+	new.Version = 2
+
+	changes := events.Diff(old, new)
+
+	assert.Equal(t, []events.Change{
+		{
+			Field: "title",
+			Old:   json.RawMessage(`"Anathema"`),
+			New:   json.RawMessage(`"Babylon By Gus Vol.1 - O Ano do Macaco"`),
+		},
+		{
+			Field: "price",
+			Old:   json.RawMessage(`{"amount":1234,"currency":"USD"}`),
+			New:   json.RawMessage(`{"amount":12345,"currency":"USD"}`),
+		},
+		{
+			Field: "artwork",
+			Old:   json.RawMessage(`""`),
+			New:   json.RawMessage(`"artwork/anathema"`),
+		},
+	}, changes)
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	tests := map[string]events.Event{
+		"album created": events.AlbumCreated{Album: events.Album{ID: uuid.New(), Title: "Anathema"}},
+		"album updated": events.AlbumUpdated{
+			Album: events.Album{ID: uuid.New(), Title: "Anathema"},
+			Changes: []events.Change{
+				{Field: "title", Old: json.RawMessage(`"Judgement"`), New: json.RawMessage(`"Anathema"`)},
+			},
+		},
+		"album deleted": events.AlbumDeleted{Album: events.Album{ID: uuid.New(), Title: "Anathema"}},
+	}
+	for testName, e := range tests {
+		t.Run(testName, func(t *testing.T) {
+			env, err := events.Wrap(uuid.New(), time.Now(), e)
+			assert.Nil(t, err)
+			assert.Equal(t, e.Type(), env.Type)
+			assert.Equal(t, events.SchemaVersion, env.SchemaVersion)
+
+			got, err := events.Unwrap(env)
+
+			assert.Nil(t, err)
+			assert.Equal(t, e, got)
+		})
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := events.Unwrap(events.Envelope{Type: "album.sold", SchemaVersion: events.SchemaVersion})
+
+		assert.ErrorIs(t, err, events.ErrUnknownType)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		_, err := events.Unwrap(events.Envelope{Type: events.TypeAlbumCreated, SchemaVersion: events.SchemaVersion + 1})
+
+		assert.ErrorIs(t, err, events.ErrUnsupportedVersion)
+	})
+
+	t.Run("version 1 price", func(t *testing.T) {
+		e, err := events.Unwrap(events.Envelope{
+			Type:          events.TypeAlbumCreated,
+			SchemaVersion: 1,
+			Data:          json.RawMessage(`{"album": {"title": "Anathema", "price": 1234}}`),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, events.AlbumCreated{Album: events.Album{Title: "Anathema", Price: money.Money{AmountMinor: 1234}}}, e)
+	})
+
+	t.Run("malformed data", func(t *testing.T) {
+		_, err := events.Unwrap(events.Envelope{
+			Type:          events.TypeAlbumCreated,
+			SchemaVersion: events.SchemaVersion,
+			Data:          json.RawMessage(`[]`),
+		})
+
+		assert.NotNil(t, err)
+	})
+}
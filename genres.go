@@ -0,0 +1,198 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/internal/pgdb"
+)
+
+// Genre is a genre albums can be assigned to, such as jazz.
+type Genre struct {
+	// Slug identifies the genre within the catalog of its tenant, such as
+	// "free-jazz". It is made of lower case letters and digits, separated by
+	// single hyphens.
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	// TenantID is the tenant whose catalog has the genre, empty for the
+	// default one.
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// GenreStorage represents a genre storage, keeping the genres and the genres
+// each album is assigned to.
+//
+// As an AlbumStorage does, a GenreStorage keeps separate genres for each
+// tenant, only operating on the genres and albums of the tenant its context is
+// scoped to by NewTenantContext.
+type GenreStorage interface {
+	// Insert inserts a Genre into the storage. It returns ErrGenreAlreadyExists
+	// if there is a Genre in the storage whose Slug is equal to genre.Slug.
+	Insert(ctx context.Context, genre Genre) error
+	// FindAll finds all Genres in the storage, ordered by slug.
+	FindAll(ctx context.Context) ([]Genre, error)
+	// Remove removes the single Genre in the storage whose Slug is equal to
+	// slug, unassigning it from its albums. It returns ErrGenreNotFound if
+	// there is no such Genre.
+	Remove(ctx context.Context, slug string) error
+	// SetAlbumGenres replaces the genres the album identified by albumID is
+	// assigned to with the Genres whose Slugs are in slugs. It returns
+	// ErrAlbumNotFound if there is no such album, and ErrGenreNotFound if
+	// any of the Genres is not in the storage.
+	SetAlbumGenres(ctx context.Context, albumID uuid.UUID, slugs []string) error
+	// FindAlbumGenres finds the Genres the album identified by albumID is
+	// assigned to, ordered by slug.
+	FindAlbumGenres(ctx context.Context, albumID uuid.UUID) ([]Genre, error)
+	// FindAllInGenre finds the Albums assigned to the Genre whose Slug is
+	// equal to slug within offset and limit, in the same order as
+	// AlbumStorage.FindAll. It returns no Albums, and no error, if there is no
+	// such Genre.
+	FindAllInGenre(ctx context.Context, slug string, offset, limit int) ([]Album, error)
+}
+
+var (
+	// ErrGenreNotFound is returned when the required genre is not found in
+	// the GenreStorage.
+	ErrGenreNotFound = errors.New("genre not found")
+	// ErrGenreAlreadyExists is returned when inserting a genre whose slug is
+	// already taken into the GenreStorage.
+	ErrGenreAlreadyExists = errors.New("genre already exists")
+)
+
+type pgGenreStorage struct {
+	db      *sql.DB
+	queries *pgdb.Queries
+}
+
+// NewPostgresGenreStorage returns a new GenreStorage that uses Postgres to
+// manage data.
+func NewPostgresGenreStorage(db *sql.DB) GenreStorage {
+	return &pgGenreStorage{db: db, queries: pgdb.New(db)}
+}
+
+func (s *pgGenreStorage) Insert(ctx context.Context, genre Genre) error {
+	err := s.queries.InsertGenre(ctx, pgdb.InsertGenreParams{
+		TenantID:  TenantFromContext(ctx),
+		Slug:      genre.Slug,
+		Name:      genre.Name,
+		CreatedAt: genre.CreatedAt,
+	})
+	if isPgError(err, uniqueViolation) {
+		return ErrGenreAlreadyExists
+	}
+	return err
+}
+
+func (s *pgGenreStorage) FindAll(ctx context.Context) ([]Genre, error) {
+	rows, err := s.queries.FindGenres(ctx, TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return genresFromRows(rows), nil
+}
+
+func (s *pgGenreStorage) Remove(ctx context.Context, slug string) error {
+	rowsAffected, err := s.queries.RemoveGenre(ctx, pgdb.RemoveGenreParams{
+		TenantID: TenantFromContext(ctx),
+		Slug:     slug,
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrGenreNotFound
+	}
+	return nil
+}
+
+func (s *pgGenreStorage) SetAlbumGenres(ctx context.Context, albumID uuid.UUID, slugs []string) error {
+	tenantID := TenantFromContext(ctx)
+	slugs = slices.Compact(slices.Sorted(slices.Values(slugs)))
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := s.queries.WithTx(tx)
+	exists, err := queries.AlbumExists(ctx, pgdb.AlbumExistsParams{ID: albumID, TenantID: tenantID})
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAlbumNotFound
+	}
+	count, err := queries.CountGenres(ctx, pgdb.CountGenresParams{TenantID: tenantID, Slugs: slugs})
+	if err != nil {
+		return err
+	}
+	if count < int64(len(slugs)) {
+		return ErrGenreNotFound
+	}
+	if err := queries.DeleteAlbumGenres(ctx, albumID); err != nil {
+		return err
+	}
+	for _, slug := range slugs {
+		err := queries.InsertAlbumGenre(ctx, pgdb.InsertAlbumGenreParams{
+			AlbumID:   albumID,
+			TenantID:  tenantID,
+			GenreSlug: slug,
+		})
+		// A genre was removed since it was counted.
+		if isPgError(err, foreignKeyViolation) {
+			return ErrGenreNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *pgGenreStorage) FindAlbumGenres(ctx context.Context, albumID uuid.UUID) ([]Genre, error) {
+	rows, err := s.queries.FindAlbumGenres(ctx, pgdb.FindAlbumGenresParams{
+		AlbumID:  albumID,
+		TenantID: TenantFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return genresFromRows(rows), nil
+}
+
+func (s *pgGenreStorage) FindAllInGenre(ctx context.Context, slug string, offset, limit int) ([]Album, error) {
+	rows, err := s.queries.FindAlbumsInGenre(ctx, pgdb.FindAlbumsInGenreParams{
+		TenantID:   TenantFromContext(ctx),
+		GenreSlug:  slug,
+		PageOffset: int32(offset),
+		PageLimit:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	albs := make([]Album, len(rows))
+	for i, row := range rows {
+		albs[i] = albumFromRow(row)
+	}
+	return albs, nil
+}
+
+// genresFromRows converts rows into Genres.
+func genresFromRows(rows []pgdb.Genre) []Genre {
+	genres := make([]Genre, len(rows))
+	for i, row := range rows {
+		genres[i] = Genre{
+			Slug:      row.Slug,
+			Name:      row.Name,
+			CreatedAt: row.CreatedAt.UTC(),
+			TenantID:  row.TenantID,
+		}
+	}
+	return genres
+}
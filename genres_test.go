@@ -0,0 +1,85 @@
+package catalog_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+)
+
+func TestPostgresGenreStorage(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresGenreStorage(db)
+	ctx := context.Background()
+	albs := randomAlbums(3)
+	insertAlbums(t, db, albs...)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	jazz := catalog.Genre{Slug: "jazz", Name: "Jazz", CreatedAt: now}
+	bebop := catalog.Genre{Slug: "bebop", Name: "Bebop", CreatedAt: now}
+
+	assert.Nil(t, storage.Insert(ctx, jazz))
+	assert.Nil(t, storage.Insert(ctx, bebop))
+	assert.ErrorIs(t, storage.Insert(ctx, jazz), catalog.ErrGenreAlreadyExists)
+
+	genres, err := storage.FindAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []catalog.Genre{bebop, jazz}, genres)
+
+	assert.Nil(t, storage.SetAlbumGenres(ctx, albs[0].ID, []string{"jazz", "bebop"}))
+	assert.Nil(t, storage.SetAlbumGenres(ctx, albs[1].ID, []string{"jazz", "jazz"}))
+	assert.ErrorIs(t, storage.SetAlbumGenres(ctx, albs[2].ID, []string{"jazz", "swing"}), catalog.ErrGenreNotFound)
+	assert.ErrorIs(t, storage.SetAlbumGenres(ctx, uuid.New(), []string{"jazz"}), catalog.ErrAlbumNotFound)
+
+	genres, err = storage.FindAlbumGenres(ctx, albs[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []catalog.Genre{bebop, jazz}, genres)
+	genres, err = storage.FindAlbumGenres(ctx, albs[2].ID)
+	assert.Nil(t, err)
+	assert.Empty(t, genres)
+
+	found, err := storage.FindAllInGenre(ctx, "jazz", 0, 10)
+	assert.Nil(t, err)
+	wantAlbs := albs[:2]
+	slices.SortFunc(wantAlbs, compareAlbums)
+	assert.Equal(t, wantAlbs, found)
+	found, err = storage.FindAllInGenre(ctx, "jazz", 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, wantAlbs[1:], found)
+
+	// Setting the genres replaces them.
+	assert.Nil(t, storage.SetAlbumGenres(ctx, albs[0].ID, []string{"bebop"}))
+	genres, err = storage.FindAlbumGenres(ctx, albs[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []catalog.Genre{bebop}, genres)
+
+	// Removing a genre unassigns it from its albums.
+	assert.Nil(t, storage.Remove(ctx, "bebop"))
+	assert.ErrorIs(t, storage.Remove(ctx, "bebop"), catalog.ErrGenreNotFound)
+	genres, err = storage.FindAlbumGenres(ctx, albs[0].ID)
+	assert.Nil(t, err)
+	assert.Empty(t, genres)
+
+	// Other tenants have their own genres, and neither find nor set the
+	// genres of the albums.
+	acmeCtx := catalog.NewTenantContext(ctx, "acme")
+	genres, err = storage.FindAll(acmeCtx)
+	assert.Nil(t, err)
+	assert.Empty(t, genres)
+	acmeJazz := catalog.Genre{Slug: "jazz", Name: "Jazz", CreatedAt: now, TenantID: "acme"}
+	assert.Nil(t, storage.Insert(acmeCtx, acmeJazz))
+	genres, err = storage.FindAll(acmeCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, []catalog.Genre{acmeJazz}, genres)
+	found, err = storage.FindAllInGenre(acmeCtx, "jazz", 0, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+	assert.ErrorIs(t, storage.SetAlbumGenres(acmeCtx, albs[1].ID, []string{"jazz"}), catalog.ErrAlbumNotFound)
+}
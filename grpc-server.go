@@ -0,0 +1,248 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+	"github.com/jhtohru/go-album-catalog/catalogpb"
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// NewGRPCServer returns a new gRPC server that serves the
+// catalogpb.AlbumService over albumStorage, validating the albums as the HTTP
+// server does. If verifier is not nil, the calls bearing a token in their
+// authorization metadata are authenticated by it, scoped to the catalog of
+// their tenant and attributed to their subject, and only the calls
+// authenticated with the role required by their method are served.
+func NewGRPCServer(
+	albumStorage AlbumStorage,
+	logger *slog.Logger,
+	validate func(Validator) map[string]string,
+	rules AlbumRules,
+	newID func() uuid.UUID,
+	clock clock.Clock,
+	verifier *auth.Verifier,
+	opts ...grpc.ServerOption,
+) *grpc.Server {
+	if verifier != nil {
+		opts = append(opts, grpc.ChainUnaryInterceptor(authenticateCalls(verifier)))
+	}
+	srv := grpc.NewServer(opts...)
+	catalogpb.RegisterAlbumServiceServer(srv, &albumService{
+		albumStorage: albumStorage,
+		logger:       logger,
+		validate:     validate,
+		rules:        rules,
+		newID:        newID,
+		clock:        clock,
+	})
+	return srv
+}
+
+// albumService implements catalogpb.AlbumServiceServer.
+type albumService struct {
+	catalogpb.UnimplementedAlbumServiceServer
+
+	albumStorage AlbumStorage
+	logger       *slog.Logger
+	validate     func(Validator) map[string]string
+	rules        AlbumRules
+	newID        func() uuid.UUID
+	clock        clock.Clock
+}
+
+func (s *albumService) CreateAlbum(ctx context.Context, req *catalogpb.CreateAlbumRequest) (*catalogpb.Album, error) {
+	albReq := request{Title: req.Title, Artist: req.Artist, Price: money.Money{AmountMinor: req.Price}, rules: s.rules}
+	if problems := s.validate(albReq); len(problems) > 0 {
+		return nil, invalidArgument("invalid request", problems)
+	}
+	alb := newAlbum(ctx, s.newID(), albReq, s.clock.Now().UTC())
+	err := s.albumStorage.Insert(ctx, alb)
+	if errors.Is(err, ErrAlbumAlreadyExists) {
+		return nil, status.Error(codes.AlreadyExists, "album already exists")
+	}
+	if err != nil {
+		return nil, s.internalError(ctx, "inserting album into the storage", err)
+	}
+	return albumToProto(alb), nil
+}
+
+func (s *albumService) GetAlbum(ctx context.Context, req *catalogpb.GetAlbumRequest) (*catalogpb.Album, error) {
+	albID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "malformed album id")
+	}
+	alb, err := s.albumStorage.FindOne(ctx, albID)
+	if errors.Is(err, ErrAlbumNotFound) {
+		return nil, status.Error(codes.NotFound, "album not found")
+	}
+	if err != nil {
+		return nil, s.internalError(ctx, "finding album in the storage", err)
+	}
+	return albumToProto(alb), nil
+}
+
+func (s *albumService) ListAlbums(ctx context.Context, req *catalogpb.ListAlbumsRequest) (*catalogpb.ListAlbumsResponse, error) {
+	offset, limit, err := albumsPage(int(req.PageSize), int(req.PageNumber))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	albs, err := s.albumStorage.FindAll(ctx, AlbumFilter{}, offset, limit)
+	if err != nil && !errors.Is(err, ErrAlbumNotFound) {
+		return nil, s.internalError(ctx, "finding albums in the storage", err)
+	}
+	resp := &catalogpb.ListAlbumsResponse{Albums: make([]*catalogpb.Album, len(albs))}
+	for i, alb := range albs {
+		resp.Albums[i] = albumToProto(alb)
+	}
+	return resp, nil
+}
+
+func (s *albumService) UpdateAlbum(ctx context.Context, req *catalogpb.UpdateAlbumRequest) (*catalogpb.Album, error) {
+	albID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "malformed album id")
+	}
+	albReq := request{Title: req.Title, Artist: req.Artist, Price: money.Money{AmountMinor: req.Price}, Version: int(req.Version), rules: s.rules}
+	if problems := s.validate(albReq); len(problems) > 0 {
+		return nil, invalidArgument("invalid request", problems)
+	}
+	alb, err := s.albumStorage.UpdateFunc(ctx, albID, func(alb Album) Album {
+		// The messages have no release dates, so the stored ones are kept.
+		albReq.ReleaseDate = alb.ReleaseDate
+		albReq.OriginalReleaseYear = alb.OriginalReleaseYear
+		return updateAlbum(ctx, alb, albReq, s.clock.Now().UTC())
+	})
+	switch {
+	case errors.Is(err, ErrAlbumNotFound):
+		return nil, status.Error(codes.NotFound, "album not found")
+	case errors.Is(err, ErrAlbumConflict):
+		return nil, status.Error(codes.Aborted, "album was concurrently modified")
+	case errors.Is(err, ErrVersionConflict):
+		return nil, status.Error(codes.Aborted, "album version conflict")
+	case errors.Is(err, ErrAlbumAlreadyExists):
+		return nil, status.Error(codes.AlreadyExists, "album already exists")
+	case err != nil:
+		return nil, s.internalError(ctx, "updating album in the storage", err)
+	}
+	return albumToProto(alb), nil
+}
+
+func (s *albumService) DeleteAlbum(ctx context.Context, req *catalogpb.DeleteAlbumRequest) (*catalogpb.Album, error) {
+	albID, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "malformed album id")
+	}
+	alb, err := s.albumStorage.RemoveReturning(ctx, albID)
+	if errors.Is(err, ErrAlbumNotFound) {
+		return nil, status.Error(codes.NotFound, "album not found")
+	}
+	if err != nil {
+		return nil, s.internalError(ctx, "removing album from the storage", err)
+	}
+	return albumToProto(alb), nil
+}
+
+// internalError logs err, which happened while doing what msg describes, and
+// returns the error the client is responded with, which does not leak it.
+func (s *albumService) internalError(ctx context.Context, msg string, err error) error {
+	s.logger.ErrorContext(ctx, msg, "error", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// invalidArgument returns an InvalidArgument error with msg as its message and
+// problems as its field violations.
+func invalidArgument(msg string, problems map[string]string) error {
+	fields := make([]string, 0, len(problems))
+	for field := range problems {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	details := &errdetails.BadRequest{}
+	for _, field := range fields {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: problems[field],
+		})
+	}
+	st, err := status.New(codes.InvalidArgument, msg).WithDetails(details)
+	if err != nil {
+		panic(fmt.Sprintf("adding details to status: %v", err))
+	}
+	return st.Err()
+}
+
+// albumToProto converts alb into its protobuf message. The messages have no
+// currency, so the price is the amount in the minor units of the currency of
+// alb, and the albums created and updated through the service are priced in
+// the default currency of its AlbumRules.
+func albumToProto(alb Album) *catalogpb.Album {
+	return &catalogpb.Album{
+		Id:        alb.ID.String(),
+		Title:     alb.Title,
+		Artist:    alb.Artist,
+		Price:     alb.Price.AmountMinor,
+		CreatedAt: timestamppb.New(alb.CreatedAt),
+		UpdatedAt: timestamppb.New(alb.UpdatedAt),
+		Version:   int64(alb.Version),
+		TenantId:  alb.TenantID,
+		CreatedBy: alb.CreatedBy,
+		UpdatedBy: alb.UpdatedBy,
+		Artwork:   alb.Artwork,
+	}
+}
+
+// grpcMethodRoles are the roles required to call each method of the
+// catalogpb.AlbumService, as required to request the HTTP route of the same
+// operation.
+var grpcMethodRoles = map[string]string{
+	catalogpb.AlbumService_CreateAlbum_FullMethodName: auth.RoleEditor,
+	catalogpb.AlbumService_GetAlbum_FullMethodName:    auth.RoleReader,
+	catalogpb.AlbumService_ListAlbums_FullMethodName:  auth.RoleReader,
+	catalogpb.AlbumService_UpdateAlbum_FullMethodName: auth.RoleEditor,
+	catalogpb.AlbumService_DeleteAlbum_FullMethodName: auth.RoleAdmin,
+}
+
+// authenticateCalls returns a gRPC interceptor that authenticates the calls
+// bearing a token verified by v in their authorization metadata, passing them
+// to their handler scoped to the tenant and attributed to the subject of their
+// Principal if it has the role required by their method. The other calls are
+// responded with an Unauthenticated or PermissionDenied error.
+func authenticateCalls(v *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "malformed authorization metadata")
+		}
+		p, err := v.Verify(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if role := grpcMethodRoles[info.FullMethod]; !p.HasRole(role) {
+			return nil, status.Errorf(codes.PermissionDenied, "missing role %s", role)
+		}
+		ctx = auth.NewContext(ctx, p)
+		ctx = NewTenantContext(ctx, p.Tenant)
+		ctx = NewActorContext(ctx, p.Subject)
+		return handler(ctx, req)
+	}
+}
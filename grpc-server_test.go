@@ -0,0 +1,400 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+	"github.com/jhtohru/go-album-catalog/catalogpb"
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// newGRPCTestClient serves srv over an in-memory listener, returning a client
+// connected to it.
+func newGRPCTestClient(t *testing.T, srv *grpc.Server) catalogpb.AlbumServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return catalogpb.NewAlbumServiceClient(conn)
+}
+
+func TestAlbumService_CreateAlbum(t *testing.T) {
+	now := time.Date(2024, 8, 28, 12, 0, 0, 0, time.UTC)
+	albID := uuid.New()
+	tests := map[string]struct {
+		problems            map[string]string
+		insertErr           error
+		codeWant            codes.Code
+		fieldViolationsWant []*errdetails.BadRequest_FieldViolation
+	}{
+		"success": {
+			codeWant: codes.OK,
+		},
+		"invalid request": {
+			problems: map[string]string{"title": "empty title", "artist": "empty artist"},
+			codeWant: codes.InvalidArgument,
+			fieldViolationsWant: []*errdetails.BadRequest_FieldViolation{
+				{Field: "artist", Description: "empty artist"},
+				{Field: "title", Description: "empty title"},
+			},
+		},
+		"album already exists": {
+			insertErr: ErrAlbumAlreadyExists,
+			codeWant:  codes.AlreadyExists,
+		},
+		"storage error": {
+			insertErr: errors.New("connection refused"),
+			codeWant:  codes.Internal,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var inserted Album
+			spy := &storageSpy{
+				insert: func(_ context.Context, alb Album) error {
+					inserted = alb
+					return test.insertErr
+				},
+			}
+			srv := NewGRPCServer(
+				spy,
+				slog.New(slog.NewTextHandler(io.Discard, nil)),
+				func(Validator) map[string]string { return test.problems },
+				DefaultAlbumRules,
+				func() uuid.UUID { return albID },
+				clock.NewFake(now),
+				nil,
+			)
+			client := newGRPCTestClient(t, srv)
+
+			alb, err := client.CreateAlbum(context.Background(), &catalogpb.CreateAlbumRequest{
+				Title:  "Kind of Blue",
+				Artist: "Miles Davis",
+				Price:  1999,
+			})
+
+			st := status.Convert(err)
+			assert.Equal(t, test.codeWant, st.Code())
+			if test.fieldViolationsWant != nil {
+				require.Len(t, st.Details(), 1)
+				details, ok := st.Details()[0].(*errdetails.BadRequest)
+				require.True(t, ok)
+				for i, v := range test.fieldViolationsWant {
+					assert.Equal(t, v.Field, details.FieldViolations[i].Field)
+					assert.Equal(t, v.Description, details.FieldViolations[i].Description)
+				}
+			}
+			if test.codeWant != codes.OK {
+				return
+			}
+			assert.Equal(t, albID.String(), alb.Id)
+			assert.Equal(t, "Kind of Blue", alb.Title)
+			assert.Equal(t, "Miles Davis", alb.Artist)
+			assert.Equal(t, int64(1999), alb.Price)
+			assert.Equal(t, now, alb.CreatedAt.AsTime())
+			assert.Equal(t, int64(1), alb.Version)
+			assert.Equal(t, albID, inserted.ID)
+			assert.Equal(t, money.New(1999, "USD"), inserted.Price)
+		})
+	}
+}
+
+func TestAlbumService_GetAlbum(t *testing.T) {
+	albID := uuid.New()
+	tests := map[string]struct {
+		id         string
+		findOneErr error
+		codeWant   codes.Code
+	}{
+		"success": {
+			id:       albID.String(),
+			codeWant: codes.OK,
+		},
+		"malformed id": {
+			id:       "not-a-uuid",
+			codeWant: codes.InvalidArgument,
+		},
+		"album not found": {
+			id:         albID.String(),
+			findOneErr: ErrAlbumNotFound,
+			codeWant:   codes.NotFound,
+		},
+		"storage error": {
+			id:         albID.String(),
+			findOneErr: errors.New("connection refused"),
+			codeWant:   codes.Internal,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			spy := &storageSpy{
+				findOne: func(_ context.Context, id uuid.UUID) (Album, error) {
+					return Album{ID: id, Title: "Kind of Blue"}, test.findOneErr
+				},
+			}
+			srv := NewGRPCServer(spy, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, DefaultAlbumRules, nil, nil, nil)
+			client := newGRPCTestClient(t, srv)
+
+			alb, err := client.GetAlbum(context.Background(), &catalogpb.GetAlbumRequest{Id: test.id})
+
+			assert.Equal(t, test.codeWant, status.Code(err))
+			if test.codeWant == codes.OK {
+				assert.Equal(t, albID.String(), alb.Id)
+				assert.Equal(t, "Kind of Blue", alb.Title)
+			}
+		})
+	}
+}
+
+func TestAlbumService_ListAlbums(t *testing.T) {
+	tests := map[string]struct {
+		pageSize   int32
+		pageNumber int32
+		albums     []Album
+		findAllErr error
+		codeWant   codes.Code
+		offsetWant int
+		limitWant  int
+		albumsWant int
+	}{
+		"success": {
+			pageSize:   10,
+			pageNumber: 3,
+			albums:     []Album{{ID: uuid.New()}, {ID: uuid.New()}},
+			codeWant:   codes.OK,
+			offsetWant: 20,
+			limitWant:  10,
+			albumsWant: 2,
+		},
+		"no albums": {
+			pageSize:   10,
+			pageNumber: 1,
+			findAllErr: ErrAlbumNotFound,
+			codeWant:   codes.OK,
+			limitWant:  10,
+		},
+		"page size too small": {
+			pageSize:   0,
+			pageNumber: 1,
+			codeWant:   codes.InvalidArgument,
+		},
+		"page size too big": {
+			pageSize:   maxAlbumsPageSize + 1,
+			pageNumber: 1,
+			codeWant:   codes.InvalidArgument,
+		},
+		"page number too small": {
+			pageSize:   10,
+			pageNumber: 0,
+			codeWant:   codes.InvalidArgument,
+		},
+		"storage error": {
+			pageSize:   10,
+			pageNumber: 1,
+			findAllErr: errors.New("connection refused"),
+			codeWant:   codes.Internal,
+			limitWant:  10,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var offset, limit int
+			spy := &storageSpy{
+				findAll: func(_ context.Context, _ AlbumFilter, o, l int) ([]Album, error) {
+					offset, limit = o, l
+					return test.albums, test.findAllErr
+				},
+			}
+			srv := NewGRPCServer(spy, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, DefaultAlbumRules, nil, nil, nil)
+			client := newGRPCTestClient(t, srv)
+
+			resp, err := client.ListAlbums(context.Background(), &catalogpb.ListAlbumsRequest{
+				PageSize:   test.pageSize,
+				PageNumber: test.pageNumber,
+			})
+
+			assert.Equal(t, test.codeWant, status.Code(err))
+			assert.Equal(t, test.offsetWant, offset)
+			assert.Equal(t, test.limitWant, limit)
+			if test.codeWant == codes.OK {
+				assert.Len(t, resp.Albums, test.albumsWant)
+			}
+		})
+	}
+}
+
+func TestAlbumService_UpdateAlbum(t *testing.T) {
+	albID := uuid.New()
+	tests := map[string]struct {
+		updateErr error
+		codeWant  codes.Code
+	}{
+		"success":          {codeWant: codes.OK},
+		"album not found":  {updateErr: ErrAlbumNotFound, codeWant: codes.NotFound},
+		"album conflict":   {updateErr: ErrAlbumConflict, codeWant: codes.Aborted},
+		"version conflict": {updateErr: ErrVersionConflict, codeWant: codes.Aborted},
+		"album exists":     {updateErr: ErrAlbumAlreadyExists, codeWant: codes.AlreadyExists},
+		"storage error":    {updateErr: errors.New("connection refused"), codeWant: codes.Internal},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			spy := &storageSpy{
+				updateFunc: func(_ context.Context, id uuid.UUID, update func(Album) Album) (Album, error) {
+					return update(Album{ID: id, Version: 2}), test.updateErr
+				},
+			}
+			srv := NewGRPCServer(
+				spy,
+				slog.New(slog.NewTextHandler(io.Discard, nil)),
+				func(Validator) map[string]string { return nil },
+				DefaultAlbumRules,
+				nil,
+				clock.System,
+				nil,
+			)
+			client := newGRPCTestClient(t, srv)
+
+			alb, err := client.UpdateAlbum(context.Background(), &catalogpb.UpdateAlbumRequest{
+				Id:      albID.String(),
+				Title:   "Kind of Blue",
+				Artist:  "Miles Davis",
+				Price:   1999,
+				Version: 1,
+			})
+
+			assert.Equal(t, test.codeWant, status.Code(err))
+			if test.codeWant == codes.OK {
+				assert.Equal(t, "Kind of Blue", alb.Title)
+				assert.Equal(t, int64(1), alb.Version)
+			}
+		})
+	}
+}
+
+func TestAlbumService_DeleteAlbum(t *testing.T) {
+	albID := uuid.New()
+	tests := map[string]struct {
+		removeErr error
+		codeWant  codes.Code
+	}{
+		"success":         {codeWant: codes.OK},
+		"album not found": {removeErr: ErrAlbumNotFound, codeWant: codes.NotFound},
+		"storage error":   {removeErr: errors.New("connection refused"), codeWant: codes.Internal},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			spy := &storageSpy{
+				removeReturning: func(_ context.Context, id uuid.UUID) (Album, error) {
+					return Album{ID: id}, test.removeErr
+				},
+			}
+			srv := NewGRPCServer(spy, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, DefaultAlbumRules, nil, nil, nil)
+			client := newGRPCTestClient(t, srv)
+
+			alb, err := client.DeleteAlbum(context.Background(), &catalogpb.DeleteAlbumRequest{Id: albID.String()})
+
+			assert.Equal(t, test.codeWant, status.Code(err))
+			if test.codeWant == codes.OK {
+				assert.Equal(t, albID.String(), alb.Id)
+			}
+		})
+	}
+}
+
+func TestAuthenticateCalls(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	sign := func(claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		require.NoError(t, err)
+		return signed
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := map[string]struct {
+		authorization string
+		codeWant      codes.Code
+		tenantWant    string
+		actorWant     string
+	}{
+		"no token": {
+			codeWant: codes.Unauthenticated,
+		},
+		"malformed authorization": {
+			authorization: "Basic dXNlcjpwYXNz",
+			codeWant:      codes.Unauthenticated,
+		},
+		"invalid token": {
+			authorization: "Bearer not-a-token",
+			codeWant:      codes.Unauthenticated,
+		},
+		"missing role": {
+			authorization: "Bearer " + sign(jwt.MapClaims{"sub": "jtohru", "exp": exp, "roles": []string{"reader"}}),
+			codeWant:      codes.PermissionDenied,
+		},
+		"authorized": {
+			authorization: "Bearer " + sign(jwt.MapClaims{"sub": "jtohru", "exp": exp, "roles": []string{"editor"}, "tenant": "acme"}),
+			codeWant:      codes.OK,
+			tenantWant:    "acme",
+			actorWant:     "jtohru",
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var tenant, actor string
+			spy := &storageSpy{
+				insert: func(ctx context.Context, _ Album) error {
+					tenant, actor = TenantFromContext(ctx), ActorFromContext(ctx)
+					return nil
+				},
+			}
+			srv := NewGRPCServer(
+				spy,
+				slog.New(slog.NewTextHandler(io.Discard, nil)),
+				func(Validator) map[string]string { return nil },
+				DefaultAlbumRules,
+				uuid.New,
+				clock.System,
+				auth.NewHS256Verifier(secret, auth.VerifierOptions{}),
+			)
+			client := newGRPCTestClient(t, srv)
+			ctx := context.Background()
+			if test.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", test.authorization)
+			}
+
+			_, err := client.CreateAlbum(ctx, &catalogpb.CreateAlbumRequest{Title: "Kind of Blue", Artist: "Miles Davis", Price: 1999})
+
+			assert.Equal(t, test.codeWant, status.Code(err))
+			assert.Equal(t, test.tenantWant, tenant)
+			assert.Equal(t, test.actorWant, actor)
+		})
+	}
+}
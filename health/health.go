@@ -0,0 +1,120 @@
+// Package health provides a registry of the health checks of the album
+// catalog dependencies and an HTTP handler reporting their results.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The statuses of a Report and of the Results it is made of.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check checks the health of a dependency, returning an error if it is not
+// healthy.
+type Check func(ctx context.Context) error
+
+// Result is the result of a Check.
+type Result struct {
+	Status string `json:"status"`
+	// Latency is how long the Check took, formatted as a Go duration.
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report is the aggregated result of every Check of a Checker. Its status is
+// StatusDown if any of the Checks failed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker is a registry of named Checks. It is safe for concurrent use.
+type Checker struct {
+	timeout time.Duration
+	mu      sync.Mutex
+	checks  map[string]Check
+}
+
+// NewChecker returns a new Checker with no Checks, which fails the Checks
+// taking longer than timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Register registers check under name, replacing the Check registered under
+// name before, if any.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Check runs every registered Check concurrently and reports their results.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.run(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status == StatusDown {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// run runs check within the timeout of c.
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	result := Result{Status: StatusUp, Latency: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Handler returns an http.Handler that responds with the Report of c as JSON,
+// with status code 200 if every Check succeeded or 503 otherwise.
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		statusCode := http.StatusOK
+		if report.Status == StatusDown {
+			statusCode = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,102 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/health"
+)
+
+func TestChecker_Check(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("no checks", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.Empty(t, report.Checks)
+	})
+
+	t.Run("every check succeeded", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+		checker.Register("postgres", ok)
+		checker.Register("cache", ok)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.Equal(t, health.StatusUp, report.Checks["postgres"].Status)
+		assert.Equal(t, health.StatusUp, report.Checks["cache"].Status)
+		assert.NotEmpty(t, report.Checks["postgres"].Latency)
+	})
+
+	t.Run("failed check", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+		checker.Register("postgres", failing)
+		checker.Register("cache", ok)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, health.Result{
+			Status:  health.StatusDown,
+			Latency: report.Checks["postgres"].Latency,
+			Error:   "connection refused",
+		}, report.Checks["postgres"])
+		assert.Equal(t, health.StatusUp, report.Checks["cache"].Status)
+	})
+
+	t.Run("timed out check", func(t *testing.T) {
+		checker := health.NewChecker(10 * time.Millisecond)
+		checker.Register("postgres", slow)
+
+		report := checker.Check(context.Background())
+
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["postgres"].Error)
+	})
+}
+
+func TestChecker_Handler(t *testing.T) {
+	tests := map[string]struct {
+		checkErr       error
+		statusCodeWant int
+		statusWant     string
+	}{
+		"up":   {statusCodeWant: http.StatusOK, statusWant: health.StatusUp},
+		"down": {checkErr: errors.New("connection refused"), statusCodeWant: http.StatusServiceUnavailable, statusWant: health.StatusDown},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			checker := health.NewChecker(time.Second)
+			checker.Register("postgres", func(context.Context) error { return test.checkErr })
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			checker.Handler().ServeHTTP(rec, req)
+
+			var report health.Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Equal(t, test.statusWant, report.Status)
+			assert.Equal(t, test.statusWant, report.Checks["postgres"].Status)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// fetchArtworkHandler returns an http.Handler to requests to fetch the cover
+// art of an album from the sources of its metadata.
+func fetchArtworkHandler(albumStorage AlbumStorage, fetcher *ArtworkFetcher, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Find album in the storage, so that unknown albums are not found.
+		_, err = albumStorage.FindOne(r.Context(), albID)
+		if errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
+			return
+		}
+		// Fetch the cover art of the album.
+		alb, err := fetcher.Fetch(r.Context(), albID)
+		switch {
+		case errors.Is(err, ErrArtworkNotFound):
+			encodeMessage(w, r, http.StatusNotFound, "album artwork not found")
+			return
+		case errors.Is(err, ErrAlbumNotFound):
+			// The album was removed while its cover art was being fetched.
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		case errors.Is(err, ErrAlbumConflict):
+			encodeMessage(w, r, http.StatusConflict, "album was concurrently modified")
+			return
+		case errors.Is(err, ErrArtworkSourceFailed):
+			msg := "fetching album artwork"
+			logger.Error(msg, "error", err)
+			recordServerError(r.Context(), fmt.Errorf("%s: %w", msg, err))
+			encodeMessage(w, r, http.StatusBadGateway, "artwork source failed")
+			return
+		case err != nil:
+			respondInternalError(w, r, logger, "saving album artwork", err)
+			return
+		}
+		// Respond with the album.
+		encode(w, http.StatusOK, alb)
+	})
+}
+
+// artworkHandler returns an http.Handler to requests to get the cover art of
+// an album.
+func artworkHandler(albumStorage AlbumStorage, fetcher *ArtworkFetcher, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Find album in the storage.
+		alb, err := albumStorage.FindOne(r.Context(), albID)
+		if errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
+			return
+		}
+		// Find the cover art of the album in the blob storage.
+		blob, err := fetcher.Artwork(r.Context(), alb)
+		if errors.Is(err, ErrArtworkNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album artwork not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding album artwork in the blob storage", err)
+			return
+		}
+		defer blob.Body.Close()
+		// Respond with the cover art.
+		w.Header().Set("Content-Type", blob.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(blob.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, blob.Body); err != nil {
+			logger.Error("writing album artwork", "error", err)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+func TestFetchArtworkHandler(t *testing.T) {
+	alb := randomAlbum()
+	now := time.Date(2024, 8, 30, 0, 0, 0, 0, time.UTC)
+	cover := []byte("\x89PNG\r\n\x1a\n")
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cover.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(cover)
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		case "/failure":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer source.Close()
+	albFetched := alb
+	albFetched.Artwork = artworkKey(alb.ID)
+	albFetched.UpdatedAt = now
+	albFetched.Version++
+	albFetchedJSON, _ := json.Marshal(albFetched)
+	type testCase struct {
+		albumID          string
+		findOneErr       error
+		metadata         []AlbumMetadata
+		updateFuncErr    error
+		statusCodeWant   int
+		responseBodyWant string
+		artworkWant      []byte
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"malformed album id": {
+			albumID: "not-an-uuid",
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed album id"}`,
+		},
+		"album not found": {
+			albumID:    alb.ID.String(),
+			findOneErr: ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"no cover art url": {
+			albumID:  alb.ID.String(),
+			metadata: []AlbumMetadata{{Source: "discogs"}},
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album artwork not found"}`,
+		},
+		"cover art missing": {
+			albumID:  alb.ID.String(),
+			metadata: []AlbumMetadata{{Source: "musicbrainz", CoverArtURL: source.URL + "/missing"}},
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album artwork not found"}`,
+		},
+		"source failure": {
+			albumID:  alb.ID.String(),
+			metadata: []AlbumMetadata{{Source: "discogs", CoverArtURL: source.URL + "/failure"}},
+
+			statusCodeWant:   http.StatusBadGateway,
+			responseBodyWant: `{"message": "artwork source failed"}`,
+			logSubstrsWant:   []string{"fetching album artwork", "discogs", "503 Service Unavailable"},
+		},
+		"not an image": {
+			albumID:  alb.ID.String(),
+			metadata: []AlbumMetadata{{Source: "discogs", CoverArtURL: source.URL + "/page.html"}},
+
+			statusCodeWant:   http.StatusBadGateway,
+			responseBodyWant: `{"message": "artwork source failed"}`,
+			logSubstrsWant:   []string{"unexpected content type"},
+		},
+		"album concurrently modified": {
+			albumID:       alb.ID.String(),
+			metadata:      []AlbumMetadata{{Source: "discogs", CoverArtURL: source.URL + "/cover.png"}},
+			updateFuncErr: ErrAlbumConflict,
+
+			statusCodeWant:   http.StatusConflict,
+			responseBodyWant: `{"message": "album was concurrently modified"}`,
+		},
+		"storage failure": {
+			albumID:       alb.ID.String(),
+			metadata:      []AlbumMetadata{{Source: "discogs", CoverArtURL: source.URL + "/cover.png"}},
+			updateFuncErr: errors.New("storage failure"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant:   []string{"saving album artwork", "storage failure"},
+		},
+		"happy path": {
+			albumID: alb.ID.String(),
+			metadata: []AlbumMetadata{
+				{Source: "discogs"},
+				{Source: "musicbrainz", CoverArtURL: source.URL + "/missing"},
+				{Source: "spy", CoverArtURL: source.URL + "/cover.png"},
+			},
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: string(albFetchedJSON),
+			artworkWant:      cover,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return alb, test.findOneErr
+			}
+			storage.updateFunc = func(ctx context.Context, id uuid.UUID, update func(Album) Album) (Album, error) {
+				assert.Equal(t, alb.ID, id)
+				if test.updateFuncErr != nil {
+					return Album{}, test.updateFuncErr
+				}
+				updated := update(alb)
+				updated.Version++
+				return updated, nil
+			}
+			metadataStorage := &metadataStorageSpy{
+				findAll: func(ctx context.Context, albumID uuid.UUID) ([]AlbumMetadata, error) {
+					return test.metadata, nil
+				},
+			}
+			blobs := NewMemoryBlobStorage()
+			fetcher := NewArtworkFetcher(storage, metadataStorage, blobs, source.Client())
+			fetcher.clock = clock.NewFake(now)
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := fetchArtworkHandler(storage, fetcher, logger)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			if test.artworkWant != nil {
+				blob, err := blobs.Get(context.Background(), artworkKey(alb.ID))
+				if assert.Nil(t, err) {
+					defer blob.Body.Close()
+					content, _ := io.ReadAll(blob.Body)
+					assert.Equal(t, "image/png", blob.ContentType)
+					assert.Equal(t, test.artworkWant, content)
+				}
+			}
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestArtworkHandler(t *testing.T) {
+	alb := randomAlbum()
+	albWithArtwork := alb
+	albWithArtwork.Artwork = artworkKey(alb.ID)
+	cover := []byte("\x89PNG\r\n\x1a\n")
+	type testCase struct {
+		album            Album
+		findOneErr       error
+		statusCodeWant   int
+		contentTypeWant  string
+		responseBodyWant string
+	}
+	tests := map[string]testCase{
+		"album not found": {
+			findOneErr: ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			contentTypeWant:  "application/json",
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"no artwork": {
+			album: alb,
+
+			statusCodeWant:   http.StatusNotFound,
+			contentTypeWant:  "application/json",
+			responseBodyWant: `{"message": "album artwork not found"}`,
+		},
+		"happy path": {
+			album: albWithArtwork,
+
+			statusCodeWant:   http.StatusOK,
+			contentTypeWant:  "image/png",
+			responseBodyWant: string(cover),
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return test.album, test.findOneErr
+			}
+			blobs := NewMemoryBlobStorage()
+			blobs.Put(context.Background(), artworkKey(alb.ID), "image/png", bytes.NewReader(cover))
+			fetcher := NewArtworkFetcher(storage, &metadataStorageSpy{}, blobs, http.DefaultClient)
+			handler := artworkHandler(storage, fetcher, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetPathValue("album_id", alb.ID.String())
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), test.contentTypeWant))
+			if test.contentTypeWant == "application/json" {
+				assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			} else {
+				assert.Equal(t, test.responseBodyWant, rec.Body.String())
+			}
+		})
+	}
+}
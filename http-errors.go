@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// ErrorReporter reports the unexpected errors the server fails requests with,
+// such as storage failures, to an error tracking service.
+type ErrorReporter interface {
+	// Report reports err, which caused a request to fail, with attrs
+	// describing the request.
+	Report(ctx context.Context, err error, attrs ...slog.Attr)
+}
+
+// ErrorReporterFunc is an adapter to allow the use of ordinary functions as
+// ErrorReporters.
+type ErrorReporterFunc func(ctx context.Context, err error, attrs ...slog.Attr)
+
+// Report makes ErrorReporterFunc implement ErrorReporter.
+func (f ErrorReporterFunc) Report(ctx context.Context, err error, attrs ...slog.Attr) {
+	f(ctx, err, attrs...)
+}
+
+// ErrorResponse is an error response of the server.
+type ErrorResponse struct {
+	// StatusCode is the status code of the response, such as 404 for an
+	// album not found.
+	StatusCode int
+	// Message describes the error, such as "album not found". It is
+	// "internal error" for every response with the 500 status code.
+	Message string
+	// Problems describes the problem of each invalid field of the request,
+	// by field name, if the request failed validation.
+	Problems map[string]string
+}
+
+// ErrorEncoder encodes the error responses of the server, such as to match
+// the error envelope of an organization or to localize their messages.
+type ErrorEncoder interface {
+	// EncodeError writes resp into w as the response to r.
+	EncodeError(w http.ResponseWriter, r *http.Request, resp ErrorResponse) error
+}
+
+// ErrorEncoderFunc is an adapter to allow the use of ordinary functions as
+// ErrorEncoders.
+type ErrorEncoderFunc func(w http.ResponseWriter, r *http.Request, resp ErrorResponse) error
+
+// EncodeError makes ErrorEncoderFunc implement ErrorEncoder.
+func (f ErrorEncoderFunc) EncodeError(w http.ResponseWriter, r *http.Request, resp ErrorResponse) error {
+	return f(w, r, resp)
+}
+
+// DefaultErrorEncoder encodes the error responses as JSON objects with their
+// message and, if any, the problems of the fields of the request.
+var DefaultErrorEncoder ErrorEncoder = ErrorEncoderFunc(func(w http.ResponseWriter, r *http.Request, resp ErrorResponse) error {
+	if resp.Problems == nil {
+		data := struct {
+			Message string `json:"message"`
+		}{
+			Message: resp.Message,
+		}
+		return encode(w, resp.StatusCode, data)
+	}
+	data := struct {
+		Message  string            `json:"message"`
+		Problems map[string]string `json:"problems"`
+	}{
+		Message:  resp.Message,
+		Problems: resp.Problems,
+	}
+	return encode(w, resp.StatusCode, data)
+})
+
+// errorEncoderKey is the context key of the ErrorEncoder of a request.
+type errorEncoderKey struct{}
+
+// errorEncoderOf returns the ErrorEncoder of r, or DefaultErrorEncoder if it
+// has none.
+func errorEncoderOf(r *http.Request) ErrorEncoder {
+	if enc, ok := r.Context().Value(errorEncoderKey{}).(ErrorEncoder); ok {
+		return enc
+	}
+	return DefaultErrorEncoder
+}
+
+// withErrorEncoder returns an http.Handler that passes requests to next with
+// enc as their ErrorEncoder.
+func withErrorEncoder(enc ErrorEncoder, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), errorEncoderKey{}, enc)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// errUnknownServerError is reported for the responses with a 5xx status code
+// whose error was not recorded.
+var errUnknownServerError = errors.New("unknown server error")
+
+// serverErrorKey is the context key of the *error where the error behind the
+// response to a request is recorded.
+type serverErrorKey struct{}
+
+// recordServerError records err as the error behind the response to the
+// request of ctx, to be reported by reportServerErrors.
+func recordServerError(ctx context.Context, err error) {
+	if recorded, ok := ctx.Value(serverErrorKey{}).(*error); ok {
+		*recorded = err
+	}
+}
+
+// respondInternalError logs err with msg through logger, records it to be
+// reported and responds to r with an internal error, or with a timeout if r
+// timed out.
+func respondInternalError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	recordServerError(r.Context(), fmt.Errorf("%s: %w", msg, err))
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		encodeMessage(w, r, http.StatusGatewayTimeout, "request timed out")
+		return
+	}
+	encodeMessage(w, r, http.StatusInternalServerError, "internal error")
+}
+
+// reportServerErrors returns an http.Handler that passes requests to next and
+// reports to reporter the error recorded for every response with a 5xx status
+// code, or for every request next aborted by panicking.
+func reportServerErrors(reporter ErrorReporter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var recorded error
+		ctx := context.WithValue(r.Context(), serverErrorKey{}, &recorded)
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		r = r.WithContext(ctx)
+		defer func() {
+			v := recover()
+			if v != nil || rec.statusCode >= http.StatusInternalServerError {
+				err := recorded
+				if err == nil {
+					err = errUnknownServerError
+				}
+				reporter.Report(ctx, err,
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("pattern", r.Pattern),
+					slog.Int("status", rec.statusCode),
+				)
+			}
+			if v != nil {
+				panic(v)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
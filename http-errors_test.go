@@ -0,0 +1,154 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportServerErrors(t *testing.T) {
+	type report struct {
+		err   error
+		attrs []slog.Attr
+	}
+	unexpectedErr := errors.New("unexpected storage error")
+	tests := map[string]struct {
+		handler     http.HandlerFunc
+		reportsWant []report
+	}{
+		"internal error": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+				respondInternalError(w, r, logger, "finding one album in the storage", unexpectedErr)
+			},
+			reportsWant: []report{{
+				err: unexpectedErr,
+				attrs: []slog.Attr{
+					slog.String("method", http.MethodGet),
+					slog.String("path", "/albums"),
+					slog.String("pattern", ""),
+					slog.Int("status", http.StatusInternalServerError),
+				},
+			}},
+		},
+		"unrecorded server error": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				encodeMessage(w, r, http.StatusServiceUnavailable, "unavailable")
+			},
+			reportsWant: []report{{
+				err: errUnknownServerError,
+				attrs: []slog.Attr{
+					slog.String("method", http.MethodGet),
+					slog.String("path", "/albums"),
+					slog.String("pattern", ""),
+					slog.Int("status", http.StatusServiceUnavailable),
+				},
+			}},
+		},
+		"client error": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				encodeMessage(w, r, http.StatusNotFound, "album not found")
+			},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var reports []report
+			reporter := ErrorReporterFunc(func(ctx context.Context, err error, attrs ...slog.Attr) {
+				reports = append(reports, report{err: err, attrs: attrs})
+			})
+			handler := reportServerErrors(reporter, test.handler)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Len(t, reports, len(test.reportsWant))
+			for i := range min(len(reports), len(test.reportsWant)) {
+				assert.ErrorIs(t, reports[i].err, test.reportsWant[i].err)
+				assert.Equal(t, test.reportsWant[i].attrs, reports[i].attrs)
+			}
+		})
+	}
+
+	t.Run("aborted request", func(t *testing.T) {
+		var reported error
+		reporter := ErrorReporterFunc(func(ctx context.Context, err error, attrs ...slog.Attr) {
+			reported = err
+		})
+		handler := reportServerErrors(reporter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recordServerError(r.Context(), unexpectedErr)
+			panic(http.ErrAbortHandler)
+		}))
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			handler.ServeHTTP(rec, req)
+		})
+		assert.ErrorIs(t, reported, unexpectedErr)
+	})
+}
+
+func TestWithErrorEncoder(t *testing.T) {
+	enc := ErrorEncoderFunc(func(w http.ResponseWriter, r *http.Request, resp ErrorResponse) error {
+		data := struct {
+			Error struct {
+				Code    int               `json:"code"`
+				Message string            `json:"message"`
+				Details map[string]string `json:"details,omitempty"`
+			} `json:"error"`
+		}{}
+		data.Error.Code = resp.StatusCode
+		data.Error.Message = resp.Message
+		data.Error.Details = resp.Problems
+		return encode(w, resp.StatusCode, data)
+	})
+	spy := &storageSpy{
+		findOne: func(ctx context.Context, id uuid.UUID) (Album, error) {
+			return Album{}, ErrAlbumNotFound
+		},
+	}
+	handler := NewServer(spy, WithErrorEncoder(enc), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	tests := map[string]struct {
+		method           string
+		target           string
+		body             string
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"not found": {
+			method:           http.MethodGet,
+			target:           "/albums/" + uuid.NewString(),
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"error": {"code": 404, "message": "album not found"}}`,
+		},
+		"validation problems": {
+			method:           http.MethodPost,
+			target:           "/albums",
+			body:             `{"title": "Nevermind", "artist": "Nirvana"}`,
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"error": {"code": 400, "message": "invalid request body", "details": {"price": "is not greater than zero"}}}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Code)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
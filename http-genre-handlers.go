@@ -0,0 +1,208 @@
+package catalog
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/validation"
+)
+
+// genreSlugPattern is the pattern of the slugs of the genres: lower case
+// letters and digits, separated by single hyphens.
+var genreSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// genreRequest is the request to create a genre.
+type genreRequest struct {
+	Slug string `json:"slug" validate:"required,max=64"`
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// Valid makes genreRequest implement Validator.
+func (req genreRequest) Valid() map[string]string {
+	problems := validation.Struct(req)
+	if _, ok := problems["slug"]; !ok && !genreSlugPattern.MatchString(req.Slug) {
+		problems["slug"] = "is not made of lower case letters and digits separated by hyphens"
+	}
+	return problems
+}
+
+// albumGenresRequest is the request to replace the genres an album is
+// assigned to.
+type albumGenresRequest struct {
+	Genres []string `json:"genres"`
+}
+
+// Valid makes albumGenresRequest implement Validator.
+func (req albumGenresRequest) Valid() map[string]string {
+	problems := make(map[string]string)
+	if req.Genres == nil {
+		problems["genres"] = "is missing"
+		return problems
+	}
+	for _, slug := range req.Genres {
+		if !genreSlugPattern.MatchString(slug) {
+			problems["genres"] = "contains an invalid slug"
+			break
+		}
+	}
+	return problems
+}
+
+// createGenreHandler returns an http.Handler to requests to create a genre.
+func createGenreHandler(
+	genreStorage GenreStorage,
+	logger *slog.Logger,
+	validate func(Validator) map[string]string,
+	clock clock.Clock,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract genre data from the request.
+		req, err := decode[genreRequest](r)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed request body")
+			return
+		}
+		if problems := validate(req); len(problems) > 0 {
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", problems)
+			return
+		}
+		// Create a new genre and insert into the storage.
+		genre := Genre{
+			Slug:      req.Slug,
+			Name:      req.Name,
+			CreatedAt: clock.Now().UTC(),
+			TenantID:  TenantFromContext(r.Context()),
+		}
+		err = genreStorage.Insert(r.Context(), genre)
+		if errors.Is(err, ErrGenreAlreadyExists) {
+			encodeProblems(w, r, http.StatusConflict, "genre already exists", map[string]string{
+				"slug": "is already taken",
+			})
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "inserting genre into the storage", err)
+			return
+		}
+		// Respond with the new genre.
+		encode(w, http.StatusCreated, genre)
+	})
+}
+
+// listGenresHandler returns an http.Handler to requests to list the genres.
+func listGenresHandler(genreStorage GenreStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		genres, err := genreStorage.FindAll(r.Context())
+		if err != nil {
+			respondInternalError(w, r, logger, "finding genres in the storage", err)
+			return
+		}
+		if genres == nil {
+			genres = []Genre{}
+		}
+		encode(w, http.StatusOK, genres)
+	})
+}
+
+// deleteGenreHandler returns an http.Handler to requests to delete a genre.
+func deleteGenreHandler(genreStorage GenreStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Remove genre from the storage, unassigning it from its albums.
+		err := genreStorage.Remove(r.Context(), r.PathValue("genre_slug"))
+		if errors.Is(err, ErrGenreNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "genre not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "removing genre from the storage", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// albumGenresHandler returns an http.Handler to requests to find the genres an
+// album is assigned to.
+func albumGenresHandler(albumStorage AlbumStorage, genreStorage GenreStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Find album in the storage, and then its genres.
+		if _, err := albumStorage.FindOne(r.Context(), albID); errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		} else if err != nil {
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
+			return
+		}
+		genres, err := genreStorage.FindAlbumGenres(r.Context(), albID)
+		if err != nil {
+			respondInternalError(w, r, logger, "finding album genres in the storage", err)
+			return
+		}
+		if genres == nil {
+			genres = []Genre{}
+		}
+		encode(w, http.StatusOK, genres)
+	})
+}
+
+// setAlbumGenresHandler returns an http.Handler to requests to replace the
+// genres an album is assigned to.
+func setAlbumGenresHandler(
+	genreStorage GenreStorage,
+	logger *slog.Logger,
+	validate func(Validator) map[string]string,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id and genres from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		req, err := decode[albumGenresRequest](r)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed request body")
+			return
+		}
+		if problems := validate(req); len(problems) > 0 {
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", problems)
+			return
+		}
+		// Replace the album genres in the storage.
+		err = genreStorage.SetAlbumGenres(r.Context(), albID, req.Genres)
+		switch {
+		case errors.Is(err, ErrAlbumNotFound):
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		case errors.Is(err, ErrGenreNotFound):
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", map[string]string{
+				"genres": "contains an unknown genre",
+			})
+			return
+		case err != nil:
+			respondInternalError(w, r, logger, "setting album genres into the storage", err)
+			return
+		}
+		// Respond with the genres the album is assigned to.
+		genres, err := genreStorage.FindAlbumGenres(r.Context(), albID)
+		if err != nil {
+			respondInternalError(w, r, logger, "finding album genres in the storage", err)
+			return
+		}
+		if genres == nil {
+			genres = []Genre{}
+		}
+		encode(w, http.StatusOK, genres)
+	})
+}
@@ -0,0 +1,446 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+type genreStorageSpy struct {
+	insert          func(ctx context.Context, genre Genre) error
+	findAll         func(ctx context.Context) ([]Genre, error)
+	remove          func(ctx context.Context, slug string) error
+	setAlbumGenres  func(ctx context.Context, albumID uuid.UUID, slugs []string) error
+	findAlbumGenres func(ctx context.Context, albumID uuid.UUID) ([]Genre, error)
+	findAllInGenre  func(ctx context.Context, slug string, offset, limit int) ([]Album, error)
+}
+
+func (spy *genreStorageSpy) Insert(ctx context.Context, genre Genre) error {
+	return spy.insert(ctx, genre)
+}
+
+func (spy *genreStorageSpy) FindAll(ctx context.Context) ([]Genre, error) {
+	return spy.findAll(ctx)
+}
+
+func (spy *genreStorageSpy) Remove(ctx context.Context, slug string) error {
+	return spy.remove(ctx, slug)
+}
+
+func (spy *genreStorageSpy) SetAlbumGenres(ctx context.Context, albumID uuid.UUID, slugs []string) error {
+	return spy.setAlbumGenres(ctx, albumID, slugs)
+}
+
+func (spy *genreStorageSpy) FindAlbumGenres(ctx context.Context, albumID uuid.UUID) ([]Genre, error) {
+	return spy.findAlbumGenres(ctx, albumID)
+}
+
+func (spy *genreStorageSpy) FindAllInGenre(ctx context.Context, slug string, offset, limit int) ([]Album, error) {
+	return spy.findAllInGenre(ctx, slug, offset, limit)
+}
+
+func TestGenreRequest(t *testing.T) {
+	tests := map[string]struct {
+		req          genreRequest
+		problemsWant map[string]string
+	}{
+		"empty": {
+			problemsWant: map[string]string{"slug": "is empty", "name": "is empty"},
+		},
+		"invalid slug": {
+			req: genreRequest{Slug: "Free Jazz", Name: "Free jazz"},
+			problemsWant: map[string]string{
+				"slug": "is not made of lower case letters and digits separated by hyphens",
+			},
+		},
+		"valid": {
+			req:          genreRequest{Slug: "free-jazz", Name: "Free jazz"},
+			problemsWant: map[string]string{},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.problemsWant, test.req.Valid())
+		})
+	}
+}
+
+func TestAlbumGenresRequest(t *testing.T) {
+	assert.Equal(t, map[string]string{"genres": "is missing"}, albumGenresRequest{}.Valid())
+	assert.Equal(t, map[string]string{}, albumGenresRequest{Genres: []string{}}.Valid())
+	assert.Equal(t, map[string]string{}, albumGenresRequest{Genres: []string{"jazz", "free-jazz"}}.Valid())
+	assert.Equal(t,
+		map[string]string{"genres": "contains an invalid slug"},
+		albumGenresRequest{Genres: []string{"jazz", "-jazz"}}.Valid(),
+	)
+}
+
+func TestCreateGenreHandler(t *testing.T) {
+	type testCase struct {
+		requestBody      string
+		insertErr        error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	now := time.Date(2024, 9, 5, 12, 0, 0, 0, time.UTC)
+	tests := map[string]testCase{
+		"malformed request body": {
+			requestBody: "", // malformed request body
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed request body"}`,
+		},
+		"invalid request body": {
+			requestBody: `{"slug": "jazz"}`,
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "invalid request body", "problems": {"name": "is empty"}}`,
+		},
+		"genre already exists": {
+			requestBody: `{"slug": "jazz", "name": "Jazz"}`,
+			insertErr:   ErrGenreAlreadyExists,
+
+			statusCodeWant:   http.StatusConflict,
+			responseBodyWant: `{"message": "genre already exists", "problems": {"slug": "is already taken"}}`,
+		},
+		"unexpected insert error": {
+			requestBody: `{"slug": "jazz", "name": "Jazz"}`,
+			insertErr:   errors.New("unexpected insert error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="inserting genre into the storage"`,
+				`error="unexpected insert error"`,
+			},
+		},
+		"happy path": {
+			requestBody: `{"slug": "jazz", "name": "Jazz"}`,
+
+			statusCodeWant:   http.StatusCreated,
+			responseBodyWant: `{"slug": "jazz", "name": "Jazz", "created_at": "2024-09-05T12:00:00Z"}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &genreStorageSpy{}
+			storage.insert = func(ctx context.Context, genre Genre) error {
+				return test.insertErr
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := createGenreHandler(storage, logger, Validate, clock.NewFake(now))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.requestBody))
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestListGenresHandler(t *testing.T) {
+	tests := map[string]struct {
+		genres           []Genre
+		findAllErr       error
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"no genres": {
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `[]`,
+		},
+		"unexpected find error": {
+			findAllErr: errors.New("unexpected find error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+		"happy path": {
+			genres: []Genre{{Slug: "jazz", Name: "Jazz", CreatedAt: time.Date(2024, 9, 5, 12, 0, 0, 0, time.UTC)}},
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `[{"slug": "jazz", "name": "Jazz", "created_at": "2024-09-05T12:00:00Z"}]`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &genreStorageSpy{}
+			storage.findAll = func(ctx context.Context) ([]Genre, error) {
+				return test.genres, test.findAllErr
+			}
+			handler := listGenresHandler(storage, slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
+
+func TestDeleteGenreHandler(t *testing.T) {
+	tests := map[string]struct {
+		removeErr        error
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"genre not found": {
+			removeErr: ErrGenreNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "genre not found"}`,
+		},
+		"unexpected remove error": {
+			removeErr: errors.New("unexpected remove error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+		"happy path": {
+			statusCodeWant: http.StatusNoContent,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &genreStorageSpy{}
+			storage.remove = func(ctx context.Context, slug string) error {
+				assert.Equal(t, "jazz", slug)
+				return test.removeErr
+			}
+			handler := deleteGenreHandler(storage, slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/", nil)
+			req.SetPathValue("genre_slug", "jazz")
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			if test.responseBodyWant != "" {
+				assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAlbumGenresHandler(t *testing.T) {
+	alb := randomAlbum()
+	jazz := Genre{Slug: "jazz", Name: "Jazz", CreatedAt: time.Date(2024, 9, 5, 12, 0, 0, 0, time.UTC)}
+	tests := map[string]struct {
+		albumID          string
+		findOneErr       error
+		genres           []Genre
+		findGenresErr    error
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"malformed album id": {
+			albumID: "not-an-uuid",
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed album id"}`,
+		},
+		"album not found": {
+			albumID:    alb.ID.String(),
+			findOneErr: ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"unexpected find error": {
+			albumID:       alb.ID.String(),
+			findGenresErr: errors.New("unexpected find error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+		"no genres": {
+			albumID: alb.ID.String(),
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `[]`,
+		},
+		"happy path": {
+			albumID: alb.ID.String(),
+			genres:  []Genre{jazz},
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `[{"slug": "jazz", "name": "Jazz", "created_at": "2024-09-05T12:00:00Z"}]`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return alb, test.findOneErr
+			}
+			genreStorage := &genreStorageSpy{}
+			genreStorage.findAlbumGenres = func(ctx context.Context, albumID uuid.UUID) ([]Genre, error) {
+				assert.Equal(t, alb.ID, albumID)
+				return test.genres, test.findGenresErr
+			}
+			handler := albumGenresHandler(storage, genreStorage, slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
+
+func TestSetAlbumGenresHandler(t *testing.T) {
+	albID := uuid.New()
+	jazz := Genre{Slug: "jazz", Name: "Jazz", CreatedAt: time.Date(2024, 9, 5, 12, 0, 0, 0, time.UTC)}
+	type testCase struct {
+		albumID          string
+		requestBody      string
+		setErr           error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"malformed album id": {
+			albumID: "not-an-uuid",
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed album id"}`,
+		},
+		"malformed request body": {
+			albumID:     albID.String(),
+			requestBody: `{"genres": "jazz"}`,
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed request body"}`,
+		},
+		"invalid request body": {
+			albumID:     albID.String(),
+			requestBody: `{}`,
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "invalid request body", "problems": {"genres": "is missing"}}`,
+		},
+		"album not found": {
+			albumID:     albID.String(),
+			requestBody: `{"genres": ["jazz"]}`,
+			setErr:      ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"unknown genre": {
+			albumID:     albID.String(),
+			requestBody: `{"genres": ["jazz"]}`,
+			setErr:      ErrGenreNotFound,
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "invalid request body", "problems": {"genres": "contains an unknown genre"}}`,
+		},
+		"unexpected set error": {
+			albumID:     albID.String(),
+			requestBody: `{"genres": ["jazz"]}`,
+			setErr:      errors.New("unexpected set error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="setting album genres into the storage"`,
+				`error="unexpected set error"`,
+			},
+		},
+		"happy path": {
+			albumID:     albID.String(),
+			requestBody: `{"genres": ["jazz"]}`,
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `[{"slug": "jazz", "name": "Jazz", "created_at": "2024-09-05T12:00:00Z"}]`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &genreStorageSpy{}
+			storage.setAlbumGenres = func(ctx context.Context, albumID uuid.UUID, slugs []string) error {
+				assert.Equal(t, albID, albumID)
+				assert.Equal(t, []string{"jazz"}, slugs)
+				return test.setErr
+			}
+			storage.findAlbumGenres = func(ctx context.Context, albumID uuid.UUID) ([]Genre, error) {
+				return []Genre{jazz}, nil
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := setAlbumGenresHandler(storage, logger, Validate)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(test.requestBody))
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestListAlbumsHandler_genre(t *testing.T) {
+	albs := randomAlbums(2)
+	storage := &storageSpy{}
+	genreStorage := &genreStorageSpy{}
+	genreStorage.findAllInGenre = func(ctx context.Context, slug string, offset, limit int) ([]Album, error) {
+		assert.Equal(t, "jazz", slug)
+		assert.Equal(t, 10, offset)
+		assert.Equal(t, 10, limit)
+		return albs, nil
+	}
+	handler := listAlbumsHandler(storage, &priceStorageSpy{}, genreStorage, slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)))
+
+	t.Run("in genre", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/albums?page_size=10&page_number=2&genre=jazz&fields=id", nil)
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+		assert.JSONEq(t, `[{"id": "`+albs[0].ID.String()+`"}, {"id": "`+albs[1].ID.String()+`"}]`, rec.Body.String())
+	})
+
+	t.Run("genre and currency", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/albums?page_size=10&page_number=2&genre=jazz&currency=EUR", nil)
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+		assert.JSONEq(t, `{"message": "query parameters currency and genre cannot be combined"}`, rec.Body.String())
+	})
+}
@@ -3,12 +3,19 @@ package catalog
 import (
 	"errors"
 	"fmt"
+	"iter"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/civil"
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/money"
+	"github.com/jhtohru/go-album-catalog/validation"
 )
 
 // Validator can validate itself.
@@ -25,58 +32,145 @@ func Validate(v Validator) map[string]string {
 }
 
 type request struct {
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  int    `json:"price"`
+	Title  string `json:"title" validate:"required"`
+	Artist string `json:"artist" validate:"required"`
+	// Price is also accepted as a bare integer amount, as before albums had
+	// a currency, which is priced in the default currency of the rules.
+	Price money.Money `json:"price"`
+	// Version is the album version the update is based on. Zero means the
+	// update is not checked against the stored version.
+	Version int `json:"version"`
+	// ReleaseDate and OriginalReleaseYear are optional, and neither can be
+	// in the future.
+	ReleaseDate         *civil.Date `json:"release_date"`
+	OriginalReleaseYear int         `json:"original_release_year" validate:"min=0"`
+
+	// rules are the rules the album is validated by, besides the ones of
+	// the validate tags.
+	rules AlbumRules
+	// today is the date the album is validated at, after which it cannot be
+	// released.
+	today civil.Date
 }
 
 // Valid makes request implement Validator.
 func (req request) Valid() map[string]string {
-	problems := make(map[string]string)
-	if req.Title == "" {
-		problems["title"] = "is empty"
+	problems := validation.Struct(req)
+	check := func(field string, v any, rules string) {
+		if _, ok := problems[field]; ok {
+			return
+		}
+		if problem := validation.Value(v, rules); problem != "" {
+			problems[field] = problem
+		}
+	}
+	if req.rules.TitleMaxLength > 0 {
+		check("title", req.Title, fmt.Sprintf("max=%d", req.rules.TitleMaxLength))
+	}
+	if req.rules.ArtistMaxLength > 0 {
+		check("artist", req.Artist, fmt.Sprintf("max=%d", req.rules.ArtistMaxLength))
 	}
-	if req.Artist == "" {
-		problems["artist"] = "is empty"
+	if req.rules.AllowZeroPrice {
+		check("price", req.Price.AmountMinor, "min=0")
+	} else {
+		check("price", req.Price.AmountMinor, "gt=0")
 	}
-	if req.Price <= 0 {
-		problems["price"] = "is not greater than zero"
+	if req.rules.MaxPrice > 0 {
+		check("price", req.Price.AmountMinor, fmt.Sprintf("max=%d", req.rules.MaxPrice))
+	}
+	if _, ok := problems["price"]; !ok {
+		switch currency := req.price().Currency; {
+		case currency == "":
+			problems["price"] = "has no currency"
+		case !money.ValidCurrency(currency):
+			problems["price"] = "has an invalid currency"
+		}
+	}
+	if req.ReleaseDate != nil && req.ReleaseDate.After(req.today) {
+		problems["release_date"] = "is in the future"
+	}
+	if _, ok := problems["original_release_year"]; !ok {
+		switch year := req.OriginalReleaseYear; {
+		case year > req.today.Year:
+			problems["original_release_year"] = "is in the future"
+		case year > 0 && req.ReleaseDate != nil && year > req.ReleaseDate.Year:
+			problems["original_release_year"] = "is after the release date"
+		}
 	}
 	return problems
 }
 
+// price returns the price of req, in the default currency of its rules if it
+// has none.
+func (req request) price() money.Money {
+	if req.Price.Currency == "" {
+		return money.New(req.Price.AmountMinor, req.rules.DefaultCurrency)
+	}
+	return req.Price
+}
+
+// AlbumRules are the rules the albums are validated by, besides their title
+// and artist not being empty.
+type AlbumRules struct {
+	// TitleMaxLength and ArtistMaxLength are the maximum lengths of the
+	// title and artist of an album, in characters. Zero means no limit.
+	TitleMaxLength  int
+	ArtistMaxLength int
+	// MaxPrice is the maximum price of an album, in the minor units of its
+	// currency. Zero means no limit.
+	MaxPrice int
+	// AllowZeroPrice allows albums to be free, such as promotional ones.
+	AllowZeroPrice bool
+	// DefaultCurrency is the currency of the prices requested without one,
+	// such as the bare integer ones. Empty means they are invalid.
+	DefaultCurrency string
+}
+
+// DefaultAlbumRules are the AlbumRules of the albums the storage can store,
+// pricing the ones requested without a currency in US dollars.
+var DefaultAlbumRules = AlbumRules{
+	TitleMaxLength:  255,
+	ArtistMaxLength: 255,
+	DefaultCurrency: "USD",
+}
+
+// albumAlreadyExistsProblems are the problems of a request to store an album
+// whose artist and title are already used by another album.
+var albumAlreadyExistsProblems = map[string]string{
+	"title": "is already used by another album of the same artist",
+}
+
 // createAlbumHandler returns an http.Handler to requests to create an album.
 func createAlbumHandler(
 	albumStorage AlbumStorage,
 	logger *slog.Logger,
 	validate func(Validator) map[string]string,
+	rules AlbumRules,
 	newID func() uuid.UUID,
-	timeNow func() time.Time,
+	clock clock.Clock,
 ) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract album data from the request.
 		req, err := decode[request](r)
 		if err != nil {
-			encodeMessage(w, http.StatusBadRequest, "malformed request body")
+			encodeMessage(w, r, http.StatusBadRequest, "malformed request body")
 			return
 		}
+		req.rules = rules
+		req.today = civil.DateOf(clock.Now().UTC())
 		if problems := validate(req); len(problems) > 0 {
-			encodeProblems(w, http.StatusBadRequest, "invalid request body", problems)
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", problems)
 			return
 		}
 		// Create a new album and insert into the storage.
-		now := timeNow()
-		alb := Album{
-			ID:        newID(),
-			Title:     req.Title,
-			Artist:    req.Artist,
-			Price:     req.Price,
-			CreatedAt: now,
-			UpdatedAt: now,
+		alb := newAlbum(r.Context(), newID(), req, clock.Now().UTC())
+		err = albumStorage.Insert(r.Context(), alb)
+		if errors.Is(err, ErrAlbumAlreadyExists) {
+			encodeProblems(w, r, http.StatusConflict, "album already exists", albumAlreadyExistsProblems)
+			return
 		}
-		if err = albumStorage.Insert(r.Context(), alb); err != nil {
-			logger.Error("inserting album into the storage", "error", err)
-			encodeMessage(w, http.StatusInternalServerError, "internal error")
+		if err != nil {
+			respondInternalError(w, r, logger, "inserting album into the storage", err)
 			return
 		}
 		// Respond with the new album.
@@ -87,59 +181,208 @@ func createAlbumHandler(
 // maxAlbumsPageSize is the maximum quantity of albums an album page can have.
 const maxAlbumsPageSize = 50
 
-// listAlbumsHandler returns an http.Handler to requests to list albums.
-func listAlbumsHandler(albumStorage AlbumStorage, logger *slog.Logger) http.Handler {
+// listAlbumsHandler returns an http.Handler to requests to list albums. If
+// priceStorage is not nil, the albums can be listed by the currency they are
+// priced in, and if genreStorage is not nil, by a genre they are in.
+func listAlbumsHandler(albumStorage AlbumStorage, priceStorage PriceStorage, genreStorage GenreStorage, logger *slog.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract page size and page number from the request.
 		q := r.URL.Query()
 		if !q.Has("page_size") {
-			encodeMessage(w, http.StatusBadRequest, "query parameter page_size is missing")
+			encodeMessage(w, r, http.StatusBadRequest, "query parameter page_size is missing")
 			return
 		}
 		pageSize, err := strconv.Atoi(q.Get("page_size"))
 		if err != nil {
-			encodeMessage(w, http.StatusBadRequest, "page size is not a valid number")
+			encodeMessage(w, r, http.StatusBadRequest, "page size is not a valid number")
 			return
 		}
 		if !q.Has("page_number") {
-			encodeMessage(w, http.StatusBadRequest, "query parameter page_number is missing")
+			encodeMessage(w, r, http.StatusBadRequest, "query parameter page_number is missing")
 			return
 		}
 		pageNumber, err := strconv.Atoi(q.Get("page_number"))
 		if err != nil {
-			encodeMessage(w, http.StatusBadRequest, "page number is not a valid number")
+			encodeMessage(w, r, http.StatusBadRequest, "page number is not a valid number")
 			return
 		}
 		// Validate page size and page number.
-		if pageSize < 1 {
-			encodeMessage(w, http.StatusBadRequest, "page size is less than 1")
+		offset, limit, err := albumsPage(pageSize, pageNumber)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Extract the fields the albums will be restricted to.
+		fields, err := parseFields(q, albumFields)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Extract the currency the albums will be priced in, or the genre they
+		// will be in, if any.
+		byCurrency := priceStorage != nil && q.Has("currency")
+		currency := q.Get("currency")
+		if byCurrency && !money.ValidCurrency(currency) {
+			encodeMessage(w, r, http.StatusBadRequest, "currency is not an ISO 4217 currency code")
+			return
+		}
+		byGenre := genreStorage != nil && q.Has("genre")
+		if byCurrency && byGenre {
+			encodeMessage(w, r, http.StatusBadRequest, "query parameters currency and genre cannot be combined")
 			return
 		}
-		if pageSize > maxAlbumsPageSize {
-			msg := fmt.Sprintf("page size is greater than %d", maxAlbumsPageSize)
-			encodeMessage(w, http.StatusBadRequest, msg)
+		// Extract the release dates the albums will be filtered by, and their
+		// order.
+		filter, err := parseAlbumFilter(q)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, err.Error())
 			return
 		}
-		if pageNumber < 1 {
-			encodeMessage(w, http.StatusBadRequest, "page number is less than 1")
+		if (byCurrency || byGenre) && filter != (AlbumFilter{}) {
+			encodeMessage(w, r, http.StatusBadRequest, "albums listed by currency or genre cannot be filtered or sorted by release date")
+			return
+		}
+		mediaType := negotiateAlbumMediaType(w, r)
+		// Find albums in the storage and respond with them as they are found.
+		var albs iter.Seq2[Album, error]
+		switch {
+		case byCurrency:
+			page, err := priceStorage.FindAllPricedIn(r.Context(), currency, offset, limit)
+			if err != nil {
+				respondInternalError(w, r, logger, "finding albums priced in a currency in the storage", err)
+				return
+			}
+			albs = albumSeq(page)
+		case byGenre:
+			page, err := genreStorage.FindAllInGenre(r.Context(), q.Get("genre"), offset, limit)
+			if err != nil {
+				respondInternalError(w, r, logger, "finding albums in a genre in the storage", err)
+				return
+			}
+			albs = albumSeq(page)
+		default:
+			albs = albumStorage.FindAllSeq(r.Context(), filter, offset, limit)
+		}
+		if mediaType != mediaTypeJSON {
+			// Only JSON is streamed, so collect the albums to respond with.
+			var page []Album
+			for alb, err := range albs {
+				if err != nil {
+					respondInternalError(w, r, logger, "finding albums in the storage", err)
+					return
+				}
+				page = append(page, alb)
+			}
+			if err := encodeAlbums(w, http.StatusOK, mediaType, page, fields); err != nil {
+				respondInternalError(w, r, logger, "encoding albums", err)
+			}
 			return
 		}
-		// Find albums in the storage.
-		offset, limit := pageSize*(pageNumber-1), pageSize
-		albs, err := albumStorage.FindAll(r.Context(), offset, limit)
+		projections := func(yield func(any, error) bool) {
+			for alb, err := range albs {
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(project(alb, fields)) {
+					return
+				}
+			}
+		}
+		err = encodeSeq(w, http.StatusOK, projections)
+		switch {
+		case errors.Is(err, errStreamInterrupted):
+			// The status code was already written, so abort the response to
+			// signal the client it is incomplete.
+			logger.Error("streaming albums from the storage", "error", err)
+			recordServerError(r.Context(), fmt.Errorf("streaming albums from the storage: %w", err))
+			panic(http.ErrAbortHandler)
+		case err != nil:
+			respondInternalError(w, r, logger, "finding albums in the storage", err)
+		}
+	})
+}
+
+// albumSeq returns an iter.Seq2 yielding albs, with no error.
+func albumSeq(albs []Album) iter.Seq2[Album, error] {
+	return func(yield func(Album, error) bool) {
+		for _, alb := range albs {
+			if !yield(alb, nil) {
+				return
+			}
+		}
+	}
+}
+
+// parseAlbumFilter returns the AlbumFilter described by the query parameters
+// sort, released_from, released_until and original_release_year of q, or an
+// error describing why they are invalid.
+func parseAlbumFilter(q url.Values) (AlbumFilter, error) {
+	var filter AlbumFilter
+	switch sort := q.Get("sort"); sort {
+	case "", "title":
+	case string(SortByReleaseDate), string(SortByReleaseDateDesc):
+		filter.Sort = AlbumSort(sort)
+	default:
+		return AlbumFilter{}, fmt.Errorf("unknown sort %q", sort)
+	}
+	if q.Has("released_from") {
+		d, err := civil.ParseDate(q.Get("released_from"))
+		if err != nil {
+			return AlbumFilter{}, errors.New("released from is not a valid date")
+		}
+		filter.ReleasedFrom = &d
+	}
+	if q.Has("released_until") {
+		d, err := civil.ParseDate(q.Get("released_until"))
+		if err != nil {
+			return AlbumFilter{}, errors.New("released until is not a valid date")
+		}
+		filter.ReleasedUntil = &d
+	}
+	if q.Has("original_release_year") {
+		year, err := strconv.Atoi(q.Get("original_release_year"))
+		if err != nil || year < 1 {
+			return AlbumFilter{}, errors.New("original release year is not a valid year")
+		}
+		filter.OriginalReleaseYear = year
+	}
+	return filter, nil
+}
+
+// maxAlbumSuggestions is the maximum quantity of albums suggested at once.
+const maxAlbumSuggestions = 10
+
+// suggestAlbumsHandler returns an http.Handler to requests to suggest albums
+// whose title or artist starts with a prefix.
+func suggestAlbumsHandler(albumStorage AlbumStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract the prefix from the request.
+		q := r.URL.Query()
+		if !q.Has("q") {
+			encodeMessage(w, r, http.StatusBadRequest, "query parameter q is missing")
+			return
+		}
+		prefix := strings.TrimSpace(q.Get("q"))
+		if prefix == "" {
+			encodeMessage(w, r, http.StatusBadRequest, "query parameter q is empty")
+			return
+		}
+		mediaType := negotiateAlbumMediaType(w, r)
+		// Find suggested albums in the storage.
+		albs, err := albumStorage.Suggest(r.Context(), prefix, maxAlbumSuggestions)
 		if err != nil {
 			switch {
 			case errors.Is(err, ErrAlbumNotFound):
 				// If no album is found, respond with an empty list and OK status code.
-				encode(w, http.StatusOK, []Album{})
+				encodeAlbums(w, http.StatusOK, mediaType, []Album{}, nil)
 			default:
-				logger.Error("finding albums in the storage", "error", err)
-				encodeMessage(w, http.StatusInternalServerError, "internal error")
+				respondInternalError(w, r, logger, "suggesting albums from the storage", err)
 			}
 			return
 		}
-		// Respond with the found albums.
-		encode(w, http.StatusOK, albs)
+		// Respond with the suggested albums.
+		encodeAlbums(w, http.StatusOK, mediaType, albs, nil)
 	})
 }
 
@@ -149,22 +392,63 @@ func getAlbumHandler(albumStorage AlbumStorage, logger *slog.Logger) http.Handle
 		// Extract album id from the request.
 		albID, err := uuid.Parse(r.PathValue("album_id"))
 		if err != nil {
-			encodeMessage(w, http.StatusBadRequest, "malformed album id")
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Extract the fields the album will be restricted to.
+		fields, err := parseFields(r.URL.Query(), albumFields)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, err.Error())
 			return
 		}
+		mediaType := negotiateAlbumMediaType(w, r)
 		// Find album in the storage.
 		alb, err := albumStorage.FindOne(r.Context(), albID)
 		if errors.Is(err, ErrAlbumNotFound) {
-			encodeMessage(w, http.StatusNotFound, "album not found")
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
 			return
 		}
 		if err != nil {
-			logger.Error("finding one album in the storage", "error", err)
-			encodeMessage(w, http.StatusInternalServerError, "internal error")
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
 			return
 		}
 		// Respond with the found album.
-		encode(w, http.StatusOK, alb)
+		if err := encodeAlbum(w, http.StatusOK, mediaType, alb, fields); err != nil {
+			respondInternalError(w, r, logger, "projecting album", err)
+		}
+	})
+}
+
+// albumHistoryHandler returns an http.Handler to requests to list the changes
+// of an album.
+func albumHistoryHandler(albumStorage AlbumStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Find album history in the storage.
+		entries, err := albumStorage.History(r.Context(), albID)
+		if errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding album history in the storage", err)
+			return
+		}
+		// Respond with the album history.
+		encode(w, http.StatusOK, entries)
+	})
+}
+
+// versionHandler returns an http.Handler to requests to find the build of
+// the application.
+func versionHandler(info BuildInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encode(w, http.StatusOK, info)
 	})
 }
 
@@ -173,49 +457,63 @@ func updateAlbumHandler(
 	albumStorage AlbumStorage,
 	logger *slog.Logger,
 	validate func(Validator) map[string]string,
-	timeNow func() time.Time,
+	rules AlbumRules,
+	clock clock.Clock,
 ) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract album id from the request.
 		albID, err := uuid.Parse(r.PathValue("album_id"))
 		if err != nil {
-			encodeMessage(w, http.StatusBadRequest, "malformed album id")
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
 			return
 		}
 		// Extract updated album data from request.
 		req, err := decode[request](r)
 		if err != nil {
-			encodeMessage(w, http.StatusBadRequest, "malformed request body")
+			encodeMessage(w, r, http.StatusBadRequest, "malformed request body")
 			return
 		}
+		req.rules = rules
+		req.today = civil.DateOf(clock.Now().UTC())
 		if problems := validate(req); len(problems) > 0 {
-			encodeProblems(w, http.StatusBadRequest, "invalid request body", problems)
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", problems)
 			return
 		}
-		// Find album in the storage.
-		alb, err := albumStorage.FindOne(r.Context(), albID)
-		if err != nil {
-			switch {
-			case errors.Is(err, ErrAlbumNotFound):
-				encodeMessage(w, http.StatusNotFound, "album not found")
-			default:
-				logger.Error("finding one album in the storage", "error", err)
-				encodeMessage(w, http.StatusInternalServerError, "internal error")
+		// Upsert album into the storage if requested.
+		if r.URL.Query().Get("upsert") == "true" {
+			alb, created, err := albumStorage.Upsert(r.Context(), newAlbum(r.Context(), albID, req, clock.Now().UTC()))
+			if errors.Is(err, ErrAlbumAlreadyExists) {
+				encodeProblems(w, r, http.StatusConflict, "album already exists", albumAlreadyExistsProblems)
+				return
 			}
+			if err != nil {
+				respondInternalError(w, r, logger, "upserting album into the storage", err)
+				return
+			}
+			// Respond with the upserted album.
+			if created {
+				encode(w, http.StatusCreated, alb)
+				return
+			}
+			encode(w, http.StatusOK, alb)
 			return
 		}
 		// Update album in the storage.
-		alb.Title = req.Title
-		alb.Artist = req.Artist
-		alb.Price = req.Price
-		alb.UpdatedAt = timeNow()
-		if err := albumStorage.Update(r.Context(), alb); err != nil {
+		alb, err := albumStorage.UpdateFunc(r.Context(), albID, func(alb Album) Album {
+			return updateAlbum(r.Context(), alb, req, clock.Now().UTC())
+		})
+		if err != nil {
 			switch {
 			case errors.Is(err, ErrAlbumNotFound):
-				encodeMessage(w, http.StatusNotFound, "album not found")
+				encodeMessage(w, r, http.StatusNotFound, "album not found")
+			case errors.Is(err, ErrAlbumConflict):
+				encodeMessage(w, r, http.StatusConflict, "album was concurrently modified")
+			case errors.Is(err, ErrVersionConflict):
+				encodeMessage(w, r, http.StatusConflict, "album version conflict")
+			case errors.Is(err, ErrAlbumAlreadyExists):
+				encodeProblems(w, r, http.StatusConflict, "album already exists", albumAlreadyExistsProblems)
 			default:
-				logger.Error("updating album in the storage", "error", err)
-				encodeMessage(w, http.StatusInternalServerError, "internal error")
+				respondInternalError(w, r, logger, "updating album in the storage", err)
 			}
 			return
 		}
@@ -230,29 +528,17 @@ func deleteAlbumHandler(albumStorage AlbumStorage, logger *slog.Logger) http.Han
 		// Extract album id from the request.
 		albID, err := uuid.Parse(r.PathValue("album_id"))
 		if err != nil {
-			encodeMessage(w, http.StatusBadRequest, "malformed album id")
-			return
-		}
-		// Find album in the storage.
-		alb, err := albumStorage.FindOne(r.Context(), albID)
-		if err != nil {
-			switch {
-			case errors.Is(err, ErrAlbumNotFound):
-				encodeMessage(w, http.StatusNotFound, "album not found")
-			default:
-				logger.Error("finding one album in the storage", "error", err)
-				encodeMessage(w, http.StatusInternalServerError, "internal error")
-			}
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
 			return
 		}
 		// Remove album from the storage.
-		if err := albumStorage.Remove(r.Context(), albID); err != nil {
+		alb, err := albumStorage.RemoveReturning(r.Context(), albID)
+		if err != nil {
 			switch {
 			case errors.Is(err, ErrAlbumNotFound):
-				encodeMessage(w, http.StatusNotFound, "album not found")
+				encodeMessage(w, r, http.StatusNotFound, "album not found")
 			default:
-				logger.Error("removing album from the storage", "error", err)
-				encodeMessage(w, http.StatusInternalServerError, "internal error")
+				respondInternalError(w, r, logger, "removing album from the storage", err)
 			}
 			return
 		}
@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"log/slog"
 	"math/rand/v2"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -17,17 +19,66 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/jhtohru/go-album-catalog/clock"
 	"github.com/jhtohru/go-album-catalog/internal/random"
+	"github.com/jhtohru/go-album-catalog/money"
 )
 
 func TestRequest(t *testing.T) {
-	problemsWant := map[string]string{
-		"title":  "is empty",
-		"artist": "is empty",
-		"price":  "is not greater than zero",
+	tests := map[string]struct {
+		req          request
+		problemsWant map[string]string
+	}{
+		"empty": {
+			req: request{},
+			problemsWant: map[string]string{
+				"title":  "is empty",
+				"artist": "is empty",
+				"price":  "is not greater than zero",
+			},
+		},
+		"valid": {
+			req:          request{Title: "Nevermind", Artist: "Nirvana", Price: money.New(2999, "EUR"), rules: DefaultAlbumRules},
+			problemsWant: map[string]string{},
+		},
+		"legacy price": {
+			req:          request{Title: "Nevermind", Artist: "Nirvana", Price: money.Money{AmountMinor: 2999}, rules: DefaultAlbumRules},
+			problemsWant: map[string]string{},
+		},
+		"no currency": {
+			req:          request{Title: "Nevermind", Artist: "Nirvana", Price: money.Money{AmountMinor: 2999}},
+			problemsWant: map[string]string{"price": "has no currency"},
+		},
+		"invalid currency": {
+			req:          request{Title: "Nevermind", Artist: "Nirvana", Price: money.New(2999, "usd"), rules: DefaultAlbumRules},
+			problemsWant: map[string]string{"price": "has an invalid currency"},
+		},
+		"too long": {
+			req: request{
+				Title:  "Nevermind",
+				Artist: "Nirvana",
+				Price:  money.New(2999, "USD"),
+				rules:  AlbumRules{TitleMaxLength: 8, ArtistMaxLength: 7, MaxPrice: 2000},
+			},
+			problemsWant: map[string]string{
+				"title": "is longer than 8 characters",
+				"price": "is greater than 2000",
+			},
+		},
+		"zero price allowed": {
+			req:          request{Title: "Nevermind", Artist: "Nirvana", Price: money.New(0, "USD"), rules: AlbumRules{AllowZeroPrice: true}},
+			problemsWant: map[string]string{},
+		},
+		"negative price": {
+			req:          request{Title: "Nevermind", Artist: "Nirvana", Price: money.New(-1, "USD"), rules: AlbumRules{AllowZeroPrice: true}},
+			problemsWant: map[string]string{"price": "is less than zero"},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.problemsWant, test.req.Valid())
+		})
 	}
-	var req request
-	assert.Equal(t, problemsWant, req.Valid())
 }
 
 func TestCreateAlbumHandler(t *testing.T) {
@@ -67,6 +118,19 @@ func TestCreateAlbumHandler(t *testing.T) {
 					}
 				}`,
 		},
+		"album already exists": {
+			requestBody: "{}",
+			insertErr:   ErrAlbumAlreadyExists,
+
+			statusCodeWant: http.StatusConflict,
+			responseBodyWant: `
+				{
+					"message": "album already exists",
+					"problems": {
+						"title": "is already used by another album of the same artist"
+					}
+				}`,
+		},
 		"unexpected insert error": {
 			requestBody: "{}",
 			insertErr:   fmt.Errorf("unexpected insert error"),
@@ -87,7 +151,7 @@ func TestCreateAlbumHandler(t *testing.T) {
 					{
 						"title":  "Anathema",
 						"artist": "Judgement",
-						"price":  1234
+						"price":  {"amount": 1234, "currency": "EUR"}
 					}`,
 				newID: newID,
 				now:   now,
@@ -98,9 +162,36 @@ func TestCreateAlbumHandler(t *testing.T) {
 						"id":         "` + newID.String() + `",
 						"title":      "Anathema",
 						"artist":     "Judgement",
-						"price":      1234,
+						"price":      {"amount": 1234, "currency": "EUR"},
 						"created_at": "` + now.Format(time.RFC3339Nano) + `",
-						"updated_at": "` + now.Format(time.RFC3339Nano) + `"
+						"updated_at": "` + now.Format(time.RFC3339Nano) + `",
+						"version":    1
+					}`,
+			}
+		}(),
+		"times in UTC": func() testCase {
+			newID := uuid.New()
+			now := time.Date(2024, 8, 22, 1, 30, 0, 0, time.FixedZone("UTC-3", -3*60*60))
+			return testCase{
+				requestBody: `
+					{
+						"title":  "Anathema",
+						"artist": "Judgement",
+						"price":  1234
+					}`,
+				newID: newID,
+				now:   now,
+
+				statusCodeWant: http.StatusCreated,
+				responseBodyWant: `
+					{
+						"id":         "` + newID.String() + `",
+						"title":      "Anathema",
+						"artist":     "Judgement",
+						"price":      {"amount": 1234, "currency": "USD"},
+						"created_at": "2024-08-22T04:30:00Z",
+						"updated_at": "2024-08-22T04:30:00Z",
+						"version":    1
 					}`,
 			}
 		}(),
@@ -119,15 +210,13 @@ func TestCreateAlbumHandler(t *testing.T) {
 			newID := func() uuid.UUID {
 				return test.newID
 			}
-			timeNow := func() time.Time {
-				return test.now
-			}
 			handler := createAlbumHandler(
 				storage,
 				logger,
 				validate,
+				DefaultAlbumRules,
 				newID,
-				timeNow,
+				clock.NewFake(test.now),
 			)
 			rec := httptest.NewRecorder()
 			req := httptest.NewRequest("", "/", strings.NewReader(test.requestBody))
@@ -154,6 +243,7 @@ func TestListAlbumsHandler(t *testing.T) {
 		limitWant        int
 		findAllAlbs      []Album
 		findAllErr       error
+		abortWant        bool
 		statusCodeWant   int
 		responseBodyWant string
 		logSubstrsWant   []string
@@ -220,6 +310,16 @@ func TestListAlbumsHandler(t *testing.T) {
 			statusCodeWant:   http.StatusBadRequest,
 			responseBodyWant: `{"message": "page number is less than 1"}`,
 		},
+		"unknown field": {
+			urlValues: url.Values{
+				"page_size":   []string{"1"},
+				"page_number": []string{"1"},
+				"fields":      []string{"id,genre"}, // unknown field
+			},
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "unknown field \"genre\""}`,
+		},
 		"unexpected find error": {
 			urlValues: url.Values{
 				"page_size":   []string{"10"},
@@ -245,10 +345,26 @@ func TestListAlbumsHandler(t *testing.T) {
 			offsetWant: 20,
 			limitWant:  10,
 
-			findAllErr:       ErrAlbumNotFound,
 			statusCodeWant:   http.StatusOK,
 			responseBodyWant: "[]",
 		},
+		"unexpected find error mid-stream": {
+			urlValues: url.Values{
+				"page_size":   []string{"10"},
+				"page_number": []string{"3"},
+			},
+			offsetWant:  20,
+			limitWant:   10,
+			findAllAlbs: randomAlbums(2),
+			findAllErr:  fmt.Errorf("unexpected find error"),
+
+			abortWant: true,
+			logSubstrsWant: []string{
+				"level=ERROR",
+				`msg="streaming albums from the storage"`,
+				`error="stream interrupted: unexpected find error"`,
+			},
+		},
 		"happy path": func() testCase {
 			albs := randomAlbums(10)
 			bodyWantBytes, _ := json.Marshal(albs)
@@ -265,18 +381,136 @@ func TestListAlbumsHandler(t *testing.T) {
 				responseBodyWant: string(bodyWantBytes),
 			}
 		}(),
+		"sparse fieldset": func() testCase {
+			albs := randomAlbums(2)
+			return testCase{
+				urlValues: url.Values{
+					"page_size":   []string{"10"},
+					"page_number": []string{"1"},
+					"fields":      []string{"id, price"},
+				},
+				offsetWant:  0,
+				limitWant:   10,
+				findAllAlbs: albs,
+
+				statusCodeWant: http.StatusOK,
+				responseBodyWant: fmt.Sprintf(`[{"id": %q, "price": {"amount": %d, "currency": "USD"}}, {"id": %q, "price": {"amount": %d, "currency": "USD"}}]`,
+					albs[0].ID, albs[0].Price.AmountMinor, albs[1].ID, albs[1].Price.AmountMinor),
+			}
+		}(),
 	}
 	for testName, test := range tests {
 		t.Run(testName, func(t *testing.T) {
 			storageSpy := &storageSpy{}
-			storageSpy.findAll = func(ctx context.Context, offset, limit int) ([]Album, error) {
+			storageSpy.findAllSeq = func(ctx context.Context, filter AlbumFilter, offset, limit int) iter.Seq2[Album, error] {
 				assert.Equal(t, test.offsetWant, offset)
 				assert.Equal(t, test.limitWant, limit)
-				return test.findAllAlbs, test.findAllErr
+				return func(yield func(Album, error) bool) {
+					for _, alb := range test.findAllAlbs {
+						if !yield(alb, nil) {
+							return
+						}
+					}
+					if test.findAllErr != nil {
+						yield(Album{}, test.findAllErr)
+					}
+				}
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := listAlbumsHandler(storageSpy, nil, nil, logger)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", "/?"+test.urlValues.Encode(), nil)
+
+			if test.abortWant {
+				assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+					handler.ServeHTTP(rec, req)
+				})
+			} else {
+				handler.ServeHTTP(rec, req)
+
+				assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+				assert.Equal(t, rec.Header().Get("Content-Type"), "application/json; charset=utf-8")
+				assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			}
+
+			logs := logsBuf.String()
+
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestSuggestAlbumsHandler(t *testing.T) {
+	type testCase struct {
+		urlValues        url.Values
+		prefixWant       string
+		suggestAlbs      []Album
+		suggestErr       error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"missing q": {
+			urlValues: url.Values{}, // missing "q"
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "query parameter q is missing"}`,
+		},
+		"empty q": {
+			urlValues: url.Values{"q": []string{" "}}, // empty q
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "query parameter q is empty"}`,
+		},
+		"unexpected suggest error": {
+			urlValues:  url.Values{"q": []string{"ana"}},
+			prefixWant: "ana",
+			suggestErr: fmt.Errorf("unexpected suggest error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				"level=ERROR",
+				`msg="suggesting albums from the storage"`,
+				`error="unexpected suggest error"`,
+			},
+		},
+		"no results": {
+			urlValues:  url.Values{"q": []string{"ana"}},
+			prefixWant: "ana",
+			suggestErr: ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: "[]",
+		},
+		"happy path": func() testCase {
+			albs := randomAlbums(3)
+			bodyWantBytes, _ := json.Marshal(albs)
+			return testCase{
+				urlValues:   url.Values{"q": []string{" ana "}},
+				prefixWant:  "ana",
+				suggestAlbs: albs,
+
+				statusCodeWant:   http.StatusOK,
+				responseBodyWant: string(bodyWantBytes),
+			}
+		}(),
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storageSpy := &storageSpy{}
+			storageSpy.suggest = func(ctx context.Context, prefix string, limit int) ([]Album, error) {
+				assert.Equal(t, test.prefixWant, prefix)
+				assert.Equal(t, maxAlbumSuggestions, limit)
+				return test.suggestAlbs, test.suggestErr
 			}
 			logsBuf := bytes.NewBuffer(nil)
 			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
-			handler := listAlbumsHandler(storageSpy, logger)
+			handler := suggestAlbumsHandler(storageSpy, logger)
 			rec := httptest.NewRecorder()
 			req := httptest.NewRequest("", "/?"+test.urlValues.Encode(), nil)
 
@@ -298,6 +532,7 @@ func TestListAlbumsHandler(t *testing.T) {
 func TestGetAlbumHandler(t *testing.T) {
 	type testCase struct {
 		albumID          string
+		urlValues        url.Values
 		findOneAlb       Album
 		findOneErr       error
 		statusCodeWant   int
@@ -311,6 +546,13 @@ func TestGetAlbumHandler(t *testing.T) {
 			statusCodeWant:   http.StatusBadRequest,
 			responseBodyWant: `{"message": "malformed album id"}`,
 		},
+		"unknown field": {
+			albumID:   "00000000-0000-0000-0000-000000000000",
+			urlValues: url.Values{"fields": []string{"genre"}}, // unknown field
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "unknown field \"genre\""}`,
+		},
 		"album not found": {
 			albumID:    "00000000-0000-0000-0000-000000000000",
 			findOneErr: ErrAlbumNotFound,
@@ -341,6 +583,17 @@ func TestGetAlbumHandler(t *testing.T) {
 				responseBodyWant: string(bodyWantBytes),
 			}
 		}(),
+		"sparse fieldset": func() testCase {
+			alb := randomAlbum()
+			return testCase{
+				albumID:    "00000000-0000-0000-0000-000000000000",
+				urlValues:  url.Values{"fields": []string{"title,artist"}},
+				findOneAlb: alb,
+
+				statusCodeWant:   http.StatusOK,
+				responseBodyWant: fmt.Sprintf(`{"title": %q, "artist": %q}`, alb.Title, alb.Artist),
+			}
+		}(),
 	}
 	for testName, test := range tests {
 		t.Run(testName, func(t *testing.T) {
@@ -352,7 +605,7 @@ func TestGetAlbumHandler(t *testing.T) {
 			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
 			handler := getAlbumHandler(storage, logger)
 			rec := httptest.NewRecorder()
-			req := httptest.NewRequest("", "/", nil)
+			req := httptest.NewRequest("", "/?"+test.urlValues.Encode(), nil)
 			req.SetPathValue("album_id", test.albumID)
 
 			handler.ServeHTTP(rec, req)
@@ -373,12 +626,15 @@ func TestGetAlbumHandler(t *testing.T) {
 func TestUpdateAlbumHandler(t *testing.T) {
 	type testCase struct {
 		albumID          string
+		upsert           bool
 		requestBody      string
 		validateProblems map[string]string
 		now              time.Time
-		findOneAlb       Album
-		findOneErr       error
-		updateErr        error
+		storedAlb        Album
+		updateFuncErr    error
+		upsertAlb        Album
+		upsertCreated    bool
+		upsertErr        error
 		statusCodeWant   int
 		responseBodyWant string
 		logSubstrsWant   []string
@@ -418,39 +674,60 @@ func TestUpdateAlbumHandler(t *testing.T) {
 			}`,
 		},
 		"album not found": {
-			albumID:     "00000000-0000-0000-0000-000000000000",
-			requestBody: "{}",
-			findOneErr:  ErrAlbumNotFound,
+			albumID:       "00000000-0000-0000-0000-000000000000",
+			requestBody:   "{}",
+			updateFuncErr: ErrAlbumNotFound,
 
 			statusCodeWant:   http.StatusNotFound,
 			responseBodyWant: `{"message": "album not found"}`,
 		},
-		"unexpected find error": {
-			albumID:     "00000000-0000-0000-0000-000000000000",
-			requestBody: "{}",
-			findOneErr:  fmt.Errorf("unexpected find error"),
+		"album conflict": {
+			albumID:       "00000000-0000-0000-0000-000000000000",
+			requestBody:   "{}",
+			updateFuncErr: ErrAlbumConflict,
 
-			statusCodeWant:   http.StatusInternalServerError,
-			responseBodyWant: `{"message": "internal error"}`,
-			logSubstrsWant: []string{
-				`level=ERROR`,
-				`msg="finding one album in the storage"`,
-				`error="unexpected find error"`,
-			},
+			statusCodeWant:   http.StatusConflict,
+			responseBodyWant: `{"message": "album was concurrently modified"}`,
 		},
-		"album not found on update": {
+		"version conflict": {
+			albumID:       "00000000-0000-0000-0000-000000000000",
+			requestBody:   `{"version": 1}`,
+			updateFuncErr: ErrVersionConflict,
+
+			statusCodeWant:   http.StatusConflict,
+			responseBodyWant: `{"message": "album version conflict"}`,
+		},
+		"album already exists": {
+			albumID:       "00000000-0000-0000-0000-000000000000",
+			requestBody:   "{}",
+			updateFuncErr: ErrAlbumAlreadyExists,
+
+			statusCodeWant: http.StatusConflict,
+			responseBodyWant: `{
+				"message": "album already exists",
+				"problems": {
+					"title": "is already used by another album of the same artist"
+				}
+			}`,
+		},
+		"upsert album already exists": {
 			albumID:     "00000000-0000-0000-0000-000000000000",
+			upsert:      true,
 			requestBody: "{}",
-			updateErr:   ErrAlbumNotFound,
+			upsertErr:   ErrAlbumAlreadyExists,
 
-			statusCodeWant:   http.StatusNotFound,
-			responseBodyWant: `{"message": "album not found"}`,
-			logSubstrsWant:   nil,
+			statusCodeWant: http.StatusConflict,
+			responseBodyWant: `{
+				"message": "album already exists",
+				"problems": {
+					"title": "is already used by another album of the same artist"
+				}
+			}`,
 		},
 		"unexpected update error": {
-			albumID:     "00000000-0000-0000-0000-000000000000",
-			requestBody: "{}",
-			updateErr:   fmt.Errorf("unexpected update error"),
+			albumID:       "00000000-0000-0000-0000-000000000000",
+			requestBody:   "{}",
+			updateFuncErr: fmt.Errorf("unexpected update error"),
 
 			statusCodeWant:   http.StatusInternalServerError,
 			responseBodyWant: `{"message": "internal error"}`,
@@ -469,10 +746,10 @@ func TestUpdateAlbumHandler(t *testing.T) {
 					{
 						"title":  "Babylon By Gus Vol.1 - O Ano do Macaco",
 						"artist": "Black Alien",
-						"price":  12345
+						"price":  {"amount": 12345, "currency": "JPY"}
 					}`,
-				now:        now,
-				findOneAlb: alb,
+				now:       now,
+				storedAlb: alb,
 
 				statusCodeWant: http.StatusOK,
 				responseBodyWant: `
@@ -480,38 +757,113 @@ func TestUpdateAlbumHandler(t *testing.T) {
 						"id":         "` + alb.ID.String() + `",
 						"title":      "Babylon By Gus Vol.1 - O Ano do Macaco",
 						"artist":     "Black Alien",
-						"price":      12345,
+						"price":      {"amount": 12345, "currency": "JPY"},
 						"created_at": "` + alb.CreatedAt.Format(time.RFC3339Nano) + `",
-						"updated_at": "` + now.Format(time.RFC3339Nano) + `"
+						"updated_at": "` + now.Format(time.RFC3339Nano) + `",
+						"version":    ` + strconv.Itoa(alb.Version) + `
 					}`,
 			}
 		}(),
+		"happy path with version": func() testCase {
+			now := random.Time()
+			alb := randomAlbum()
+			return testCase{
+				albumID: "00000000-0000-0000-0000-000000000000",
+				requestBody: `
+					{
+						"title":   "Babylon By Gus Vol.1 - O Ano do Macaco",
+						"artist":  "Black Alien",
+						"price":   12345,
+						"version": 42
+					}`,
+				now:       now,
+				storedAlb: alb,
+
+				statusCodeWant: http.StatusOK,
+				responseBodyWant: `
+					{
+						"id":         "` + alb.ID.String() + `",
+						"title":      "Babylon By Gus Vol.1 - O Ano do Macaco",
+						"artist":     "Black Alien",
+						"price":      {"amount": 12345, "currency": "USD"},
+						"created_at": "` + alb.CreatedAt.Format(time.RFC3339Nano) + `",
+						"updated_at": "` + now.Format(time.RFC3339Nano) + `",
+						"version":    42
+					}`,
+			}
+		}(),
+		"unexpected upsert error": {
+			albumID:     "00000000-0000-0000-0000-000000000000",
+			upsert:      true,
+			requestBody: "{}",
+			upsertErr:   fmt.Errorf("unexpected upsert error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="upserting album into the storage"`,
+				`error="unexpected upsert error"`,
+			},
+		},
+		"upsert creates album": func() testCase {
+			alb := randomAlbum()
+			bodyWantBytes, _ := json.Marshal(alb)
+			return testCase{
+				albumID:       alb.ID.String(),
+				upsert:        true,
+				requestBody:   "{}",
+				upsertAlb:     alb,
+				upsertCreated: true,
+
+				statusCodeWant:   http.StatusCreated,
+				responseBodyWant: string(bodyWantBytes),
+			}
+		}(),
+		"upsert updates album": func() testCase {
+			alb := randomAlbum()
+			bodyWantBytes, _ := json.Marshal(alb)
+			return testCase{
+				albumID:     alb.ID.String(),
+				upsert:      true,
+				requestBody: "{}",
+				upsertAlb:   alb,
+
+				statusCodeWant:   http.StatusOK,
+				responseBodyWant: string(bodyWantBytes),
+			}
+		}(),
 	}
 	for testName, test := range tests {
 		t.Run(testName, func(t *testing.T) {
 			storage := &storageSpy{}
-			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
-				return test.findOneAlb, test.findOneErr
+			storage.updateFunc = func(ctx context.Context, id uuid.UUID, update func(Album) Album) (Album, error) {
+				if test.updateFuncErr != nil {
+					return Album{}, test.updateFuncErr
+				}
+				return update(test.storedAlb), nil
 			}
-			storage.update = func(context.Context, Album) error {
-				return test.updateErr
+			storage.upsert = func(context.Context, Album) (Album, bool, error) {
+				return test.upsertAlb, test.upsertCreated, test.upsertErr
 			}
 			logsBuf := bytes.NewBuffer(nil)
 			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
 			validate := func(Validator) map[string]string {
 				return test.validateProblems
 			}
-			timeNow := func() time.Time {
-				return test.now
-			}
 			handler := updateAlbumHandler(
 				storage,
 				logger,
 				validate,
-				timeNow,
+				DefaultAlbumRules,
+				clock.NewFake(test.now),
 			)
+			target := "/"
+			if test.upsert {
+				target = "/?upsert=true"
+			}
 			rec := httptest.NewRecorder()
-			req := httptest.NewRequest("", "/", strings.NewReader(test.requestBody))
+			req := httptest.NewRequest("", target, strings.NewReader(test.requestBody))
 			req.SetPathValue("album_id", test.albumID)
 
 			handler.ServeHTTP(rec, req)
@@ -531,13 +883,12 @@ func TestUpdateAlbumHandler(t *testing.T) {
 
 func TestDeleteAlbumHandler(t *testing.T) {
 	type testCase struct {
-		albumID          string
-		findOneAlb       Album
-		findOneErr       error
-		removeErr        error
-		statusCodeWant   int
-		responseBodyWant string
-		logSubstrsWant   []string
+		albumID            string
+		removeReturningAlb Album
+		removeReturningErr error
+		statusCodeWant     int
+		responseBodyWant   string
+		logSubstrsWant     []string
 	}
 	tests := map[string]testCase{
 		"malformed album id": {
@@ -547,49 +898,114 @@ func TestDeleteAlbumHandler(t *testing.T) {
 			responseBodyWant: `{"message":"malformed album id"}`,
 		},
 		"album not found": {
-			albumID:    "00000000-0000-0000-0000-000000000000",
-			findOneErr: ErrAlbumNotFound,
+			albumID:            "00000000-0000-0000-0000-000000000000",
+			removeReturningErr: ErrAlbumNotFound,
 
 			statusCodeWant:   http.StatusNotFound,
 			responseBodyWant: `{"message":"album not found"}`,
 		},
-		"unexpected find error": {
-			albumID:        "00000000-0000-0000-0000-000000000000",
-			findOneErr:     fmt.Errorf("unexpected find error"),
-			statusCodeWant: http.StatusInternalServerError,
+		"unexpected remove error": {
+			albumID:            "00000000-0000-0000-0000-000000000000",
+			removeReturningErr: fmt.Errorf("unexpected remove error"),
 
+			statusCodeWant:   http.StatusInternalServerError,
 			responseBodyWant: `{"message":"internal error"}`,
 			logSubstrsWant: []string{
 				"level=ERROR",
-				`msg="finding one album in the storage"`,
-				`error="unexpected find error"`,
+				`msg="removing album from the storage"`,
+				`error="unexpected remove error"`,
 			},
 		},
-		"album not found on remove": {
-			albumID:   "00000000-0000-0000-0000-000000000000",
-			removeErr: ErrAlbumNotFound,
+		"happy path": func() testCase {
+			alb := randomAlbum()
+			bodyWantBytes, _ := json.Marshal(alb)
+			return testCase{
+				albumID:            "00000000-0000-0000-0000-000000000000",
+				removeReturningAlb: alb,
+
+				statusCodeWant:   http.StatusOK,
+				responseBodyWant: string(bodyWantBytes),
+			}
+		}(),
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.removeReturning = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return test.removeReturningAlb, test.removeReturningErr
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := deleteAlbumHandler(
+				storage,
+				logger,
+			)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", "/", nil)
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.Equal(t, rec.Header().Get("Content-Type"), "application/json; charset=utf-8")
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+
+			logs := logsBuf.String()
+
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestAlbumHistoryHandler(t *testing.T) {
+	type testCase struct {
+		albumID          string
+		historyEntries   []AlbumAuditEntry
+		historyErr       error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"malformed album id": {
+			albumID: "", // malformed album id
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message":"malformed album id"}`,
+		},
+		"album not found": {
+			albumID:    "00000000-0000-0000-0000-000000000000",
+			historyErr: ErrAlbumNotFound,
 
 			statusCodeWant:   http.StatusNotFound,
 			responseBodyWant: `{"message":"album not found"}`,
 		},
-		"unexpected remove error": {
-			albumID:   "00000000-0000-0000-0000-000000000000",
-			removeErr: fmt.Errorf("unexpected remove error"),
+		"unexpected history error": {
+			albumID:    "00000000-0000-0000-0000-000000000000",
+			historyErr: fmt.Errorf("unexpected history error"),
 
 			statusCodeWant:   http.StatusInternalServerError,
 			responseBodyWant: `{"message":"internal error"}`,
 			logSubstrsWant: []string{
 				"level=ERROR",
-				`msg="removing album from the storage"`,
-				`error="unexpected remove error"`,
+				`msg="finding album history in the storage"`,
+				`error="unexpected history error"`,
 			},
 		},
 		"happy path": func() testCase {
-			alb := randomAlbum()
-			bodyWantBytes, _ := json.Marshal(alb)
+			before := randomAlbum()
+			after := randomAlbum()
+			entries := []AlbumAuditEntry{
+				{Action: "insert", After: &before, CreatedAt: random.Time()},
+				{Action: "update", Actor: "jtohru", Before: &before, After: &after, CreatedAt: random.Time()},
+				{Action: "delete", Before: &after, CreatedAt: random.Time()},
+			}
+			bodyWantBytes, _ := json.Marshal(entries)
 			return testCase{
-				albumID:    "00000000-0000-0000-0000-000000000000",
-				findOneAlb: alb,
+				albumID:        "00000000-0000-0000-0000-000000000000",
+				historyEntries: entries,
 
 				statusCodeWant:   http.StatusOK,
 				responseBodyWant: string(bodyWantBytes),
@@ -599,15 +1015,12 @@ func TestDeleteAlbumHandler(t *testing.T) {
 	for testName, test := range tests {
 		t.Run(testName, func(t *testing.T) {
 			storage := &storageSpy{}
-			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
-				return test.findOneAlb, test.findOneErr
-			}
-			storage.remove = func(ctx context.Context, id uuid.UUID) error {
-				return test.removeErr
+			storage.history = func(ctx context.Context, id uuid.UUID) ([]AlbumAuditEntry, error) {
+				return test.historyEntries, test.historyErr
 			}
 			logsBuf := bytes.NewBuffer(nil)
 			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
-			handler := deleteAlbumHandler(
+			handler := albumHistoryHandler(
 				storage,
 				logger,
 			)
@@ -631,42 +1044,78 @@ func TestDeleteAlbumHandler(t *testing.T) {
 }
 
 type storageSpy struct {
-	insert  func(ctx context.Context, alb Album) error
-	findAll func(ctx context.Context, offset, limit int) ([]Album, error)
-	findOne func(ctx context.Context, id uuid.UUID) (Album, error)
-	update  func(ctx context.Context, alb Album) error
-	remove  func(ctx context.Context, id uuid.UUID) error
+	insert          func(ctx context.Context, alb Album) error
+	insertBatch     func(ctx context.Context, albs []Album) error
+	findAll         func(ctx context.Context, filter AlbumFilter, offset, limit int) ([]Album, error)
+	findAllSeq      func(ctx context.Context, filter AlbumFilter, offset, limit int) iter.Seq2[Album, error]
+	findOne         func(ctx context.Context, id uuid.UUID) (Album, error)
+	suggest         func(ctx context.Context, prefix string, limit int) ([]Album, error)
+	update          func(ctx context.Context, alb Album) error
+	upsert          func(ctx context.Context, alb Album) (Album, bool, error)
+	remove          func(ctx context.Context, id uuid.UUID) error
+	updateFunc      func(ctx context.Context, id uuid.UUID, update func(Album) Album) (Album, error)
+	removeReturning func(ctx context.Context, id uuid.UUID) (Album, error)
+	history         func(ctx context.Context, id uuid.UUID) ([]AlbumAuditEntry, error)
 }
 
 func (spy *storageSpy) Insert(ctx context.Context, alb Album) error {
 	return spy.insert(ctx, alb)
 }
 
-func (spy *storageSpy) FindAll(ctx context.Context, offset, limit int) ([]Album, error) {
-	return spy.findAll(ctx, offset, limit)
+func (spy *storageSpy) InsertBatch(ctx context.Context, albs []Album) error {
+	return spy.insertBatch(ctx, albs)
+}
+
+func (spy *storageSpy) FindAll(ctx context.Context, filter AlbumFilter, offset, limit int) ([]Album, error) {
+	return spy.findAll(ctx, filter, offset, limit)
+}
+
+func (spy *storageSpy) FindAllSeq(ctx context.Context, filter AlbumFilter, offset, limit int) iter.Seq2[Album, error] {
+	return spy.findAllSeq(ctx, filter, offset, limit)
 }
 
 func (spy *storageSpy) FindOne(ctx context.Context, id uuid.UUID) (Album, error) {
 	return spy.findOne(ctx, id)
 }
 
+func (spy *storageSpy) UpdateFunc(ctx context.Context, id uuid.UUID, update func(Album) Album) (Album, error) {
+	return spy.updateFunc(ctx, id, update)
+}
+
+func (spy *storageSpy) Suggest(ctx context.Context, prefix string, limit int) ([]Album, error) {
+	return spy.suggest(ctx, prefix, limit)
+}
+
 func (spy *storageSpy) Update(ctx context.Context, alb Album) error {
 	return spy.update(ctx, alb)
 }
 
+func (spy *storageSpy) Upsert(ctx context.Context, alb Album) (Album, bool, error) {
+	return spy.upsert(ctx, alb)
+}
+
 func (spy *storageSpy) Remove(ctx context.Context, id uuid.UUID) error {
 	return spy.remove(ctx, id)
 }
 
+func (spy *storageSpy) RemoveReturning(ctx context.Context, id uuid.UUID) (Album, error) {
+	return spy.removeReturning(ctx, id)
+}
+
+func (spy *storageSpy) History(ctx context.Context, id uuid.UUID) ([]AlbumAuditEntry, error) {
+	return spy.history(ctx, id)
+}
+
 // randomAlbum returns a randomly generated Album.
 func randomAlbum() Album {
 	return Album{
 		ID:        uuid.New(),
 		Title:     random.String(20 + rand.IntN(20)),
 		Artist:    random.String(20 + rand.IntN(20)),
-		Price:     rand.IntN(100000),
+		Price:     money.New(rand.Int64N(100000), "USD"),
 		CreatedAt: random.Time(),
 		UpdatedAt: random.Time(),
+		Version:   rand.IntN(100) + 1,
 	}
 }
 
@@ -678,3 +1127,27 @@ func randomAlbums(n int) []Album {
 	}
 	return albs
 }
+
+func TestVersionHandler(t *testing.T) {
+	info := BuildInfo{
+		Version:   "v1.2.3",
+		Commit:    "0123456789abcdef0123456789abcdef01234567",
+		BuildTime: "2024-08-23T09:00:00Z",
+		GoVersion: "go1.23.0",
+	}
+	handler := versionHandler(info)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("", "/", nil)
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	assert.Equal(t, rec.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	assert.JSONEq(t, `
+		{
+			"version":    "v1.2.3",
+			"commit":     "0123456789abcdef0123456789abcdef01234567",
+			"build_time": "2024-08-23T09:00:00Z",
+			"go_version": "go1.23.0"
+		}`, rec.Body.String())
+}
@@ -0,0 +1,123 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// enrichAlbumHandler returns an http.Handler to requests to enrich an album
+// with the metadata of its release found by the MetadataProvider of enricher.
+func enrichAlbumHandler(albumStorage AlbumStorage, enricher *MetadataEnricher, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Find album in the storage.
+		alb, err := albumStorage.FindOne(r.Context(), albID)
+		if errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
+			return
+		}
+		// Enrich the album with the metadata of its release.
+		md, err := enricher.Enrich(r.Context(), alb)
+		switch {
+		case errors.Is(err, ErrReleaseNotFound):
+			encodeMessage(w, r, http.StatusNotFound, "album release not found")
+			return
+		case errors.Is(err, ErrAlbumNotFound):
+			// The album was removed while it was being enriched.
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		case errors.Is(err, ErrMetadataProviderFailed):
+			msg := "enriching album"
+			logger.Error(msg, "error", err)
+			recordServerError(r.Context(), fmt.Errorf("%s: %w", msg, err))
+			encodeMessage(w, r, http.StatusBadGateway, "metadata provider failed")
+			return
+		case err != nil:
+			respondInternalError(w, r, logger, "saving album metadata into the storage", err)
+			return
+		}
+		// Respond with the album metadata.
+		encode(w, http.StatusOK, md)
+	})
+}
+
+// albumMetadataHandler returns an http.Handler to requests to list the
+// metadata an album was enriched with.
+func albumMetadataHandler(albumStorage AlbumStorage, enricher *MetadataEnricher, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Find album in the storage, so that unknown albums are not found.
+		_, err = albumStorage.FindOne(r.Context(), albID)
+		if errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
+			return
+		}
+		// Find album metadata in the storage.
+		mds, err := enricher.Metadata(r.Context(), albID)
+		if err != nil {
+			respondInternalError(w, r, logger, "finding album metadata in the storage", err)
+			return
+		}
+		// Respond with the album metadata.
+		encode(w, http.StatusOK, mds)
+	})
+}
+
+// lookupReleasesHandler returns an http.Handler to requests to look up the
+// releases of an album by its artist and title, so that catalog entries can be
+// verified against provider before being created.
+func lookupReleasesHandler(provider MetadataProvider, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract artist and title from the request.
+		artist := strings.TrimSpace(r.URL.Query().Get("artist"))
+		title := strings.TrimSpace(r.URL.Query().Get("title"))
+		problems := make(map[string]string)
+		if artist == "" {
+			problems["artist"] = "artist is empty"
+		}
+		if title == "" {
+			problems["title"] = "title is empty"
+		}
+		if len(problems) > 0 {
+			encodeProblems(w, r, http.StatusBadRequest, "invalid query parameters", problems)
+			return
+		}
+		// Search for the releases in the provider.
+		releases, err := provider.SearchReleases(r.Context(), artist, title)
+		if err != nil {
+			msg := "searching " + provider.Name() + " releases"
+			logger.Error(msg, "error", err)
+			recordServerError(r.Context(), fmt.Errorf("%s: %w", msg, err))
+			encodeMessage(w, r, http.StatusBadGateway, "metadata provider failed")
+			return
+		}
+		if releases == nil {
+			releases = []Release{}
+		}
+		// Respond with the releases.
+		encode(w, http.StatusOK, releases)
+	})
+}
@@ -0,0 +1,329 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+type metadataStorageSpy struct {
+	save    func(ctx context.Context, albumID uuid.UUID, md AlbumMetadata) error
+	findAll func(ctx context.Context, albumID uuid.UUID) ([]AlbumMetadata, error)
+}
+
+func (spy *metadataStorageSpy) Save(ctx context.Context, albumID uuid.UUID, md AlbumMetadata) error {
+	return spy.save(ctx, albumID, md)
+}
+
+func (spy *metadataStorageSpy) FindAll(ctx context.Context, albumID uuid.UUID) ([]AlbumMetadata, error) {
+	return spy.findAll(ctx, albumID)
+}
+
+func TestEnrichAlbumHandler(t *testing.T) {
+	alb := randomAlbum()
+	now := time.Date(2024, 8, 29, 0, 0, 0, 0, time.UTC)
+	release := Release{
+		ID:          "1234",
+		Title:       alb.Title,
+		Artist:      alb.Artist,
+		Date:        "1997-05-21",
+		Formats:     []string{"CD"},
+		Genres:      []string{"Hip Hop"},
+		CoverArtURL: "https://i.discogs.com/1234.jpg",
+	}
+	type testCase struct {
+		albumID          string
+		findOneErr       error
+		releases         []Release
+		searchErr        error
+		saveErr          error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"malformed album id": {
+			albumID: "not-an-uuid",
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed album id"}`,
+		},
+		"album not found": {
+			albumID:    alb.ID.String(),
+			findOneErr: ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"release not found": {
+			albumID: alb.ID.String(),
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album release not found"}`,
+		},
+		"provider error": {
+			albumID:   alb.ID.String(),
+			searchErr: fmt.Errorf("unexpected status 503 Service Unavailable"),
+
+			statusCodeWant:   http.StatusBadGateway,
+			responseBodyWant: `{"message": "metadata provider failed"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="enriching album"`,
+				`error="metadata provider failed: searching spy releases: unexpected status 503 Service Unavailable"`,
+			},
+		},
+		"unexpected save error": {
+			albumID:  alb.ID.String(),
+			releases: []Release{release},
+			saveErr:  errors.New("unexpected save error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="saving album metadata into the storage"`,
+				`error="unexpected save error"`,
+			},
+		},
+		"happy path": {
+			albumID:  alb.ID.String(),
+			releases: []Release{release, {ID: "5678"}},
+
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `{
+				"source": "spy",
+				"external_id": "1234",
+				"year": 1997,
+				"genres": ["Hip Hop"],
+				"cover_art_url": "https://i.discogs.com/1234.jpg",
+				"enriched_at": "2024-08-29T00:00:00Z"
+			}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return alb, test.findOneErr
+			}
+			provider := &metadataProviderSpy{
+				searchReleases: func(ctx context.Context, artist, title string) ([]Release, error) {
+					assert.Equal(t, alb.Artist, artist)
+					assert.Equal(t, alb.Title, title)
+					return test.releases, test.searchErr
+				},
+			}
+			var saved AlbumMetadata
+			metadataStorage := &metadataStorageSpy{
+				save: func(ctx context.Context, albumID uuid.UUID, md AlbumMetadata) error {
+					assert.Equal(t, alb.ID, albumID)
+					saved = md
+					return test.saveErr
+				},
+			}
+			enricher := NewMetadataEnricher(provider, metadataStorage)
+			enricher.clock = clock.NewFake(now)
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := enrichAlbumHandler(storage, enricher, logger)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			if test.statusCodeWant == http.StatusOK {
+				var got AlbumMetadata
+				json.Unmarshal(rec.Body.Bytes(), &got)
+				assert.Equal(t, saved, got)
+			}
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestAlbumMetadataHandler(t *testing.T) {
+	type testCase struct {
+		albumID          string
+		findOneErr       error
+		mds              []AlbumMetadata
+		findAllErr       error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"malformed album id": {
+			albumID: "not-an-uuid",
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed album id"}`,
+		},
+		"album not found": {
+			albumID:    uuid.NewString(),
+			findOneErr: ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"unexpected find error": {
+			albumID:    uuid.NewString(),
+			findAllErr: errors.New("unexpected find error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="finding album metadata in the storage"`,
+				`error="unexpected find error"`,
+			},
+		},
+		"happy path": {
+			albumID: uuid.NewString(),
+			mds: []AlbumMetadata{{
+				Source:     "discogs",
+				ExternalID: "1234",
+				Genres:     []string{"Hip Hop"},
+				EnrichedAt: time.Date(2024, 8, 29, 0, 0, 0, 0, time.UTC),
+			}},
+
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `[{
+				"source": "discogs",
+				"external_id": "1234",
+				"genres": ["Hip Hop"],
+				"enriched_at": "2024-08-29T00:00:00Z"
+			}]`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return Album{ID: id}, test.findOneErr
+			}
+			metadataStorage := &metadataStorageSpy{
+				findAll: func(ctx context.Context, albumID uuid.UUID) ([]AlbumMetadata, error) {
+					assert.Equal(t, test.albumID, albumID.String())
+					return test.mds, test.findAllErr
+				},
+			}
+			enricher := NewMetadataEnricher(&metadataProviderSpy{}, metadataStorage)
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := albumMetadataHandler(storage, enricher, logger)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestLookupReleasesHandler(t *testing.T) {
+	type testCase struct {
+		query            string
+		releases         []Release
+		searchErr        error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"missing artist and title": {
+			query: "?artist=%20",
+
+			statusCodeWant: http.StatusBadRequest,
+			responseBodyWant: `{
+				"message": "invalid query parameters",
+				"problems": {"artist": "artist is empty", "title": "title is empty"}
+			}`,
+		},
+		"provider failure": {
+			query:     "?artist=Racionais&title=Nada%20Como%20Um%20Dia",
+			searchErr: errors.New("provider failure"),
+
+			statusCodeWant:   http.StatusBadGateway,
+			responseBodyWant: `{"message": "metadata provider failed"}`,
+			logSubstrsWant:   []string{"searching spy releases", "provider failure"},
+		},
+		"no releases": {
+			query: "?artist=Racionais&title=Nada%20Como%20Um%20Dia",
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `[]`,
+		},
+		"happy path": {
+			query: "?artist=Racionais&title=Nada%20Como%20Um%20Dia",
+			releases: []Release{{
+				ID:      "4f2c6a34-5b0e-4c58-9f1c-0f2b0e1c7d8a",
+				Title:   "Nada Como Um Dia Após o Outro Dia",
+				Artist:  "Racionais MC's",
+				Date:    "2002-11",
+				Formats: []string{"CD", "CD"},
+				Genres:  []string{},
+			}},
+
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `[{
+				"id": "4f2c6a34-5b0e-4c58-9f1c-0f2b0e1c7d8a",
+				"title": "Nada Como Um Dia Após o Outro Dia",
+				"artist": "Racionais MC's",
+				"date": "2002-11",
+				"formats": ["CD", "CD"],
+				"genres": []
+			}]`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			provider := &metadataProviderSpy{
+				searchReleases: func(ctx context.Context, artist, title string) ([]Release, error) {
+					assert.Equal(t, "Racionais", artist)
+					assert.Equal(t, "Nada Como Um Dia", title)
+					return test.releases, test.searchErr
+				},
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := lookupReleasesHandler(provider, logger)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/"+test.query, nil)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// rejectUnknownQueryParams returns an http.Handler that responds to requests
+// having any query parameter not in accepted with a problem for each of them,
+// and passes the other requests to next.
+func rejectUnknownQueryParams(accepted []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problems := make(map[string]string)
+		for name := range r.URL.Query() {
+			if !slices.Contains(accepted, name) {
+				problems[name] = "is unknown"
+			}
+		}
+		if len(problems) > 0 {
+			msg := "unknown query parameters"
+			if len(accepted) > 0 {
+				msg += ", accepted query parameters are: " + strings.Join(accepted, ", ")
+			}
+			encodeProblems(w, r, http.StatusBadRequest, msg, problems)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// timeoutRequests returns an http.Handler that passes requests to next with
+// a context done after timeout, so that the storage queries made for them
+// are canceled by then instead of holding their connection open. The
+// requests failing because of it are responded with 504 Gateway Timeout.
+func timeoutRequests(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// logAccess returns an http.Handler that passes requests to next and logs an
+// access entry for each of them through logger, except for the requests to
+// the paths in skipPaths.
+func logAccess(logger *slog.Logger, skipPaths []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(skipPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logger.InfoContext(r.Context(), "access",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.statusCode,
+			"latency", time.Since(start),
+			"bytes", rec.bytes,
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// responseRecorder is an http.ResponseWriter that records the status code and
+// the number of bytes of the response written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	if !rec.wroteHeader {
+		rec.statusCode = statusCode
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so that
+// http.ResponseController can reach it.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Hijack hijacks the connection of the wrapped http.ResponseWriter, recording
+// the response as switching protocols, so that WebSocket upgrades work through
+// the recorder.
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rec.ResponseWriter).Hijack()
+	if err == nil && !rec.wroteHeader {
+		rec.statusCode = http.StatusSwitchingProtocols
+		rec.wroteHeader = true
+	}
+	return conn, brw, err
+}
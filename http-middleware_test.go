@@ -0,0 +1,158 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectUnknownQueryParams(t *testing.T) {
+	type testCase struct {
+		target           string
+		accepted         []string
+		statusCodeWant   int
+		responseBodyWant string
+	}
+	tests := map[string]testCase{
+		"unknown query parameters": {
+			target:   "/?page_sze=10&page_number=1&sort=title",
+			accepted: []string{"page_size", "page_number"},
+
+			statusCodeWant: http.StatusBadRequest,
+			responseBodyWant: `
+				{
+					"message": "unknown query parameters, accepted query parameters are: page_size, page_number",
+					"problems": {
+						"page_sze": "is unknown",
+						"sort":     "is unknown"
+					}
+				}`,
+		},
+		"no accepted query parameters": {
+			target: "/?upsert=true",
+
+			statusCodeWant: http.StatusBadRequest,
+			responseBodyWant: `
+				{
+					"message": "unknown query parameters",
+					"problems": {
+						"upsert": "is unknown"
+					}
+				}`,
+		},
+		"happy path": {
+			target:   "/?page_size=10&page_number=1",
+			accepted: []string{"page_size", "page_number"},
+
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `{"message": "next"}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				encodeMessage(w, r, http.StatusOK, "next")
+			})
+			handler := rejectUnknownQueryParams(test.accepted, next)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", test.target, nil)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
+
+func TestLogAccess(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodeMessage(w, r, http.StatusTeapot, "next")
+	})
+
+	t.Run("request logged", func(t *testing.T) {
+		logsBuf := bytes.NewBuffer(nil)
+		logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+		handler := logAccess(logger, []string{"/healthz"}, next)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/albums?page_size=10", nil)
+
+		handler.ServeHTTP(rec, req)
+
+		logs := logsBuf.String()
+		assert.Equal(t, http.StatusTeapot, rec.Result().StatusCode)
+		assert.Contains(t, logs, `msg=access`)
+		assert.Contains(t, logs, `method=GET`)
+		assert.Contains(t, logs, `path=/albums`)
+		assert.Contains(t, logs, `status=418`)
+		assert.Contains(t, logs, `bytes=`+strconv.Itoa(rec.Body.Len()))
+		assert.Contains(t, logs, `remote_addr=`+req.RemoteAddr)
+		assert.Contains(t, logs, `latency=`)
+	})
+
+	t.Run("skipped path", func(t *testing.T) {
+		logsBuf := bytes.NewBuffer(nil)
+		logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+		handler := logAccess(logger, []string{"/healthz"}, next)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusTeapot, rec.Result().StatusCode)
+		assert.Empty(t, logsBuf.String())
+	})
+}
+
+func TestTimeoutRequests(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := map[string]struct {
+		next             http.HandlerFunc
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"handled in time": {
+			next: func(w http.ResponseWriter, r *http.Request) {
+				encodeMessage(w, r, http.StatusOK, "next")
+			},
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `{"message": "next"}`,
+		},
+		"timed out": {
+			next: func(w http.ResponseWriter, r *http.Request) {
+				// The storage fails the query when the context is done.
+				<-r.Context().Done()
+				respondInternalError(w, r, logger, "finding albums in the storage", r.Context().Err())
+			},
+			statusCodeWant:   http.StatusGatewayTimeout,
+			responseBodyWant: `{"message": "request timed out"}`,
+		},
+		"failed in time": {
+			next: func(w http.ResponseWriter, r *http.Request) {
+				respondInternalError(w, r, logger, "finding albums in the storage", errors.New("connection refused"))
+			},
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			handler := timeoutRequests(10*time.Millisecond, test.next)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Code)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
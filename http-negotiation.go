@@ -0,0 +1,213 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jhtohru/go-album-catalog/catalogpb"
+)
+
+// The media types albums can be responded in.
+const (
+	mediaTypeJSON     = "application/json"
+	mediaTypeProtobuf = "application/x-protobuf"
+	mediaTypeMsgpack  = "application/x-msgpack"
+)
+
+// albumMediaTypes are the media types albums can be responded in, in order of
+// preference.
+var albumMediaTypes = []string{mediaTypeJSON, mediaTypeProtobuf, mediaTypeMsgpack}
+
+// negotiateAlbumMediaType returns the media type the albums requested by r are
+// responded in, telling caches of w that it depends on the Accept header.
+func negotiateAlbumMediaType(w http.ResponseWriter, r *http.Request) string {
+	w.Header().Add("Vary", "Accept")
+	return negotiateMediaType(r.Header.Values("Accept"), albumMediaTypes)
+}
+
+// negotiateMediaType returns the one of offered with the greatest quality in
+// the accept header values, the earliest one of offered on ties. It returns
+// offered[0] if there is no accept header, or if it accepts none of offered,
+// so that clients not negotiating are responded as before.
+func negotiateMediaType(accept []string, offered []string) string {
+	best, bestQuality := offered[0], 0.0
+	for _, mediaType := range offered {
+		if quality := acceptQuality(accept, mediaType); quality > bestQuality {
+			best, bestQuality = mediaType, quality
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality of mediaType in the accept header values:
+// the quality of the most specific media range matching it, or zero if none
+// does.
+func acceptQuality(accept []string, mediaType string) float64 {
+	quality, specificity := 0.0, -1
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+			var s int
+			switch {
+			case rangeType == mediaType:
+				s = 2
+			case rangeType == "*/*":
+				s = 0
+			case strings.HasSuffix(rangeType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(rangeType, "*")):
+				s = 1
+			default:
+				continue
+			}
+			if s <= specificity {
+				continue
+			}
+			q := 1.0
+			if params["q"] != "" {
+				if q, err = strconv.ParseFloat(params["q"], 64); err != nil {
+					continue
+				}
+			}
+			quality, specificity = q, s
+		}
+	}
+	return quality
+}
+
+// encodeAlbum setup w, write statusCode as its status code and write alb
+// restricted to fields into its body, in mediaType. If fields is nil, alb is
+// written as is. It returns an error, without writing anything into w, if alb
+// cannot be encoded. Errors writing into w are not returned, as the response
+// can no longer be changed then.
+func encodeAlbum(w http.ResponseWriter, statusCode int, mediaType string, alb Album, fields []string) error {
+	switch mediaType {
+	case mediaTypeProtobuf:
+		return encodeProto(w, statusCode, albumProto(alb, fields))
+	case mediaTypeMsgpack:
+		return encodeMsgpack(w, statusCode, albumMsgpack(alb, fields))
+	}
+	projection, err := project(alb, fields)
+	if err != nil {
+		return err
+	}
+	encode(w, statusCode, projection)
+	return nil
+}
+
+// encodeAlbums setup w, write statusCode as its status code and write albs
+// restricted to fields into its body, in mediaType. In protobuf, albs are
+// written as a catalogpb.ListAlbumsResponse. It returns errors as encodeAlbum
+// does.
+func encodeAlbums(w http.ResponseWriter, statusCode int, mediaType string, albs []Album, fields []string) error {
+	switch mediaType {
+	case mediaTypeProtobuf:
+		resp := &catalogpb.ListAlbumsResponse{Albums: make([]*catalogpb.Album, len(albs))}
+		for i, alb := range albs {
+			resp.Albums[i] = albumProto(alb, fields)
+		}
+		return encodeProto(w, statusCode, resp)
+	case mediaTypeMsgpack:
+		objs := make([]map[string]any, len(albs))
+		for i, alb := range albs {
+			objs[i] = albumMsgpack(alb, fields)
+		}
+		return encodeMsgpack(w, statusCode, objs)
+	}
+	projections, err := projectAll(albs, fields)
+	if err != nil {
+		return err
+	}
+	encode(w, statusCode, projections)
+	return nil
+}
+
+// encodeProto setup w, write statusCode as its status code and write m into
+// its body in the protobuf binary format. It returns errors as encodeAlbum
+// does.
+func encodeProto(w http.ResponseWriter, statusCode int, m proto.Message) error {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encoding protobuf: %w", err)
+	}
+	w.Header().Set("Content-Type", mediaTypeProtobuf)
+	w.WriteHeader(statusCode)
+	w.Write(data)
+	return nil
+}
+
+// encodeMsgpack setup w, write statusCode as its status code and write v into
+// its body in MessagePack. It returns errors as encodeAlbum does.
+func encodeMsgpack(w http.ResponseWriter, statusCode int, v any) error {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encoding msgpack: %w", err)
+	}
+	w.Header().Set("Content-Type", mediaTypeMsgpack)
+	w.WriteHeader(statusCode)
+	w.Write(buf.Bytes())
+	return nil
+}
+
+// albumProto returns the protobuf message of alb restricted to fields, whose
+// names are the same as the JSON ones. The fields left out are unset.
+func albumProto(alb Album, fields []string) *catalogpb.Album {
+	msg := albumToProto(alb)
+	if fields == nil {
+		return msg
+	}
+	m := msg.ProtoReflect()
+	fds := m.Descriptor().Fields()
+	for i := range fds.Len() {
+		if fd := fds.Get(i); !slices.Contains(fields, string(fd.Name())) {
+			m.Clear(fd)
+		}
+	}
+	return msg
+}
+
+// albumMsgpack returns the MessagePack object of alb restricted to fields,
+// keyed by the same names as the JSON one. The times are MessagePack
+// timestamps.
+func albumMsgpack(alb Album, fields []string) map[string]any {
+	obj := map[string]any{
+		"id":         alb.ID.String(),
+		"title":      alb.Title,
+		"artist":     alb.Artist,
+		"price":      map[string]any{"amount": alb.Price.AmountMinor, "currency": alb.Price.Currency},
+		"created_at": alb.CreatedAt,
+		"updated_at": alb.UpdatedAt,
+		"version":    alb.Version,
+	}
+	if alb.TenantID != "" {
+		obj["tenant_id"] = alb.TenantID
+	}
+	if alb.CreatedBy != "" {
+		obj["created_by"] = alb.CreatedBy
+	}
+	if alb.UpdatedBy != "" {
+		obj["updated_by"] = alb.UpdatedBy
+	}
+	if alb.Artwork != "" {
+		obj["artwork"] = alb.Artwork
+	}
+	if fields == nil {
+		return obj
+	}
+	projection := make(map[string]any, len(fields))
+	for _, field := range fields {
+		projection[field] = obj[field]
+	}
+	return projection
+}
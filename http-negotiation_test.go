@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jhtohru/go-album-catalog/catalogpb"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := map[string]struct {
+		accept []string
+		want   string
+	}{
+		"no accept header":  {accept: nil, want: mediaTypeJSON},
+		"any":               {accept: []string{"*/*"}, want: mediaTypeJSON},
+		"json":              {accept: []string{"application/json"}, want: mediaTypeJSON},
+		"protobuf":          {accept: []string{"application/x-protobuf"}, want: mediaTypeProtobuf},
+		"msgpack":           {accept: []string{"application/x-msgpack"}, want: mediaTypeMsgpack},
+		"application range": {accept: []string{"application/*"}, want: mediaTypeJSON},
+		"quality": {
+			accept: []string{"application/json;q=0.5, application/x-msgpack"},
+			want:   mediaTypeMsgpack,
+		},
+		"specific range over any": {
+			accept: []string{"application/x-protobuf;q=0.9, */*;q=0.1"},
+			want:   mediaTypeProtobuf,
+		},
+		"several headers": {
+			accept: []string{"text/html", "application/x-protobuf"},
+			want:   mediaTypeProtobuf,
+		},
+		"excluded": {
+			accept: []string{"application/json;q=0, */*"},
+			want:   mediaTypeProtobuf,
+		},
+		"none acceptable": {accept: []string{"text/html"}, want: mediaTypeJSON},
+		"malformed":       {accept: []string{"application/x-protobuf;q=high"}, want: mediaTypeJSON},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.want, negotiateMediaType(test.accept, albumMediaTypes))
+		})
+	}
+}
+
+func TestGetAlbumHandlerMediaTypes(t *testing.T) {
+	alb := randomAlbum()
+	storage := &storageSpy{}
+	storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+		return alb, nil
+	}
+	handler := getAlbumHandler(storage, slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)))
+	get := func(accept, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("", "/"+query, nil)
+		req.Header.Set("Accept", accept)
+		req.SetPathValue("album_id", alb.ID.String())
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("protobuf", func(t *testing.T) {
+		rec := get("application/x-protobuf", "")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-protobuf", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+		var got catalogpb.Album
+		assert.Nil(t, proto.Unmarshal(rec.Body.Bytes(), &got))
+		assert.True(t, proto.Equal(albumToProto(alb), &got))
+	})
+
+	t.Run("protobuf sparse fieldset", func(t *testing.T) {
+		rec := get("application/x-protobuf", "?fields=title,artist")
+
+		var got catalogpb.Album
+		assert.Nil(t, proto.Unmarshal(rec.Body.Bytes(), &got))
+		assert.True(t, proto.Equal(&catalogpb.Album{Title: alb.Title, Artist: alb.Artist}, &got))
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		rec := get("application/x-msgpack", "")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-msgpack", rec.Header().Get("Content-Type"))
+		var got map[string]any
+		assert.Nil(t, msgpack.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, alb.ID.String(), got["id"])
+		assert.Equal(t, alb.Title, got["title"])
+		assert.EqualValues(t, map[string]any{"amount": alb.Price.AmountMinor, "currency": alb.Price.Currency}, got["price"])
+		if createdAt, ok := got["created_at"].(time.Time); assert.True(t, ok) {
+			assert.True(t, alb.CreatedAt.Equal(createdAt))
+		}
+	})
+
+	t.Run("msgpack sparse fieldset", func(t *testing.T) {
+		rec := get("application/x-msgpack", "?fields=title")
+
+		var got map[string]any
+		assert.Nil(t, msgpack.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, map[string]any{"title": alb.Title}, got)
+	})
+}
+
+func TestListAlbumsHandlerMediaTypes(t *testing.T) {
+	albs := randomAlbums(3)
+	storage := &storageSpy{}
+	storage.findAllSeq = func(ctx context.Context, filter AlbumFilter, offset, limit int) iter.Seq2[Album, error] {
+		return func(yield func(Album, error) bool) {
+			for _, alb := range albs {
+				if !yield(alb, nil) {
+					return
+				}
+			}
+		}
+	}
+	handler := listAlbumsHandler(storage, nil, nil, slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)))
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("", "/?page_size=10&page_number=1", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-protobuf", rec.Header().Get("Content-Type"))
+	var got catalogpb.ListAlbumsResponse
+	assert.Nil(t, proto.Unmarshal(rec.Body.Bytes(), &got))
+	want := &catalogpb.ListAlbumsResponse{}
+	for _, alb := range albs {
+		want.Albums = append(want.Albums, albumToProto(alb))
+	}
+	assert.True(t, proto.Equal(want, &got))
+}
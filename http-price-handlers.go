@@ -0,0 +1,153 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/money"
+	"github.com/jhtohru/go-album-catalog/validation"
+)
+
+// albumPrices is the representation of the prices of an album: its default
+// price and its price list in other currencies.
+type albumPrices struct {
+	Price  money.Money   `json:"price"`
+	Prices []money.Money `json:"prices"`
+}
+
+// pricesRequest is a request to replace the price list of an album.
+type pricesRequest struct {
+	Prices []money.Money `json:"prices"`
+
+	// rules are the rules the prices are validated by.
+	rules AlbumRules
+}
+
+// Valid makes pricesRequest implement Validator.
+func (req pricesRequest) Valid() map[string]string {
+	problems := make(map[string]string)
+	if req.Prices == nil {
+		problems["prices"] = "is missing"
+		return problems
+	}
+	amountRules := "gt=0"
+	if req.rules.AllowZeroPrice {
+		amountRules = "min=0"
+	}
+	if req.rules.MaxPrice > 0 {
+		amountRules += fmt.Sprintf(",max=%d", req.rules.MaxPrice)
+	}
+	currencies := make(map[string]bool, len(req.Prices))
+	for _, price := range req.Prices {
+		switch {
+		case !money.ValidCurrency(price.Currency):
+			problems["prices"] = "contains an invalid currency"
+		case currencies[price.Currency]:
+			problems["prices"] = "contains a currency more than once"
+		default:
+			if problem := validation.Value(price.AmountMinor, amountRules); problem != "" {
+				problems["prices"] = "contains a price that " + problem
+			}
+		}
+		if len(problems) > 0 {
+			return problems
+		}
+		currencies[price.Currency] = true
+	}
+	return problems
+}
+
+// albumPricesHandler returns an http.Handler to requests to find the prices of
+// an album.
+func albumPricesHandler(albumStorage AlbumStorage, priceStorage PriceStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		// Find album in the storage, along with its price list.
+		alb, err := albumStorage.FindOne(r.Context(), albID)
+		if errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
+			return
+		}
+		prices, err := priceStorage.FindPrices(r.Context(), albID)
+		if err != nil {
+			respondInternalError(w, r, logger, "finding album prices in the storage", err)
+			return
+		}
+		// Respond with the album prices.
+		encode(w, http.StatusOK, albumPrices{Price: alb.Price, Prices: prices})
+	})
+}
+
+// setAlbumPricesHandler returns an http.Handler to requests to replace the
+// price list of an album.
+func setAlbumPricesHandler(
+	albumStorage AlbumStorage,
+	priceStorage PriceStorage,
+	logger *slog.Logger,
+	validate func(Validator) map[string]string,
+	rules AlbumRules,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract album id and prices from the request.
+		albID, err := uuid.Parse(r.PathValue("album_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed album id")
+			return
+		}
+		req, err := decode[pricesRequest](r)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed request body")
+			return
+		}
+		req.rules = rules
+		if problems := validate(req); len(problems) > 0 {
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", problems)
+			return
+		}
+		// Find album in the storage, whose default price is not listed.
+		alb, err := albumStorage.FindOne(r.Context(), albID)
+		if errors.Is(err, ErrAlbumNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding one album in the storage", err)
+			return
+		}
+		if slices.ContainsFunc(req.Prices, func(price money.Money) bool { return price.Currency == alb.Price.Currency }) {
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", map[string]string{
+				"prices": "contains the currency of the default price",
+			})
+			return
+		}
+		// Replace the album price list in the storage.
+		err = priceStorage.SetPrices(r.Context(), albID, req.Prices)
+		if errors.Is(err, ErrAlbumNotFound) {
+			// The album was removed since it was found.
+			encodeMessage(w, r, http.StatusNotFound, "album not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "setting album prices into the storage", err)
+			return
+		}
+		// Respond with the album prices.
+		slices.SortFunc(req.Prices, func(a, b money.Money) int { return strings.Compare(a.Currency, b.Currency) })
+		encode(w, http.StatusOK, albumPrices{Price: alb.Price, Prices: req.Prices})
+	})
+}
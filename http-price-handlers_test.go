@@ -0,0 +1,319 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+type priceStorageSpy struct {
+	setPrices       func(ctx context.Context, albumID uuid.UUID, prices []money.Money) error
+	findPrices      func(ctx context.Context, albumID uuid.UUID) ([]money.Money, error)
+	findAllPricedIn func(ctx context.Context, currency string, offset, limit int) ([]Album, error)
+}
+
+func (spy *priceStorageSpy) SetPrices(ctx context.Context, albumID uuid.UUID, prices []money.Money) error {
+	return spy.setPrices(ctx, albumID, prices)
+}
+
+func (spy *priceStorageSpy) FindPrices(ctx context.Context, albumID uuid.UUID) ([]money.Money, error) {
+	return spy.findPrices(ctx, albumID)
+}
+
+func (spy *priceStorageSpy) FindAllPricedIn(ctx context.Context, currency string, offset, limit int) ([]Album, error) {
+	return spy.findAllPricedIn(ctx, currency, offset, limit)
+}
+
+func TestPricesRequest(t *testing.T) {
+	tests := map[string]struct {
+		req          pricesRequest
+		problemsWant map[string]string
+	}{
+		"missing": {
+			req:          pricesRequest{},
+			problemsWant: map[string]string{"prices": "is missing"},
+		},
+		"empty": {
+			req:          pricesRequest{Prices: []money.Money{}},
+			problemsWant: map[string]string{},
+		},
+		"valid": {
+			req:          pricesRequest{Prices: []money.Money{money.New(2999, "EUR"), money.New(4500, "JPY")}, rules: DefaultAlbumRules},
+			problemsWant: map[string]string{},
+		},
+		"invalid currency": {
+			req:          pricesRequest{Prices: []money.Money{money.New(2999, "EUR"), {AmountMinor: 2999}}},
+			problemsWant: map[string]string{"prices": "contains an invalid currency"},
+		},
+		"repeated currency": {
+			req:          pricesRequest{Prices: []money.Money{money.New(2999, "EUR"), money.New(3999, "EUR")}},
+			problemsWant: map[string]string{"prices": "contains a currency more than once"},
+		},
+		"zero price": {
+			req:          pricesRequest{Prices: []money.Money{money.New(0, "EUR")}},
+			problemsWant: map[string]string{"prices": "contains a price that is not greater than zero"},
+		},
+		"zero price allowed": {
+			req:          pricesRequest{Prices: []money.Money{money.New(0, "EUR")}, rules: AlbumRules{AllowZeroPrice: true}},
+			problemsWant: map[string]string{},
+		},
+		"too expensive": {
+			req:          pricesRequest{Prices: []money.Money{money.New(2999, "EUR")}, rules: AlbumRules{MaxPrice: 2000}},
+			problemsWant: map[string]string{"prices": "contains a price that is greater than 2000"},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.problemsWant, test.req.Valid())
+		})
+	}
+}
+
+func TestAlbumPricesHandler(t *testing.T) {
+	alb := randomAlbum()
+	type testCase struct {
+		albumID          string
+		findOneErr       error
+		prices           []money.Money
+		findPricesErr    error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"malformed album id": {
+			albumID: "not-an-uuid",
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed album id"}`,
+		},
+		"album not found": {
+			albumID:    alb.ID.String(),
+			findOneErr: ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"unexpected find error": {
+			albumID:       alb.ID.String(),
+			findPricesErr: errors.New("unexpected find error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="finding album prices in the storage"`,
+				`error="unexpected find error"`,
+			},
+		},
+		"happy path": {
+			albumID: alb.ID.String(),
+			prices:  []money.Money{money.New(2999, "EUR"), money.New(4500, "JPY")},
+
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `{
+				"price": {"amount": ` + formatAmount(alb.Price) + `, "currency": "USD"},
+				"prices": [{"amount": 2999, "currency": "EUR"}, {"amount": 4500, "currency": "JPY"}]
+			}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return alb, test.findOneErr
+			}
+			priceStorage := &priceStorageSpy{}
+			priceStorage.findPrices = func(ctx context.Context, albumID uuid.UUID) ([]money.Money, error) {
+				assert.Equal(t, alb.ID, albumID)
+				return test.prices, test.findPricesErr
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := albumPricesHandler(storage, priceStorage, logger)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestSetAlbumPricesHandler(t *testing.T) {
+	alb := randomAlbum()
+	type testCase struct {
+		albumID          string
+		requestBody      string
+		findOneErr       error
+		setPricesErr     error
+		pricesWant       []money.Money
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	tests := map[string]testCase{
+		"malformed album id": {
+			albumID: "not-an-uuid",
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed album id"}`,
+		},
+		"malformed request body": {
+			albumID:     alb.ID.String(),
+			requestBody: `{"prices": 2999}`,
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed request body"}`,
+		},
+		"invalid request body": {
+			albumID:     alb.ID.String(),
+			requestBody: `{"prices": [{"amount": 2999, "currency": "eur"}]}`,
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "invalid request body", "problems": {"prices": "contains an invalid currency"}}`,
+		},
+		"album not found": {
+			albumID:     alb.ID.String(),
+			requestBody: `{"prices": []}`,
+			findOneErr:  ErrAlbumNotFound,
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"currency of the default price": {
+			albumID:     alb.ID.String(),
+			requestBody: `{"prices": [{"amount": 2999, "currency": "USD"}]}`,
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "invalid request body", "problems": {"prices": "contains the currency of the default price"}}`,
+		},
+		"album removed": {
+			albumID:      alb.ID.String(),
+			requestBody:  `{"prices": []}`,
+			setPricesErr: ErrAlbumNotFound,
+			pricesWant:   []money.Money{},
+
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "album not found"}`,
+		},
+		"unexpected set error": {
+			albumID:      alb.ID.String(),
+			requestBody:  `{"prices": []}`,
+			setPricesErr: errors.New("unexpected set error"),
+			pricesWant:   []money.Money{},
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="setting album prices into the storage"`,
+				`error="unexpected set error"`,
+			},
+		},
+		"happy path": {
+			albumID:     alb.ID.String(),
+			requestBody: `{"prices": [{"amount": 4500, "currency": "JPY"}, {"amount": 2999, "currency": "EUR"}]}`,
+			pricesWant:  []money.Money{money.New(4500, "JPY"), money.New(2999, "EUR")},
+
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `{
+				"price": {"amount": ` + formatAmount(alb.Price) + `, "currency": "USD"},
+				"prices": [{"amount": 2999, "currency": "EUR"}, {"amount": 4500, "currency": "JPY"}]
+			}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &storageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (Album, error) {
+				return alb, test.findOneErr
+			}
+			priceStorage := &priceStorageSpy{}
+			var setPrices []money.Money
+			priceStorage.setPrices = func(ctx context.Context, albumID uuid.UUID, prices []money.Money) error {
+				assert.Equal(t, alb.ID, albumID)
+				setPrices = append([]money.Money{}, prices...)
+				return test.setPricesErr
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := setAlbumPricesHandler(storage, priceStorage, logger, Validate, DefaultAlbumRules)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(test.requestBody))
+			req.SetPathValue("album_id", test.albumID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			assert.Equal(t, test.pricesWant, setPrices)
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestListAlbumsHandler_currency(t *testing.T) {
+	albs := randomAlbums(2)
+	for i := range albs {
+		albs[i].Price = money.New(int64(1000+i), "EUR")
+	}
+	storage := &storageSpy{}
+	priceStorage := &priceStorageSpy{}
+	priceStorage.findAllPricedIn = func(ctx context.Context, currency string, offset, limit int) ([]Album, error) {
+		assert.Equal(t, "EUR", currency)
+		assert.Equal(t, 10, offset)
+		assert.Equal(t, 10, limit)
+		return albs, nil
+	}
+	handler := listAlbumsHandler(storage, priceStorage, nil, slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)))
+
+	t.Run("priced in currency", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/albums?page_size=10&page_number=2&currency=EUR&fields=id,price", nil)
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+		assert.JSONEq(t, `[
+			{"id": "`+albs[0].ID.String()+`", "price": {"amount": 1000, "currency": "EUR"}},
+			{"id": "`+albs[1].ID.String()+`", "price": {"amount": 1001, "currency": "EUR"}}
+		]`, rec.Body.String())
+	})
+
+	t.Run("invalid currency", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/albums?page_size=10&page_number=2&currency=euro", nil)
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+		assert.JSONEq(t, `{"message": "currency is not an ISO 4217 currency code"}`, rec.Body.String())
+	})
+}
+
+// formatAmount formats the amount of price as in its JSON representation.
+func formatAmount(price money.Money) string {
+	return strconv.FormatInt(price.AmountMinor, 10)
+}
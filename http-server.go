@@ -3,38 +3,561 @@ package catalog
 import (
 	"log/slog"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/health"
 )
 
-// NewServer returns a new HTTP server that handles requests to CRUD albums.
-func NewServer(
+// serverConfig is the configuration of a server returned by NewServer, set
+// by its ServerOptions.
+type serverConfig struct {
+	webhookStorage     WebhookStorage
+	bus                *EventBus
+	enricher           *MetadataEnricher
+	lookup             MetadataProvider
+	artwork            *ArtworkFetcher
+	priceStorage       PriceStorage
+	genreStorage       GenreStorage
+	logger             *slog.Logger
+	validate           func(Validator) map[string]string
+	albumRules         AlbumRules
+	newID              func() uuid.UUID
+	clock              clock.Clock
+	strictQueryParams  bool
+	accessLogSkipPaths []string
+	readiness          *health.Checker
+	reporter           ErrorReporter
+	errorEncoder       ErrorEncoder
+	metrics            *HTTPMetrics
+	verifier           *auth.Verifier
+	limiter            RateLimiter
+	requestTimeout     time.Duration
+	middleware         []func(http.Handler) http.Handler
+}
+
+// ServerOption configures a server returned by NewServer.
+type ServerOption func(*serverConfig)
+
+// WithLogger makes the server log through logger instead of slog.Default().
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.logger = logger
+	}
+}
+
+// WithClock makes the server tell the time by c instead of clock.System.
+func WithClock(c clock.Clock) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.clock = c
+	}
+}
+
+// WithIDGenerator makes the server identify the albums it creates by newID
+// instead of uuid.New.
+func WithIDGenerator(newID func() uuid.UUID) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.newID = newID
+	}
+}
+
+// WithValidator makes the server validate the requests by validate instead
+// of Validate.
+func WithValidator(validate func(Validator) map[string]string) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.validate = validate
+	}
+}
+
+// WithAlbumRules makes the server validate the albums by rules instead of
+// DefaultAlbumRules.
+func WithAlbumRules(rules AlbumRules) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.albumRules = rules
+	}
+}
+
+// WithMiddleware makes the server pass the requests through middleware, the
+// first one first, after authenticating them and before routing them.
+func WithMiddleware(middleware ...func(http.Handler) http.Handler) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.middleware = append(cfg.middleware, middleware...)
+	}
+}
+
+// WithStrictQueryParams makes the server reject the requests with query
+// parameters not accepted by their route.
+func WithStrictQueryParams() ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.strictQueryParams = true
+	}
+}
+
+// WithRateLimiter makes the server rate limit the requests of each client to
+// the API routes by limiter.
+func WithRateLimiter(limiter RateLimiter) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.limiter = limiter
+	}
+}
+
+// WithRequestTimeout makes the server cancel the context of the requests to
+// the API routes, except the live updates one, after timeout, and respond to
+// the ones failing because of it with 504 Gateway Timeout.
+func WithRequestTimeout(timeout time.Duration) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.requestTimeout = timeout
+	}
+}
+
+// WithAccessLogSkipPaths makes the server not log an access entry for the
+// requests to paths.
+func WithAccessLogSkipPaths(paths ...string) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.accessLogSkipPaths = append(cfg.accessLogSkipPaths, paths...)
+	}
+}
+
+// WithReadiness makes the server serve the report of readiness at /readyz.
+func WithReadiness(readiness *health.Checker) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.readiness = readiness
+	}
+}
+
+// WithErrorReporter makes the server report to reporter the errors behind
+// the responses with a 5xx status code.
+func WithErrorReporter(reporter ErrorReporter) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.reporter = reporter
+	}
+}
+
+// WithErrorEncoder makes the server encode its error responses, such as the
+// validation problems of a request, an album not found or an internal error,
+// by enc instead of DefaultErrorEncoder.
+func WithErrorEncoder(enc ErrorEncoder) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.errorEncoder = enc
+	}
+}
+
+// WithMetrics makes the server record the latency of the requests to each
+// route into metrics.
+func WithMetrics(metrics *HTTPMetrics) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.metrics = metrics
+	}
+}
+
+// WithVerifier makes the server authenticate the requests bearing a token by
+// verifier, scope them to the catalog of their tenant and attribute them to
+// their subject, and only serve the requests authenticated with the role
+// required by their route.
+func WithVerifier(verifier *auth.Verifier) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.verifier = verifier
+	}
+}
+
+// WithWebhooks makes the server handle requests to CRUD the webhook
+// subscriptions of webhookStorage.
+func WithWebhooks(webhookStorage WebhookStorage) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.webhookStorage = webhookStorage
+	}
+}
+
+// WithLiveUpdates makes the server push the album change events published to
+// bus to the WebSocket clients of /ws.
+func WithLiveUpdates(bus *EventBus) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.bus = bus
+	}
+}
+
+// WithMetadataEnricher makes the server handle requests to enrich albums with
+// their metadata by enricher, and to find it.
+func WithMetadataEnricher(enricher *MetadataEnricher) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.enricher = enricher
+	}
+}
+
+// WithReleaseLookup makes the server handle requests to look up the releases
+// of albums in lookup before creating them.
+func WithReleaseLookup(lookup MetadataProvider) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.lookup = lookup
+	}
+}
+
+// WithArtwork makes the server handle requests to fetch the cover art of
+// albums by artwork, and to get it.
+func WithArtwork(artwork *ArtworkFetcher) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.artwork = artwork
+	}
+}
+
+// WithPrices makes the server handle requests to find and replace the price
+// lists of the albums in priceStorage, and to list the albums priced in a
+// currency.
+func WithPrices(priceStorage PriceStorage) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.priceStorage = priceStorage
+	}
+}
+
+// WithGenres makes the server handle requests to manage the genres of
+// genreStorage and to assign them to albums, and to list the albums in a
+// genre.
+func WithGenres(genreStorage GenreStorage) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.genreStorage = genreStorage
+	}
+}
+
+// NewServer returns a new HTTP server that handles requests to CRUD the
+// albums of albumStorage, logging an access entry for each request, as
+// configured by opts.
+func NewServer(albumStorage AlbumStorage, opts ...ServerOption) http.Handler {
+	cfg := serverConfig{
+		logger:     slog.Default(),
+		validate:   Validate,
+		albumRules: DefaultAlbumRules,
+		newID:      uuid.New,
+		clock:      clock.System,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	mux := http.NewServeMux()
+
+	registerRoutes(mux, albumStorage, cfg.webhookStorage, cfg.bus, cfg.enricher, cfg.lookup, cfg.artwork, cfg.priceStorage, cfg.genreStorage, cfg.logger, cfg.validate, cfg.albumRules, cfg.newID, cfg.clock, cfg.strictQueryParams, cfg.metrics, cfg.verifier != nil, cfg.limiter, cfg.requestTimeout)
+	if cfg.readiness != nil {
+		mux.Handle("GET /readyz", cfg.readiness.Handler())
+	}
+
+	var handler http.Handler = mux
+	for _, middleware := range slices.Backward(cfg.middleware) {
+		handler = middleware(handler)
+	}
+	if cfg.verifier != nil {
+		handler = auth.Middleware(cfg.verifier, scopeToTenant(attributeToActor(handler)))
+	}
+	if cfg.errorEncoder != nil {
+		handler = withErrorEncoder(cfg.errorEncoder, handler)
+	}
+	if cfg.reporter != nil {
+		handler = reportServerErrors(cfg.reporter, handler)
+	}
+	return logAccess(cfg.logger, cfg.accessLogSkipPaths, handler)
+}
+
+// NewLegacyServer returns a new HTTP server as NewServer does, configured by
+// positional arguments instead of ServerOptions. Its nil arguments leave the
+// server features they configure disabled.
+//
+// Deprecated: Use NewServer with ServerOptions instead.
+func NewLegacyServer(
 	albumStorage AlbumStorage,
+	webhookStorage WebhookStorage,
+	bus *EventBus,
+	enricher *MetadataEnricher,
+	lookup MetadataProvider,
+	artwork *ArtworkFetcher,
 	logger *slog.Logger,
 	validate func(Validator) map[string]string,
 	newID func() uuid.UUID,
 	timeNow func() time.Time,
+	strictQueryParams bool,
+	accessLogSkipPaths []string,
+	readiness *health.Checker,
+	reporter ErrorReporter,
+	metrics *HTTPMetrics,
+	verifier *auth.Verifier,
+	limiter RateLimiter,
 ) http.Handler {
-	mux := http.NewServeMux()
-
-	registerRoutes(mux, albumStorage, logger, validate, newID, timeNow)
+	return NewServer(albumStorage, func(cfg *serverConfig) {
+		*cfg = serverConfig{
+			webhookStorage:     webhookStorage,
+			bus:                bus,
+			enricher:           enricher,
+			lookup:             lookup,
+			artwork:            artwork,
+			logger:             logger,
+			validate:           validate,
+			albumRules:         DefaultAlbumRules,
+			newID:              newID,
+			clock:              clock.Func(timeNow),
+			strictQueryParams:  strictQueryParams,
+			accessLogSkipPaths: accessLogSkipPaths,
+			readiness:          readiness,
+			reporter:           reporter,
+			metrics:            metrics,
+			verifier:           verifier,
+			limiter:            limiter,
+		}
+	})
+}
 
+// NewAdminServer returns a new HTTP server that serves the report of readiness
+// at /readyz and the build information at /version, for the endpoints to be
+// served apart from the API, such as on a port not exposed to the clients.
+func NewAdminServer(readiness *health.Checker) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /readyz", readiness.Handler())
+	mux.Handle("GET /version", versionHandler(ReadBuildInfo()))
 	return mux
 }
 
-// registerRoutes registers HTTP handlers to API routes.
+// route describes an API route.
+type route struct {
+	// pattern is the http.ServeMux pattern the route is registered with.
+	pattern string
+	// queryParams are the query parameters accepted by the route.
+	queryParams []string
+	// role is the role required to request the route, if any.
+	role string
+	// handler handles the requests to the route.
+	handler http.Handler
+	// longLived is whether the requests to the route last as long as their
+	// client wants, so they are not timed out.
+	longLived bool
+}
+
+// registerRoutes registers HTTP handlers to API routes, tracing the requests
+// to each route in a span named after its pattern. If strictQueryParams is
+// true, requests with query parameters not accepted by their route are
+// rejected. If metrics is not nil, the latency of the requests to each route
+// is recorded into it. If enforceRoles is true, only the requests
+// authenticated with the role required by their route are served. If limiter
+// is not nil, the requests of each client are rate limited by it. If
+// requestTimeout is greater than zero, the requests not to long-lived routes
+// are timed out after it. The webhook
+// routes are only registered if webhookStorage is not nil, the live updates
+// route only if bus is not nil, the album metadata routes only if enricher is
+// not nil, the release lookup route only if lookup is not nil, the album
+// artwork routes only if artwork is not nil, the album prices routes only if
+// priceStorage is not nil, which also makes the albums listable by currency,
+// and the genre routes only if genreStorage is not nil, which also makes the
+// albums listable by genre.
 func registerRoutes(
 	mux *http.ServeMux,
 	albumStorage AlbumStorage,
+	webhookStorage WebhookStorage,
+	bus *EventBus,
+	enricher *MetadataEnricher,
+	lookup MetadataProvider,
+	artwork *ArtworkFetcher,
+	priceStorage PriceStorage,
+	genreStorage GenreStorage,
 	logger *slog.Logger,
 	validate func(Validator) map[string]string,
+	albumRules AlbumRules,
 	newID func() uuid.UUID,
-	timeNow func() time.Time,
+	clock clock.Clock,
+	strictQueryParams bool,
+	metrics *HTTPMetrics,
+	enforceRoles bool,
+	limiter RateLimiter,
+	requestTimeout time.Duration,
 ) {
-	mux.Handle("POST /albums", createAlbumHandler(albumStorage, logger, validate, newID, timeNow))
-	mux.Handle("GET /albums", listAlbumsHandler(albumStorage, logger))
-	mux.Handle("GET /albums/{album_id}", getAlbumHandler(albumStorage, logger))
-	mux.Handle("PUT /albums/{album_id}", updateAlbumHandler(albumStorage, logger, validate, timeNow))
-	mux.Handle("DELETE /albums/{album_id}", deleteAlbumHandler(albumStorage, logger))
+	listQueryParams := []string{
+		"page_size", "page_number", "fields",
+		"sort", "released_from", "released_until", "original_release_year",
+	}
+	if priceStorage != nil {
+		listQueryParams = append(listQueryParams, "currency")
+	}
+	if genreStorage != nil {
+		listQueryParams = append(listQueryParams, "genre")
+	}
+	routes := []route{
+		{
+			pattern: "POST /albums",
+			role:    auth.RoleEditor,
+			handler: createAlbumHandler(albumStorage, logger, validate, albumRules, newID, clock),
+		},
+		{
+			pattern:     "GET /albums",
+			role:        auth.RoleReader,
+			queryParams: listQueryParams,
+			handler:     listAlbumsHandler(albumStorage, priceStorage, genreStorage, logger),
+		},
+		{
+			pattern:     "GET /albums/suggest",
+			role:        auth.RoleReader,
+			queryParams: []string{"q"},
+			handler:     suggestAlbumsHandler(albumStorage, logger),
+		},
+		{
+			pattern:     "GET /albums/{album_id}",
+			role:        auth.RoleReader,
+			queryParams: []string{"fields"},
+			handler:     getAlbumHandler(albumStorage, logger),
+		},
+		{
+			pattern: "GET /albums/{album_id}/history",
+			role:    auth.RoleReader,
+			handler: albumHistoryHandler(albumStorage, logger),
+		},
+		{
+			pattern:     "PUT /albums/{album_id}",
+			role:        auth.RoleEditor,
+			queryParams: []string{"upsert"},
+			handler:     updateAlbumHandler(albumStorage, logger, validate, albumRules, clock),
+		},
+		{
+			pattern: "DELETE /albums/{album_id}",
+			role:    auth.RoleAdmin,
+			handler: deleteAlbumHandler(albumStorage, logger),
+		},
+		{
+			pattern: "GET /version",
+			handler: versionHandler(ReadBuildInfo()),
+		},
+	}
+	if webhookStorage != nil {
+		routes = append(routes,
+			route{
+				pattern: "POST /webhooks",
+				role:    auth.RoleAdmin,
+				handler: createWebhookHandler(webhookStorage, logger, validate, newID, clock),
+			},
+			route{
+				pattern: "GET /webhooks",
+				role:    auth.RoleAdmin,
+				handler: listWebhooksHandler(webhookStorage, logger),
+			},
+			route{
+				pattern: "GET /webhooks/{webhook_id}",
+				role:    auth.RoleAdmin,
+				handler: getWebhookHandler(webhookStorage, logger),
+			},
+			route{
+				pattern: "PUT /webhooks/{webhook_id}",
+				role:    auth.RoleAdmin,
+				handler: updateWebhookHandler(webhookStorage, logger, validate, clock),
+			},
+			route{
+				pattern: "DELETE /webhooks/{webhook_id}",
+				role:    auth.RoleAdmin,
+				handler: deleteWebhookHandler(webhookStorage, logger),
+			},
+		)
+	}
+	if bus != nil {
+		routes = append(routes, route{
+			pattern:     "GET /ws",
+			role:        auth.RoleReader,
+			queryParams: []string{"artist"},
+			handler:     liveUpdatesHandler(bus, logger),
+			longLived:   true,
+		})
+	}
+	if enricher != nil {
+		routes = append(routes,
+			route{
+				pattern: "POST /albums/{album_id}/enrich",
+				role:    auth.RoleEditor,
+				handler: enrichAlbumHandler(albumStorage, enricher, logger),
+			},
+			route{
+				pattern: "GET /albums/{album_id}/metadata",
+				role:    auth.RoleReader,
+				handler: albumMetadataHandler(albumStorage, enricher, logger),
+			},
+		)
+	}
+	if lookup != nil {
+		routes = append(routes, route{
+			pattern:     "GET /lookup",
+			role:        auth.RoleEditor,
+			queryParams: []string{"artist", "title"},
+			handler:     lookupReleasesHandler(lookup, logger),
+		})
+	}
+	if artwork != nil {
+		routes = append(routes,
+			route{
+				pattern: "POST /albums/{album_id}/artwork",
+				role:    auth.RoleEditor,
+				handler: fetchArtworkHandler(albumStorage, artwork, logger),
+			},
+			route{
+				pattern: "GET /albums/{album_id}/artwork",
+				role:    auth.RoleReader,
+				handler: artworkHandler(albumStorage, artwork, logger),
+			},
+		)
+	}
+	if priceStorage != nil {
+		routes = append(routes,
+			route{
+				pattern: "GET /albums/{album_id}/prices",
+				role:    auth.RoleReader,
+				handler: albumPricesHandler(albumStorage, priceStorage, logger),
+			},
+			route{
+				pattern: "PUT /albums/{album_id}/prices",
+				role:    auth.RoleEditor,
+				handler: setAlbumPricesHandler(albumStorage, priceStorage, logger, validate, albumRules),
+			},
+		)
+	}
+	if genreStorage != nil {
+		routes = append(routes,
+			route{
+				pattern: "POST /genres",
+				role:    auth.RoleEditor,
+				handler: createGenreHandler(genreStorage, logger, validate, clock),
+			},
+			route{
+				pattern: "GET /genres",
+				role:    auth.RoleReader,
+				handler: listGenresHandler(genreStorage, logger),
+			},
+			route{
+				pattern: "DELETE /genres/{genre_slug}",
+				role:    auth.RoleAdmin,
+				handler: deleteGenreHandler(genreStorage, logger),
+			},
+			route{
+				pattern: "GET /albums/{album_id}/genres",
+				role:    auth.RoleReader,
+				handler: albumGenresHandler(albumStorage, genreStorage, logger),
+			},
+			route{
+				pattern: "PUT /albums/{album_id}/genres",
+				role:    auth.RoleEditor,
+				handler: setAlbumGenresHandler(genreStorage, logger, validate),
+			},
+		)
+	}
+	for _, rt := range routes {
+		handler := rt.handler
+		if requestTimeout > 0 && !rt.longLived {
+			handler = timeoutRequests(requestTimeout, handler)
+		}
+		if strictQueryParams {
+			handler = rejectUnknownQueryParams(rt.queryParams, handler)
+		}
+		if enforceRoles && rt.role != "" {
+			handler = auth.RequireRole(rt.role, handler)
+		}
+		if limiter != nil {
+			handler = limitRate(limiter, logger, handler)
+		}
+		if metrics != nil {
+			handler = observeLatency(metrics, rt.pattern, handler)
+		}
+		mux.Handle(rt.pattern, otelhttp.NewHandler(handler, rt.pattern))
+	}
 }
@@ -0,0 +1,80 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+func TestNewServer_options(t *testing.T) {
+	id := uuid.New()
+	now := time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC)
+	var inserted Album
+	spy := &storageSpy{
+		insert: func(ctx context.Context, alb Album) error {
+			inserted = alb
+			return nil
+		},
+	}
+	var calls []string
+	middleware := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := NewServer(spy,
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithIDGenerator(func() uuid.UUID { return id }),
+		WithClock(clock.NewFake(now)),
+		WithMiddleware(middleware("first"), middleware("second")),
+		WithMiddleware(middleware("third")),
+	)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(`{"title": "Nevermind", "artist": "Nirvana", "price": 2999}`))
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, id, inserted.ID)
+	assert.Equal(t, now, inserted.CreatedAt)
+	assert.Equal(t, []string{"first", "second", "third"}, calls)
+}
+
+func TestNewServer_strictQueryParams(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for name, test := range map[string]struct {
+		opts           []ServerOption
+		statusCodeWant int
+	}{
+		"lenient by default": {
+			statusCodeWant: http.StatusOK,
+		},
+		"strict": {
+			opts:           []ServerOption{WithStrictQueryParams()},
+			statusCodeWant: http.StatusBadRequest,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := NewServer(nil, append(test.opts, WithLogger(logger))...)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/version?verbose=true", nil)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Code)
+		})
+	}
+}
@@ -2,8 +2,14 @@ package catalog
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 )
 
 // decode decodes a T from r.
@@ -25,26 +31,104 @@ func encode[T any](w http.ResponseWriter, statusCode int, v T) error {
 	return nil
 }
 
-// encodeMessage write an HTTP response with the statusCode as its status code
-// and writes msg into its body.
-func encodeMessage(w http.ResponseWriter, statusCode int, msg string) error {
-	data := struct {
-		Message string `json:"message"`
-	}{
-		Message: msg,
+// errStreamInterrupted is returned by encodeSeq when its sequence yields an
+// error after the response status code was written.
+var errStreamInterrupted = errors.New("stream interrupted")
+
+// encodeSeq setup w and writes the values yielded by seq into its body as a JSON
+// array, as they are yielded. If seq yields an error before any value, nothing
+// is written into w and the error is returned, so the caller can still respond
+// with an error status code. If seq yields an error afterwards, the error is
+// returned wrapping errStreamInterrupted.
+func encodeSeq[T any](w http.ResponseWriter, statusCode int, seq iter.Seq2[T, error]) error {
+	next, stop := iter.Pull2(seq)
+	defer stop()
+	v, err, ok := next()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	enc := json.NewEncoder(w)
+	io.WriteString(w, "[")
+	for i := 0; ok; i++ {
+		if err != nil {
+			return fmt.Errorf("%w: %w", errStreamInterrupted, err)
+		}
+		if i > 0 {
+			io.WriteString(w, ",")
+		}
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("%w: encoding json: %w", errStreamInterrupted, err)
+		}
+		v, err, ok = next()
+	}
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// encodeMessage responds to r with statusCode as its status code and msg as
+// its error message, encoded by the ErrorEncoder of the request.
+func encodeMessage(w http.ResponseWriter, r *http.Request, statusCode int, msg string) error {
+	return errorEncoderOf(r).EncodeError(w, r, ErrorResponse{StatusCode: statusCode, Message: msg})
+}
+
+// encodeProblems responds to r with statusCode as its status code, msg as its
+// error message and problems as the problems of its fields, encoded by the
+// ErrorEncoder of the request.
+func encodeProblems(w http.ResponseWriter, r *http.Request, statusCode int, msg string, problems map[string]string) error {
+	return errorEncoderOf(r).EncodeError(w, r, ErrorResponse{StatusCode: statusCode, Message: msg, Problems: problems})
+}
+
+// parseFields extracts the comma separated list of fields from the fields
+// query parameter in q. It returns nil if the parameter is absent and an error
+// if any of the fields is not in accepted.
+func parseFields(q url.Values, accepted []string) ([]string, error) {
+	if !q.Has("fields") {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(q.Get("fields"), ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(accepted, field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// project returns the JSON object representation of v restricted to fields.
+// If fields is nil, v is returned as is.
+func project[T any](v T, fields []string) (any, error) {
+	if fields == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encoding json: %w", err)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("decoding json: %w", err)
+	}
+	projection := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		projection[field] = obj[field]
 	}
-	return encode(w, statusCode, data)
+	return projection, nil
 }
 
-// encodeproblems write an HTTP response with the statusCode as its status code
-// and writes msg and problems into its body.
-func encodeProblems(w http.ResponseWriter, statusCode int, msg string, problems map[string]string) error {
-	data := struct {
-		Message  string            `json:"message"`
-		Problems map[string]string `json:"problems"`
-	}{
-		Message:  msg,
-		Problems: problems,
+// projectAll returns the JSON object representations of vs restricted to
+// fields.
+func projectAll[T any](vs []T, fields []string) ([]any, error) {
+	projections := make([]any, len(vs))
+	for i, v := range vs {
+		projection, err := project(v, fields)
+		if err != nil {
+			return nil, err
+		}
+		projections[i] = projection
 	}
-	return encode(w, statusCode, data)
+	return projections, nil
 }
@@ -0,0 +1,191 @@
+package catalog
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/validation"
+)
+
+// webhookRequest is the request to create or update a webhook subscription.
+type webhookRequest struct {
+	URL        string        `json:"url" validate:"http_url"`
+	EventTypes []events.Type `json:"event_types" validate:"required"`
+	// Secret is at least 16 characters long, so that the signatures of the
+	// webhook cannot be forged by brute force.
+	Secret string `json:"secret" validate:"min=16"`
+}
+
+// webhookEventTypes are the types of the events webhooks can subscribe to.
+var webhookEventTypes = []events.Type{
+	events.TypeAlbumCreated,
+	events.TypeAlbumUpdated,
+	events.TypeAlbumDeleted,
+}
+
+// Valid makes webhookRequest implement Validator.
+func (req webhookRequest) Valid() map[string]string {
+	problems := validation.Struct(req)
+	for _, t := range req.EventTypes {
+		if !slices.Contains(webhookEventTypes, t) {
+			problems["event_types"] = "contains an unknown event type"
+			break
+		}
+	}
+	return problems
+}
+
+// createWebhookHandler returns an http.Handler to requests to create a webhook
+// subscription.
+func createWebhookHandler(
+	webhookStorage WebhookStorage,
+	logger *slog.Logger,
+	validate func(Validator) map[string]string,
+	newID func() uuid.UUID,
+	clock clock.Clock,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract subscription data from the request.
+		req, err := decode[webhookRequest](r)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed request body")
+			return
+		}
+		if problems := validate(req); len(problems) > 0 {
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", problems)
+			return
+		}
+		// Create a new subscription and insert into the storage.
+		now := clock.Now().UTC()
+		sub := WebhookSubscription{
+			ID:         newID(),
+			URL:        req.URL,
+			EventTypes: req.EventTypes,
+			Secret:     req.Secret,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			TenantID:   TenantFromContext(r.Context()),
+		}
+		if err := webhookStorage.Insert(r.Context(), sub); err != nil {
+			respondInternalError(w, r, logger, "inserting webhook subscription into the storage", err)
+			return
+		}
+		// Respond with the new subscription.
+		encode(w, http.StatusCreated, sub)
+	})
+}
+
+// listWebhooksHandler returns an http.Handler to requests to list the webhook
+// subscriptions.
+func listWebhooksHandler(webhookStorage WebhookStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subs, err := webhookStorage.FindAll(r.Context())
+		if err != nil {
+			respondInternalError(w, r, logger, "finding webhook subscriptions in the storage", err)
+			return
+		}
+		if subs == nil {
+			subs = []WebhookSubscription{}
+		}
+		encode(w, http.StatusOK, subs)
+	})
+}
+
+// getWebhookHandler returns an http.Handler to requests to find a webhook
+// subscription.
+func getWebhookHandler(webhookStorage WebhookStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract subscription id from the request.
+		subID, err := uuid.Parse(r.PathValue("webhook_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed webhook id")
+			return
+		}
+		// Find subscription in the storage.
+		sub, err := webhookStorage.FindOne(r.Context(), subID)
+		if errors.Is(err, ErrWebhookNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "webhook not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "finding webhook subscription in the storage", err)
+			return
+		}
+		encode(w, http.StatusOK, sub)
+	})
+}
+
+// updateWebhookHandler returns an http.Handler to requests to update a webhook
+// subscription.
+func updateWebhookHandler(
+	webhookStorage WebhookStorage,
+	logger *slog.Logger,
+	validate func(Validator) map[string]string,
+	clock clock.Clock,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract subscription id from the request.
+		subID, err := uuid.Parse(r.PathValue("webhook_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed webhook id")
+			return
+		}
+		// Extract updated subscription data from the request.
+		req, err := decode[webhookRequest](r)
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed request body")
+			return
+		}
+		if problems := validate(req); len(problems) > 0 {
+			encodeProblems(w, r, http.StatusBadRequest, "invalid request body", problems)
+			return
+		}
+		// Update subscription in the storage.
+		sub, err := webhookStorage.Update(r.Context(), WebhookSubscription{
+			ID:         subID,
+			URL:        req.URL,
+			EventTypes: req.EventTypes,
+			Secret:     req.Secret,
+			UpdatedAt:  clock.Now().UTC(),
+		})
+		if errors.Is(err, ErrWebhookNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "webhook not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "updating webhook subscription in the storage", err)
+			return
+		}
+		encode(w, http.StatusOK, sub)
+	})
+}
+
+// deleteWebhookHandler returns an http.Handler to requests to delete a webhook
+// subscription.
+func deleteWebhookHandler(webhookStorage WebhookStorage, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Extract subscription id from the request.
+		subID, err := uuid.Parse(r.PathValue("webhook_id"))
+		if err != nil {
+			encodeMessage(w, r, http.StatusBadRequest, "malformed webhook id")
+			return
+		}
+		// Remove subscription from the storage.
+		err = webhookStorage.Remove(r.Context(), subID)
+		if errors.Is(err, ErrWebhookNotFound) {
+			encodeMessage(w, r, http.StatusNotFound, "webhook not found")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, r, logger, "removing webhook subscription from the storage", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,437 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+func TestWebhookRequest(t *testing.T) {
+	tests := map[string]struct {
+		req          webhookRequest
+		problemsWant map[string]string
+	}{
+		"empty": {
+			problemsWant: map[string]string{
+				"url":         "is not an absolute http or https url",
+				"event_types": "is empty",
+				"secret":      "is shorter than 16 characters",
+			},
+		},
+		"relative url and unknown event type": {
+			req: webhookRequest{
+				URL:        "/hooks",
+				EventTypes: []events.Type{events.TypeAlbumCreated, "album.sold"},
+				Secret:     "0123456789abcdef",
+			},
+			problemsWant: map[string]string{
+				"url":         "is not an absolute http or https url",
+				"event_types": "contains an unknown event type",
+			},
+		},
+		"valid": {
+			req: webhookRequest{
+				URL:        "https://example.com/hooks",
+				EventTypes: []events.Type{events.TypeAlbumCreated, events.TypeAlbumDeleted},
+				Secret:     "0123456789abcdef",
+			},
+			problemsWant: map[string]string{},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.problemsWant, test.req.Valid())
+		})
+	}
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	type testCase struct {
+		requestBody      string
+		validateProblems map[string]string
+		insertErr        error
+		statusCodeWant   int
+		responseBodyWant string
+		logSubstrsWant   []string
+	}
+	newID := uuid.New()
+	now := time.Date(2024, 8, 28, 12, 0, 0, 0, time.UTC)
+	tests := map[string]testCase{
+		"malformed request body": {
+			requestBody: "", // malformed request body
+
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed request body"}`,
+		},
+		"invalid request body": {
+			requestBody:      "{}",
+			validateProblems: map[string]string{"url": "is not an absolute http or https url"},
+
+			statusCodeWant: http.StatusBadRequest,
+			responseBodyWant: `
+				{
+					"message": "invalid request body",
+					"problems": {"url": "is not an absolute http or https url"}
+				}`,
+		},
+		"unexpected insert error": {
+			requestBody: "{}",
+			insertErr:   fmt.Errorf("unexpected insert error"),
+
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+			logSubstrsWant: []string{
+				`level=ERROR`,
+				`msg="inserting webhook subscription into the storage"`,
+				`error="unexpected insert error"`,
+			},
+		},
+		"happy path": {
+			requestBody: `
+				{
+					"url":         "https://example.com/hooks",
+					"event_types": ["album.created"],
+					"secret":      "0123456789abcdef"
+				}`,
+
+			statusCodeWant: http.StatusCreated,
+			responseBodyWant: `
+				{
+					"id":          "` + newID.String() + `",
+					"url":         "https://example.com/hooks",
+					"event_types": ["album.created"],
+					"created_at":  "2024-08-28T12:00:00Z",
+					"updated_at":  "2024-08-28T12:00:00Z"
+				}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &webhookStorageSpy{}
+			var inserted WebhookSubscription
+			storage.insert = func(ctx context.Context, sub WebhookSubscription) error {
+				inserted = sub
+				return test.insertErr
+			}
+			logsBuf := bytes.NewBuffer(nil)
+			logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+			handler := createWebhookHandler(
+				storage,
+				logger,
+				func(Validator) map[string]string { return test.validateProblems },
+				func() uuid.UUID { return newID },
+				clock.NewFake(now),
+			)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", "/", strings.NewReader(test.requestBody))
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			if test.statusCodeWant == http.StatusCreated {
+				assert.Equal(t, "0123456789abcdef", inserted.Secret)
+			}
+			logs := logsBuf.String()
+			for _, substr := range test.logSubstrsWant {
+				assert.Contains(t, logs, substr)
+			}
+		})
+	}
+}
+
+func TestListWebhooksHandler(t *testing.T) {
+	sub := WebhookSubscription{
+		ID:         uuid.New(),
+		URL:        "https://example.com/hooks",
+		EventTypes: []events.Type{events.TypeAlbumUpdated},
+		Secret:     "0123456789abcdef",
+		CreatedAt:  time.Date(2024, 8, 28, 12, 0, 0, 0, time.UTC),
+		UpdatedAt:  time.Date(2024, 8, 28, 12, 0, 0, 0, time.UTC),
+	}
+	tests := map[string]struct {
+		subs             []WebhookSubscription
+		findAllErr       error
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"no subscriptions": {
+			statusCodeWant:   http.StatusOK,
+			responseBodyWant: `[]`,
+		},
+		"unexpected find error": {
+			findAllErr:       fmt.Errorf("unexpected find error"),
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+		"happy path": {
+			subs:           []WebhookSubscription{sub},
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `
+				[{
+					"id":          "` + sub.ID.String() + `",
+					"url":         "https://example.com/hooks",
+					"event_types": ["album.updated"],
+					"created_at":  "2024-08-28T12:00:00Z",
+					"updated_at":  "2024-08-28T12:00:00Z"
+				}]`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &webhookStorageSpy{}
+			storage.findAll = func(ctx context.Context) ([]WebhookSubscription, error) {
+				return test.subs, test.findAllErr
+			}
+			logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", "/", nil)
+
+			listWebhooksHandler(storage, logger).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
+
+func TestGetWebhookHandler(t *testing.T) {
+	tests := map[string]struct {
+		webhookID        string
+		findOneErr       error
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"malformed webhook id": {
+			webhookID:        "",
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed webhook id"}`,
+		},
+		"webhook not found": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			findOneErr:       ErrWebhookNotFound,
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "webhook not found"}`,
+		},
+		"unexpected find error": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			findOneErr:       fmt.Errorf("unexpected find error"),
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+		"happy path": {
+			webhookID:      "00000000-0000-0000-0000-000000000000",
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `
+				{
+					"id":          "00000000-0000-0000-0000-000000000000",
+					"url":         "https://example.com/hooks",
+					"event_types": ["album.deleted"],
+					"created_at":  "0001-01-01T00:00:00Z",
+					"updated_at":  "0001-01-01T00:00:00Z"
+				}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &webhookStorageSpy{}
+			storage.findOne = func(ctx context.Context, id uuid.UUID) (WebhookSubscription, error) {
+				return WebhookSubscription{
+					ID:         id,
+					URL:        "https://example.com/hooks",
+					EventTypes: []events.Type{events.TypeAlbumDeleted},
+				}, test.findOneErr
+			}
+			logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", "/", nil)
+			req.SetPathValue("webhook_id", test.webhookID)
+
+			getWebhookHandler(storage, logger).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
+
+func TestUpdateWebhookHandler(t *testing.T) {
+	now := time.Date(2024, 8, 28, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		webhookID        string
+		requestBody      string
+		validateProblems map[string]string
+		updateErr        error
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"malformed webhook id": {
+			webhookID:        "",
+			requestBody:      "{}",
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed webhook id"}`,
+		},
+		"malformed request body": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			requestBody:      "",
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed request body"}`,
+		},
+		"invalid request body": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			requestBody:      "{}",
+			validateProblems: map[string]string{"secret": "is shorter than 16 characters"},
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `
+				{
+					"message": "invalid request body",
+					"problems": {"secret": "is shorter than 16 characters"}
+				}`,
+		},
+		"webhook not found": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			requestBody:      "{}",
+			updateErr:        ErrWebhookNotFound,
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "webhook not found"}`,
+		},
+		"unexpected update error": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			requestBody:      "{}",
+			updateErr:        fmt.Errorf("unexpected update error"),
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+		"happy path": {
+			webhookID: "00000000-0000-0000-0000-000000000000",
+			requestBody: `
+				{
+					"url":         "https://example.com/new-hooks",
+					"event_types": ["album.created", "album.updated"],
+					"secret":      "fedcba9876543210"
+				}`,
+			statusCodeWant: http.StatusOK,
+			responseBodyWant: `
+				{
+					"id":          "00000000-0000-0000-0000-000000000000",
+					"url":         "https://example.com/new-hooks",
+					"event_types": ["album.created", "album.updated"],
+					"created_at":  "0001-01-01T00:00:00Z",
+					"updated_at":  "2024-08-28T12:00:00Z"
+				}`,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &webhookStorageSpy{}
+			storage.update = func(ctx context.Context, sub WebhookSubscription) (WebhookSubscription, error) {
+				return sub, test.updateErr
+			}
+			logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+			handler := updateWebhookHandler(
+				storage,
+				logger,
+				func(Validator) map[string]string { return test.validateProblems },
+				clock.NewFake(now),
+			)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", "/", strings.NewReader(test.requestBody))
+			req.SetPathValue("webhook_id", test.webhookID)
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+		})
+	}
+}
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	tests := map[string]struct {
+		webhookID        string
+		removeErr        error
+		statusCodeWant   int
+		responseBodyWant string
+	}{
+		"malformed webhook id": {
+			webhookID:        "",
+			statusCodeWant:   http.StatusBadRequest,
+			responseBodyWant: `{"message": "malformed webhook id"}`,
+		},
+		"webhook not found": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			removeErr:        ErrWebhookNotFound,
+			statusCodeWant:   http.StatusNotFound,
+			responseBodyWant: `{"message": "webhook not found"}`,
+		},
+		"unexpected remove error": {
+			webhookID:        "00000000-0000-0000-0000-000000000000",
+			removeErr:        fmt.Errorf("unexpected remove error"),
+			statusCodeWant:   http.StatusInternalServerError,
+			responseBodyWant: `{"message": "internal error"}`,
+		},
+		"happy path": {
+			webhookID:      "00000000-0000-0000-0000-000000000000",
+			statusCodeWant: http.StatusNoContent,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage := &webhookStorageSpy{}
+			storage.remove = func(ctx context.Context, id uuid.UUID) error {
+				return test.removeErr
+			}
+			logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("", "/", nil)
+			req.SetPathValue("webhook_id", test.webhookID)
+
+			deleteWebhookHandler(storage, logger).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			if test.responseBodyWant != "" {
+				assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			}
+		})
+	}
+}
+
+type webhookStorageSpy struct {
+	insert  func(ctx context.Context, sub WebhookSubscription) error
+	findAll func(ctx context.Context) ([]WebhookSubscription, error)
+	findOne func(ctx context.Context, id uuid.UUID) (WebhookSubscription, error)
+	update  func(ctx context.Context, sub WebhookSubscription) (WebhookSubscription, error)
+	remove  func(ctx context.Context, id uuid.UUID) error
+}
+
+func (spy *webhookStorageSpy) Insert(ctx context.Context, sub WebhookSubscription) error {
+	return spy.insert(ctx, sub)
+}
+
+func (spy *webhookStorageSpy) FindAll(ctx context.Context) ([]WebhookSubscription, error) {
+	return spy.findAll(ctx)
+}
+
+func (spy *webhookStorageSpy) FindOne(ctx context.Context, id uuid.UUID) (WebhookSubscription, error) {
+	return spy.findOne(ctx, id)
+}
+
+func (spy *webhookStorageSpy) Update(ctx context.Context, sub WebhookSubscription) (WebhookSubscription, error) {
+	return spy.update(ctx, sub)
+}
+
+func (spy *webhookStorageSpy) Remove(ctx context.Context, id uuid.UUID) error {
+	return spy.remove(ctx, id)
+}
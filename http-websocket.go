@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+// liveUpdatesPingInterval is how often the live update connections are pinged
+// to keep them alive through proxies and to detect dead clients.
+const liveUpdatesPingInterval = 30 * time.Second
+
+// liveUpdatesWriteTimeout is how long writing an event or a ping to a live
+// update connection may take before the client is considered dead.
+const liveUpdatesWriteTimeout = 10 * time.Second
+
+// liveUpdatesHandler returns an http.Handler to requests to receive the album
+// change events published to bus, as they are published, over a WebSocket.
+// Each event is sent as a JSON text message. Only the events of the albums of
+// the tenant of the request are sent and, if the request has artist query
+// parameters, only the ones of the albums of those artists, ignoring case.
+// Clients too slow to receive the events are disconnected.
+func liveUpdatesHandler(bus *EventBus, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := TenantFromContext(r.Context())
+		artists := r.URL.Query()["artist"]
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			// Accept has already responded to the request.
+			logger.DebugContext(r.Context(), "accepting websocket", "error", err)
+			return
+		}
+		defer conn.CloseNow()
+		envs, unsubscribe := bus.Subscribe()
+		defer unsubscribe()
+		// Clients are not expected to send messages, so reading only handles
+		// the control frames, canceling ctx once the connection is closed.
+		ctx := conn.CloseRead(r.Context())
+		ping := time.NewTicker(liveUpdatesPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case env, ok := <-envs:
+				if !ok {
+					conn.Close(websocket.StatusTryAgainLater, "too slow to receive the events")
+					return
+				}
+				if !liveUpdateMatches(env, tenant, artists) {
+					continue
+				}
+				if err := writeLiveUpdate(ctx, conn, env); err != nil {
+					return
+				}
+			case <-ping.C:
+				pingCtx, cancel := context.WithTimeout(ctx, liveUpdatesWriteTimeout)
+				err := conn.Ping(pingCtx)
+				cancel()
+				if err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// writeLiveUpdate writes env to conn as JSON.
+func writeLiveUpdate(ctx context.Context, conn *websocket.Conn, env events.Envelope) error {
+	ctx, cancel := context.WithTimeout(ctx, liveUpdatesWriteTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, conn, env)
+}
+
+// liveUpdateMatches reports whether env is an event of an album of tenant and,
+// if artists is not empty, of one of artists, ignoring case.
+func liveUpdateMatches(env events.Envelope, tenant string, artists []string) bool {
+	alb, err := envelopeAlbum(env)
+	if err != nil || alb.TenantID != tenant {
+		return false
+	}
+	if len(artists) == 0 {
+		return true
+	}
+	for _, artist := range artists {
+		if strings.EqualFold(alb.Artist, artist) {
+			return true
+		}
+	}
+	return false
+}
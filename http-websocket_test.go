@@ -0,0 +1,62 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+func TestLiveUpdatesHandler(t *testing.T) {
+	bus := NewEventBus()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := httptest.NewServer(NewServer(nil, WithLiveUpdates(bus), WithLogger(logger)))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?artist=judgement"
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	require.NoError(t, err)
+	defer conn.CloseNow()
+	// Wait for the handler to subscribe to the bus.
+	require.Eventually(t, func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.subs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	otherArtist := albumCreatedEnvelope(t, events.Album{Artist: "Black Alien"})
+	otherTenant := albumCreatedEnvelope(t, events.Album{Artist: "Judgement", TenantID: "acme"})
+	matching := albumCreatedEnvelope(t, events.Album{Artist: "JUDGEMENT"})
+	for _, env := range []events.Envelope{otherArtist, otherTenant, matching} {
+		require.NoError(t, bus.Publish(ctx, env))
+	}
+
+	var received events.Envelope
+	err = wsjson.Read(ctx, conn, &received)
+
+	assert.Nil(t, err)
+	assert.Equal(t, matching.ID, received.ID)
+	assert.JSONEq(t, string(matching.Data), string(received.Data))
+}
+
+// albumCreatedEnvelope returns the envelope of the creation of alb.
+func albumCreatedEnvelope(t *testing.T, alb events.Album) events.Envelope {
+	t.Helper()
+
+	alb.ID = uuid.New()
+	env, err := events.Wrap(uuid.New(), time.Now().UTC(), events.AlbumCreated{Album: alb})
+	require.NoError(t, err)
+	return env
+}
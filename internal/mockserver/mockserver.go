@@ -0,0 +1,192 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// document is the subset of an OpenAPI Specification document needed to mock
+// its operations.
+type document struct {
+	Paths      map[string]map[string]operation `yaml:"paths"`
+	Components struct {
+		Schemas map[string]*schema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type operation struct {
+	Responses map[string]response `yaml:"responses"`
+}
+
+type response struct {
+	Content map[string]struct {
+		Schema *schema `yaml:"schema"`
+	} `yaml:"content"`
+}
+
+type schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       string             `yaml:"type"`
+	Properties map[string]*schema `yaml:"properties"`
+	Items      *schema            `yaml:"items"`
+	OneOf      []*schema          `yaml:"oneOf"`
+	AllOf      []*schema          `yaml:"allOf"`
+	Example    any                `yaml:"example"`
+}
+
+// preferCodeRegexp matches the Prefer header used to choose the status code of
+// a mocked response, e.g. "Prefer: code=404".
+var preferCodeRegexp = regexp.MustCompile(`\bcode=(\d{3})\b`)
+
+// New returns an http.Handler that responds to each operation in the OpenAPI
+// Specification document spec with the example of its lowest 2xx response,
+// after waiting for latency. Clients can choose another documented response by
+// sending the "Prefer: code=<status code>" header.
+func New(spec []byte, latency time.Duration) (http.Handler, error) {
+	var doc document
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("decoding yaml: %w", err)
+	}
+	mux := http.NewServeMux()
+	for path, operations := range doc.Paths {
+		for method, op := range operations {
+			if len(op.Responses) == 0 {
+				continue
+			}
+			examples, err := responseExamples(doc, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			pattern := strings.ToUpper(method) + " " + path
+			mux.Handle(pattern, mockHandler(examples, latency))
+		}
+	}
+	return mux, nil
+}
+
+// responseExamples returns the example body of each response of op by its
+// status code.
+func responseExamples(doc document, op operation) (map[int]any, error) {
+	examples := make(map[int]any, len(op.Responses))
+	for code, resp := range op.Responses {
+		statusCode, err := strconv.Atoi(code)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", code)
+		}
+		media, ok := resp.Content["application/json"]
+		if !ok {
+			examples[statusCode] = nil
+			continue
+		}
+		example, err := exampleOf(doc, media.Schema)
+		if err != nil {
+			return nil, err
+		}
+		examples[statusCode] = example
+	}
+	return examples, nil
+}
+
+// exampleOf builds an example value of s from the examples of its properties.
+func exampleOf(doc document, s *schema) (any, error) {
+	switch {
+	case s == nil:
+		return nil, nil
+	case s.Ref != "":
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		ref, ok := doc.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema reference %q", s.Ref)
+		}
+		return exampleOf(doc, ref)
+	case s.Example != nil:
+		return s.Example, nil
+	case len(s.OneOf) > 0:
+		return exampleOf(doc, s.OneOf[0])
+	case len(s.AllOf) > 0:
+		// Merge the examples of the object schemas.
+		obj := make(map[string]any)
+		for _, sub := range s.AllOf {
+			example, err := exampleOf(doc, sub)
+			if err != nil {
+				return nil, err
+			}
+			if len(s.AllOf) == 1 {
+				return example, nil
+			}
+			fields, _ := example.(map[string]any)
+			for name, value := range fields {
+				obj[name] = value
+			}
+		}
+		return obj, nil
+	case s.Type == "array":
+		item, err := exampleOf(doc, s.Items)
+		if err != nil {
+			return nil, err
+		}
+		return []any{item}, nil
+	case s.Type == "object":
+		obj := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			example, err := exampleOf(doc, prop)
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = example
+		}
+		return obj, nil
+	}
+	return nil, nil
+}
+
+// mockHandler returns an http.Handler that responds with one of examples.
+func mockHandler(examples map[int]any, latency time.Duration) http.Handler {
+	codes := make([]int, 0, len(examples))
+	for code := range examples {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	defaultCode := codes[0]
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			defaultCode = code
+			break
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+		statusCode := defaultCode
+		if m := preferCodeRegexp.FindStringSubmatch(r.Header.Get("Prefer")); m != nil {
+			statusCode, _ = strconv.Atoi(m[1])
+		}
+		example, ok := examples[statusCode]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusNotImplemented)
+			json.NewEncoder(w).Encode(map[string]string{
+				"message": fmt.Sprintf("no mocked response with status code %d", statusCode),
+			})
+			return
+		}
+		if example == nil {
+			w.WriteHeader(statusCode)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(example)
+	})
+}
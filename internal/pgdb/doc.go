@@ -0,0 +1,5 @@
+// Package pgdb provides the type-safe Postgres queries used by the album
+// storage, generated by sqlc from queries.sql and the migrations schema.
+package pgdb
+
+//go:generate go run github.com/sqlc-dev/sqlc/cmd/sqlc@v1.26.0 generate
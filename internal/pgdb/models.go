@@ -0,0 +1,96 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+
+package pgdb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Album struct {
+	ID                  uuid.UUID
+	Title               string
+	Artist              string
+	Price               int64
+	Currency            string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Version             int32
+	TenantID            string
+	CreatedBy           string
+	UpdatedBy           string
+	Artwork             string
+	ReleaseDate         sql.NullTime
+	OriginalReleaseYear sql.NullInt32
+}
+
+type AlbumAudit struct {
+	ID        int64
+	AlbumID   uuid.UUID
+	Actor     sql.NullString
+	Action    string
+	Before    json.RawMessage
+	After     json.RawMessage
+	CreatedAt time.Time
+}
+
+type AlbumGenre struct {
+	AlbumID   uuid.UUID
+	TenantID  string
+	GenreSlug string
+}
+
+type AlbumMetadata struct {
+	AlbumID     uuid.UUID
+	Source      string
+	ExternalID  string
+	Year        sql.NullInt32
+	Genres      []string
+	CoverArtUrl string
+	EnrichedAt  time.Time
+}
+
+type AlbumOutbox struct {
+	AuditID int64
+	EventID uuid.UUID
+}
+
+type AlbumPrice struct {
+	AlbumID  uuid.UUID
+	Currency string
+	Amount   int64
+}
+
+type Genre struct {
+	TenantID  string
+	Slug      string
+	Name      string
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	Payload        json.RawMessage
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastError      sql.NullString
+	DeliveredAt    sql.NullTime
+	DeadAt         sql.NullTime
+}
+
+type WebhookSubscription struct {
+	ID         uuid.UUID
+	TenantID   string
+	Url        string
+	EventTypes []string
+	Secret     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
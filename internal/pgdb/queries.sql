@@ -0,0 +1,327 @@
+-- name: InsertAlbum :exec
+INSERT INTO
+	album (id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);
+
+-- name: FindAlbums :many
+-- The albums are sorted by title, unless sort is release_date or
+-- -release_date, which sort them by release date, oldest or newest first, and
+-- then by title. The albums with no release date are sorted last.
+SELECT
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+FROM
+	album
+WHERE
+	tenant_id = sqlc.arg(tenant_id) AND
+	(sqlc.narg(released_from)::date IS NULL OR release_date >= sqlc.narg(released_from)::date) AND
+	(sqlc.narg(released_until)::date IS NULL OR release_date <= sqlc.narg(released_until)::date) AND
+	(sqlc.narg(original_release_year)::integer IS NULL OR original_release_year = sqlc.narg(original_release_year)::integer)
+ORDER BY
+	CASE WHEN sqlc.arg(sort)::text = 'release_date' THEN release_date END ASC NULLS LAST,
+	CASE WHEN sqlc.arg(sort)::text = '-release_date' THEN release_date END DESC NULLS LAST,
+	lower(title) ASC, id ASC
+OFFSET
+	sqlc.arg(page_offset)
+LIMIT
+	sqlc.arg(page_limit);
+
+-- name: FindAlbum :one
+SELECT
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+FROM
+	album
+WHERE
+	id = $1 AND tenant_id = $2;
+
+-- name: SuggestAlbums :many
+SELECT
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+FROM
+	album
+WHERE
+	tenant_id = sqlc.arg(tenant_id) AND
+	(title ILIKE sqlc.arg(pattern)::text || '%' OR artist ILIKE sqlc.arg(pattern)::text || '%')
+ORDER BY
+	greatest(similarity(title, sqlc.arg(prefix)::text), similarity(artist, sqlc.arg(prefix)::text)) DESC,
+	lower(title) ASC, id ASC
+LIMIT
+	sqlc.arg(max_albums);
+
+-- name: UpdateAlbum :execrows
+UPDATE
+	album
+SET
+	title = $1,
+	artist = $2,
+	price = $3,
+	currency = $4,
+	created_at = $5,
+	updated_at = $6,
+	updated_by = $7,
+	artwork = $8,
+	release_date = $9,
+	original_release_year = $10,
+	version = version + 1
+WHERE
+	id = $11 AND version = $12 AND tenant_id = $13;
+
+-- name: AlbumExists :one
+SELECT EXISTS (SELECT 1 FROM album WHERE id = $1 AND tenant_id = $2);
+
+-- name: UpsertAlbum :one
+-- The album is not updated if it belongs to another tenant, returning no row.
+-- The artwork of a replaced album is kept.
+INSERT INTO
+	album (id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (id) DO UPDATE SET
+	title = EXCLUDED.title,
+	artist = EXCLUDED.artist,
+	price = EXCLUDED.price,
+	currency = EXCLUDED.currency,
+	release_date = EXCLUDED.release_date,
+	original_release_year = EXCLUDED.original_release_year,
+	updated_at = EXCLUDED.updated_at,
+	updated_by = EXCLUDED.updated_by,
+	version = album.version + 1
+WHERE
+	album.tenant_id = EXCLUDED.tenant_id
+RETURNING
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year, (xmax = 0)::boolean AS created;
+
+-- name: RemoveAlbum :execrows
+DELETE FROM
+	album
+WHERE
+	id = $1 AND tenant_id = $2;
+
+-- name: RemoveAlbumReturning :one
+DELETE FROM
+	album
+WHERE
+	id = $1 AND tenant_id = $2
+RETURNING
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year;
+
+-- name: SetActor :exec
+-- The actor is recorded into the history of the album changes of the
+-- transaction.
+SELECT set_config('catalog.actor', sqlc.arg(actor)::text, true);
+
+-- name: FindAlbumHistory :many
+-- The changes recorded before albums had a tenant belong to the default one.
+SELECT
+	action, actor, before, after, created_at
+FROM
+	album_audit
+WHERE
+	album_id = sqlc.arg(album_id) AND
+	coalesce(coalesce(after, before) ->> 'tenant_id', '') = sqlc.arg(tenant_id)::text
+ORDER BY
+	id ASC;
+
+-- name: InsertWebhookSubscription :exec
+INSERT INTO
+	webhook_subscription (id, tenant_id, url, event_types, secret, created_at, updated_at)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7);
+
+-- name: FindWebhookSubscriptions :many
+SELECT
+	id, tenant_id, url, event_types, secret, created_at, updated_at
+FROM
+	webhook_subscription
+WHERE
+	tenant_id = $1
+ORDER BY
+	created_at ASC, id ASC;
+
+-- name: FindWebhookSubscription :one
+SELECT
+	id, tenant_id, url, event_types, secret, created_at, updated_at
+FROM
+	webhook_subscription
+WHERE
+	id = $1 AND tenant_id = $2;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE
+	webhook_subscription
+SET
+	url = $1,
+	event_types = $2,
+	secret = $3,
+	updated_at = $4
+WHERE
+	id = $5 AND tenant_id = $6
+RETURNING
+	id, tenant_id, url, event_types, secret, created_at, updated_at;
+
+-- name: RemoveWebhookSubscription :execrows
+DELETE FROM
+	webhook_subscription
+WHERE
+	id = $1 AND tenant_id = $2;
+
+-- name: UpsertAlbumMetadata :execrows
+INSERT INTO
+	album_metadata (album_id, source, external_id, year, genres, cover_art_url, enriched_at)
+SELECT
+	id, sqlc.arg(source)::text, sqlc.arg(external_id)::text, sqlc.narg(year)::integer, sqlc.arg(genres)::text[], sqlc.arg(cover_art_url)::text, sqlc.arg(enriched_at)::timestamptz
+FROM
+	album
+WHERE
+	id = sqlc.arg(album_id) AND tenant_id = sqlc.arg(tenant_id)
+ON CONFLICT (album_id, source) DO UPDATE SET
+	external_id = excluded.external_id,
+	year = excluded.year,
+	genres = excluded.genres,
+	cover_art_url = excluded.cover_art_url,
+	enriched_at = excluded.enriched_at;
+
+-- name: FindAlbumMetadata :many
+SELECT
+	m.album_id, m.source, m.external_id, m.year, m.genres, m.cover_art_url, m.enriched_at
+FROM
+	album_metadata m
+	JOIN album a ON a.id = m.album_id
+WHERE
+	m.album_id = sqlc.arg(album_id) AND a.tenant_id = sqlc.arg(tenant_id)
+ORDER BY
+	m.source ASC;
+
+-- name: FindAlbumPrices :many
+SELECT
+	p.currency, p.amount
+FROM
+	album_price p
+	JOIN album a ON a.id = p.album_id
+WHERE
+	p.album_id = sqlc.arg(album_id) AND a.tenant_id = sqlc.arg(tenant_id)
+ORDER BY
+	p.currency ASC;
+
+-- name: DeleteAlbumPrices :exec
+DELETE FROM
+	album_price
+WHERE
+	album_id = $1;
+
+-- name: InsertAlbumPrice :exec
+INSERT INTO
+	album_price (album_id, currency, amount)
+VALUES
+	($1, $2, $3);
+
+-- name: FindAlbumsPricedIn :many
+-- The albums are priced in their default price if it is in the currency, and
+-- in the one of their price list otherwise.
+SELECT
+	a.id, a.title, a.artist,
+	(CASE WHEN a.currency = sqlc.arg(currency)::text THEN a.price ELSE p.amount END)::bigint AS price,
+	sqlc.arg(currency)::text AS currency,
+	a.created_at, a.updated_at, a.version, a.tenant_id, a.created_by, a.updated_by, a.artwork, a.release_date, a.original_release_year
+FROM
+	album a
+	LEFT JOIN album_price p ON p.album_id = a.id AND p.currency = sqlc.arg(currency)::text
+WHERE
+	a.tenant_id = sqlc.arg(tenant_id) AND (a.currency = sqlc.arg(currency)::text OR p.album_id IS NOT NULL)
+ORDER BY
+	lower(a.title) ASC, a.id ASC
+OFFSET
+	sqlc.arg(page_offset)
+LIMIT
+	sqlc.arg(page_limit);
+
+-- name: InsertGenre :exec
+INSERT INTO
+	genre (tenant_id, slug, name, created_at)
+VALUES
+	($1, $2, $3, $4);
+
+-- name: FindGenres :many
+SELECT
+	tenant_id, slug, name, created_at
+FROM
+	genre
+WHERE
+	tenant_id = $1
+ORDER BY
+	slug ASC;
+
+-- name: CountGenres :one
+SELECT
+	count(*)
+FROM
+	genre
+WHERE
+	tenant_id = sqlc.arg(tenant_id) AND slug = ANY(sqlc.arg(slugs)::text[]);
+
+-- name: RemoveGenre :execrows
+DELETE FROM
+	genre
+WHERE
+	tenant_id = $1 AND slug = $2;
+
+-- name: FindAlbumGenres :many
+SELECT
+	g.tenant_id, g.slug, g.name, g.created_at
+FROM
+	album_genre ag
+	JOIN genre g ON g.tenant_id = ag.tenant_id AND g.slug = ag.genre_slug
+WHERE
+	ag.album_id = $1 AND ag.tenant_id = $2
+ORDER BY
+	g.slug ASC;
+
+-- name: DeleteAlbumGenres :exec
+DELETE FROM
+	album_genre
+WHERE
+	album_id = $1;
+
+-- name: InsertAlbumGenre :exec
+INSERT INTO
+	album_genre (album_id, tenant_id, genre_slug)
+VALUES
+	($1, $2, $3);
+
+-- name: FindAlbumsInGenre :many
+SELECT
+	a.id, a.title, a.artist, a.price, a.currency, a.created_at, a.updated_at, a.version, a.tenant_id, a.created_by, a.updated_by, a.artwork, a.release_date, a.original_release_year
+FROM
+	album a
+	JOIN album_genre ag ON ag.album_id = a.id
+WHERE
+	ag.tenant_id = sqlc.arg(tenant_id) AND ag.genre_slug = sqlc.arg(genre_slug)
+ORDER BY
+	lower(a.title) ASC, a.id ASC
+OFFSET
+	sqlc.arg(page_offset)
+LIMIT
+	sqlc.arg(page_limit);
+
+-- name: BackfillOriginalReleaseYears :execrows
+-- Up to batch_size albums with no original release year are set the earliest
+-- year of their metadata, if any.
+UPDATE
+	album
+SET
+	original_release_year = m.year
+FROM (
+	SELECT
+		m.album_id, min(m.year)::integer AS year
+	FROM
+		album_metadata m
+		JOIN album a ON a.id = m.album_id
+	WHERE
+		a.original_release_year IS NULL AND m.year > 0
+	GROUP BY
+		m.album_id
+	LIMIT
+		sqlc.arg(batch_size)
+) m
+WHERE
+	album.id = m.album_id;
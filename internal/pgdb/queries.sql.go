@@ -0,0 +1,1225 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: queries.sql
+
+package pgdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const albumExists = `-- name: AlbumExists :one
+SELECT EXISTS (SELECT 1 FROM album WHERE id = $1 AND tenant_id = $2)
+`
+
+type AlbumExistsParams struct {
+	ID       uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) AlbumExists(ctx context.Context, arg AlbumExistsParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, albumExists, arg.ID, arg.TenantID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const backfillOriginalReleaseYears = `-- name: BackfillOriginalReleaseYears :execrows
+UPDATE
+	album
+SET
+	original_release_year = m.year
+FROM (
+	SELECT
+		m.album_id, min(m.year)::integer AS year
+	FROM
+		album_metadata m
+		JOIN album a ON a.id = m.album_id
+	WHERE
+		a.original_release_year IS NULL AND m.year > 0
+	GROUP BY
+		m.album_id
+	LIMIT
+		$1
+) m
+WHERE
+	album.id = m.album_id
+`
+
+// Up to batch_size albums with no original release year are set the earliest
+// year of their metadata, if any.
+func (q *Queries) BackfillOriginalReleaseYears(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, backfillOriginalReleaseYears, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countGenres = `-- name: CountGenres :one
+SELECT
+	count(*)
+FROM
+	genre
+WHERE
+	tenant_id = $1 AND slug = ANY($2::text[])
+`
+
+type CountGenresParams struct {
+	TenantID string
+	Slugs    []string
+}
+
+func (q *Queries) CountGenres(ctx context.Context, arg CountGenresParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countGenres, arg.TenantID, pq.Array(arg.Slugs))
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteAlbumGenres = `-- name: DeleteAlbumGenres :exec
+DELETE FROM
+	album_genre
+WHERE
+	album_id = $1
+`
+
+func (q *Queries) DeleteAlbumGenres(ctx context.Context, albumID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteAlbumGenres, albumID)
+	return err
+}
+
+const deleteAlbumPrices = `-- name: DeleteAlbumPrices :exec
+DELETE FROM
+	album_price
+WHERE
+	album_id = $1
+`
+
+func (q *Queries) DeleteAlbumPrices(ctx context.Context, albumID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteAlbumPrices, albumID)
+	return err
+}
+
+const findAlbum = `-- name: FindAlbum :one
+SELECT
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+FROM
+	album
+WHERE
+	id = $1 AND tenant_id = $2
+`
+
+type FindAlbumParams struct {
+	ID       uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) FindAlbum(ctx context.Context, arg FindAlbumParams) (Album, error) {
+	row := q.db.QueryRowContext(ctx, findAlbum, arg.ID, arg.TenantID)
+	var i Album
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Artist,
+		&i.Price,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.TenantID,
+		&i.CreatedBy,
+		&i.UpdatedBy,
+		&i.Artwork,
+		&i.ReleaseDate,
+		&i.OriginalReleaseYear,
+	)
+	return i, err
+}
+
+const findAlbumGenres = `-- name: FindAlbumGenres :many
+SELECT
+	g.tenant_id, g.slug, g.name, g.created_at
+FROM
+	album_genre ag
+	JOIN genre g ON g.tenant_id = ag.tenant_id AND g.slug = ag.genre_slug
+WHERE
+	ag.album_id = $1 AND ag.tenant_id = $2
+ORDER BY
+	g.slug ASC
+`
+
+type FindAlbumGenresParams struct {
+	AlbumID  uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) FindAlbumGenres(ctx context.Context, arg FindAlbumGenresParams) ([]Genre, error) {
+	rows, err := q.db.QueryContext(ctx, findAlbumGenres, arg.AlbumID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Genre
+	for rows.Next() {
+		var i Genre
+		if err := rows.Scan(
+			&i.TenantID,
+			&i.Slug,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAlbumHistory = `-- name: FindAlbumHistory :many
+SELECT
+	action, actor, before, after, created_at
+FROM
+	album_audit
+WHERE
+	album_id = $1 AND
+	coalesce(coalesce(after, before) ->> 'tenant_id', '') = $2::text
+ORDER BY
+	id ASC
+`
+
+type FindAlbumHistoryParams struct {
+	AlbumID  uuid.UUID
+	TenantID string
+}
+
+type FindAlbumHistoryRow struct {
+	Action    string
+	Actor     sql.NullString
+	Before    json.RawMessage
+	After     json.RawMessage
+	CreatedAt time.Time
+}
+
+// The changes recorded before albums had a tenant belong to the default one.
+func (q *Queries) FindAlbumHistory(ctx context.Context, arg FindAlbumHistoryParams) ([]FindAlbumHistoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, findAlbumHistory, arg.AlbumID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAlbumHistoryRow
+	for rows.Next() {
+		var i FindAlbumHistoryRow
+		if err := rows.Scan(
+			&i.Action,
+			&i.Actor,
+			&i.Before,
+			&i.After,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAlbumMetadata = `-- name: FindAlbumMetadata :many
+SELECT
+	m.album_id, m.source, m.external_id, m.year, m.genres, m.cover_art_url, m.enriched_at
+FROM
+	album_metadata m
+	JOIN album a ON a.id = m.album_id
+WHERE
+	m.album_id = $1 AND a.tenant_id = $2
+ORDER BY
+	m.source ASC
+`
+
+type FindAlbumMetadataParams struct {
+	AlbumID  uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) FindAlbumMetadata(ctx context.Context, arg FindAlbumMetadataParams) ([]AlbumMetadata, error) {
+	rows, err := q.db.QueryContext(ctx, findAlbumMetadata, arg.AlbumID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AlbumMetadata
+	for rows.Next() {
+		var i AlbumMetadata
+		if err := rows.Scan(
+			&i.AlbumID,
+			&i.Source,
+			&i.ExternalID,
+			&i.Year,
+			pq.Array(&i.Genres),
+			&i.CoverArtUrl,
+			&i.EnrichedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAlbumPrices = `-- name: FindAlbumPrices :many
+SELECT
+	p.currency, p.amount
+FROM
+	album_price p
+	JOIN album a ON a.id = p.album_id
+WHERE
+	p.album_id = $1 AND a.tenant_id = $2
+ORDER BY
+	p.currency ASC
+`
+
+type FindAlbumPricesParams struct {
+	AlbumID  uuid.UUID
+	TenantID string
+}
+
+type FindAlbumPricesRow struct {
+	Currency string
+	Amount   int64
+}
+
+func (q *Queries) FindAlbumPrices(ctx context.Context, arg FindAlbumPricesParams) ([]FindAlbumPricesRow, error) {
+	rows, err := q.db.QueryContext(ctx, findAlbumPrices, arg.AlbumID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAlbumPricesRow
+	for rows.Next() {
+		var i FindAlbumPricesRow
+		if err := rows.Scan(&i.Currency, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAlbums = `-- name: FindAlbums :many
+SELECT
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+FROM
+	album
+WHERE
+	tenant_id = $1 AND
+	($2::date IS NULL OR release_date >= $2::date) AND
+	($3::date IS NULL OR release_date <= $3::date) AND
+	($4::integer IS NULL OR original_release_year = $4::integer)
+ORDER BY
+	CASE WHEN $5::text = 'release_date' THEN release_date END ASC NULLS LAST,
+	CASE WHEN $5::text = '-release_date' THEN release_date END DESC NULLS LAST,
+	lower(title) ASC, id ASC
+OFFSET
+	$6
+LIMIT
+	$7
+`
+
+type FindAlbumsParams struct {
+	TenantID            string
+	ReleasedFrom        sql.NullTime
+	ReleasedUntil       sql.NullTime
+	OriginalReleaseYear sql.NullInt32
+	Sort                string
+	PageOffset          int32
+	PageLimit           int32
+}
+
+// The albums are sorted by title, unless sort is release_date or
+// -release_date, which sort them by release date, oldest or newest first, and
+// then by title. The albums with no release date are sorted last.
+func (q *Queries) FindAlbums(ctx context.Context, arg FindAlbumsParams) ([]Album, error) {
+	rows, err := q.db.QueryContext(ctx, findAlbums,
+		arg.TenantID,
+		arg.ReleasedFrom,
+		arg.ReleasedUntil,
+		arg.OriginalReleaseYear,
+		arg.Sort,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Album
+	for rows.Next() {
+		var i Album
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.TenantID,
+			&i.CreatedBy,
+			&i.UpdatedBy,
+			&i.Artwork,
+			&i.ReleaseDate,
+			&i.OriginalReleaseYear,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAlbumsInGenre = `-- name: FindAlbumsInGenre :many
+SELECT
+	a.id, a.title, a.artist, a.price, a.currency, a.created_at, a.updated_at, a.version, a.tenant_id, a.created_by, a.updated_by, a.artwork, a.release_date, a.original_release_year
+FROM
+	album a
+	JOIN album_genre ag ON ag.album_id = a.id
+WHERE
+	ag.tenant_id = $1 AND ag.genre_slug = $2
+ORDER BY
+	lower(a.title) ASC, a.id ASC
+OFFSET
+	$3
+LIMIT
+	$4
+`
+
+type FindAlbumsInGenreParams struct {
+	TenantID   string
+	GenreSlug  string
+	PageOffset int32
+	PageLimit  int32
+}
+
+func (q *Queries) FindAlbumsInGenre(ctx context.Context, arg FindAlbumsInGenreParams) ([]Album, error) {
+	rows, err := q.db.QueryContext(ctx, findAlbumsInGenre,
+		arg.TenantID,
+		arg.GenreSlug,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Album
+	for rows.Next() {
+		var i Album
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.TenantID,
+			&i.CreatedBy,
+			&i.UpdatedBy,
+			&i.Artwork,
+			&i.ReleaseDate,
+			&i.OriginalReleaseYear,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAlbumsPricedIn = `-- name: FindAlbumsPricedIn :many
+SELECT
+	a.id, a.title, a.artist,
+	(CASE WHEN a.currency = $1::text THEN a.price ELSE p.amount END)::bigint AS price,
+	$1::text AS currency,
+	a.created_at, a.updated_at, a.version, a.tenant_id, a.created_by, a.updated_by, a.artwork, a.release_date, a.original_release_year
+FROM
+	album a
+	LEFT JOIN album_price p ON p.album_id = a.id AND p.currency = $1::text
+WHERE
+	a.tenant_id = $2 AND (a.currency = $1::text OR p.album_id IS NOT NULL)
+ORDER BY
+	lower(a.title) ASC, a.id ASC
+OFFSET
+	$3
+LIMIT
+	$4
+`
+
+type FindAlbumsPricedInParams struct {
+	Currency   string
+	TenantID   string
+	PageOffset int32
+	PageLimit  int32
+}
+
+type FindAlbumsPricedInRow struct {
+	ID                  uuid.UUID
+	Title               string
+	Artist              string
+	Price               int64
+	Currency            string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Version             int32
+	TenantID            string
+	CreatedBy           string
+	UpdatedBy           string
+	Artwork             string
+	ReleaseDate         sql.NullTime
+	OriginalReleaseYear sql.NullInt32
+}
+
+// The albums are priced in their default price if it is in the currency, and
+// in the one of their price list otherwise.
+func (q *Queries) FindAlbumsPricedIn(ctx context.Context, arg FindAlbumsPricedInParams) ([]FindAlbumsPricedInRow, error) {
+	rows, err := q.db.QueryContext(ctx, findAlbumsPricedIn,
+		arg.Currency,
+		arg.TenantID,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAlbumsPricedInRow
+	for rows.Next() {
+		var i FindAlbumsPricedInRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.TenantID,
+			&i.CreatedBy,
+			&i.UpdatedBy,
+			&i.Artwork,
+			&i.ReleaseDate,
+			&i.OriginalReleaseYear,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findGenres = `-- name: FindGenres :many
+SELECT
+	tenant_id, slug, name, created_at
+FROM
+	genre
+WHERE
+	tenant_id = $1
+ORDER BY
+	slug ASC
+`
+
+func (q *Queries) FindGenres(ctx context.Context, tenantID string) ([]Genre, error) {
+	rows, err := q.db.QueryContext(ctx, findGenres, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Genre
+	for rows.Next() {
+		var i Genre
+		if err := rows.Scan(
+			&i.TenantID,
+			&i.Slug,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findWebhookSubscription = `-- name: FindWebhookSubscription :one
+SELECT
+	id, tenant_id, url, event_types, secret, created_at, updated_at
+FROM
+	webhook_subscription
+WHERE
+	id = $1 AND tenant_id = $2
+`
+
+type FindWebhookSubscriptionParams struct {
+	ID       uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) FindWebhookSubscription(ctx context.Context, arg FindWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRowContext(ctx, findWebhookSubscription, arg.ID, arg.TenantID)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Url,
+		pq.Array(&i.EventTypes),
+		&i.Secret,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const findWebhookSubscriptions = `-- name: FindWebhookSubscriptions :many
+SELECT
+	id, tenant_id, url, event_types, secret, created_at, updated_at
+FROM
+	webhook_subscription
+WHERE
+	tenant_id = $1
+ORDER BY
+	created_at ASC, id ASC
+`
+
+func (q *Queries) FindWebhookSubscriptions(ctx context.Context, tenantID string) ([]WebhookSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, findWebhookSubscriptions, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookSubscription
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Url,
+			pq.Array(&i.EventTypes),
+			&i.Secret,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertAlbum = `-- name: InsertAlbum :exec
+INSERT INTO
+	album (id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+`
+
+type InsertAlbumParams struct {
+	ID                  uuid.UUID
+	Title               string
+	Artist              string
+	Price               int64
+	Currency            string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Version             int32
+	TenantID            string
+	CreatedBy           string
+	UpdatedBy           string
+	Artwork             string
+	ReleaseDate         sql.NullTime
+	OriginalReleaseYear sql.NullInt32
+}
+
+func (q *Queries) InsertAlbum(ctx context.Context, arg InsertAlbumParams) error {
+	_, err := q.db.ExecContext(ctx, insertAlbum,
+		arg.ID,
+		arg.Title,
+		arg.Artist,
+		arg.Price,
+		arg.Currency,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Version,
+		arg.TenantID,
+		arg.CreatedBy,
+		arg.UpdatedBy,
+		arg.Artwork,
+		arg.ReleaseDate,
+		arg.OriginalReleaseYear,
+	)
+	return err
+}
+
+const insertAlbumGenre = `-- name: InsertAlbumGenre :exec
+INSERT INTO
+	album_genre (album_id, tenant_id, genre_slug)
+VALUES
+	($1, $2, $3)
+`
+
+type InsertAlbumGenreParams struct {
+	AlbumID   uuid.UUID
+	TenantID  string
+	GenreSlug string
+}
+
+func (q *Queries) InsertAlbumGenre(ctx context.Context, arg InsertAlbumGenreParams) error {
+	_, err := q.db.ExecContext(ctx, insertAlbumGenre, arg.AlbumID, arg.TenantID, arg.GenreSlug)
+	return err
+}
+
+const insertAlbumPrice = `-- name: InsertAlbumPrice :exec
+INSERT INTO
+	album_price (album_id, currency, amount)
+VALUES
+	($1, $2, $3)
+`
+
+type InsertAlbumPriceParams struct {
+	AlbumID  uuid.UUID
+	Currency string
+	Amount   int64
+}
+
+func (q *Queries) InsertAlbumPrice(ctx context.Context, arg InsertAlbumPriceParams) error {
+	_, err := q.db.ExecContext(ctx, insertAlbumPrice, arg.AlbumID, arg.Currency, arg.Amount)
+	return err
+}
+
+const insertGenre = `-- name: InsertGenre :exec
+INSERT INTO
+	genre (tenant_id, slug, name, created_at)
+VALUES
+	($1, $2, $3, $4)
+`
+
+type InsertGenreParams struct {
+	TenantID  string
+	Slug      string
+	Name      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) InsertGenre(ctx context.Context, arg InsertGenreParams) error {
+	_, err := q.db.ExecContext(ctx, insertGenre,
+		arg.TenantID,
+		arg.Slug,
+		arg.Name,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const insertWebhookSubscription = `-- name: InsertWebhookSubscription :exec
+INSERT INTO
+	webhook_subscription (id, tenant_id, url, event_types, secret, created_at, updated_at)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7)
+`
+
+type InsertWebhookSubscriptionParams struct {
+	ID         uuid.UUID
+	TenantID   string
+	Url        string
+	EventTypes []string
+	Secret     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) InsertWebhookSubscription(ctx context.Context, arg InsertWebhookSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, insertWebhookSubscription,
+		arg.ID,
+		arg.TenantID,
+		arg.Url,
+		pq.Array(arg.EventTypes),
+		arg.Secret,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const removeAlbum = `-- name: RemoveAlbum :execrows
+DELETE FROM
+	album
+WHERE
+	id = $1 AND tenant_id = $2
+`
+
+type RemoveAlbumParams struct {
+	ID       uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) RemoveAlbum(ctx context.Context, arg RemoveAlbumParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeAlbum, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removeAlbumReturning = `-- name: RemoveAlbumReturning :one
+DELETE FROM
+	album
+WHERE
+	id = $1 AND tenant_id = $2
+RETURNING
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+`
+
+type RemoveAlbumReturningParams struct {
+	ID       uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) RemoveAlbumReturning(ctx context.Context, arg RemoveAlbumReturningParams) (Album, error) {
+	row := q.db.QueryRowContext(ctx, removeAlbumReturning, arg.ID, arg.TenantID)
+	var i Album
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Artist,
+		&i.Price,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.TenantID,
+		&i.CreatedBy,
+		&i.UpdatedBy,
+		&i.Artwork,
+		&i.ReleaseDate,
+		&i.OriginalReleaseYear,
+	)
+	return i, err
+}
+
+const removeGenre = `-- name: RemoveGenre :execrows
+DELETE FROM
+	genre
+WHERE
+	tenant_id = $1 AND slug = $2
+`
+
+type RemoveGenreParams struct {
+	TenantID string
+	Slug     string
+}
+
+func (q *Queries) RemoveGenre(ctx context.Context, arg RemoveGenreParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeGenre, arg.TenantID, arg.Slug)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removeWebhookSubscription = `-- name: RemoveWebhookSubscription :execrows
+DELETE FROM
+	webhook_subscription
+WHERE
+	id = $1 AND tenant_id = $2
+`
+
+type RemoveWebhookSubscriptionParams struct {
+	ID       uuid.UUID
+	TenantID string
+}
+
+func (q *Queries) RemoveWebhookSubscription(ctx context.Context, arg RemoveWebhookSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeWebhookSubscription, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setActor = `-- name: SetActor :exec
+SELECT set_config('catalog.actor', $1::text, true)
+`
+
+// The actor is recorded into the history of the album changes of the
+// transaction.
+func (q *Queries) SetActor(ctx context.Context, actor string) error {
+	_, err := q.db.ExecContext(ctx, setActor, actor)
+	return err
+}
+
+const suggestAlbums = `-- name: SuggestAlbums :many
+SELECT
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+FROM
+	album
+WHERE
+	tenant_id = $1 AND
+	(title ILIKE $2::text || '%' OR artist ILIKE $2::text || '%')
+ORDER BY
+	greatest(similarity(title, $3::text), similarity(artist, $3::text)) DESC,
+	lower(title) ASC, id ASC
+LIMIT
+	$4
+`
+
+type SuggestAlbumsParams struct {
+	TenantID  string
+	Pattern   string
+	Prefix    string
+	MaxAlbums int32
+}
+
+func (q *Queries) SuggestAlbums(ctx context.Context, arg SuggestAlbumsParams) ([]Album, error) {
+	rows, err := q.db.QueryContext(ctx, suggestAlbums, arg.TenantID, arg.Pattern, arg.Prefix, arg.MaxAlbums)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Album
+	for rows.Next() {
+		var i Album
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.TenantID,
+			&i.CreatedBy,
+			&i.UpdatedBy,
+			&i.Artwork,
+			&i.ReleaseDate,
+			&i.OriginalReleaseYear,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateAlbum = `-- name: UpdateAlbum :execrows
+UPDATE
+	album
+SET
+	title = $1,
+	artist = $2,
+	price = $3,
+	currency = $4,
+	created_at = $5,
+	updated_at = $6,
+	updated_by = $7,
+	artwork = $8,
+	release_date = $9,
+	original_release_year = $10,
+	version = version + 1
+WHERE
+	id = $11 AND version = $12 AND tenant_id = $13
+`
+
+type UpdateAlbumParams struct {
+	Title               string
+	Artist              string
+	Price               int64
+	Currency            string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	UpdatedBy           string
+	Artwork             string
+	ReleaseDate         sql.NullTime
+	OriginalReleaseYear sql.NullInt32
+	ID                  uuid.UUID
+	Version             int32
+	TenantID            string
+}
+
+func (q *Queries) UpdateAlbum(ctx context.Context, arg UpdateAlbumParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateAlbum,
+		arg.Title,
+		arg.Artist,
+		arg.Price,
+		arg.Currency,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UpdatedBy,
+		arg.Artwork,
+		arg.ReleaseDate,
+		arg.OriginalReleaseYear,
+		arg.ID,
+		arg.Version,
+		arg.TenantID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateWebhookSubscription = `-- name: UpdateWebhookSubscription :one
+UPDATE
+	webhook_subscription
+SET
+	url = $1,
+	event_types = $2,
+	secret = $3,
+	updated_at = $4
+WHERE
+	id = $5 AND tenant_id = $6
+RETURNING
+	id, tenant_id, url, event_types, secret, created_at, updated_at
+`
+
+type UpdateWebhookSubscriptionParams struct {
+	Url        string
+	EventTypes []string
+	Secret     string
+	UpdatedAt  time.Time
+	ID         uuid.UUID
+	TenantID   string
+}
+
+func (q *Queries) UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhookSubscription,
+		arg.Url,
+		pq.Array(arg.EventTypes),
+		arg.Secret,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.TenantID,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Url,
+		pq.Array(&i.EventTypes),
+		&i.Secret,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertAlbum = `-- name: UpsertAlbum :one
+INSERT INTO
+	album (id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (id) DO UPDATE SET
+	title = EXCLUDED.title,
+	artist = EXCLUDED.artist,
+	price = EXCLUDED.price,
+	currency = EXCLUDED.currency,
+	release_date = EXCLUDED.release_date,
+	original_release_year = EXCLUDED.original_release_year,
+	updated_at = EXCLUDED.updated_at,
+	updated_by = EXCLUDED.updated_by,
+	version = album.version + 1
+WHERE
+	album.tenant_id = EXCLUDED.tenant_id
+RETURNING
+	id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year, (xmax = 0)::boolean AS created
+`
+
+type UpsertAlbumParams struct {
+	ID                  uuid.UUID
+	Title               string
+	Artist              string
+	Price               int64
+	Currency            string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Version             int32
+	TenantID            string
+	CreatedBy           string
+	UpdatedBy           string
+	Artwork             string
+	ReleaseDate         sql.NullTime
+	OriginalReleaseYear sql.NullInt32
+}
+
+type UpsertAlbumRow struct {
+	ID                  uuid.UUID
+	Title               string
+	Artist              string
+	Price               int64
+	Currency            string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Version             int32
+	TenantID            string
+	CreatedBy           string
+	UpdatedBy           string
+	Artwork             string
+	ReleaseDate         sql.NullTime
+	OriginalReleaseYear sql.NullInt32
+	Created             bool
+}
+
+// The album is not updated if it belongs to another tenant, returning no row.
+// The artwork of a replaced album is kept.
+func (q *Queries) UpsertAlbum(ctx context.Context, arg UpsertAlbumParams) (UpsertAlbumRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertAlbum,
+		arg.ID,
+		arg.Title,
+		arg.Artist,
+		arg.Price,
+		arg.Currency,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Version,
+		arg.TenantID,
+		arg.CreatedBy,
+		arg.UpdatedBy,
+		arg.Artwork,
+		arg.ReleaseDate,
+		arg.OriginalReleaseYear,
+	)
+	var i UpsertAlbumRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Artist,
+		&i.Price,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.TenantID,
+		&i.CreatedBy,
+		&i.UpdatedBy,
+		&i.Artwork,
+		&i.ReleaseDate,
+		&i.OriginalReleaseYear,
+		&i.Created,
+	)
+	return i, err
+}
+
+const upsertAlbumMetadata = `-- name: UpsertAlbumMetadata :execrows
+INSERT INTO
+	album_metadata (album_id, source, external_id, year, genres, cover_art_url, enriched_at)
+SELECT
+	id, $1::text, $2::text, $3::integer, $4::text[], $5::text, $6::timestamptz
+FROM
+	album
+WHERE
+	id = $7 AND tenant_id = $8
+ON CONFLICT (album_id, source) DO UPDATE SET
+	external_id = excluded.external_id,
+	year = excluded.year,
+	genres = excluded.genres,
+	cover_art_url = excluded.cover_art_url,
+	enriched_at = excluded.enriched_at
+`
+
+type UpsertAlbumMetadataParams struct {
+	Source      string
+	ExternalID  string
+	Year        sql.NullInt32
+	Genres      []string
+	CoverArtUrl string
+	EnrichedAt  time.Time
+	AlbumID     uuid.UUID
+	TenantID    string
+}
+
+func (q *Queries) UpsertAlbumMetadata(ctx context.Context, arg UpsertAlbumMetadataParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, upsertAlbumMetadata,
+		arg.Source,
+		arg.ExternalID,
+		arg.Year,
+		pq.Array(arg.Genres),
+		arg.CoverArtUrl,
+		arg.EnrichedAt,
+		arg.AlbumID,
+		arg.TenantID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,7 +7,9 @@ import (
 	"math/rand/v2"
 	"testing"
 
-	"github.com/pressly/goose/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	catalog "github.com/jhtohru/go-album-catalog"
 )
 
 type Postgres struct {
@@ -42,6 +44,21 @@ func (p *Postgres) CreateDBOrFailNow(t *testing.T) *sql.DB {
 	return db
 }
 
+func (p *Postgres) CreatePgxPoolOrFailNow(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	db := p.CreateDBOrFailNow(t)
+	defer db.Close()
+	var dbName string
+	if err := db.QueryRow("SELECT current_database()").Scan(&dbName); err != nil {
+		t.Fatalf("Finding the database name: %v\n", err)
+	}
+	pool, err := pgxpool.New(context.Background(), dsn(p.addr, p.user, p.password, dbName))
+	if err != nil {
+		t.Fatalf("Creating a pgx pool: %v\n", err)
+	}
+	return pool
+}
+
 func (p *Postgres) CreateDB() (*sql.DB, error) {
 	if p.defaultDB == nil {
 		return nil, ErrTerminated
@@ -60,7 +77,7 @@ func (p *Postgres) createDB(dbName string) (*sql.DB, error) {
 		return nil, err
 	}
 	// Migrate database.
-	if err := goose.Up(db, "migrations"); err != nil {
+	if err := catalog.MigrateDB(context.Background(), db); err != nil {
 		p.dropDB(dbName)
 		db.Close()
 		return nil, err
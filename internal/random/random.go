@@ -3,6 +3,8 @@ package random
 import (
 	"math/rand/v2"
 	"time"
+
+	"github.com/jhtohru/go-album-catalog/money"
 )
 
 // String returns a randomly generated string with size of n.
@@ -26,6 +28,78 @@ func Time() time.Time {
 		rand.IntN(60),               // random minute [0, 59]
 		rand.IntN(60),               // random second [0, 59]
 		rand.IntN(1000000)*1000,     // random microseconds [0, 999999μs]
-		time.Local,
+		time.UTC,
 	)
 }
+
+// The corpora the plausible artists and album titles are made of.
+var (
+	firstNames = []string{
+		"Ana", "Bruno", "Carla", "Dmitri", "Elena", "Felipe", "Grace", "Hiro",
+		"Ines", "Jamal", "Kaito", "Lucia", "Marcus", "Nadia", "Oscar", "Priya",
+		"Rafael", "Sofia", "Tomas", "Yara",
+	}
+	lastNames = []string{
+		"Almeida", "Baker", "Castillo", "Duarte", "Eriksen", "Fontaine", "Gallo",
+		"Haddad", "Ibarra", "Jensen", "Kowalski", "Lindqvist", "Moreau", "Nakamura",
+		"Okafor", "Petrov", "Quintana", "Rossi", "Silva", "Tanaka",
+	}
+	adjectives = []string{
+		"Black", "Broken", "Crimson", "Distant", "Electric", "Endless", "Fading",
+		"Golden", "Hollow", "Last", "Lonely", "Midnight", "Neon", "Northern",
+		"Quiet", "Restless", "Silent", "Velvet", "Wild", "Wooden",
+	}
+	nouns = []string{
+		"Anchors", "Birds", "Bridges", "Cities", "Dreams", "Echoes", "Engines",
+		"Fires", "Ghosts", "Horizons", "Lights", "Mirrors", "Oceans", "Rivers",
+		"Satellites", "Shadows", "Signals", "Stones", "Tides", "Wolves",
+	}
+	places = []string{
+		"the City", "the Desert", "the Harbor", "the Machine", "the Mountain",
+		"the North", "the Radio", "the River", "the Sea", "the Valley",
+	}
+)
+
+// pick returns a randomly chosen element of s.
+func pick(s []string) string {
+	return s[rand.IntN(len(s))]
+}
+
+// Artist returns a randomly generated plausible artist name, either a person
+// or a band, such as "Nadia Okafor" or "The Velvet Signals".
+func Artist() string {
+	switch rand.IntN(3) {
+	case 0:
+		return pick(firstNames) + " " + pick(lastNames)
+	case 1:
+		return "The " + pick(adjectives) + " " + pick(nouns)
+	default:
+		return pick(nouns) + " of " + pick(places)
+	}
+}
+
+// AlbumTitle returns a randomly generated plausible album title, such as
+// "Songs from the Harbor" or "Midnight Mirrors".
+func AlbumTitle() string {
+	switch rand.IntN(4) {
+	case 0:
+		return pick(adjectives) + " " + pick(nouns)
+	case 1:
+		return "Songs from " + pick(places)
+	case 2:
+		return pick(nouns) + " and " + pick(nouns)
+	default:
+		return pick(adjectives) + " " + pick(nouns) + " Vol." + string(rune('1'+rand.IntN(3)))
+	}
+}
+
+// Price returns a randomly generated plausible album price in US dollars, from
+// 4.99 up to 49.99.
+func Price() money.Money {
+	return money.New(int64((rand.IntN(46)+5)*100-1), "USD")
+}
+
+// TimeBetween returns a randomly generated time from from up to to.
+func TimeBetween(from, to time.Time) time.Time {
+	return from.Add(time.Duration(rand.Int64N(int64(to.Sub(from)))))
+}
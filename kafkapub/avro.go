@@ -0,0 +1,119 @@
+package kafkapub
+
+import (
+	"time"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+// AvroSchema is the Avro schema of the value of the messages published in the
+// Avro format. The old and new values of the changes are encoded as JSON, the
+// same as in the events.Envelope. The price of the album is its amount in the
+// minor units of its currency, which is empty in the messages published before
+// albums had one.
+const AvroSchema = `{
+	"type": "record",
+	"name": "AlbumEvent",
+	"namespace": "catalog",
+	"fields": [
+		{"name": "id", "type": {"type": "string", "logicalType": "uuid"}},
+		{"name": "type", "type": "string"},
+		{"name": "schema_version", "type": "int"},
+		{"name": "occurred_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+		{"name": "album", "type": {
+			"type": "record",
+			"name": "Album",
+			"fields": [
+				{"name": "id", "type": {"type": "string", "logicalType": "uuid"}},
+				{"name": "title", "type": "string"},
+				{"name": "artist", "type": "string"},
+				{"name": "price", "type": "long"},
+				{"name": "currency", "type": "string", "default": ""},
+				{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+				{"name": "updated_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+				{"name": "version", "type": "int"},
+				{"name": "tenant_id", "type": "string"},
+				{"name": "created_by", "type": "string"},
+				{"name": "updated_by", "type": "string"},
+				{"name": "artwork", "type": "string", "default": ""}
+			]
+		}},
+		{"name": "changes", "type": {
+			"type": "array",
+			"items": {
+				"type": "record",
+				"name": "Change",
+				"fields": [
+					{"name": "field", "type": "string"},
+					{"name": "old", "type": "string"},
+					{"name": "new", "type": "string"}
+				]
+			}
+		}}
+	]
+}`
+
+var avroSchema = avro.MustParse(AvroSchema)
+
+// avroEvent is the Go representation of an AvroSchema record.
+type avroEvent struct {
+	ID            string       `avro:"id"`
+	Type          string       `avro:"type"`
+	SchemaVersion int          `avro:"schema_version"`
+	OccurredAt    time.Time    `avro:"occurred_at"`
+	Album         avroAlbum    `avro:"album"`
+	Changes       []avroChange `avro:"changes"`
+}
+
+type avroAlbum struct {
+	ID        string    `avro:"id"`
+	Title     string    `avro:"title"`
+	Artist    string    `avro:"artist"`
+	Price     int64     `avro:"price"`
+	Currency  string    `avro:"currency"`
+	CreatedAt time.Time `avro:"created_at"`
+	UpdatedAt time.Time `avro:"updated_at"`
+	Version   int       `avro:"version"`
+	TenantID  string    `avro:"tenant_id"`
+	CreatedBy string    `avro:"created_by"`
+	UpdatedBy string    `avro:"updated_by"`
+	Artwork   string    `avro:"artwork"`
+}
+
+type avroChange struct {
+	Field string `avro:"field"`
+	Old   string `avro:"old"`
+	New   string `avro:"new"`
+}
+
+// marshalAvro returns the Avro binary encoding of e, carried by env.
+func marshalAvro(env events.Envelope, e events.Event) ([]byte, error) {
+	alb := eventAlbum(e)
+	changes := []avroChange{}
+	for _, c := range eventChanges(e) {
+		changes = append(changes, avroChange{Field: c.Field, Old: string(c.Old), New: string(c.New)})
+	}
+	return avro.Marshal(avroSchema, avroEvent{
+		ID:            env.ID.String(),
+		Type:          string(env.Type),
+		SchemaVersion: env.SchemaVersion,
+		OccurredAt:    env.OccurredAt,
+		Album: avroAlbum{
+			ID:        alb.ID.String(),
+			Title:     alb.Title,
+			Artist:    alb.Artist,
+			Price:     alb.Price.AmountMinor,
+			Currency:  alb.Price.Currency,
+			CreatedAt: alb.CreatedAt,
+			UpdatedAt: alb.UpdatedAt,
+			Version:   alb.Version,
+			TenantID:  alb.TenantID,
+			CreatedBy: alb.CreatedBy,
+			UpdatedBy: alb.UpdatedBy,
+			Artwork:   alb.Artwork,
+		},
+		Changes: changes,
+	})
+}
@@ -0,0 +1,115 @@
+// Package kafkapub provides a catalog.EventPublisher that publishes album
+// events to a Kafka topic.
+//
+// Each event is published as a message keyed by the ID of its album, so that
+// the events of an album are published to the same partition, in order. The
+// message carries the type, ID and schema version of the event in its
+// event-type, event-id and event-schema-version headers, and the event itself
+// as its value, encoded either as the JSON of its events.Envelope or as Avro
+// binary data of AvroSchema.
+package kafkapub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+// Format is the format of the value of the published messages.
+type Format string
+
+// The formats of the value of the published messages.
+const (
+	JSON Format = "json"
+	Avro Format = "avro"
+)
+
+// Writer writes messages to a Kafka topic. *kafka.Writer implements it.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Publisher is a catalog.EventPublisher that publishes album events to a
+// Kafka topic.
+type Publisher struct {
+	w      Writer
+	format Format
+}
+
+// New returns a new Publisher that writes the events through w, encoded in
+// format. It returns an error if format is unknown.
+func New(w Writer, format Format) (*Publisher, error) {
+	switch format {
+	case JSON, Avro:
+	default:
+		return nil, fmt.Errorf("unknown kafka message format %q", format)
+	}
+	return &Publisher{w: w, format: format}, nil
+}
+
+// NewWriter returns a new *kafka.Writer that writes messages to topic through
+// brokers, partitioned by the hash of their key, and waits for them to be
+// acknowledged by every in-sync replica, so that events published by the
+// outbox relay are delivered at least once.
+func NewWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+}
+
+// Publish makes Publisher implement catalog.EventPublisher.
+func (p *Publisher) Publish(ctx context.Context, env events.Envelope) error {
+	e, err := events.Unwrap(env)
+	if err != nil {
+		return err
+	}
+	alb := eventAlbum(e)
+	var value []byte
+	switch p.format {
+	case JSON:
+		value, err = json.Marshal(env)
+	case Avro:
+		value, err = marshalAvro(env, e)
+	}
+	if err != nil {
+		return fmt.Errorf("encoding %s event: %w", env.Type, err)
+	}
+	return p.w.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(alb.ID.String()),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "event-type", Value: []byte(env.Type)},
+			{Key: "event-id", Value: []byte(env.ID.String())},
+			{Key: "event-schema-version", Value: []byte(strconv.Itoa(env.SchemaVersion))},
+		},
+	})
+}
+
+// eventAlbum returns the album of e.
+func eventAlbum(e events.Event) events.Album {
+	switch e := e.(type) {
+	case events.AlbumCreated:
+		return e.Album
+	case events.AlbumUpdated:
+		return e.Album
+	case events.AlbumDeleted:
+		return e.Album
+	}
+	return events.Album{}
+}
+
+// eventChanges returns the changes of e, if it is an update.
+func eventChanges(e events.Event) []events.Change {
+	if e, ok := e.(events.AlbumUpdated); ok {
+		return e.Changes
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package kafkapub_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamba/avro/v2"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/kafkapub"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+type writerSpy struct {
+	msgs []kafka.Message
+	err  error
+}
+
+func (w *writerSpy) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.msgs = append(w.msgs, msgs...)
+	return w.err
+}
+
+func TestNew(t *testing.T) {
+	_, err := kafkapub.New(&writerSpy{}, "xml")
+
+	assert.EqualError(t, err, `unknown kafka message format "xml"`)
+}
+
+func TestPublisherPublish(t *testing.T) {
+	alb := events.Album{
+		ID:        uuid.New(),
+		Title:     "Anathema",
+		Artist:    "Judgement",
+		Price:     money.New(1234, "EUR"),
+		CreatedAt: time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 8, 2, 0, 0, 0, 0, time.UTC),
+		Version:   2,
+		TenantID:  "acme",
+	}
+	env, err := events.Wrap(uuid.New(), time.Date(2024, 8, 2, 0, 0, 0, 0, time.UTC), events.AlbumUpdated{
+		Album: alb,
+		Changes: []events.Change{
+			{Field: "price", Old: json.RawMessage(`123`), New: json.RawMessage(`1234`)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHeaders := []kafka.Header{
+		{Key: "event-type", Value: []byte("album.updated")},
+		{Key: "event-id", Value: []byte(env.ID.String())},
+		{Key: "event-schema-version", Value: []byte("2")},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := &writerSpy{}
+		p, err := kafkapub.New(w, kafkapub.JSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = p.Publish(context.Background(), env)
+
+		assert.Nil(t, err)
+		if assert.Len(t, w.msgs, 1) {
+			assert.Equal(t, []byte(alb.ID.String()), w.msgs[0].Key)
+			assert.Equal(t, wantHeaders, w.msgs[0].Headers)
+			var got events.Envelope
+			assert.Nil(t, json.Unmarshal(w.msgs[0].Value, &got))
+			assert.Equal(t, env.ID, got.ID)
+			assert.JSONEq(t, string(env.Data), string(got.Data))
+		}
+	})
+
+	t.Run("avro", func(t *testing.T) {
+		w := &writerSpy{}
+		p, err := kafkapub.New(w, kafkapub.Avro)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = p.Publish(context.Background(), env)
+
+		assert.Nil(t, err)
+		if assert.Len(t, w.msgs, 1) {
+			assert.Equal(t, []byte(alb.ID.String()), w.msgs[0].Key)
+			assert.Equal(t, wantHeaders, w.msgs[0].Headers)
+			var got map[string]any
+			assert.Nil(t, avro.Unmarshal(avro.MustParse(kafkapub.AvroSchema), w.msgs[0].Value, &got))
+			assert.Equal(t, env.ID.String(), got["id"])
+			assert.Equal(t, "album.updated", got["type"])
+			gotAlbum := got["album"].(map[string]any)
+			assert.Equal(t, "Anathema", gotAlbum["title"])
+			assert.Equal(t, int64(1234), gotAlbum["price"])
+			assert.Equal(t, "EUR", gotAlbum["currency"])
+			assert.Equal(t, "acme", gotAlbum["tenant_id"])
+			assert.Equal(t, []any{
+				map[string]any{"field": "price", "old": "123", "new": "1234"},
+			}, got["changes"])
+		}
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		dummyErr := errors.New("dummy error")
+		p, err := kafkapub.New(&writerSpy{err: dummyErr}, kafkapub.JSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = p.Publish(context.Background(), env)
+
+		assert.ErrorIs(t, err, dummyErr)
+	})
+}
@@ -0,0 +1,123 @@
+package catalog
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+// CachedMetadataProvider is a MetadataProvider that keeps the most recently
+// searched releases in memory, so that repeated searches for the same album do
+// not call the underlying provider until they expire.
+type CachedMetadataProvider struct {
+	MetadataProvider
+
+	size  int
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *releasesCacheEntry, most recently used first
+}
+
+type releasesCacheEntry struct {
+	key       string
+	releases  []Release
+	expiresAt time.Time
+}
+
+// NewCachedMetadataProvider returns a new CachedMetadataProvider that caches
+// the releases of up to size searches of provider for ttl each.
+func NewCachedMetadataProvider(provider MetadataProvider, size int, ttl time.Duration) *CachedMetadataProvider {
+	return &CachedMetadataProvider{
+		MetadataProvider: provider,
+		size:             size,
+		ttl:              ttl,
+		clock:            clock.System,
+		entries:          make(map[string]*list.Element, size),
+		lru:              list.New(),
+	}
+}
+
+// SearchReleases makes CachedMetadataProvider implement MetadataProvider. The
+// searches are cached by artist and title, ignoring case. Failed searches are
+// not cached.
+func (p *CachedMetadataProvider) SearchReleases(ctx context.Context, artist, title string) ([]Release, error) {
+	key := strings.ToLower(artist) + "\x00" + strings.ToLower(title)
+	p.mu.Lock()
+	if elem, ok := p.entries[key]; ok {
+		entry := elem.Value.(*releasesCacheEntry)
+		if p.clock.Now().Before(entry.expiresAt) {
+			p.lru.MoveToFront(elem)
+			p.mu.Unlock()
+			return entry.releases, nil
+		}
+		p.lru.Remove(elem)
+		delete(p.entries, key)
+	}
+	p.mu.Unlock()
+
+	releases, err := p.MetadataProvider.SearchReleases(ctx, artist, title)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if elem, ok := p.entries[key]; ok {
+		// A concurrent search cached the releases first.
+		p.lru.Remove(elem)
+	}
+	p.entries[key] = p.lru.PushFront(&releasesCacheEntry{
+		key:       key,
+		releases:  releases,
+		expiresAt: p.clock.Now().Add(p.ttl),
+	})
+	for p.lru.Len() > p.size {
+		oldest := p.lru.Back()
+		p.lru.Remove(oldest)
+		delete(p.entries, oldest.Value.(*releasesCacheEntry).key)
+	}
+	return releases, nil
+}
+
+// rateLimitedMetadataProvider is a MetadataProvider whose searches wait for
+// the tokens of a RateLimiter.
+type rateLimitedMetadataProvider struct {
+	MetadataProvider
+
+	limiter RateLimiter
+}
+
+// NewRateLimitedMetadataProvider returns a MetadataProvider that searches
+// provider at the rate allowed by limiter to the key of the name of provider,
+// waiting for a token before each search, so that the rate limits of the
+// external database are honored.
+func NewRateLimitedMetadataProvider(provider MetadataProvider, limiter RateLimiter) MetadataProvider {
+	return &rateLimitedMetadataProvider{MetadataProvider: provider, limiter: limiter}
+}
+
+func (p *rateLimitedMetadataProvider) SearchReleases(ctx context.Context, artist, title string) ([]Release, error) {
+	for {
+		ok, retryAfter, err := p.limiter.Allow(ctx, p.Name())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	return p.MetadataProvider.SearchReleases(ctx, artist, title)
+}
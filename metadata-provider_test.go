@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+type metadataProviderSpy struct {
+	searchReleases func(ctx context.Context, artist, title string) ([]Release, error)
+	searches       int
+}
+
+func (spy *metadataProviderSpy) Name() string {
+	return "spy"
+}
+
+func (spy *metadataProviderSpy) SearchReleases(ctx context.Context, artist, title string) ([]Release, error) {
+	spy.searches++
+	return spy.searchReleases(ctx, artist, title)
+}
+
+func TestReleaseYear(t *testing.T) {
+	tests := map[string]int{
+		"":           0,
+		"1997":       1997,
+		"1997-05":    1997,
+		"1997-05-21": 1997,
+		"97":         0,
+		"unknown":    0,
+	}
+	for date, want := range tests {
+		t.Run(date, func(t *testing.T) {
+			assert.Equal(t, want, Release{Date: date}.Year())
+		})
+	}
+}
+
+func TestCachedMetadataProvider(t *testing.T) {
+	releases := []Release{{ID: "1234", Title: "Anathema", Artist: "Judgement"}}
+	newProvider := func() (*metadataProviderSpy, *CachedMetadataProvider, *clock.Fake) {
+		spy := &metadataProviderSpy{
+			searchReleases: func(ctx context.Context, artist, title string) ([]Release, error) {
+				return releases, nil
+			},
+		}
+		cached := NewCachedMetadataProvider(spy, 2, time.Minute)
+		fake := clock.NewFake(time.Date(2024, 8, 29, 0, 0, 0, 0, time.UTC))
+		cached.clock = fake
+		return spy, cached, fake
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		spy, cached, _ := newProvider()
+
+		got1, err1 := cached.SearchReleases(context.Background(), "Judgement", "Anathema")
+		got2, err2 := cached.SearchReleases(context.Background(), "JUDGEMENT", "anathema")
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, releases, got1)
+		assert.Equal(t, releases, got2)
+		assert.Equal(t, 1, spy.searches)
+		assert.Equal(t, "spy", cached.Name())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		spy, cached, fake := newProvider()
+
+		cached.SearchReleases(context.Background(), "Judgement", "Anathema")
+		fake.Advance(time.Minute)
+		cached.SearchReleases(context.Background(), "Judgement", "Anathema")
+
+		assert.Equal(t, 2, spy.searches)
+	})
+
+	t.Run("evicted", func(t *testing.T) {
+		spy, cached, _ := newProvider()
+
+		cached.SearchReleases(context.Background(), "Judgement", "Anathema")
+		cached.SearchReleases(context.Background(), "Black Alien", "Babylon By Gus")
+		cached.SearchReleases(context.Background(), "Sabotage", "Rap é Compromisso")
+		cached.SearchReleases(context.Background(), "Judgement", "Anathema")
+
+		assert.Equal(t, 4, spy.searches)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		spy, cached, _ := newProvider()
+		dummyErr := errors.New("dummy error")
+		spy.searchReleases = func(ctx context.Context, artist, title string) ([]Release, error) {
+			return nil, dummyErr
+		}
+
+		_, err1 := cached.SearchReleases(context.Background(), "Judgement", "Anathema")
+		_, err2 := cached.SearchReleases(context.Background(), "Judgement", "Anathema")
+
+		assert.ErrorIs(t, err1, dummyErr)
+		assert.ErrorIs(t, err2, dummyErr)
+		assert.Equal(t, 2, spy.searches)
+	})
+}
+
+func TestRateLimitedMetadataProvider(t *testing.T) {
+	spy := &metadataProviderSpy{
+		searchReleases: func(ctx context.Context, artist, title string) ([]Release, error) {
+			return nil, nil
+		},
+	}
+	var keys []string
+	allowed := false
+	limiter := rateLimiterFunc(func(ctx context.Context, key string) (bool, time.Duration, error) {
+		keys = append(keys, key)
+		if allowed {
+			return true, 0, nil
+		}
+		allowed = true
+		return false, time.Millisecond, nil
+	})
+	provider := NewRateLimitedMetadataProvider(spy, limiter)
+
+	_, err := provider.SearchReleases(context.Background(), "Judgement", "Anathema")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"spy", "spy"}, keys)
+	assert.Equal(t, 1, spy.searches)
+
+	t.Run("canceled", func(t *testing.T) {
+		limiter := rateLimiterFunc(func(ctx context.Context, key string) (bool, time.Duration, error) {
+			return false, time.Hour, nil
+		})
+		provider := NewRateLimitedMetadataProvider(spy, limiter)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := provider.SearchReleases(ctx, "Judgement", "Anathema")
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,193 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/clock"
+	"github.com/jhtohru/go-album-catalog/internal/pgdb"
+)
+
+// Release is a release of an album found by a MetadataProvider.
+type Release struct {
+	// ID identifies the release in its MetadataProvider.
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	// Date is when the release was released, as precisely as known: a year,
+	// a year and a month, or a full date, such as "1997", "1997-05" or
+	// "1997-05-21". It is empty if unknown.
+	Date        string   `json:"date,omitempty"`
+	Formats     []string `json:"formats"`
+	Genres      []string `json:"genres"`
+	CoverArtURL string   `json:"cover_art_url,omitempty"`
+}
+
+// Year returns the year r was released, or zero if unknown.
+func (r Release) Year() int {
+	if len(r.Date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(r.Date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// MetadataProvider searches for the releases of albums in an external music
+// database, such as Discogs.
+//
+// Implementations must be safe for concurrent use.
+type MetadataProvider interface {
+	// Name returns the name of the provider, such as "discogs".
+	Name() string
+	// SearchReleases returns the releases of the album titled title by
+	// artist, most relevant first. It returns no releases, and no error, if
+	// none is found.
+	SearchReleases(ctx context.Context, artist, title string) ([]Release, error)
+}
+
+// AlbumMetadata is the metadata of an album found by a MetadataProvider.
+type AlbumMetadata struct {
+	// Source is the name of the MetadataProvider the metadata was found by.
+	Source string `json:"source"`
+	// ExternalID identifies the release of the album in its Source.
+	ExternalID  string    `json:"external_id"`
+	Year        int       `json:"year,omitempty"`
+	Genres      []string  `json:"genres"`
+	CoverArtURL string    `json:"cover_art_url,omitempty"`
+	EnrichedAt  time.Time `json:"enriched_at"`
+}
+
+// MetadataStorage represents an album metadata storage, keeping the metadata
+// of each album found by each MetadataProvider.
+//
+// As an AlbumStorage does, a MetadataStorage only operates on the metadata of
+// the albums of the tenant its context is scoped to by NewTenantContext.
+type MetadataStorage interface {
+	// Save saves md as the metadata of the album identified by albumID found
+	// by md.Source, replacing the metadata previously found by it. It returns
+	// ErrAlbumNotFound if there is no such album.
+	Save(ctx context.Context, albumID uuid.UUID, md AlbumMetadata) error
+	// FindAll finds the metadata of the album identified by albumID, ordered
+	// by source.
+	FindAll(ctx context.Context, albumID uuid.UUID) ([]AlbumMetadata, error)
+}
+
+type pgMetadataStorage struct {
+	queries *pgdb.Queries
+}
+
+// NewPostgresMetadataStorage returns a new MetadataStorage that uses Postgres
+// to manage data.
+func NewPostgresMetadataStorage(db *sql.DB) MetadataStorage {
+	return &pgMetadataStorage{queries: pgdb.New(db)}
+}
+
+func (s *pgMetadataStorage) Save(ctx context.Context, albumID uuid.UUID, md AlbumMetadata) error {
+	rowsAffected, err := s.queries.UpsertAlbumMetadata(ctx, pgdb.UpsertAlbumMetadataParams{
+		Source:      md.Source,
+		ExternalID:  md.ExternalID,
+		Year:        sql.NullInt32{Int32: int32(md.Year), Valid: md.Year != 0},
+		Genres:      md.Genres,
+		CoverArtUrl: md.CoverArtURL,
+		EnrichedAt:  md.EnrichedAt,
+		AlbumID:     albumID,
+		TenantID:    TenantFromContext(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
+
+func (s *pgMetadataStorage) FindAll(ctx context.Context, albumID uuid.UUID) ([]AlbumMetadata, error) {
+	rows, err := s.queries.FindAlbumMetadata(ctx, pgdb.FindAlbumMetadataParams{
+		AlbumID:  albumID,
+		TenantID: TenantFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+	mds := make([]AlbumMetadata, len(rows))
+	for i, row := range rows {
+		mds[i] = AlbumMetadata{
+			Source:      row.Source,
+			ExternalID:  row.ExternalID,
+			Year:        int(row.Year.Int32),
+			Genres:      row.Genres,
+			CoverArtURL: row.CoverArtUrl,
+			EnrichedAt:  row.EnrichedAt.UTC(),
+		}
+	}
+	return mds, nil
+}
+
+// ErrReleaseNotFound is returned by a MetadataEnricher when no release of an
+// album is found by its MetadataProvider.
+var ErrReleaseNotFound = errors.New("release not found")
+
+// ErrMetadataProviderFailed is returned by a MetadataEnricher, wrapping the
+// error of its MetadataProvider, when the provider fails to search for the
+// releases of an album.
+var ErrMetadataProviderFailed = errors.New("metadata provider failed")
+
+// MetadataEnricher backfills the metadata of albums with the one of their most
+// relevant release found by a MetadataProvider, saving it into a
+// MetadataStorage.
+type MetadataEnricher struct {
+	provider MetadataProvider
+	storage  MetadataStorage
+	clock    clock.Clock
+}
+
+// NewMetadataEnricher returns a new MetadataEnricher that enriches the albums
+// with the releases found by provider, saving their metadata into storage.
+func NewMetadataEnricher(provider MetadataProvider, storage MetadataStorage) *MetadataEnricher {
+	return &MetadataEnricher{provider: provider, storage: storage, clock: clock.System}
+}
+
+// Enrich searches for the releases of alb, saving the metadata of the most
+// relevant one as the metadata of alb and returning it. It returns
+// ErrReleaseNotFound if no release is found, and an error wrapping
+// ErrMetadataProviderFailed if the search fails.
+func (e *MetadataEnricher) Enrich(ctx context.Context, alb Album) (AlbumMetadata, error) {
+	releases, err := e.provider.SearchReleases(ctx, alb.Artist, alb.Title)
+	if err != nil {
+		return AlbumMetadata{}, fmt.Errorf("%w: searching %s releases: %w", ErrMetadataProviderFailed, e.provider.Name(), err)
+	}
+	if len(releases) == 0 {
+		return AlbumMetadata{}, ErrReleaseNotFound
+	}
+	release := releases[0]
+	md := AlbumMetadata{
+		Source:      e.provider.Name(),
+		ExternalID:  release.ID,
+		Year:        release.Year(),
+		Genres:      release.Genres,
+		CoverArtURL: release.CoverArtURL,
+		EnrichedAt:  e.clock.Now().UTC(),
+	}
+	if md.Genres == nil {
+		md.Genres = []string{}
+	}
+	if err := e.storage.Save(ctx, alb.ID, md); err != nil {
+		return AlbumMetadata{}, err
+	}
+	return md, nil
+}
+
+// Metadata returns the metadata of the album identified by albumID.
+func (e *MetadataEnricher) Metadata(ctx context.Context, albumID uuid.UUID) ([]AlbumMetadata, error) {
+	return e.storage.FindAll(ctx, albumID)
+}
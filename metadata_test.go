@@ -0,0 +1,61 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+)
+
+func TestPostgresMetadataStorage(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresMetadataStorage(db)
+	ctx := context.Background()
+	alb := randomAlbum()
+	insertAlbums(t, db, alb)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	discogs := catalog.AlbumMetadata{
+		Source:      "discogs",
+		ExternalID:  "1234",
+		Year:        1997,
+		Genres:      []string{"Hip Hop"},
+		CoverArtURL: "https://i.discogs.com/1234.jpg",
+		EnrichedAt:  now,
+	}
+	musicBrainz := catalog.AlbumMetadata{
+		Source:     "musicbrainz",
+		ExternalID: "5678",
+		Genres:     []string{},
+		EnrichedAt: now,
+	}
+
+	assert.Nil(t, storage.Save(ctx, alb.ID, musicBrainz))
+	assert.Nil(t, storage.Save(ctx, alb.ID, discogs))
+
+	mds, err := storage.FindAll(ctx, alb.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []catalog.AlbumMetadata{discogs, musicBrainz}, mds)
+
+	// Saving metadata of the same source replaces it.
+	discogs.ExternalID = "4321"
+	discogs.Year = 0
+	assert.Nil(t, storage.Save(ctx, alb.ID, discogs))
+	mds, err = storage.FindAll(ctx, alb.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []catalog.AlbumMetadata{discogs, musicBrainz}, mds)
+
+	// Other tenants neither find nor save the metadata of the album.
+	acmeCtx := catalog.NewTenantContext(ctx, "acme")
+	mds, err = storage.FindAll(acmeCtx, alb.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, mds)
+	assert.ErrorIs(t, storage.Save(acmeCtx, alb.ID, discogs), catalog.ErrAlbumNotFound)
+	assert.ErrorIs(t, storage.Save(ctx, uuid.New(), discogs), catalog.ErrAlbumNotFound)
+}
@@ -0,0 +1,51 @@
+package catalog
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPMetrics are the Prometheus metrics of the requests served by the
+// server.
+type HTTPMetrics struct {
+	requestDuration *prometheus.HistogramVec
+}
+
+// NewHTTPMetrics returns new HTTPMetrics registered into registerer.
+func NewHTTPMetrics(registerer prometheus.Registerer) *HTTPMetrics {
+	requestDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "catalog",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests by route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"pattern", "status"})
+	registerer.MustRegister(requestDuration)
+	return &HTTPMetrics{requestDuration: requestDuration}
+}
+
+// observeLatency returns an http.Handler that passes requests to next and
+// records their latency into the histogram of the route registered with
+// pattern. If the request is traced, its trace ID is attached to the
+// observation as an exemplar.
+func observeLatency(metrics *HTTPMetrics, pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		observer := metrics.requestDuration.WithLabelValues(pattern, strconv.Itoa(rec.statusCode))
+		elapsed := time.Since(start).Seconds()
+		spanContext := trace.SpanContextFromContext(r.Context())
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && spanContext.IsSampled() {
+			exemplarObserver.ObserveWithExemplar(elapsed, prometheus.Labels{
+				"trace_id": spanContext.TraceID().String(),
+			})
+			return
+		}
+		observer.Observe(elapsed)
+	})
+}
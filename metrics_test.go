@@ -0,0 +1,72 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestObserveLatency(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodeMessage(w, r, http.StatusNotFound, "album not found")
+	})
+	traceID := trace.TraceID{0x01, 0x02, 0x03}
+	tests := map[string]struct {
+		ctx          context.Context
+		exemplarWant bool
+	}{
+		"untraced request": {
+			ctx: context.Background(),
+		},
+		"traced request": {
+			ctx: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     trace.SpanID{0x01},
+				TraceFlags: trace.FlagsSampled,
+			})),
+			exemplarWant: true,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			handler := observeLatency(NewHTTPMetrics(registry), "GET /albums/{album_id}", next)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequestWithContext(test.ctx, http.MethodGet, "/albums/1", nil)
+
+			handler.ServeHTTP(rec, req)
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !assert.Len(t, families, 1) || !assert.Len(t, families[0].Metric, 1) {
+				return
+			}
+			metric := families[0].Metric[0]
+			labels := make(map[string]string)
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			assert.Equal(t, "catalog_http_request_duration_seconds", families[0].GetName())
+			assert.Equal(t, map[string]string{"pattern": "GET /albums/{album_id}", "status": "404"}, labels)
+			assert.Equal(t, uint64(1), metric.Histogram.GetSampleCount())
+			var exemplarTraceIDs []string
+			for _, bucket := range metric.Histogram.Bucket {
+				if exemplar := bucket.GetExemplar(); exemplar != nil {
+					exemplarTraceIDs = append(exemplarTraceIDs, exemplar.Label[0].GetValue())
+				}
+			}
+			if test.exemplarWant {
+				assert.Equal(t, []string{traceID.String()}, exemplarTraceIDs)
+			} else {
+				assert.Empty(t, exemplarTraceIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/jhtohru/go-album-catalog/internal/pgdb"
+)
+
+// migrationsFS holds the goose migrations of the database.
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// NewMigrationProvider returns a goose.Provider that migrates db with the
+// migrations of the application.
+func NewMigrationProvider(db *sql.DB) (*goose.Provider, error) {
+	migrations, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, db, migrations)
+}
+
+// MigrateDB migrates db up to the latest migration.
+func MigrateDB(ctx context.Context, db *sql.DB) error {
+	provider, err := NewMigrationProvider(db)
+	if err != nil {
+		return err
+	}
+	_, err = provider.Up(ctx)
+	return err
+}
+
+// BackfillOriginalReleaseYears sets the original release year of the albums
+// with none to the earliest year of their metadata, batchSize albums at a
+// time, until there is no album left to backfill. It returns the quantity of
+// albums backfilled.
+func BackfillOriginalReleaseYears(ctx context.Context, db *sql.DB, batchSize int) (int64, error) {
+	queries := pgdb.New(db)
+	var total int64
+	for {
+		n, err := queries.BackfillOriginalReleaseYears(ctx, int32(batchSize))
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX album_title_trgm_index ON album USING gin (title gin_trgm_ops);
+
+CREATE INDEX album_artist_trgm_index ON album USING gin (artist gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX album_artist_trgm_index;
+
+DROP INDEX album_title_trgm_index;
+
+DROP EXTENSION pg_trgm;
+-- +goose StatementEnd
@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE album_audit (
+	id			bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	album_id	uuid NOT NULL,
+	actor		text,
+	action		text NOT NULL,
+	before		jsonb,
+	after		jsonb,
+	created_at	timestamp NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX album_audit_album_id_index ON album_audit (album_id, id);
+
+-- record_album_audit records the album row change that fired it. The album
+-- timestamps are stored in UTC without a time zone, so they are formatted
+-- explicitly as UTC times.
+CREATE FUNCTION record_album_audit() RETURNS trigger AS $$
+DECLARE
+	audited_id	uuid;
+	old_row		jsonb;
+	new_row		jsonb;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		audited_id := OLD.id;
+		old_row := to_jsonb(OLD) || jsonb_build_object(
+			'created_at', to_char(OLD.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(OLD.updated_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		audited_id := NEW.id;
+		new_row := to_jsonb(NEW) || jsonb_build_object(
+			'created_at', to_char(NEW.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(NEW.updated_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	INSERT INTO
+		album_audit (album_id, actor, action, before, after)
+	VALUES
+		(audited_id, nullif(current_setting('catalog.actor', true), ''), lower(TG_OP), old_row, new_row);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER album_audit_trigger
+	AFTER INSERT OR UPDATE OR DELETE ON album
+	FOR EACH ROW EXECUTE FUNCTION record_album_audit();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER album_audit_trigger ON album;
+
+DROP FUNCTION record_album_audit;
+
+DROP TABLE album_audit;
+-- +goose StatementEnd
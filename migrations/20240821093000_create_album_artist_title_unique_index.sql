@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE UNIQUE INDEX album_artist_title_index ON album (lower(artist), lower(title));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX album_artist_title_index;
+-- +goose StatementEnd
@@ -0,0 +1,94 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE album
+	ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC',
+	ALTER COLUMN updated_at TYPE timestamptz USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE album_audit
+	ALTER COLUMN created_at TYPE timestamptz USING created_at AT TIME ZONE 'UTC',
+	ALTER COLUMN created_at SET DEFAULT now();
+
+-- record_album_audit records the album row change that fired it. The album
+-- timestamps are formatted explicitly as UTC times, regardless of the session
+-- time zone. Every recorded change is queued into the outbox to be published.
+CREATE OR REPLACE FUNCTION record_album_audit() RETURNS trigger AS $$
+DECLARE
+	audited_id	uuid;
+	old_row		jsonb;
+	new_row		jsonb;
+	recorded_id	bigint;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		audited_id := OLD.id;
+		old_row := to_jsonb(OLD) || jsonb_build_object(
+			'created_at', to_char(OLD.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(OLD.updated_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		audited_id := NEW.id;
+		new_row := to_jsonb(NEW) || jsonb_build_object(
+			'created_at', to_char(NEW.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(NEW.updated_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	INSERT INTO
+		album_audit (album_id, actor, action, before, after)
+	VALUES
+		(audited_id, nullif(current_setting('catalog.actor', true), ''), lower(TG_OP), old_row, new_row)
+	RETURNING
+		id INTO recorded_id;
+	INSERT INTO
+		album_outbox (audit_id)
+	VALUES
+		(recorded_id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_album_audit() RETURNS trigger AS $$
+DECLARE
+	audited_id	uuid;
+	old_row		jsonb;
+	new_row		jsonb;
+	recorded_id	bigint;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		audited_id := OLD.id;
+		old_row := to_jsonb(OLD) || jsonb_build_object(
+			'created_at', to_char(OLD.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(OLD.updated_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		audited_id := NEW.id;
+		new_row := to_jsonb(NEW) || jsonb_build_object(
+			'created_at', to_char(NEW.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(NEW.updated_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	INSERT INTO
+		album_audit (album_id, actor, action, before, after)
+	VALUES
+		(audited_id, nullif(current_setting('catalog.actor', true), ''), lower(TG_OP), old_row, new_row)
+	RETURNING
+		id INTO recorded_id;
+	INSERT INTO
+		album_outbox (audit_id)
+	VALUES
+		(recorded_id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE album_audit
+	ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC',
+	ALTER COLUMN created_at SET DEFAULT (now() AT TIME ZONE 'UTC');
+
+ALTER TABLE album
+	ALTER COLUMN created_at TYPE timestamp USING created_at AT TIME ZONE 'UTC',
+	ALTER COLUMN updated_at TYPE timestamp USING updated_at AT TIME ZONE 'UTC';
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX album_lower_title_id_index ON album (lower(title), id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX album_lower_title_id_index;
+-- +goose StatementEnd
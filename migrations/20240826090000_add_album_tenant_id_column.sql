@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE album ADD COLUMN tenant_id text NOT NULL DEFAULT '';
+
+-- Albums are unique and ordered within the catalog of their tenant.
+DROP INDEX album_artist_title_index;
+CREATE UNIQUE INDEX album_artist_title_index ON album (tenant_id, lower(artist), lower(title));
+
+DROP INDEX album_lower_title_id_index;
+CREATE INDEX album_lower_title_id_index ON album (tenant_id, lower(title), id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX album_lower_title_id_index;
+CREATE INDEX album_lower_title_id_index ON album (lower(title), id);
+
+DROP INDEX album_artist_title_index;
+CREATE UNIQUE INDEX album_artist_title_index ON album (lower(artist), lower(title));
+
+ALTER TABLE album DROP COLUMN tenant_id;
+-- +goose StatementEnd
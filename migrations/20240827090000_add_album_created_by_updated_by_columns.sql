@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE album
+	ADD COLUMN created_by text NOT NULL DEFAULT '',
+	ADD COLUMN updated_by text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE album
+	DROP COLUMN updated_by,
+	DROP COLUMN created_by;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webhook_subscription (
+	id			uuid PRIMARY KEY,
+	tenant_id	text NOT NULL DEFAULT '',
+	url			text NOT NULL,
+	event_types	text[] NOT NULL,
+	secret		text NOT NULL,
+	created_at	timestamptz NOT NULL,
+	updated_at	timestamptz NOT NULL
+);
+
+CREATE INDEX webhook_subscription_tenant_id_index ON webhook_subscription (tenant_id, created_at, id);
+
+-- webhook_delivery queues the deliveries of the events to the subscriptions.
+-- Deliveries are retried until they are delivered or dead, which happens
+-- when they run out of attempts.
+CREATE TABLE webhook_delivery (
+	id				bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	subscription_id	uuid NOT NULL REFERENCES webhook_subscription (id) ON DELETE CASCADE,
+	event_id		uuid NOT NULL,
+	payload			jsonb NOT NULL,
+	attempts		integer NOT NULL DEFAULT 0,
+	next_attempt_at	timestamptz NOT NULL DEFAULT now(),
+	last_error		text,
+	delivered_at	timestamptz,
+	dead_at			timestamptz,
+	UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX webhook_delivery_pending_index ON webhook_delivery (next_attempt_at)
+	WHERE delivered_at IS NULL AND dead_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_delivery;
+DROP TABLE webhook_subscription;
+-- +goose StatementEnd
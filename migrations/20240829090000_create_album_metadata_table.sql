@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- album_metadata keeps the metadata of the albums found by each metadata
+-- provider, such as Discogs, along with the ID of the album in the provider.
+CREATE TABLE album_metadata (
+	album_id		uuid NOT NULL REFERENCES album (id) ON DELETE CASCADE,
+	source			text NOT NULL,
+	external_id		text NOT NULL,
+	year			integer,
+	genres			text[] NOT NULL,
+	cover_art_url	text NOT NULL,
+	enriched_at		timestamptz NOT NULL,
+	PRIMARY KEY (album_id, source)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE album_metadata;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE album
+	ADD CONSTRAINT album_title_not_empty_check CHECK (title <> ''),
+	ADD CONSTRAINT album_artist_not_empty_check CHECK (artist <> ''),
+	ADD CONSTRAINT album_price_not_negative_check CHECK (price >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE album
+	DROP CONSTRAINT album_price_not_negative_check,
+	DROP CONSTRAINT album_artist_not_empty_check,
+	DROP CONSTRAINT album_title_not_empty_check;
+-- +goose StatementEnd
@@ -0,0 +1,109 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The prices were always whole cents of US dollars.
+ALTER TABLE album
+	ALTER COLUMN price TYPE bigint USING round(price)::bigint,
+	ADD COLUMN currency text NOT NULL DEFAULT 'USD',
+	ADD CONSTRAINT album_currency_check CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE album ALTER COLUMN currency DROP DEFAULT;
+
+-- The recorded album rows are priced the same as the albums of the API.
+UPDATE album_audit SET before = before || jsonb_build_object(
+	'price', jsonb_build_object('amount', round((before ->> 'price')::numeric), 'currency', 'USD')
+) WHERE jsonb_typeof(before -> 'price') = 'number';
+
+UPDATE album_audit SET after = after || jsonb_build_object(
+	'price', jsonb_build_object('amount', round((after ->> 'price')::numeric), 'currency', 'USD')
+) WHERE jsonb_typeof(after -> 'price') = 'number';
+
+-- record_album_audit records the album row change that fired it. The album
+-- timestamps are formatted explicitly as UTC times, regardless of the session
+-- time zone, and the price is recorded along with its currency. Every recorded
+-- change is queued into the outbox to be published.
+CREATE OR REPLACE FUNCTION record_album_audit() RETURNS trigger AS $$
+DECLARE
+	audited_id	uuid;
+	old_row		jsonb;
+	new_row		jsonb;
+	recorded_id	bigint;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		audited_id := OLD.id;
+		old_row := (to_jsonb(OLD) - 'currency') || jsonb_build_object(
+			'price', jsonb_build_object('amount', OLD.price, 'currency', OLD.currency),
+			'created_at', to_char(OLD.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(OLD.updated_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		audited_id := NEW.id;
+		new_row := (to_jsonb(NEW) - 'currency') || jsonb_build_object(
+			'price', jsonb_build_object('amount', NEW.price, 'currency', NEW.currency),
+			'created_at', to_char(NEW.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(NEW.updated_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	INSERT INTO
+		album_audit (album_id, actor, action, before, after)
+	VALUES
+		(audited_id, nullif(current_setting('catalog.actor', true), ''), lower(TG_OP), old_row, new_row)
+	RETURNING
+		id INTO recorded_id;
+	INSERT INTO
+		album_outbox (audit_id)
+	VALUES
+		(recorded_id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_album_audit() RETURNS trigger AS $$
+DECLARE
+	audited_id	uuid;
+	old_row		jsonb;
+	new_row		jsonb;
+	recorded_id	bigint;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		audited_id := OLD.id;
+		old_row := to_jsonb(OLD) || jsonb_build_object(
+			'created_at', to_char(OLD.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(OLD.updated_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		audited_id := NEW.id;
+		new_row := to_jsonb(NEW) || jsonb_build_object(
+			'created_at', to_char(NEW.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+			'updated_at', to_char(NEW.updated_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+		);
+	END IF;
+	INSERT INTO
+		album_audit (album_id, actor, action, before, after)
+	VALUES
+		(audited_id, nullif(current_setting('catalog.actor', true), ''), lower(TG_OP), old_row, new_row)
+	RETURNING
+		id INTO recorded_id;
+	INSERT INTO
+		album_outbox (audit_id)
+	VALUES
+		(recorded_id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE album_audit SET after = after || jsonb_build_object('price', after -> 'price' -> 'amount')
+WHERE jsonb_typeof(after -> 'price') = 'object';
+
+UPDATE album_audit SET before = before || jsonb_build_object('price', before -> 'price' -> 'amount')
+WHERE jsonb_typeof(before -> 'price') = 'object';
+
+ALTER TABLE album
+	DROP CONSTRAINT album_currency_check,
+	DROP COLUMN currency,
+	ALTER COLUMN price TYPE double precision;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- album_price keeps the prices of the albums in currencies other than the one
+-- of their default price, kept by the album table.
+CREATE TABLE album_price (
+	album_id	uuid NOT NULL REFERENCES album (id) ON DELETE CASCADE,
+	currency	text NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+	amount		bigint NOT NULL CHECK (amount >= 0),
+	PRIMARY KEY (album_id, currency)
+);
+
+CREATE INDEX album_price_currency_index ON album_price (currency, album_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE album_price;
+-- +goose StatementEnd
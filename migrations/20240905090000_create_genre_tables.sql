@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- genre keeps the genres of the catalog of each tenant, identified within it
+-- by their slug.
+CREATE TABLE genre (
+	tenant_id	text NOT NULL DEFAULT '',
+	slug		text NOT NULL CHECK (slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$'),
+	name		text NOT NULL CHECK (name <> ''),
+	created_at	timestamptz NOT NULL,
+	PRIMARY KEY (tenant_id, slug)
+);
+
+-- album_genre assigns the genres to the albums of the same tenant.
+CREATE TABLE album_genre (
+	album_id	uuid NOT NULL REFERENCES album (id) ON DELETE CASCADE,
+	tenant_id	text NOT NULL,
+	genre_slug	text NOT NULL,
+	PRIMARY KEY (album_id, genre_slug),
+	FOREIGN KEY (tenant_id, genre_slug) REFERENCES genre (tenant_id, slug) ON DELETE CASCADE
+);
+
+CREATE INDEX album_genre_genre_index ON album_genre (tenant_id, genre_slug, album_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE album_genre;
+DROP TABLE genre;
+-- +goose StatementEnd
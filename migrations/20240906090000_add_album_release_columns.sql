@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The release years of the existing albums are backfilled from their metadata
+-- by the backfill-release-years migrate command, in batches, instead of here,
+-- so as not to lock the whole album table at once.
+ALTER TABLE album
+	ADD COLUMN release_date date,
+	ADD COLUMN original_release_year integer CHECK (original_release_year > 0);
+
+CREATE INDEX album_release_date_index ON album (tenant_id, release_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX album_release_date_index;
+
+ALTER TABLE album
+	DROP COLUMN original_release_year,
+	DROP COLUMN release_date;
+-- +goose StatementEnd
@@ -0,0 +1,63 @@
+// Package money provides the Money the albums of the catalog are priced in.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Money is an amount of a currency, in its minor units, such as cents.
+//
+// Its JSON representation is {"amount": 1234, "currency": "USD"}. A bare
+// integer, the representation of the prices before they had a currency, is
+// decoded as an amount without a currency.
+type Money struct {
+	AmountMinor int64  `json:"amount"`
+	Currency    string `json:"currency"`
+}
+
+// New returns the Money of amountMinor in the minor units of currency.
+func New(amountMinor int64, currency string) Money {
+	return Money{AmountMinor: amountMinor, Currency: currency}
+}
+
+// String returns m as its amount in minor units followed by its currency, such
+// as "1234 USD".
+func (m Money) String() string {
+	if m.Currency == "" {
+		return strconv.FormatInt(m.AmountMinor, 10)
+	}
+	return strconv.FormatInt(m.AmountMinor, 10) + " " + m.Currency
+}
+
+// UnmarshalJSON decodes m from its JSON representation or from a bare integer
+// amount.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' && !bytes.Equal(data, []byte("null")) {
+		var amount int64
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return fmt.Errorf("decoding money amount: %w", err)
+		}
+		*m = Money{AmountMinor: amount}
+		return nil
+	}
+	type plain Money
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// ValidCurrency reports whether code is a well-formed ISO 4217 currency code,
+// three upper case letters.
+func ValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range []byte(code) {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(money.New(1234, "USD"))
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"amount": 1234, "currency": "USD"}`, string(data))
+
+	tests := map[string]struct {
+		data      string
+		moneyWant money.Money
+		wantErr   bool
+	}{
+		"object":         {data: `{"amount": 1234, "currency": "EUR"}`, moneyWant: money.New(1234, "EUR")},
+		"legacy integer": {data: ` 1234`, moneyWant: money.Money{AmountMinor: 1234}},
+		"null":           {data: `null`, moneyWant: money.Money{}},
+		"fraction":       {data: `12.34`, wantErr: true},
+		"string":         {data: `"12.34 USD"`, wantErr: true},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var m money.Money
+
+			err := json.Unmarshal([]byte(test.data), &m)
+
+			if test.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.moneyWant, m)
+		})
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "1234 USD", money.New(1234, "USD").String())
+	assert.Equal(t, "1234", money.Money{AmountMinor: 1234}.String())
+}
+
+func TestValidCurrency(t *testing.T) {
+	assert.True(t, money.ValidCurrency("USD"))
+	assert.False(t, money.ValidCurrency("usd"))
+	assert.False(t, money.ValidCurrency("US"))
+	assert.False(t, money.ValidCurrency("USDT"))
+	assert.False(t, money.ValidCurrency(""))
+}
@@ -0,0 +1,135 @@
+// Package musicbrainz provides a catalog.MetadataProvider that searches for
+// album releases in the MusicBrainz database through its web service.
+//
+// See https://musicbrainz.org/doc/MusicBrainz_API for the web service and its
+// rate limits.
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+)
+
+// DefaultBaseURL is the base URL of the MusicBrainz web service.
+const DefaultBaseURL = "https://musicbrainz.org/ws/2"
+
+// CoverArtArchiveURL is the base URL of the Cover Art Archive, which serves
+// the cover art of the MusicBrainz releases.
+const CoverArtArchiveURL = "https://coverartarchive.org"
+
+// userAgent identifies the requests to the MusicBrainz web service, which
+// requires a meaningful one.
+const userAgent = "go-album-catalog/1.0 ( https://github.com/jhtohru/go-album-catalog )"
+
+// maxResults is the maximum number of releases returned by a search.
+const maxResults = 10
+
+// Client is a catalog.MetadataProvider that searches for releases through the
+// MusicBrainz web service. The releases it returns are identified by their
+// MusicBrainz ID (MBID). It is safe for concurrent use.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a new Client that requests the MusicBrainz web service at
+// baseURL, usually DefaultBaseURL, through client.
+func New(baseURL string, client *http.Client) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+// Name makes Client implement catalog.MetadataProvider.
+func (c *Client) Name() string {
+	return "musicbrainz"
+}
+
+// searchResponse is the response of the release search.
+type searchResponse struct {
+	Releases []struct {
+		ID           string `json:"id"`
+		Title        string `json:"title"`
+		Date         string `json:"date"`
+		ArtistCredit []struct {
+			Name       string `json:"name"`
+			JoinPhrase string `json:"joinphrase"`
+		} `json:"artist-credit"`
+		Media []struct {
+			Format string `json:"format"`
+		} `json:"media"`
+		Tags []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+	} `json:"releases"`
+}
+
+// SearchReleases makes Client implement catalog.MetadataProvider. The genres
+// of the releases are their tags, which MusicBrainz curates as genres, and
+// their cover art URL is the one of their front cover in the Cover Art
+// Archive, which is not found if they have none.
+func (c *Client) SearchReleases(ctx context.Context, artist, title string) ([]catalog.Release, error) {
+	q := url.Values{
+		"query": []string{fmt.Sprintf("artist:%s AND release:%s", phrase(artist), phrase(title))},
+		"limit": []string{strconv.Itoa(maxResults)},
+		"fmt":   []string{"json"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/release?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var body searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding json: %w", err)
+	}
+	releases := make([]catalog.Release, len(body.Releases))
+	for i, r := range body.Releases {
+		var credit strings.Builder
+		for _, ac := range r.ArtistCredit {
+			credit.WriteString(ac.Name + ac.JoinPhrase)
+		}
+		formats := []string{}
+		for _, m := range r.Media {
+			if m.Format != "" {
+				formats = append(formats, m.Format)
+			}
+		}
+		genres := []string{}
+		for _, tag := range r.Tags {
+			genres = append(genres, tag.Name)
+		}
+		releases[i] = catalog.Release{
+			ID:          r.ID,
+			Title:       r.Title,
+			Artist:      credit.String(),
+			Date:        r.Date,
+			Formats:     formats,
+			Genres:      genres,
+			CoverArtURL: CoverArtArchiveURL + "/release/" + r.ID + "/front",
+		}
+	}
+	return releases, nil
+}
+
+// phrase returns s as a Lucene phrase query, matching its words in order.
+func phrase(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,84 @@
+package musicbrainz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/musicbrainz"
+)
+
+func TestClientSearchReleases(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/release", r.URL.Path)
+			assert.Equal(t, `artist:"Racionais MC's" AND release:"Sobrevivendo no \"Inferno\""`, r.URL.Query().Get("query"))
+			assert.Equal(t, "json", r.URL.Query().Get("fmt"))
+			assert.NotEmpty(t, r.Header.Get("User-Agent"))
+			w.Write([]byte(`{
+				"count": 2,
+				"releases": [
+					{
+						"id": "5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d",
+						"score": 100,
+						"title": "Sobrevivendo no Inferno",
+						"date": "1997-12-20",
+						"artist-credit": [{"name": "Racionais MC's", "joinphrase": ""}],
+						"media": [{"format": "CD"}, {"format": "CD"}],
+						"tags": [{"count": 1, "name": "hip hop"}]
+					},
+					{
+						"id": "0c7a2e7e-6f0a-4d0b-9d1e-2f2a1b3c4d5e",
+						"title": "Sobrevivendo no Inferno",
+						"artist-credit": [
+							{"name": "Racionais MC's", "joinphrase": " & "},
+							{"name": "Black Alien", "joinphrase": ""}
+						],
+						"media": [{}]
+					}
+				]
+			}`))
+		}))
+		defer api.Close()
+		client := musicbrainz.New(api.URL, api.Client())
+
+		releases, err := client.SearchReleases(context.Background(), "Racionais MC's", `Sobrevivendo no "Inferno"`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []catalog.Release{
+			{
+				ID:          "5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d",
+				Title:       "Sobrevivendo no Inferno",
+				Artist:      "Racionais MC's",
+				Date:        "1997-12-20",
+				Formats:     []string{"CD", "CD"},
+				Genres:      []string{"hip hop"},
+				CoverArtURL: "https://coverartarchive.org/release/5b1f8fbb-0a2f-4c3c-b1a2-3b7f8e0b0b1d/front",
+			},
+			{
+				ID:          "0c7a2e7e-6f0a-4d0b-9d1e-2f2a1b3c4d5e",
+				Title:       "Sobrevivendo no Inferno",
+				Artist:      "Racionais MC's & Black Alien",
+				Formats:     []string{},
+				Genres:      []string{},
+				CoverArtURL: "https://coverartarchive.org/release/0c7a2e7e-6f0a-4d0b-9d1e-2f2a1b3c4d5e/front",
+			},
+		}, releases)
+	})
+
+	t.Run("unexpected status", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer api.Close()
+		client := musicbrainz.New(api.URL, api.Client())
+
+		_, err := client.SearchReleases(context.Background(), "Racionais MC's", "Sobrevivendo no Inferno")
+
+		assert.EqualError(t, err, "unexpected status 503 Service Unavailable")
+	})
+}
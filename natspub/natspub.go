@@ -0,0 +1,59 @@
+// Package natspub provides a catalog.EventPublisher that publishes album
+// events to NATS JetStream.
+//
+// Each event is published as the JSON of its events.Envelope to the subject
+// made of a prefix and the type of the event, such as
+// catalog.events.album.created, so that consumers can subscribe to the types
+// they are interested in. The message carries the ID of the event in its
+// Nats-Msg-Id header, so that JetStream discards the events published more
+// than once within the duplicate window of the stream, and the type and schema
+// version of the event in its Event-Type and Event-Schema-Version headers.
+package natspub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+// JetStream publishes messages to JetStream. jetstream.JetStream implements it.
+type JetStream interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// Publisher is a catalog.EventPublisher that publishes album events to NATS
+// JetStream.
+type Publisher struct {
+	js            JetStream
+	subjectPrefix string
+}
+
+// New returns a new Publisher that publishes the events through js to the
+// subjects prefixed by subjectPrefix. The subjects must be captured by a
+// stream, such as one of subjectPrefix.> subjects, for the events to be
+// acknowledged.
+func New(js JetStream, subjectPrefix string) *Publisher {
+	return &Publisher{js: js, subjectPrefix: subjectPrefix}
+}
+
+// Publish makes Publisher implement catalog.EventPublisher. It returns once
+// the event is stored by the stream.
+func (p *Publisher) Publish(ctx context.Context, env events.Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("encoding %s event: %w", env.Type, err)
+	}
+	msg := nats.NewMsg(p.subjectPrefix + "." + string(env.Type))
+	msg.Data = data
+	msg.Header.Set(jetstream.MsgIDHeader, env.ID.String())
+	msg.Header.Set("Event-Type", string(env.Type))
+	msg.Header.Set("Event-Schema-Version", strconv.Itoa(env.SchemaVersion))
+	_, err = p.js.PublishMsg(ctx, msg)
+	return err
+}
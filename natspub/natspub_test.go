@@ -0,0 +1,68 @@
+package natspub_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/natspub"
+)
+
+type jetStreamSpy struct {
+	msgs []*nats.Msg
+	err  error
+}
+
+func (js *jetStreamSpy) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.msgs = append(js.msgs, msg)
+	if js.err != nil {
+		return nil, js.err
+	}
+	return &jetstream.PubAck{Stream: "ALBUMS", Sequence: uint64(len(js.msgs))}, nil
+}
+
+func TestPublisherPublish(t *testing.T) {
+	env, err := events.Wrap(uuid.New(), time.Now(), events.AlbumDeleted{
+		Album: events.Album{ID: uuid.New(), Title: "Anathema", Artist: "Judgement"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("happy path", func(t *testing.T) {
+		js := &jetStreamSpy{}
+		p := natspub.New(js, "catalog.events")
+
+		err := p.Publish(context.Background(), env)
+
+		assert.Nil(t, err)
+		if assert.Len(t, js.msgs, 1) {
+			msg := js.msgs[0]
+			assert.Equal(t, "catalog.events.album.deleted", msg.Subject)
+			assert.Equal(t, env.ID.String(), msg.Header.Get("Nats-Msg-Id"))
+			assert.Equal(t, "album.deleted", msg.Header.Get("Event-Type"))
+			assert.Equal(t, "2", msg.Header.Get("Event-Schema-Version"))
+			var got events.Envelope
+			assert.Nil(t, json.Unmarshal(msg.Data, &got))
+			assert.Equal(t, env.ID, got.ID)
+			assert.JSONEq(t, string(env.Data), string(got.Data))
+		}
+	})
+
+	t.Run("publish error", func(t *testing.T) {
+		dummyErr := errors.New("dummy error")
+		p := natspub.New(&jetStreamSpy{err: dummyErr}, "catalog.events")
+
+		err := p.Publish(context.Background(), env)
+
+		assert.ErrorIs(t, err, dummyErr)
+	})
+}
@@ -0,0 +1,181 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/jhtohru/go-album-catalog/events"
+)
+
+// EventPublisher publishes album domain events to downstream systems.
+type EventPublisher interface {
+	// Publish publishes env. An event may be published more than once, always
+	// with the same ID, so subscribers must be idempotent.
+	Publish(ctx context.Context, env events.Envelope) error
+}
+
+// EventPublisherFunc is an adapter to allow the use of ordinary functions as
+// EventPublishers.
+type EventPublisherFunc func(ctx context.Context, env events.Envelope) error
+
+// Publish makes EventPublisherFunc implement EventPublisher.
+func (f EventPublisherFunc) Publish(ctx context.Context, env events.Envelope) error {
+	return f(ctx, env)
+}
+
+// MultiEventPublisher returns an EventPublisher that publishes every event to
+// each of publishers, in order, stopping at the first one that fails.
+func MultiEventPublisher(publishers ...EventPublisher) EventPublisher {
+	return EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+		for _, p := range publishers {
+			if err := p.Publish(ctx, env); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// outboxBatchSize is the maximum number of events published by RelayOutbox.
+const outboxBatchSize = 100
+
+// RelayOutbox publishes up to 100 of the pending album change events queued
+// into the outbox of db to publisher, oldest first, and removes them from the
+// outbox. Events are only removed once published, so the ones that failed to
+// be published are published again by the next relay. It returns the number
+// of events published.
+func RelayOutbox(ctx context.Context, db *sql.DB, publisher EventPublisher) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	// Skip the events locked by concurrent relays, so that each event is
+	// published by a single relay at a time.
+	query := `
+		SELECT
+			o.audit_id, o.event_id, a.action, a.before, a.after, a.created_at
+		FROM
+			album_outbox o
+			JOIN album_audit a ON a.id = o.audit_id
+		ORDER BY
+			o.audit_id ASC
+		LIMIT
+			$1
+		FOR UPDATE OF o SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var (
+		auditIDs []int64
+		envs     []events.Envelope
+	)
+	for rows.Next() {
+		var (
+			auditID       int64
+			eventID       uuid.UUID
+			entry         AlbumAuditEntry
+			before, after []byte
+		)
+		if err := rows.Scan(&auditID, &eventID, &entry.Action, &before, &after, &entry.CreatedAt); err != nil {
+			return 0, err
+		}
+		if entry.Before, err = unmarshalAlbum(before); err != nil {
+			return 0, err
+		}
+		if entry.After, err = unmarshalAlbum(after); err != nil {
+			return 0, err
+		}
+		env, err := events.Wrap(eventID, entry.CreatedAt.UTC(), auditEvent(entry))
+		if err != nil {
+			return 0, err
+		}
+		auditIDs = append(auditIDs, auditID)
+		envs = append(envs, env)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+	var published int
+	for _, env := range envs {
+		if err = publisher.Publish(ctx, env); err != nil {
+			err = fmt.Errorf("publishing event %s: %w", env.ID, err)
+			break
+		}
+		published++
+	}
+	// Remove the published events even if publishing the others failed.
+	if published > 0 {
+		query = "DELETE FROM album_outbox WHERE audit_id = ANY($1)"
+		if _, err := tx.ExecContext(ctx, query, pq.Array(auditIDs[:published])); err != nil {
+			return 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+	}
+
+	return published, err
+}
+
+// RunOutboxRelay relays the outbox of db to publisher once every interval until
+// ctx is done, relaying again at once while there are pending events left.
+// Relay failures are reported to onError and do not stop the relays.
+func RunOutboxRelay(
+	ctx context.Context,
+	db *sql.DB,
+	publisher EventPublisher,
+	interval time.Duration,
+	onError func(error),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				published, err := RelayOutbox(ctx, db, publisher)
+				if err != nil {
+					onError(err)
+				}
+				if err != nil || published < outboxBatchSize {
+					break
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// auditEvent returns the album domain event of the change recorded by entry.
+func auditEvent(entry AlbumAuditEntry) events.Event {
+	switch {
+	case entry.Before == nil:
+		return events.AlbumCreated{Album: events.Album(*entry.After)}
+	case entry.After == nil:
+		return events.AlbumDeleted{Album: events.Album(*entry.Before)}
+	}
+	before, after := events.Album(*entry.Before), events.Album(*entry.After)
+	return events.AlbumUpdated{Album: after, Changes: events.Diff(before, after)}
+}
+
+// envelopeAlbum returns the album carried by the album event of env.
+func envelopeAlbum(env events.Envelope) (events.Album, error) {
+	var data struct {
+		Album events.Album `json:"album"`
+	}
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		return events.Album{}, fmt.Errorf("decoding %s event: %w", env.Type, err)
+	}
+	return data.Album, nil
+}
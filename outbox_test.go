@@ -0,0 +1,119 @@
+package catalog_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/internal/random"
+)
+
+func TestMultiEventPublisher(t *testing.T) {
+	var published []string
+	publisher := func(name string, err error) catalog.EventPublisher {
+		return catalog.EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+			published = append(published, name)
+			return err
+		})
+	}
+	multi := catalog.MultiEventPublisher(
+		publisher("first", nil),
+		publisher("second", fmt.Errorf("unexpected publish error")),
+		publisher("third", nil),
+	)
+
+	err := multi.Publish(context.Background(), events.Envelope{})
+
+	assert.ErrorContains(t, err, "unexpected publish error")
+	assert.Equal(t, []string{"first", "second"}, published)
+}
+
+func TestRelayOutbox(t *testing.T) {
+	t.Parallel()
+
+	t.Run("happy path", func(t *testing.T) {
+		db := postgresTest.CreateDBOrFailNow(t)
+		defer db.Close()
+		storage := catalog.NewPostgresAlbumStorage(db)
+		alb := randomAlbum()
+		if err := storage.Insert(context.Background(), alb); err != nil {
+			t.Fatal(err)
+		}
+		albUpdated := alb
+		albUpdated.Title = random.String(20)
+		if err := storage.Update(context.Background(), albUpdated); err != nil {
+			t.Fatal(err)
+		}
+		albUpdated.Version++
+		if err := storage.Remove(context.Background(), alb.ID); err != nil {
+			t.Fatal(err)
+		}
+		var published []events.Envelope
+		publisher := catalog.EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+			published = append(published, env)
+			return nil
+		})
+
+		n, err := catalog.RelayOutbox(context.Background(), db, publisher)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 3, n)
+		want := []events.Event{
+			events.AlbumCreated{Album: events.Album(alb)},
+			events.AlbumUpdated{
+				Album:   events.Album(albUpdated),
+				Changes: events.Diff(events.Album(alb), events.Album(albUpdated)),
+			},
+			events.AlbumDeleted{Album: events.Album(albUpdated)},
+		}
+		if assert.Len(t, published, len(want)) {
+			for i, e := range want {
+				dataWant, _ := json.Marshal(e)
+				assert.Equal(t, e.Type(), published[i].Type)
+				assert.JSONEq(t, string(dataWant), string(published[i].Data))
+			}
+		}
+
+		n, err = catalog.RelayOutbox(context.Background(), db, publisher)
+
+		assert.Nil(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("publish error", func(t *testing.T) {
+		db := postgresTest.CreateDBOrFailNow(t)
+		defer db.Close()
+		insertAlbums(t, db, randomAlbums(3)...)
+		var published []events.Envelope
+		publisher := catalog.EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+			if len(published) == 1 {
+				return fmt.Errorf("unexpected publish error")
+			}
+			published = append(published, env)
+			return nil
+		})
+
+		n, err := catalog.RelayOutbox(context.Background(), db, publisher)
+
+		assert.ErrorContains(t, err, "unexpected publish error")
+		assert.Equal(t, 1, n)
+
+		// The events that failed to be published are published again.
+		var republished []events.Envelope
+		publisher = catalog.EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+			republished = append(republished, env)
+			return nil
+		})
+
+		n, err = catalog.RelayOutbox(context.Background(), db, publisher)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, n)
+		assert.NotEqual(t, published[0].ID, republished[0].ID)
+	})
+}
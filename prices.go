@@ -0,0 +1,111 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/internal/pgdb"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// PriceStorage represents an album price list storage, keeping the prices of
+// each album in currencies other than the one of its default price, the Price
+// of the Album.
+//
+// As an AlbumStorage does, a PriceStorage only operates on the prices of the
+// albums of the tenant its context is scoped to by NewTenantContext.
+type PriceStorage interface {
+	// SetPrices replaces the price list of the album identified by albumID
+	// with prices, each in a different currency. It returns ErrAlbumNotFound
+	// if there is no such album.
+	SetPrices(ctx context.Context, albumID uuid.UUID, prices []money.Money) error
+	// FindPrices finds the price list of the album identified by albumID,
+	// ordered by currency.
+	FindPrices(ctx context.Context, albumID uuid.UUID) ([]money.Money, error)
+	// FindAllPricedIn finds the Albums priced in currency within offset and
+	// limit, either by their default price or by their price list, in the same
+	// order as AlbumStorage.FindAll. The Price of the Albums found is their
+	// price in currency, the default one if it is in currency. It returns no
+	// Albums, and no error, if none is priced in currency.
+	FindAllPricedIn(ctx context.Context, currency string, offset, limit int) ([]Album, error)
+}
+
+type pgPriceStorage struct {
+	db      *sql.DB
+	queries *pgdb.Queries
+}
+
+// NewPostgresPriceStorage returns a new PriceStorage that uses Postgres to
+// manage data.
+func NewPostgresPriceStorage(db *sql.DB) PriceStorage {
+	return &pgPriceStorage{db: db, queries: pgdb.New(db)}
+}
+
+func (s *pgPriceStorage) SetPrices(ctx context.Context, albumID uuid.UUID, prices []money.Money) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := s.queries.WithTx(tx)
+	exists, err := queries.AlbumExists(ctx, pgdb.AlbumExistsParams{ID: albumID, TenantID: TenantFromContext(ctx)})
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAlbumNotFound
+	}
+	if err := queries.DeleteAlbumPrices(ctx, albumID); err != nil {
+		return err
+	}
+	for _, price := range prices {
+		err := queries.InsertAlbumPrice(ctx, pgdb.InsertAlbumPriceParams{
+			AlbumID:  albumID,
+			Currency: price.Currency,
+			Amount:   price.AmountMinor,
+		})
+		// The album was removed since it was found.
+		if isPgError(err, foreignKeyViolation) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *pgPriceStorage) FindPrices(ctx context.Context, albumID uuid.UUID) ([]money.Money, error) {
+	rows, err := s.queries.FindAlbumPrices(ctx, pgdb.FindAlbumPricesParams{
+		AlbumID:  albumID,
+		TenantID: TenantFromContext(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+	prices := make([]money.Money, len(rows))
+	for i, row := range rows {
+		prices[i] = money.New(row.Amount, row.Currency)
+	}
+	return prices, nil
+}
+
+func (s *pgPriceStorage) FindAllPricedIn(ctx context.Context, currency string, offset, limit int) ([]Album, error) {
+	rows, err := s.queries.FindAlbumsPricedIn(ctx, pgdb.FindAlbumsPricedInParams{
+		Currency:   currency,
+		TenantID:   TenantFromContext(ctx),
+		PageOffset: int32(offset),
+		PageLimit:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	albs := make([]Album, len(rows))
+	for i, row := range rows {
+		albs[i] = albumFromRow(pgdb.Album(row))
+	}
+	return albs, nil
+}
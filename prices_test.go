@@ -0,0 +1,75 @@
+package catalog_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+func TestPostgresPriceStorage(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresPriceStorage(db)
+	ctx := context.Background()
+	albs := randomAlbums(3)
+	albs[2].Price = money.New(albs[2].Price.AmountMinor, "JPY")
+	insertAlbums(t, db, albs...)
+
+	assert.Nil(t, storage.SetPrices(ctx, albs[0].ID, []money.Money{money.New(4500, "JPY"), money.New(2999, "BRL")}))
+	assert.Nil(t, storage.SetPrices(ctx, albs[1].ID, []money.Money{money.New(1999, "BRL")}))
+
+	prices, err := storage.FindPrices(ctx, albs[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []money.Money{money.New(2999, "BRL"), money.New(4500, "JPY")}, prices)
+
+	// Setting the prices replaces the price list.
+	assert.Nil(t, storage.SetPrices(ctx, albs[0].ID, []money.Money{money.New(5000, "JPY")}))
+	prices, err = storage.FindPrices(ctx, albs[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []money.Money{money.New(5000, "JPY")}, prices)
+
+	// Albums are found priced in a currency either by their default price or
+	// by their price list.
+	found, err := storage.FindAllPricedIn(ctx, "JPY", 0, 10)
+	assert.Nil(t, err)
+	wantAlbs := []catalog.Album{albs[0], albs[2]}
+	wantAlbs[0].Price = money.New(5000, "JPY")
+	slices.SortFunc(wantAlbs, compareAlbums)
+	assert.Equal(t, wantAlbs, found)
+	found, err = storage.FindAllPricedIn(ctx, "JPY", 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, wantAlbs[1:], found)
+	found, err = storage.FindAllPricedIn(ctx, "GBP", 0, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+
+	// Other tenants neither find nor set the prices of the albums.
+	acmeCtx := catalog.NewTenantContext(ctx, "acme")
+	prices, err = storage.FindPrices(acmeCtx, albs[1].ID)
+	assert.Nil(t, err)
+	assert.Empty(t, prices)
+	found, err = storage.FindAllPricedIn(acmeCtx, "BRL", 0, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+	assert.ErrorIs(t, storage.SetPrices(acmeCtx, albs[1].ID, nil), catalog.ErrAlbumNotFound)
+	assert.ErrorIs(t, storage.SetPrices(ctx, uuid.New(), nil), catalog.ErrAlbumNotFound)
+}
+
+// compareAlbums compares a and b in the order the storages find them.
+func compareAlbums(a, b catalog.Album) int {
+	switch {
+	case albumLess(a, b):
+		return -1
+	case albumLess(b, a):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+// RateLimiter limits the rate of the requests of each client.
+//
+// Implementations sharing their limits between instances, such as ones backed
+// by Redis, must be safe for concurrent use.
+type RateLimiter interface {
+	// Allow takes a token of the bucket of the client identified by key,
+	// reporting whether there was one. If there was not, it also returns how
+	// long until there is.
+	Allow(ctx context.Context, key string) (ok bool, retryAfter time.Duration, err error)
+}
+
+// MemoryRateLimiter is a RateLimiter that keeps the token buckets of the
+// clients in memory, limiting each client of a single instance.
+type MemoryRateLimiter struct {
+	rate  float64
+	burst float64
+	clock clock.Clock
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// NewMemoryRateLimiter returns a new MemoryRateLimiter that refills the bucket
+// of each client with rate tokens per second, up to burst tokens.
+func NewMemoryRateLimiter(rate float64, burst int) *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		clock:   clock.System,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (l *MemoryRateLimiter) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, updatedAt: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updatedAt = now
+	if b.tokens < 1 {
+		retryAfter := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, retryAfter, nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// refill returns the tokens of b at now.
+func (l *MemoryRateLimiter) refill(b *tokenBucket, now time.Time) float64 {
+	return min(l.burst, b.tokens+now.Sub(b.updatedAt).Seconds()*l.rate)
+}
+
+// sweep removes the buckets refilled by now, which are the same as a new
+// bucket, at most once every time it takes to refill an empty bucket.
+func (l *MemoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep).Seconds() < l.burst/l.rate {
+		return
+	}
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// limitRate returns an http.Handler that passes to next the requests allowed
+// by limiter, and responds to the other ones with 429 Too Many Requests and a
+// Retry-After header. Authenticated requests are limited by the subject of
+// their Principal, while the other ones are limited by their client IP. If
+// limiter fails, the error is logged through logger and the request is
+// allowed.
+func limitRate(limiter RateLimiter, logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, retryAfter, err := limiter.Allow(r.Context(), rateLimitKey(r))
+		if err != nil {
+			logger.ErrorContext(r.Context(), "limiting request rate", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			encodeMessage(w, r, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey returns the key identifying the client of r to a RateLimiter.
+func rateLimitKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "subject:" + p.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
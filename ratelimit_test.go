@@ -0,0 +1,129 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+	"github.com/jhtohru/go-album-catalog/clock"
+)
+
+func TestMemoryRateLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 8, 26, 12, 0, 0, 0, time.UTC))
+	limiter := NewMemoryRateLimiter(2, 3)
+	limiter.clock = fake
+
+	for i := range 3 {
+		ok, _, err := limiter.Allow(ctx, "ip:192.0.2.1")
+		assert.Nil(t, err)
+		assert.True(t, ok, "request %d", i)
+	}
+	ok, retryAfter, err := limiter.Allow(ctx, "ip:192.0.2.1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Other clients have their own bucket.
+	ok, _, err = limiter.Allow(ctx, "ip:192.0.2.2")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// The bucket is refilled at the rate, up to the burst.
+	fake.Advance(500 * time.Millisecond)
+	ok, _, err = limiter.Allow(ctx, "ip:192.0.2.1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, retryAfter, err = limiter.Allow(ctx, "ip:192.0.2.1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Refilled buckets are swept.
+	fake.Advance(time.Hour)
+	ok, _, err = limiter.Allow(ctx, "ip:192.0.2.3")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Len(t, limiter.buckets, 1)
+}
+
+// rateLimiterFunc is an adapter to use a function as a RateLimiter.
+type rateLimiterFunc func(ctx context.Context, key string) (bool, time.Duration, error)
+
+func (f rateLimiterFunc) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return f(ctx, key)
+}
+
+func TestLimitRate(t *testing.T) {
+	type testCase struct {
+		principal        *auth.Principal
+		allowed          bool
+		retryAfter       time.Duration
+		limiterErr       error
+		keyWant          string
+		statusCodeWant   int
+		retryAfterWant   string
+		responseBodyWant string
+	}
+	tests := map[string]testCase{
+		"allowed by client ip": {
+			allowed:        true,
+			keyWant:        "ip:192.0.2.1",
+			statusCodeWant: http.StatusNoContent,
+		},
+		"allowed by subject": {
+			principal:      &auth.Principal{Subject: "jtohru"},
+			allowed:        true,
+			keyWant:        "subject:jtohru",
+			statusCodeWant: http.StatusNoContent,
+		},
+		"too many requests": {
+			retryAfter:       1500 * time.Millisecond,
+			keyWant:          "ip:192.0.2.1",
+			statusCodeWant:   http.StatusTooManyRequests,
+			retryAfterWant:   "2",
+			responseBodyWant: `{"message": "too many requests"}`,
+		},
+		"limiter error": {
+			limiterErr:     errors.New("redis is down"),
+			keyWant:        "ip:192.0.2.1",
+			statusCodeWant: http.StatusNoContent,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var key string
+			limiter := rateLimiterFunc(func(_ context.Context, k string) (bool, time.Duration, error) {
+				key = k
+				return test.allowed, test.retryAfter, test.limiterErr
+			})
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+			req.RemoteAddr = "192.0.2.1:51234"
+			if test.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), *test.principal))
+			}
+
+			limitRate(limiter, logger, next).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.keyWant, key)
+			assert.Equal(t, test.statusCodeWant, rec.Result().StatusCode)
+			assert.Equal(t, test.retryAfterWant, rec.Header().Get("Retry-After"))
+			if test.responseBodyWant != "" {
+				assert.JSONEq(t, test.responseBodyWant, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package catalog
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/jhtohru/go-album-catalog/health"
+)
+
+// DefaultDrainTimeout is the drain timeout of Run when its Config leaves it
+// zero.
+const DefaultDrainTimeout = 10 * time.Second
+
+// errShuttingDown is the readiness check error of a server shutting down.
+var errShuttingDown = errors.New("shutting down")
+
+// Config configures how Run runs and shuts down the servers of the catalog.
+type Config struct {
+	// Servers are the HTTP servers run, over TLS if they have a TLSConfig.
+	Servers []*http.Server
+	// Listeners are the listeners each of Servers is served on, such as Unix
+	// domain sockets or sockets passed by systemd. A server without listeners
+	// listens on its Addr.
+	Listeners map[*http.Server][]net.Listener
+	// GRPCServer, if not nil, is served on GRPCListener.
+	GRPCServer   *grpc.Server
+	GRPCListener net.Listener
+	// Readiness, if not nil, is registered a "shutdown" check that fails as
+	// soon as the servers start shutting down, so load balancers stop
+	// routing new requests to them.
+	Readiness *health.Checker
+	// DrainDelay is how long the servers keep accepting new requests after
+	// being marked unready, giving load balancers time to notice it.
+	DrainDelay time.Duration
+	// DrainTimeout is how long the in-flight requests are waited for after
+	// the servers stop accepting new ones, before their connections are
+	// closed. Zero means DefaultDrainTimeout.
+	DrainTimeout time.Duration
+	// Logger logs the lifecycle of the servers. Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Run runs the servers of cfg until ctx is done, the process receives an
+// interrupt or a SIGTERM, or any of them fails. The servers are then shut
+// down gracefully as cfg describes: marked unready at once, kept accepting
+// requests for the drain delay, and waited for their in-flight requests up to
+// the drain timeout. A second signal received while shutting down terminates
+// the process. Run returns the error of the server that failed, if any.
+func Run(ctx context.Context, cfg Config) error {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	drainTimeout := cfg.DrainTimeout
+	if drainTimeout == 0 {
+		drainTimeout = DefaultDrainTimeout
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var shuttingDown atomic.Bool
+	if cfg.Readiness != nil {
+		cfg.Readiness.Register("shutdown", func(context.Context) error {
+			if shuttingDown.Load() {
+				return errShuttingDown
+			}
+			return nil
+		})
+	}
+
+	type serving struct {
+		srv *http.Server
+		lis net.Listener
+		// tls is whether srv is served over TLS, which is decided before
+		// serving it, as serving sets up its TLSConfig for HTTP/2.
+		tls bool
+	}
+	var servings []serving
+	for _, srv := range cfg.Servers {
+		listeners := cfg.Listeners[srv]
+		if len(listeners) == 0 {
+			lis, err := net.Listen("tcp", cmp.Or(srv.Addr, ":http"))
+			if err != nil {
+				for _, s := range servings {
+					s.lis.Close()
+				}
+				return fmt.Errorf("listening on %s: %w", srv.Addr, err)
+			}
+			listeners = []net.Listener{lis}
+		}
+		for _, lis := range listeners {
+			servings = append(servings, serving{srv, lis, srv.TLSConfig != nil})
+		}
+	}
+
+	// Each server reports why it stopped serving each of its listeners,
+	// which is nil if it was shut down.
+	stopped := make(chan error, len(servings)+1)
+	for _, s := range servings {
+		go func() {
+			addr := s.lis.Addr().String()
+			logger.Info("serving http", "addr", addr, "network", s.lis.Addr().Network())
+			var err error
+			if s.tls {
+				err = s.srv.ServeTLS(s.lis, "", "")
+			} else {
+				err = s.srv.Serve(s.lis)
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			if err != nil {
+				err = fmt.Errorf("serving http on %s: %w", addr, err)
+			}
+			stopped <- err
+		}()
+	}
+	if cfg.GRPCServer != nil {
+		go func() {
+			logger.Info("serving grpc", "addr", cfg.GRPCListener.Addr().String())
+			err := cfg.GRPCServer.Serve(cfg.GRPCListener)
+			if err != nil {
+				err = fmt.Errorf("serving grpc on %s: %w", cfg.GRPCListener.Addr(), err)
+			}
+			stopped <- err
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-stopped:
+	}
+	// Restore the default behavior of the signals, so a second one
+	// terminates the process instead of waiting for the drain.
+	stop()
+	shuttingDown.Store(true)
+	if err != nil {
+		logger.Error("shutting down after a server failed", "error", err)
+	} else {
+		logger.Info("shutting down", "drain_delay", cfg.DrainDelay.String(), "drain_timeout", drainTimeout.String())
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, srv := range cfg.Servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(drainCtx); err != nil {
+				logger.Warn("closing the connections left after the drain timeout", "addr", srv.Addr, "error", err)
+				srv.Close()
+			}
+		}()
+	}
+	if cfg.GRPCServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			drained := make(chan struct{})
+			go func() {
+				cfg.GRPCServer.GracefulStop()
+				close(drained)
+			}()
+			select {
+			case <-drained:
+			case <-drainCtx.Done():
+				logger.Warn("closing the grpc connections left after the drain timeout")
+				cfg.GRPCServer.Stop()
+				<-drained
+			}
+		}()
+	}
+	wg.Wait()
+	logger.Info("shut down")
+
+	return err
+}
@@ -0,0 +1,137 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jhtohru/go-album-catalog/health"
+)
+
+// freeAddr returns a local address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	return addr
+}
+
+func TestRun_drainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{
+		Addr: freeAddr(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			io.WriteString(w, "done")
+		}),
+	}
+	readiness := health.NewChecker(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ran := make(chan error, 1)
+	go func() {
+		ran <- Run(ctx, Config{
+			Servers:      []*http.Server{srv},
+			Readiness:    readiness,
+			DrainTimeout: 5 * time.Second,
+			Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+	}()
+
+	var resp *http.Response
+	responded := make(chan error, 1)
+	go func() {
+		// Retry until the server listens.
+		var err error
+		for range 100 {
+			if resp, err = http.Get("http://" + srv.Addr); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		responded <- err
+	}()
+	<-started
+	assert.Equal(t, health.StatusUp, readiness.Check(ctx).Status)
+	cancel()
+
+	// The servers are marked unready at once, while the request is still in
+	// flight.
+	assert.Eventually(t, func() bool {
+		return readiness.Check(context.Background()).Status == health.StatusDown
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case err := <-ran:
+		t.Fatalf("Run returned before the in-flight request completed: %v", err)
+	default:
+	}
+
+	close(release)
+	require.NoError(t, <-responded)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "done", string(body))
+	assert.Nil(t, <-ran)
+}
+
+func TestRun_serverFails(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	err = Run(context.Background(), Config{
+		Servers: []*http.Server{{Addr: lis.Addr().String()}},
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	assert.ErrorContains(t, err, "listening on "+lis.Addr().String())
+}
+
+func TestRun_listeners(t *testing.T) {
+	lis1, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	lis2, err := net.Listen("unix", t.TempDir()+"/catalog.sock")
+	require.NoError(t, err)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "ok")
+		}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan error, 1)
+	go func() {
+		ran <- Run(ctx, Config{
+			Servers:   []*http.Server{srv},
+			Listeners: map[*http.Server][]net.Listener{srv: {lis1, lis2}},
+			Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+	}()
+
+	for _, lis := range []net.Listener{lis1, lis2} {
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, lis.Addr().Network(), lis.Addr().String())
+			},
+		}}
+		resp, err := client.Get("http://catalog/")
+		require.NoError(t, err, lis.Addr().Network())
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, "ok", string(body))
+	}
+	cancel()
+	assert.Nil(t, <-ran)
+}
@@ -0,0 +1,77 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ResetSandbox replaces the albums and their history stored in the schema
+// named schema with a copy of the ones stored in the public schema, creating
+// the schema if it does not exist. Its tables are recreated on every reset, so
+// they always have the same columns as the public ones.
+func ResetSandbox(ctx context.Context, db *sql.DB, schema string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	schema = pq.QuoteIdentifier(schema)
+	queries := []string{
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", schema),
+		fmt.Sprintf("DROP TABLE IF EXISTS %[1]s.album, %[1]s.album_audit, %[1]s.album_outbox", schema),
+		fmt.Sprintf("CREATE TABLE %s.album (LIKE public.album INCLUDING ALL)", schema),
+		fmt.Sprintf("CREATE TABLE %s.album_audit (LIKE public.album_audit INCLUDING ALL)", schema),
+		// The sandbox outbox is never relayed, its events are dropped on reset.
+		fmt.Sprintf("CREATE TABLE %s.album_outbox (LIKE public.album_outbox INCLUDING ALL)", schema),
+		fmt.Sprintf("INSERT INTO %s.album SELECT * FROM public.album", schema),
+		fmt.Sprintf(`
+			INSERT INTO
+				%s.album_audit (album_id, actor, action, before, after, created_at)
+			SELECT
+				album_id, actor, action, before, after, created_at
+			FROM
+				public.album_audit
+			ORDER BY
+				id ASC`, schema),
+		// Audit the sandbox album changes only after copying the albums.
+		fmt.Sprintf(`
+			CREATE TRIGGER album_audit_trigger
+				AFTER INSERT OR UPDATE OR DELETE ON %s.album
+				FOR EACH ROW EXECUTE FUNCTION public.record_album_audit()`, schema),
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RunSandboxResets resets the sandbox schema named schema once every interval
+// until ctx is done. Reset failures are reported to onError and do not stop
+// the resets.
+func RunSandboxResets(
+	ctx context.Context,
+	db *sql.DB,
+	schema string,
+	interval time.Duration,
+	onError func(error),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := ResetSandbox(ctx, db, schema); err != nil {
+				onError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
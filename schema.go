@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// albumColumns are the columns of the album table used by the AlbumStorage.
+var albumColumns = []string{"id", "title", "artist", "price", "currency", "created_at", "updated_at", "version", "tenant_id", "created_by", "updated_by", "artwork"}
+
+// albumIndexes are the indexes of the album table the AlbumStorage relies on.
+var albumIndexes = []string{
+	"album_pkey",
+	"album_title_index",
+	"album_title_trgm_index",
+	"album_artist_trgm_index",
+	"album_artist_title_index",
+	"album_lower_title_id_index",
+}
+
+// SchemaDriftError is returned when the database schema does not match the
+// schema expected by the application.
+type SchemaDriftError struct {
+	Problems []string
+}
+
+func (e *SchemaDriftError) Error() string {
+	return "schema drift: " + strings.Join(e.Problems, "; ")
+}
+
+// CheckSchema verifies that the database db is migrated to the latest
+// migration and that its album table has the columns and indexes the
+// AlbumStorage relies on. It returns a *SchemaDriftError listing every
+// mismatch found.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	var problems []string
+	provider, err := NewMigrationProvider(db)
+	if err != nil {
+		return err
+	}
+	dbVersion, version, err := provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("getting database version: %w", err)
+	}
+	if dbVersion != version {
+		problems = append(problems, fmt.Sprintf("database is migrated to version %d instead of %d", dbVersion, version))
+	}
+	query := `
+		SELECT
+			column_name
+		FROM
+			information_schema.columns
+		WHERE
+			table_schema = current_schema() AND table_name = 'album'`
+	columns, err := queryStrings(ctx, db, query)
+	if err != nil {
+		return fmt.Errorf("listing album columns: %w", err)
+	}
+	for _, column := range albumColumns {
+		if !slices.Contains(columns, column) {
+			problems = append(problems, fmt.Sprintf("album column %q is missing", column))
+		}
+	}
+	query = `
+		SELECT
+			indexname
+		FROM
+			pg_indexes
+		WHERE
+			schemaname = current_schema() AND tablename = 'album'`
+	indexes, err := queryStrings(ctx, db, query)
+	if err != nil {
+		return fmt.Errorf("listing album indexes: %w", err)
+	}
+	for _, index := range albumIndexes {
+		if !slices.Contains(indexes, index) {
+			problems = append(problems, fmt.Sprintf("album index %q is missing", index))
+		}
+	}
+	if len(problems) > 0 {
+		return &SchemaDriftError{Problems: problems}
+	}
+
+	return nil
+}
+
+// queryStrings returns the single string column of the rows of query.
+func queryStrings(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
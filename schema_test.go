@@ -0,0 +1,45 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+)
+
+func TestCheckSchema(t *testing.T) {
+	t.Parallel()
+
+	t.Run("happy path", func(t *testing.T) {
+		db := postgresTest.CreateDBOrFailNow(t)
+		defer db.Close()
+
+		err := catalog.CheckSchema(context.Background(), db)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("schema drift", func(t *testing.T) {
+		db := postgresTest.CreateDBOrFailNow(t)
+		defer db.Close()
+		if _, err := db.Exec("ALTER TABLE album DROP COLUMN version"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("DROP INDEX album_title_index"); err != nil {
+			t.Fatal(err)
+		}
+		// Forget the latest migration was applied, without undoing it.
+		if _, err := db.Exec("DELETE FROM goose_db_version WHERE version_id = (SELECT max(version_id) FROM goose_db_version)"); err != nil {
+			t.Fatal(err)
+		}
+
+		err := catalog.CheckSchema(context.Background(), db)
+
+		var driftErr *catalog.SchemaDriftError
+		if assert.ErrorAs(t, err, &driftErr) {
+			assert.Len(t, driftErr.Problems, 3)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+// Package sentryreport provides a catalog.ErrorReporter that reports errors
+// to Sentry.
+package sentryreport
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Reporter is a catalog.ErrorReporter that reports errors to Sentry.
+type Reporter struct {
+	hub *sentry.Hub
+}
+
+// New returns a new Reporter that reports errors through hub, unless the
+// context of an error carries a hub of its own.
+func New(hub *sentry.Hub) *Reporter {
+	return &Reporter{hub: hub}
+}
+
+// Report reports err to Sentry as an exception, with attrs as its tags.
+func (r *Reporter) Report(ctx context.Context, err error, attrs ...slog.Attr) {
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = r.hub
+	}
+	hub.WithScope(func(scope *sentry.Scope) {
+		for _, attr := range attrs {
+			scope.SetTag(attr.Key, attr.Value.String())
+		}
+		hub.CaptureException(err)
+	})
+}
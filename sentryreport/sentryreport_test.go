@@ -0,0 +1,37 @@
+package sentryreport_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/sentryreport"
+)
+
+func TestReporter_Report(t *testing.T) {
+	var events []*sentry.Event
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			events = append(events, event)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := sentryreport.New(sentry.NewHub(client, sentry.NewScope()))
+
+	reporter.Report(context.Background(), errors.New("unexpected storage error"),
+		slog.String("method", "GET"),
+		slog.Int("status", 500),
+	)
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "unexpected storage error", events[0].Exception[0].Value)
+		assert.Equal(t, map[string]string{"method": "GET", "status": "500"}, events[0].Tags)
+	}
+}
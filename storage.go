@@ -3,111 +3,369 @@ package catalog
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"iter"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jhtohru/go-album-catalog/civil"
+	"github.com/jhtohru/go-album-catalog/internal/pgdb"
+	"github.com/jhtohru/go-album-catalog/money"
 )
 
 // AlbumStorage representes an album storage.
+//
+// An AlbumStorage keeps a separate catalog for each tenant, only operating on
+// the Albums of the tenant its context is scoped to by NewTenantContext. The
+// Albums of other tenants are never found, updated or removed, and only
+// conflict with the ones of the tenant by their ID.
 type AlbumStorage interface {
-	// Insert inserts an Album into the storage.
+	// Insert inserts an Album into the storage. It returns
+	// ErrAlbumAlreadyExists if there is already an Album in the storage whose
+	// ID is equal to alb.ID, or whose artist and title are equal to the alb
+	// ones, ignoring case.
 	Insert(ctx context.Context, alb Album) error
-	// FindAll finds all Albums into the storage within offset and limit. It
-	// returns ErrAlbumNotFound if no Album was found in the storage within
-	// offset and limit.
-	FindAll(ctx context.Context, offset, limit int) ([]Album, error)
+	// InsertBatch inserts all albs into the storage at once. Either all or none
+	// of albs are inserted. It returns ErrAlbumAlreadyExists if any of albs
+	// already exists, as Insert does.
+	InsertBatch(ctx context.Context, albs []Album) error
+	// FindAll finds all Albums into the storage matching filter within offset
+	// and limit, ordered by title, ignoring case, and then by ID, so that
+	// every Album has a single position in the order, unless filter sorts
+	// them otherwise. It returns ErrAlbumNotFound if no Album was found in the
+	// storage within offset and limit.
+	FindAll(ctx context.Context, filter AlbumFilter, offset, limit int) ([]Album, error)
+	// FindAllSeq returns an iterator over all Albums into the storage matching
+	// filter within offset and limit, in the same order as FindAll, scanning
+	// each Album as it is iterated. The iteration stops after the first error.
+	FindAllSeq(ctx context.Context, filter AlbumFilter, offset, limit int) iter.Seq2[Album, error]
 	// FindOne finds a single Album in the storage. It returns ErrAlbumNotFound
 	// if there is no Album in the storage whose ID is equal to id.
 	FindOne(ctx context.Context, id uuid.UUID) (Album, error)
 	// Update updates the single Album in the storage whose ID is equal to
-	// alb.ID setting its state equal to the alb state. It returns
-	// ErrAlbumNotFound if there is no Album in the storage whose ID is equal to
-	// id.
+	// alb.ID setting its state equal to the alb state and incrementing its
+	// version. It returns ErrAlbumNotFound if there is no Album in the storage
+	// whose ID is equal to id, ErrVersionConflict if its version is not equal
+	// to alb.Version, or ErrAlbumAlreadyExists if there is another Album in the
+	// storage whose artist and title are equal to the alb ones, ignoring case.
 	Update(ctx context.Context, alb Album) error
+	// UpdateFunc atomically updates the single Album in the storage whose ID is
+	// equal to id setting its state equal to the state returned by update,
+	// which is called with its current state, and incrementing its version. It
+	// returns the updated Album, ErrAlbumNotFound if there is no Album in the
+	// storage whose ID is equal to id, ErrVersionConflict if the version of the
+	// state returned by update is not equal to its version, ErrAlbumConflict
+	// if the Album was concurrently modified, or ErrAlbumAlreadyExists as
+	// Update does.
+	UpdateFunc(ctx context.Context, id uuid.UUID, update func(Album) Album) (Album, error)
+	// Suggest finds up to limit Albums in the storage whose title or artist
+	// starts with prefix, ignoring case, ordered by relevance and then as
+	// FindAll orders them. It returns
+	// ErrAlbumNotFound if no Album matches prefix.
+	Suggest(ctx context.Context, prefix string, limit int) ([]Album, error)
+	// Upsert inserts alb into the storage or, if there is already an Album in
+	// the storage whose ID is equal to alb.ID, updates its title, artist, price
+	// and update time and increments its version. It returns the stored Album
+	// and whether it was created, or ErrAlbumAlreadyExists if there is another
+	// Album in the storage whose artist and title are equal to the alb ones,
+	// ignoring case.
+	Upsert(ctx context.Context, alb Album) (stored Album, created bool, err error)
 	// Remove removes the single Album in the storage whose ID is equal to id.
 	// It returns ErrAlbumNotFound if there is no Album in the storage whose ID
 	// is equal to id.
 	Remove(ctx context.Context, id uuid.UUID) error
+	// RemoveReturning atomically removes the single Album in the storage whose
+	// ID is equal to id and returns it. It returns ErrAlbumNotFound if there is
+	// no Album in the storage whose ID is equal to id.
+	RemoveReturning(ctx context.Context, id uuid.UUID) (Album, error)
+	// History finds every change of the Album whose ID is equal to id, oldest
+	// first, including the changes of a removed Album. It returns
+	// ErrAlbumNotFound if no Album whose ID is equal to id was ever stored.
+	History(ctx context.Context, id uuid.UUID) ([]AlbumAuditEntry, error)
+}
+
+// AlbumFilter restricts the Albums found by AlbumStorage.FindAll, and sorts
+// them. The zero AlbumFilter finds every Album, sorted by title.
+type AlbumFilter struct {
+	// ReleasedFrom and ReleasedUntil, if not nil, restrict the Albums to the
+	// ones released on or after, and on or before, them. Either excludes the
+	// Albums with no release date.
+	ReleasedFrom  *civil.Date
+	ReleasedUntil *civil.Date
+	// OriginalReleaseYear, if not zero, restricts the Albums to the ones
+	// originally released in it.
+	OriginalReleaseYear int
+	// Sort is the order of the Albums.
+	Sort AlbumSort
 }
 
+// AlbumSort is an order of the Albums found by AlbumStorage.FindAll.
+type AlbumSort string
+
+// The orders of the Albums. The Albums with the same release date are sorted
+// by title, and the ones with no release date are sorted last.
+const (
+	// SortByTitle sorts the Albums by title, ignoring case, and then by ID.
+	SortByTitle AlbumSort = ""
+	// SortByReleaseDate sorts the Albums by release date, oldest first.
+	SortByReleaseDate AlbumSort = "release_date"
+	// SortByReleaseDateDesc sorts the Albums by release date, newest first.
+	SortByReleaseDateDesc AlbumSort = "-release_date"
+)
+
 // ErrAlbumNotFound is returned when the required album was not found in the
 // AlbumStorage.
 var ErrAlbumNotFound = errors.New("album not found")
 
+// ErrAlbumConflict is returned when the required album was concurrently
+// modified in the AlbumStorage.
+var ErrAlbumConflict = errors.New("album conflict")
+
+// ErrAlbumAlreadyExists is returned when the album to be stored already exists
+// in the AlbumStorage.
+var ErrAlbumAlreadyExists = errors.New("album already exists")
+
+// ErrVersionConflict is returned when the version of an album does not match
+// the version of the album in the AlbumStorage.
+var ErrVersionConflict = errors.New("album version conflict")
+
 type pgAlbumStorage struct {
-	db *sql.DB
+	db      *sql.DB
+	queries *pgdb.Queries
+	// pool is the pool db is opened from, if it was opened from one. It allows
+	// using pgx features not available through database/sql.
+	pool *pgxpool.Pool
+	// slowQueryLogger logs the queries taking slowQueryThreshold or longer,
+	// if set.
+	slowQueryLogger    *slog.Logger
+	slowQueryThreshold time.Duration
+}
+
+// PostgresOption configures an AlbumStorage that uses Postgres.
+type PostgresOption func(*pgAlbumStorage)
+
+// WithSlowQueryLog makes the AlbumStorage log a warning through logger for
+// every query taking threshold or longer, with the query name and the
+// parameters identifying what it was run for.
+func WithSlowQueryLog(logger *slog.Logger, threshold time.Duration) PostgresOption {
+	return func(s *pgAlbumStorage) {
+		s.slowQueryLogger = logger
+		s.slowQueryThreshold = threshold
+	}
 }
 
 // NewPostgresAlbumStorage returns a new AlbumStorage that uses Postgres to
 // manage data
-func NewPostgresAlbumStorage(db *sql.DB) AlbumStorage {
-	return &pgAlbumStorage{
-		db: db,
+func NewPostgresAlbumStorage(db *sql.DB, opts ...PostgresOption) AlbumStorage {
+	s := &pgAlbumStorage{
+		db:      db,
+		queries: pgdb.New(db),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewPgxAlbumStorage returns a new AlbumStorage that uses Postgres through a
+// pgx connection pool to manage data.
+func NewPgxAlbumStorage(pool *pgxpool.Pool, opts ...PostgresOption) AlbumStorage {
+	db := stdlib.OpenDBFromPool(pool)
+	s := &pgAlbumStorage{
+		db:      db,
+		queries: pgdb.New(db),
+		pool:    pool,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *pgAlbumStorage) Insert(ctx context.Context, alb Album) error {
-	query := `
-		INSERT INTO
-			album (id, title, artist, price, created_at, updated_at)
-		VALUES
-			($1, $2, $3, $4, $5, $6)`
-	_, err := s.db.QueryContext(ctx, query,
-		alb.ID,
-		alb.Title,
-		alb.Artist,
-		alb.Price,
-		alb.CreatedAt.UTC(),
-		alb.UpdatedAt.UTC(),
-	)
+	err := s.attributed(ctx, func(queries *pgdb.Queries) error {
+		ctx, done := s.startQuery(ctx, "InsertAlbum", "id", alb.ID)
+		err := queries.InsertAlbum(ctx, insertAlbumParams(ctx, alb))
+		done(oneRow(err), err)
+		return err
+	})
+	if isPgError(err, uniqueViolation) {
+		return ErrAlbumAlreadyExists
+	}
 
 	return err
 }
 
-func (s *pgAlbumStorage) FindAll(ctx context.Context, offset, limit int) ([]Album, error) {
-	query := `
-		SELECT
-			id, title, artist, price, created_at, updated_at
-		FROM
-			album
-		ORDER BY
-			title ASC
-		OFFSET
-			$1
-		LIMIT
-			$2`
-	rows, err := s.db.QueryContext(ctx, query, offset, limit)
+// insertAlbumQuery is the query of pgdb.Queries.InsertAlbum. pgx batches
+// queue raw queries, which the generated code does not expose.
+const insertAlbumQuery = `
+	INSERT INTO
+		album (id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+// setActorQuery is the query of pgdb.Queries.SetActor.
+const setActorQuery = `SELECT set_config('catalog.actor', $1::text, true)`
+
+func (s *pgAlbumStorage) InsertBatch(ctx context.Context, albs []Album) error {
+	ctx, done := s.startQuery(ctx, "InsertAlbumBatch", "albums", len(albs))
+	err := s.insertBatch(ctx, albs)
 	if err != nil {
-		return nil, err
+		done(0, err)
+	} else {
+		done(int64(len(albs)), nil)
 	}
-	defer rows.Close()
+	return err
+}
 
-	var albs []Album
-	for rows.Next() {
-		alb, err := scanAlbum(rows)
-		if err != nil {
-			return nil, err
+func (s *pgAlbumStorage) insertBatch(ctx context.Context, albs []Album) error {
+	if s.pool != nil {
+		// Send all inserts in a single round trip. A batch runs in an implicit
+		// transaction.
+		var batch pgx.Batch
+		if actor := ActorFromContext(ctx); actor != "" {
+			batch.Queue(setActorQuery, actor)
+		}
+		for _, alb := range albs {
+			arg := insertAlbumParams(ctx, alb)
+			batch.Queue(insertAlbumQuery,
+				arg.ID,
+				arg.Title,
+				arg.Artist,
+				arg.Price,
+				arg.Currency,
+				arg.CreatedAt,
+				arg.UpdatedAt,
+				arg.Version,
+				arg.TenantID,
+				arg.CreatedBy,
+				arg.UpdatedBy,
+				arg.Artwork,
+				arg.ReleaseDate,
+				arg.OriginalReleaseYear,
+			)
+		}
+		err := s.pool.SendBatch(ctx, &batch).Close()
+		if isPgError(err, uniqueViolation) {
+			return ErrAlbumAlreadyExists
+		}
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := s.queries.WithTx(tx)
+	if err := setActor(ctx, queries); err != nil {
+		return err
+	}
+	for _, alb := range albs {
+		err := queries.InsertAlbum(ctx, insertAlbumParams(ctx, alb))
+		switch {
+		case isPgError(err, uniqueViolation):
+			return ErrAlbumAlreadyExists
+		case err != nil:
+			return err
 		}
-		albs = append(albs, alb)
 	}
-	if len(albs) == 0 {
+
+	return tx.Commit()
+}
+
+func (s *pgAlbumStorage) FindAll(ctx context.Context, filter AlbumFilter, offset, limit int) ([]Album, error) {
+	ctx, done := s.startQuery(ctx, "FindAlbums", "offset", offset, "limit", limit)
+	rows, err := s.queries.FindAlbums(ctx, findAlbumsParams(ctx, filter, offset, limit))
+	done(int64(len(rows)), err)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
 		return nil, ErrAlbumNotFound
 	}
 
-	return albs, nil
+	return albumsFromRows(rows), nil
+}
+
+func (s *pgAlbumStorage) FindAllSeq(ctx context.Context, filter AlbumFilter, offset, limit int) iter.Seq2[Album, error] {
+	return func(yield func(Album, error) bool) {
+		// The generated FindAlbums reads every row before returning, so the
+		// rows are streamed with the same query written by hand instead.
+		query := `
+			SELECT
+				id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork, release_date, original_release_year
+			FROM
+				album
+			WHERE
+				tenant_id = $1 AND
+				($2::date IS NULL OR release_date >= $2::date) AND
+				($3::date IS NULL OR release_date <= $3::date) AND
+				($4::integer IS NULL OR original_release_year = $4::integer)
+			ORDER BY
+				CASE WHEN $5::text = 'release_date' THEN release_date END ASC NULLS LAST,
+				CASE WHEN $5::text = '-release_date' THEN release_date END DESC NULLS LAST,
+				lower(title) ASC, id ASC
+			OFFSET
+				$6
+			LIMIT
+				$7`
+		ctx, done := s.startQuery(ctx, "FindAlbumsSeq", "offset", offset, "limit", limit)
+		var (
+			scanned int64
+			err     error
+		)
+		defer func() { done(scanned, err) }()
+		arg := findAlbumsParams(ctx, filter, offset, limit)
+		rows, err := s.db.QueryContext(ctx, query,
+			arg.TenantID,
+			arg.ReleasedFrom,
+			arg.ReleasedUntil,
+			arg.OriginalReleaseYear,
+			arg.Sort,
+			arg.PageOffset,
+			arg.PageLimit,
+		)
+		if err != nil {
+			yield(Album{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var alb Album
+			if alb, err = scanAlbum(rows); err != nil {
+				yield(Album{}, err)
+				return
+			}
+			scanned++
+			if !yield(alb, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(Album{}, err)
+		}
+	}
 }
 
 func (s *pgAlbumStorage) FindOne(ctx context.Context, id uuid.UUID) (Album, error) {
-	query := `
-		SELECT
-			id, title, artist, price, created_at, updated_at
-		FROM
-			album
-		WHERE
-			id = $1`
-	row := s.db.QueryRowContext(ctx, query, id)
-	alb, err := scanAlbum(row)
+	ctx, done := s.startQuery(ctx, "FindAlbum", "id", id)
+	row, err := s.queries.FindAlbum(ctx, pgdb.FindAlbumParams{ID: id, TenantID: TenantFromContext(ctx)})
+	done(oneRow(err), err)
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		return Album{}, ErrAlbumNotFound
@@ -115,54 +373,152 @@ func (s *pgAlbumStorage) FindOne(ctx context.Context, id uuid.UUID) (Album, erro
 		return Album{}, err
 	}
 
-	return alb, nil
+	return albumFromRow(row), nil
 }
 
-func (s *pgAlbumStorage) Update(ctx context.Context, alb Album) error {
-	query := `
-		UPDATE
-			album
-		SET
-			title = $1,
-			artist = $2,
-			price = $3,
-			created_at = $4,
-			updated_at = $5
-		WHERE
-			id = $6`
-	result, err := s.db.ExecContext(ctx, query,
-		alb.Title,
-		alb.Artist,
-		alb.Price,
-		alb.CreatedAt.UTC(),
-		alb.UpdatedAt.UTC(),
-		alb.ID,
-	)
+func (s *pgAlbumStorage) UpdateFunc(ctx context.Context, id uuid.UUID, update func(Album) Album) (Album, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
 	if err != nil {
-		return err
+		return Album{}, err
+	}
+	defer tx.Rollback()
+	queries := s.queries.WithTx(tx)
+	if err := setActor(ctx, queries); err != nil {
+		return Album{}, err
 	}
-	rowsAffected, err := result.RowsAffected()
+	findCtx, done := s.startQuery(ctx, "FindAlbum", "id", id)
+	row, err := queries.FindAlbum(findCtx, pgdb.FindAlbumParams{ID: id, TenantID: TenantFromContext(ctx)})
+	done(oneRow(err), err)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return Album{}, ErrAlbumNotFound
+	case err != nil:
+		return Album{}, err
+	}
+	alb := update(albumFromRow(row))
+	alb.ID = id
+	updateCtx, done := s.startQuery(ctx, "UpdateAlbum", "id", alb.ID, "version", alb.Version)
+	rowsAffected, err := queries.UpdateAlbum(updateCtx, updateAlbumParams(ctx, alb))
+	done(rowsAffected, err)
+	switch {
+	case isPgError(err, serializationFailure):
+		return Album{}, ErrAlbumConflict
+	case isPgError(err, uniqueViolation):
+		return Album{}, ErrAlbumAlreadyExists
+	case err != nil:
+		return Album{}, err
+	}
+	if rowsAffected == 0 {
+		return Album{}, ErrVersionConflict
+	}
+	err = tx.Commit()
+	switch {
+	case isPgError(err, serializationFailure):
+		return Album{}, ErrAlbumConflict
+	case err != nil:
+		return Album{}, err
+	}
+	alb.Version++
+
+	return alb, nil
+}
+
+func (s *pgAlbumStorage) Suggest(ctx context.Context, prefix string, limit int) ([]Album, error) {
+	ctx, done := s.startQuery(ctx, "SuggestAlbums", "prefix", prefix, "limit", limit)
+	rows, err := s.queries.SuggestAlbums(ctx, pgdb.SuggestAlbumsParams{
+		TenantID:  TenantFromContext(ctx),
+		Pattern:   escapeLike(prefix),
+		Prefix:    prefix,
+		MaxAlbums: int32(limit),
+	})
+	done(int64(len(rows)), err)
 	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrAlbumNotFound
+	}
+
+	return albumsFromRows(rows), nil
+}
+
+func (s *pgAlbumStorage) Update(ctx context.Context, alb Album) error {
+	var rowsAffected int64
+	err := s.attributed(ctx, func(queries *pgdb.Queries) error {
+		ctx, done := s.startQuery(ctx, "UpdateAlbum", "id", alb.ID, "version", alb.Version)
+		var err error
+		rowsAffected, err = queries.UpdateAlbum(ctx, updateAlbumParams(ctx, alb))
+		done(rowsAffected, err)
+		return err
+	})
+	switch {
+	case isPgError(err, uniqueViolation):
+		return ErrAlbumAlreadyExists
+	case err != nil:
 		return err
 	}
 	if rowsAffected == 0 {
+		// Tell a missing Album apart from an outdated version.
+		ctx, done := s.startQuery(ctx, "AlbumExists", "id", alb.ID)
+		exists, err := s.queries.AlbumExists(ctx, pgdb.AlbumExistsParams{ID: alb.ID, TenantID: TenantFromContext(ctx)})
+		done(oneRow(err), err)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrVersionConflict
+		}
 		return ErrAlbumNotFound
 	}
 
 	return nil
 }
 
-func (s *pgAlbumStorage) Remove(ctx context.Context, id uuid.UUID) error {
-	query := `
-		DELETE FROM
-			album
-		WHERE
-			id = $1`
-	result, err := s.db.ExecContext(ctx, query, id)
-	if err != nil {
+func (s *pgAlbumStorage) Upsert(ctx context.Context, alb Album) (Album, bool, error) {
+	var row pgdb.UpsertAlbumRow
+	err := s.attributed(ctx, func(queries *pgdb.Queries) error {
+		ctx, done := s.startQuery(ctx, "UpsertAlbum", "id", alb.ID)
+		var err error
+		row, err = queries.UpsertAlbum(ctx, pgdb.UpsertAlbumParams(insertAlbumParams(ctx, alb)))
+		done(oneRow(err), err)
 		return err
+	})
+	switch {
+	// No row is returned when the Album of alb.ID belongs to another tenant.
+	case isPgError(err, uniqueViolation), errors.Is(err, sql.ErrNoRows):
+		return Album{}, false, ErrAlbumAlreadyExists
+	case err != nil:
+		return Album{}, false, err
 	}
-	rowsAffected, err := result.RowsAffected()
+	stored := albumFromRow(pgdb.Album{
+		ID:                  row.ID,
+		Title:               row.Title,
+		Artist:              row.Artist,
+		Price:               row.Price,
+		Currency:            row.Currency,
+		CreatedAt:           row.CreatedAt,
+		UpdatedAt:           row.UpdatedAt,
+		Version:             row.Version,
+		TenantID:            row.TenantID,
+		CreatedBy:           row.CreatedBy,
+		UpdatedBy:           row.UpdatedBy,
+		Artwork:             row.Artwork,
+		ReleaseDate:         row.ReleaseDate,
+		OriginalReleaseYear: row.OriginalReleaseYear,
+	})
+
+	return stored, row.Created, nil
+}
+
+func (s *pgAlbumStorage) Remove(ctx context.Context, id uuid.UUID) error {
+	var rowsAffected int64
+	err := s.attributed(ctx, func(queries *pgdb.Queries) error {
+		ctx, done := s.startQuery(ctx, "RemoveAlbum", "id", id)
+		var err error
+		rowsAffected, err = queries.RemoveAlbum(ctx, pgdb.RemoveAlbumParams{ID: id, TenantID: TenantFromContext(ctx)})
+		done(rowsAffected, err)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -173,27 +529,334 @@ func (s *pgAlbumStorage) Remove(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (s *pgAlbumStorage) RemoveReturning(ctx context.Context, id uuid.UUID) (Album, error) {
+	var row pgdb.Album
+	err := s.attributed(ctx, func(queries *pgdb.Queries) error {
+		ctx, done := s.startQuery(ctx, "RemoveAlbumReturning", "id", id)
+		var err error
+		row, err = queries.RemoveAlbumReturning(ctx, pgdb.RemoveAlbumReturningParams{ID: id, TenantID: TenantFromContext(ctx)})
+		done(oneRow(err), err)
+		return err
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return Album{}, ErrAlbumNotFound
+	case err != nil:
+		return Album{}, err
+	}
+
+	return albumFromRow(row), nil
+}
+
+func (s *pgAlbumStorage) History(ctx context.Context, id uuid.UUID) ([]AlbumAuditEntry, error) {
+	ctx, done := s.startQuery(ctx, "FindAlbumHistory", "id", id)
+	rows, err := s.queries.FindAlbumHistory(ctx, pgdb.FindAlbumHistoryParams{AlbumID: id, TenantID: TenantFromContext(ctx)})
+	done(int64(len(rows)), err)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrAlbumNotFound
+	}
+	entries := make([]AlbumAuditEntry, len(rows))
+	for i, row := range rows {
+		entry := AlbumAuditEntry{
+			Action:    row.Action,
+			Actor:     row.Actor.String,
+			CreatedAt: row.CreatedAt.UTC(),
+		}
+		if entry.Before, err = unmarshalAlbum(row.Before); err != nil {
+			return nil, err
+		}
+		if entry.After, err = unmarshalAlbum(row.After); err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+
+	return entries, nil
+}
+
+// attributed calls f with the queries to change the Albums as the actor of
+// ctx, recording the changes into their history as made by it. The queries of
+// an attributed ctx are run in a transaction setting its actor.
+func (s *pgAlbumStorage) attributed(ctx context.Context, f func(*pgdb.Queries) error) error {
+	if ActorFromContext(ctx) == "" {
+		return f(s.queries)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := s.queries.WithTx(tx)
+	if err := setActor(ctx, queries); err != nil {
+		return err
+	}
+	if err := f(queries); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setActor sets the actor of ctx, if any, as the actor of the transaction of
+// queries.
+func setActor(ctx context.Context, queries *pgdb.Queries) error {
+	actor := ActorFromContext(ctx)
+	if actor == "" {
+		return nil
+	}
+	return queries.SetActor(ctx, actor)
+}
+
+// tracer creates the spans of the queries run by the AlbumStorage.
+var tracer = otel.Tracer("github.com/jhtohru/go-album-catalog")
+
+// startQuery starts a span of the query named name, returning its context and
+// a function that ends the span. The function must be called once the query
+// is done, with the number of rows it returned or affected and its error.
+// params are the key-value pairs of the query parameters logged if the query
+// is slow.
+func (s *pgAlbumStorage) startQuery(ctx context.Context, name string, params ...any) (context.Context, func(rows int64, err error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", name),
+		),
+	)
+	return ctx, func(rows int64, err error) {
+		span.SetAttributes(attribute.Int64("db.response.rows", rows))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if elapsed := time.Since(start); s.slowQueryLogger != nil && elapsed >= s.slowQueryThreshold {
+			s.slowQueryLogger.WarnContext(ctx, "slow query",
+				"query", name,
+				"duration", elapsed,
+				"rows", rows,
+				slog.Group("params", sanitizeQueryParams(params)...),
+			)
+		}
+	}
+}
+
+// maxLoggedParamLen is the maximum length of the string query parameters
+// logged.
+const maxLoggedParamLen = 32
+
+// sanitizeQueryParams returns the key-value pairs of query parameters params
+// with their string values truncated, so that logs are not flooded with user
+// input.
+func sanitizeQueryParams(params []any) []any {
+	sanitized := make([]any, len(params))
+	for i, param := range params {
+		if str, ok := param.(string); ok && i%2 == 1 && utf8.RuneCountInString(str) > maxLoggedParamLen {
+			param = string([]rune(str)[:maxLoggedParamLen]) + "..."
+		}
+		sanitized[i] = param
+	}
+	return sanitized
+}
+
+// oneRow returns the number of rows returned or affected by a query of a
+// single row that failed with err.
+func oneRow(err error) int64 {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+// insertAlbumParams returns the arguments of the query inserting alb into the
+// catalog of the tenant of ctx.
+func insertAlbumParams(ctx context.Context, alb Album) pgdb.InsertAlbumParams {
+	return pgdb.InsertAlbumParams{
+		ID:                  alb.ID,
+		Title:               alb.Title,
+		Artist:              alb.Artist,
+		Price:               alb.Price.AmountMinor,
+		Currency:            alb.Price.Currency,
+		CreatedAt:           alb.CreatedAt.UTC(),
+		UpdatedAt:           alb.UpdatedAt.UTC(),
+		Version:             int32(alb.Version),
+		TenantID:            TenantFromContext(ctx),
+		CreatedBy:           alb.CreatedBy,
+		UpdatedBy:           alb.UpdatedBy,
+		Artwork:             alb.Artwork,
+		ReleaseDate:         nullDate(alb.ReleaseDate),
+		OriginalReleaseYear: nullYear(alb.OriginalReleaseYear),
+	}
+}
+
+// findAlbumsParams returns the arguments of the query finding the albums of
+// the catalog of the tenant of ctx matching filter within offset and limit.
+func findAlbumsParams(ctx context.Context, filter AlbumFilter, offset, limit int) pgdb.FindAlbumsParams {
+	return pgdb.FindAlbumsParams{
+		TenantID:            TenantFromContext(ctx),
+		ReleasedFrom:        nullDate(filter.ReleasedFrom),
+		ReleasedUntil:       nullDate(filter.ReleasedUntil),
+		OriginalReleaseYear: nullYear(filter.OriginalReleaseYear),
+		Sort:                string(filter.Sort),
+		PageOffset:          int32(offset),
+		PageLimit:           int32(limit),
+	}
+}
+
+// updateAlbumParams returns the arguments of the query updating alb in the
+// catalog of the tenant of ctx.
+func updateAlbumParams(ctx context.Context, alb Album) pgdb.UpdateAlbumParams {
+	return pgdb.UpdateAlbumParams{
+		Title:               alb.Title,
+		Artist:              alb.Artist,
+		Price:               alb.Price.AmountMinor,
+		Currency:            alb.Price.Currency,
+		CreatedAt:           alb.CreatedAt.UTC(),
+		UpdatedAt:           alb.UpdatedAt.UTC(),
+		UpdatedBy:           alb.UpdatedBy,
+		Artwork:             alb.Artwork,
+		ReleaseDate:         nullDate(alb.ReleaseDate),
+		OriginalReleaseYear: nullYear(alb.OriginalReleaseYear),
+		ID:                  alb.ID,
+		Version:             int32(alb.Version),
+		TenantID:            TenantFromContext(ctx),
+	}
+}
+
+// albumFromRow converts an album row into an Album, with its times in UTC.
+func albumFromRow(row pgdb.Album) Album {
+	return Album{
+		ID:                  row.ID,
+		Title:               row.Title,
+		Artist:              row.Artist,
+		Price:               money.New(row.Price, row.Currency),
+		CreatedAt:           row.CreatedAt.UTC(),
+		UpdatedAt:           row.UpdatedAt.UTC(),
+		Version:             int(row.Version),
+		TenantID:            row.TenantID,
+		CreatedBy:           row.CreatedBy,
+		UpdatedBy:           row.UpdatedBy,
+		Artwork:             row.Artwork,
+		ReleaseDate:         datePtr(row.ReleaseDate),
+		OriginalReleaseYear: int(row.OriginalReleaseYear.Int32),
+	}
+}
+
+// nullDate converts d into a nullable date column value, null if d is nil.
+func nullDate(d *civil.Date) sql.NullTime {
+	if d == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: d.In(time.UTC), Valid: true}
+}
+
+// datePtr converts a nullable date column value into a Date, nil if it is
+// null.
+func datePtr(t sql.NullTime) *civil.Date {
+	if !t.Valid {
+		return nil
+	}
+	d := civil.DateOf(t.Time)
+	return &d
+}
+
+// nullYear converts year into a nullable year column value, null if year is
+// zero.
+func nullYear(year int) sql.NullInt32 {
+	return sql.NullInt32{Int32: int32(year), Valid: year != 0}
+}
+
+// albumsFromRows converts album rows into Albums.
+func albumsFromRows(rows []pgdb.Album) []Album {
+	albs := make([]Album, len(rows))
+	for i, row := range rows {
+		albs[i] = albumFromRow(row)
+	}
+	return albs
+}
+
 // scanner abstracts *sql.Row and *sql.Rows.
 type scanner interface {
 	// Scan decode dest from scanner inner data.
 	Scan(dest ...any) error
 }
 
-// scanAlbum extracts an Album from a scanner.
+// scanAlbum extracts an Album from a scanner, with its times in UTC.
 func scanAlbum(scn scanner) (Album, error) {
-	var alb Album
+	var (
+		alb                 Album
+		releaseDate         sql.NullTime
+		originalReleaseYear sql.NullInt32
+	)
 	err := scn.Scan(
 		&alb.ID,
 		&alb.Title,
 		&alb.Artist,
-		&alb.Price,
+		&alb.Price.AmountMinor,
+		&alb.Price.Currency,
 		&alb.CreatedAt,
 		&alb.UpdatedAt,
+		&alb.Version,
+		&alb.TenantID,
+		&alb.CreatedBy,
+		&alb.UpdatedBy,
+		&alb.Artwork,
+		&releaseDate,
+		&originalReleaseYear,
 	)
 	if err != nil {
 		return Album{}, err
 	}
-	alb.CreatedAt = alb.CreatedAt.Local()
-	alb.UpdatedAt = alb.UpdatedAt.Local()
+	alb.CreatedAt = alb.CreatedAt.UTC()
+	alb.UpdatedAt = alb.UpdatedAt.UTC()
+	alb.ReleaseDate = datePtr(releaseDate)
+	alb.OriginalReleaseYear = int(originalReleaseYear.Int32)
 	return alb, nil
 }
+
+// unmarshalAlbum decodes an Album from its JSON representation, returning nil
+// if data is nil.
+func unmarshalAlbum(data []byte) (*Album, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var alb Album
+	if err := json.Unmarshal(data, &alb); err != nil {
+		return nil, err
+	}
+	return &alb, nil
+}
+
+// escapeLike escapes the LIKE pattern wildcards in s.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// serializationFailure is the code of the Postgres error raised when a
+// transaction conflicts with a concurrent one.
+const serializationFailure = "40001"
+
+// uniqueViolation is the code of the Postgres error raised when a row violates
+// a unique constraint.
+const uniqueViolation = "23505"
+
+// foreignKeyViolation is the code of the Postgres error raised when a row
+// references a missing one.
+const foreignKeyViolation = "23503"
+
+// isPgError reports whether err is a Postgres error, from either lib/pq or
+// pgx, whose code is equal to code.
+func isPgError(err error, code string) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code) == code
+	}
+	var pgxErr *pgconn.PgError
+	return errors.As(err, &pgxErr) && pgxErr.Code == code
+}
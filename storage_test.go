@@ -1,10 +1,13 @@
 package catalog_test
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
+	"iter"
 	"log"
+	"log/slog"
 	"math/rand/v2"
 	"os"
 	"sort"
@@ -13,12 +16,15 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/stretchr/testify/assert"
 
 	catalog "github.com/jhtohru/go-album-catalog"
 	"github.com/jhtohru/go-album-catalog/internal/postgrestest"
 	"github.com/jhtohru/go-album-catalog/internal/random"
 	"github.com/jhtohru/go-album-catalog/internal/runutil"
+	"github.com/jhtohru/go-album-catalog/money"
+	"github.com/jhtohru/go-album-catalog/storagetest"
 )
 
 func TestMain(m *testing.M) {
@@ -57,18 +63,93 @@ func run(ctx context.Context, m *testing.M) (int, error) {
 	return m.Run(), nil
 }
 
+func TestPostgresAlbumStorage_Conformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.RunConformanceTests(t, func() catalog.AlbumStorage {
+		db := postgresTest.CreateDBOrFailNow(t)
+		t.Cleanup(func() { db.Close() })
+		return catalog.NewPostgresAlbumStorage(db)
+	})
+}
+
 func TestPostgresAlbumStorage_Insert(t *testing.T) {
 	t.Parallel()
 
 	db := postgresTest.CreateDBOrFailNow(t)
 	defer db.Close()
 	storage := catalog.NewPostgresAlbumStorage(db)
-	alb := randomAlbum()
 
-	err := storage.Insert(context.Background(), alb)
+	t.Run("happy path", func(t *testing.T) {
+		alb := randomAlbum()
+
+		err := storage.Insert(context.Background(), alb)
+
+		assert.Equal(t, alb, findAlbum(t, db, alb.ID))
+		assert.Nil(t, err)
+	})
+
+	t.Run("album already exists", func(t *testing.T) {
+		stored := randomAlbum()
+		insertAlbums(t, db, stored)
+		alb := randomAlbum()
+		alb.Artist = strings.ToUpper(stored.Artist)
+		alb.Title = strings.ToLower(stored.Title)
+
+		err := storage.Insert(context.Background(), alb)
+
+		assert.ErrorIs(t, err, catalog.ErrAlbumAlreadyExists)
+		assert.False(t, albumExists(t, db, alb.ID))
+	})
+}
+
+func TestPostgresAlbumStorage_InsertBatch(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	testInsertBatch(t, db, storage)
+}
+
+func TestPgxAlbumStorage_InsertBatch(t *testing.T) {
+	t.Parallel()
+
+	pool := postgresTest.CreatePgxPoolOrFailNow(t)
+	defer pool.Close()
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+	storage := catalog.NewPgxAlbumStorage(pool)
+
+	testInsertBatch(t, db, storage)
+}
+
+// testInsertBatch tests the InsertBatch method of storage, which stores albums
+// into db.
+func testInsertBatch(t *testing.T, db *sql.DB, storage catalog.AlbumStorage) {
+	t.Run("happy path", func(t *testing.T) {
+		albs := randomAlbums(3)
+
+		err := storage.InsertBatch(context.Background(), albs)
+
+		assert.Nil(t, err)
+		for _, alb := range albs {
+			assert.Equal(t, alb, findAlbum(t, db, alb.ID))
+		}
+	})
+
+	t.Run("all or none", func(t *testing.T) {
+		duplicate := randomAlbum()
+		insertAlbums(t, db, duplicate)
+		albs := append(randomAlbums(2), duplicate)
+
+		err := storage.InsertBatch(context.Background(), albs)
 
-	assert.Equal(t, alb, findAlbum(t, db, alb.ID))
-	assert.Nil(t, err)
+		assert.NotNil(t, err)
+		assert.False(t, albumExists(t, db, albs[0].ID))
+		assert.False(t, albumExists(t, db, albs[1].ID))
+	})
 }
 
 func TestPostgresAlbumStorage_FindAll(t *testing.T) {
@@ -79,7 +160,7 @@ func TestPostgresAlbumStorage_FindAll(t *testing.T) {
 	storage := catalog.NewPostgresAlbumStorage(db)
 
 	t.Run("no results from empty database", func(t *testing.T) {
-		albs, err := storage.FindAll(context.Background(), 0, 100)
+		albs, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, 0, 100)
 
 		assert.Empty(t, albs)
 		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
@@ -96,11 +177,11 @@ func TestPostgresAlbumStorage_FindAll(t *testing.T) {
 		limit := 3
 		want := fixture[:]
 		sort.Slice(want, func(i, j int) bool {
-			return strings.ToLower(want[i].Title) < strings.ToLower(want[j].Title)
+			return albumLess(want[i], want[j])
 		})
 		want = want[offset : offset+limit]
 
-		albs, err := storage.FindAll(context.Background(), offset, limit)
+		albs, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, offset, limit)
 
 		assert.Equal(t, albs, want)
 		assert.Nil(t, err)
@@ -110,13 +191,66 @@ func TestPostgresAlbumStorage_FindAll(t *testing.T) {
 		offset := len(fixture)
 		limit := offset + 10
 
-		albs, err := storage.FindAll(context.Background(), offset, limit)
+		albs, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, offset, limit)
 
 		assert.Empty(t, albs)
 		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
 	})
 }
 
+func TestPostgresAlbumStorage_FindAllSeq(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	t.Run("no results from empty database", func(t *testing.T) {
+		albs, err := collectAlbums(storage.FindAllSeq(context.Background(), catalog.AlbumFilter{}, 0, 100))
+
+		assert.Empty(t, albs)
+		assert.Nil(t, err)
+	})
+
+	fixture := randomAlbums(5)
+	insertAlbums(t, db, fixture...)
+
+	t.Run("happy path", func(t *testing.T) {
+		want, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, 1, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		albs, err := collectAlbums(storage.FindAllSeq(context.Background(), catalog.AlbumFilter{}, 1, 3))
+
+		assert.Equal(t, want, albs)
+		assert.Nil(t, err)
+	})
+
+	t.Run("stop iterating", func(t *testing.T) {
+		var albs []catalog.Album
+		for alb, err := range storage.FindAllSeq(context.Background(), catalog.AlbumFilter{}, 0, 100) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			albs = append(albs, alb)
+			break
+		}
+
+		assert.Len(t, albs, 1)
+	})
+
+	t.Run("unexpected error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		albs, err := collectAlbums(storage.FindAllSeq(ctx, catalog.AlbumFilter{}, 0, 100))
+
+		assert.Empty(t, albs)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
 func TestPostgresAlbumStorage_FindOne(t *testing.T) {
 	t.Parallel()
 
@@ -142,6 +276,86 @@ func TestPostgresAlbumStorage_FindOne(t *testing.T) {
 	})
 }
 
+func TestPostgresAlbumStorage_slowQueryLog(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+
+	t.Run("slow query logged", func(t *testing.T) {
+		logsBuf := bytes.NewBuffer(nil)
+		logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+		storage := catalog.NewPostgresAlbumStorage(db, catalog.WithSlowQueryLog(logger, 0))
+		prefix := strings.Repeat("a", 40)
+
+		storage.Suggest(context.Background(), prefix, 10)
+
+		logs := logsBuf.String()
+		assert.Contains(t, logs, `level=WARN`)
+		assert.Contains(t, logs, `msg="slow query"`)
+		assert.Contains(t, logs, `query=SuggestAlbums`)
+		assert.Contains(t, logs, `params.prefix=`+strings.Repeat("a", 32)+`...`)
+		assert.Contains(t, logs, `params.limit=10`)
+	})
+
+	t.Run("fast query not logged", func(t *testing.T) {
+		logsBuf := bytes.NewBuffer(nil)
+		logger := slog.New(slog.NewTextHandler(logsBuf, nil))
+		storage := catalog.NewPostgresAlbumStorage(db, catalog.WithSlowQueryLog(logger, time.Hour))
+
+		storage.FindOne(context.Background(), uuid.New())
+
+		assert.Empty(t, logsBuf.String())
+	})
+}
+
+func TestPostgresAlbumStorage_Suggest(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	t.Run("no results from empty database", func(t *testing.T) {
+		albs, err := storage.Suggest(context.Background(), "ana", 10)
+
+		assert.Empty(t, albs)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	byTitle := randomAlbum()
+	byTitle.Title = "Anathema"
+	byArtist := randomAlbum()
+	byArtist.Artist = "ANAVITÓRIA"
+	unmatched := randomAlbum()
+	unmatched.Title = "Banana"
+	unmatched.Artist = "Bananarama"
+	wildcard := randomAlbum()
+	wildcard.Title = "An_Album"
+	insertAlbums(t, db, byTitle, byArtist, unmatched, wildcard)
+
+	t.Run("happy path", func(t *testing.T) {
+		albs, err := storage.Suggest(context.Background(), "ana", 10)
+
+		assert.ElementsMatch(t, []catalog.Album{byTitle, byArtist}, albs)
+		assert.Nil(t, err)
+	})
+
+	t.Run("wildcards are matched literally", func(t *testing.T) {
+		albs, err := storage.Suggest(context.Background(), "an_", 10)
+
+		assert.Equal(t, []catalog.Album{wildcard}, albs)
+		assert.Nil(t, err)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		albs, err := storage.Suggest(context.Background(), "ana", 1)
+
+		assert.Len(t, albs, 1)
+		assert.Nil(t, err)
+	})
+}
+
 func TestPostgresAlbumStorage_Update(t *testing.T) {
 	t.Parallel()
 
@@ -155,16 +369,159 @@ func TestPostgresAlbumStorage_Update(t *testing.T) {
 		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
 	})
 
+	t.Run("album already exists", func(t *testing.T) {
+		other := randomAlbum()
+		albOutdated := randomAlbum()
+		insertAlbums(t, db, other, albOutdated)
+		albUpdated := albOutdated
+		albUpdated.Artist = other.Artist
+		albUpdated.Title = other.Title
+
+		err := storage.Update(context.Background(), albUpdated)
+
+		assert.ErrorIs(t, err, catalog.ErrAlbumAlreadyExists)
+		assert.Equal(t, albOutdated, findAlbum(t, db, albOutdated.ID))
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		albOutdated := randomAlbum()
+		insertAlbums(t, db, albOutdated)
+		albUpdated := randomAlbum()
+		albUpdated.ID = albOutdated.ID
+		albUpdated.Version = albOutdated.Version + 1
+
+		err := storage.Update(context.Background(), albUpdated)
+
+		assert.ErrorIs(t, err, catalog.ErrVersionConflict)
+		assert.Equal(t, albOutdated, findAlbum(t, db, albOutdated.ID))
+	})
+
 	t.Run("happy path", func(t *testing.T) {
 		albOutdated := randomAlbum()
 		insertAlbums(t, db, albOutdated)
 		albUpdated := randomAlbum()
 		albUpdated.ID = albOutdated.ID
+		albUpdated.Version = albOutdated.Version
+		albUpdated.Artwork = "artwork/" + albUpdated.ID.String()
+		want := albUpdated
+		want.Version++
 
 		err := storage.Update(context.Background(), albUpdated)
 
 		assert.Nil(t, err)
-		assert.Equal(t, albUpdated, findAlbum(t, db, albUpdated.ID))
+		assert.Equal(t, want, findAlbum(t, db, albUpdated.ID))
+	})
+}
+
+func TestPostgresAlbumStorage_Upsert(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	t.Run("album created", func(t *testing.T) {
+		alb := randomAlbum()
+
+		stored, created, err := storage.Upsert(context.Background(), alb)
+
+		assert.Nil(t, err)
+		assert.True(t, created)
+		assert.Equal(t, alb, stored)
+		assert.Equal(t, alb, findAlbum(t, db, alb.ID))
+	})
+
+	t.Run("album updated", func(t *testing.T) {
+		albOutdated := randomAlbum()
+		albOutdated.Artwork = "artwork/" + albOutdated.ID.String()
+		insertAlbums(t, db, albOutdated)
+		alb := randomAlbum()
+		alb.ID = albOutdated.ID
+		want := alb
+		want.CreatedAt = albOutdated.CreatedAt
+		want.Artwork = albOutdated.Artwork
+		want.Version = albOutdated.Version + 1
+
+		stored, created, err := storage.Upsert(context.Background(), alb)
+
+		assert.Nil(t, err)
+		assert.False(t, created)
+		assert.Equal(t, want, stored)
+		assert.Equal(t, want, findAlbum(t, db, alb.ID))
+	})
+}
+
+func TestPostgresAlbumStorage_UpdateFunc(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	t.Run("album not found", func(t *testing.T) {
+		alb, err := storage.UpdateFunc(context.Background(), uuid.New(), func(alb catalog.Album) catalog.Album {
+			return alb
+		})
+
+		assert.Empty(t, alb)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	t.Run("album conflict", func(t *testing.T) {
+		albOutdated := randomAlbum()
+		insertAlbums(t, db, albOutdated)
+		albConcurrent := randomAlbum()
+		albConcurrent.ID = albOutdated.ID
+		albConcurrent.Version = albOutdated.Version
+		want := albConcurrent
+		want.Version++
+
+		alb, err := storage.UpdateFunc(context.Background(), albOutdated.ID, func(alb catalog.Album) catalog.Album {
+			// Update the album concurrently, outside of the UpdateFunc transaction.
+			if err := storage.Update(context.Background(), albConcurrent); err != nil {
+				t.Fatal(err)
+			}
+			albUpdated := randomAlbum()
+			albUpdated.Version = alb.Version
+			return albUpdated
+		})
+
+		assert.Empty(t, alb)
+		assert.ErrorIs(t, err, catalog.ErrAlbumConflict)
+		assert.Equal(t, want, findAlbum(t, db, albOutdated.ID))
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		albOutdated := randomAlbum()
+		insertAlbums(t, db, albOutdated)
+
+		alb, err := storage.UpdateFunc(context.Background(), albOutdated.ID, func(alb catalog.Album) catalog.Album {
+			alb.Version++
+			return alb
+		})
+
+		assert.Empty(t, alb)
+		assert.ErrorIs(t, err, catalog.ErrVersionConflict)
+		assert.Equal(t, albOutdated, findAlbum(t, db, albOutdated.ID))
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		albOutdated := randomAlbum()
+		insertAlbums(t, db, albOutdated)
+		albUpdated := randomAlbum()
+		albUpdated.ID = albOutdated.ID
+		albUpdated.Version = albOutdated.Version
+		want := albUpdated
+		want.Version++
+
+		alb, err := storage.UpdateFunc(context.Background(), albOutdated.ID, func(alb catalog.Album) catalog.Album {
+			assert.Equal(t, albOutdated, alb)
+			return albUpdated
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, alb)
+		assert.Equal(t, want, findAlbum(t, db, albUpdated.ID))
 	})
 }
 
@@ -192,15 +549,221 @@ func TestPostgresAlbumStorage_Remove(t *testing.T) {
 	})
 }
 
+func TestPostgresAlbumStorage_RemoveReturning(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	t.Run("album not found", func(t *testing.T) {
+		alb, err := storage.RemoveReturning(context.Background(), uuid.New())
+
+		assert.Empty(t, alb)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		want := randomAlbum()
+		insertAlbums(t, db, want)
+
+		alb, err := storage.RemoveReturning(context.Background(), want.ID)
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, alb)
+		assert.False(t, albumExists(t, db, want.ID))
+	})
+}
+
+func TestPostgresAlbumStorage_History(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	t.Run("album not found", func(t *testing.T) {
+		entries, err := storage.History(context.Background(), uuid.New())
+
+		assert.Empty(t, entries)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		albInserted := randomAlbum()
+		if err := storage.Insert(context.Background(), albInserted); err != nil {
+			t.Fatal(err)
+		}
+		albUpdated := randomAlbum()
+		albUpdated.ID = albInserted.ID
+		albUpdated.Version = albInserted.Version
+		if err := storage.Update(context.Background(), albUpdated); err != nil {
+			t.Fatal(err)
+		}
+		albUpdated.Version++
+		if err := storage.Remove(context.Background(), albInserted.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := storage.History(context.Background(), albInserted.ID)
+
+		assert.Nil(t, err)
+		if assert.Len(t, entries, 3) {
+			assert.Equal(t, "insert", entries[0].Action)
+			assert.Nil(t, entries[0].Before)
+			assert.Equal(t, &albInserted, entries[0].After)
+			assert.Equal(t, "update", entries[1].Action)
+			assert.Equal(t, &albInserted, entries[1].Before)
+			assert.Equal(t, &albUpdated, entries[1].After)
+			assert.Equal(t, "delete", entries[2].Action)
+			assert.Equal(t, &albUpdated, entries[2].Before)
+			assert.Nil(t, entries[2].After)
+		}
+	})
+}
+
+func TestPostgresAlbumStorage_actors(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+
+	t.Run("changes attributed to their actor", func(t *testing.T) {
+		albInserted := randomAlbum()
+		albInserted.CreatedBy = "jtohru"
+		albInserted.UpdatedBy = "jtohru"
+		if err := storage.Insert(catalog.NewActorContext(context.Background(), "jtohru"), albInserted); err != nil {
+			t.Fatal(err)
+		}
+		albUpdated := albInserted
+		albUpdated.Title = random.String(20)
+		albUpdated.UpdatedBy = "curator"
+		if err := storage.Update(catalog.NewActorContext(context.Background(), "curator"), albUpdated); err != nil {
+			t.Fatal(err)
+		}
+		albUpdated.Version++
+		if err := storage.Remove(catalog.NewActorContext(context.Background(), "admin"), albInserted.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := storage.History(context.Background(), albInserted.ID)
+
+		assert.Nil(t, err)
+		if assert.Len(t, entries, 3) {
+			assert.Equal(t, "jtohru", entries[0].Actor)
+			assert.Equal(t, &albInserted, entries[0].After)
+			assert.Equal(t, "curator", entries[1].Actor)
+			assert.Equal(t, &albUpdated, entries[1].After)
+			assert.Equal(t, "admin", entries[2].Actor)
+		}
+	})
+
+	t.Run("unattributed changes", func(t *testing.T) {
+		alb := randomAlbum()
+		if err := storage.Insert(context.Background(), alb); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := storage.History(context.Background(), alb.ID)
+
+		assert.Nil(t, err)
+		if assert.Len(t, entries, 1) {
+			assert.Empty(t, entries[0].Actor)
+		}
+	})
+}
+
+func TestPostgresAlbumStorage_tenants(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresAlbumStorage(db)
+	acme := catalog.NewTenantContext(context.Background(), "acme")
+	globex := catalog.NewTenantContext(context.Background(), "globex")
+
+	t.Run("same artist and title", func(t *testing.T) {
+		albAcme := randomAlbum()
+		albAcme.TenantID = "acme"
+		albGlobex := randomAlbum()
+		albGlobex.Artist = albAcme.Artist
+		albGlobex.Title = albAcme.Title
+		albGlobex.TenantID = "globex"
+		insertAlbums(t, db, albAcme)
+
+		err := storage.Insert(globex, albGlobex)
+
+		assert.Nil(t, err)
+		assert.Equal(t, albGlobex, findAlbum(t, db, albGlobex.ID))
+	})
+
+	t.Run("suggest", func(t *testing.T) {
+		albAcme := randomAlbum()
+		albAcme.Title = "Tenant Suggestion " + albAcme.Title
+		albAcme.TenantID = "acme"
+		albGlobex := randomAlbum()
+		albGlobex.Title = "Tenant Suggestion " + albGlobex.Title
+		albGlobex.TenantID = "globex"
+		insertAlbums(t, db, albAcme, albGlobex)
+
+		albs, err := storage.Suggest(acme, "tenant suggestion", 10)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []catalog.Album{albAcme}, albs)
+	})
+
+	t.Run("upsert album of another tenant", func(t *testing.T) {
+		albGlobex := randomAlbum()
+		albGlobex.TenantID = "globex"
+		insertAlbums(t, db, albGlobex)
+		alb := randomAlbum()
+		alb.ID = albGlobex.ID
+
+		_, _, err := storage.Upsert(acme, alb)
+
+		assert.ErrorIs(t, err, catalog.ErrAlbumAlreadyExists)
+		assert.Equal(t, albGlobex, findAlbum(t, db, albGlobex.ID))
+	})
+
+	t.Run("history of album of another tenant", func(t *testing.T) {
+		alb := randomAlbum()
+		alb.TenantID = "globex"
+		if err := storage.Insert(globex, alb); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := storage.History(acme, alb.ID)
+
+		assert.Empty(t, entries)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+		entries, err = storage.History(globex, alb.ID)
+		assert.Nil(t, err)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, &alb, entries[0].After)
+		}
+	})
+}
+
+// albumLess reports whether a comes before b in the order Albums are found
+// by FindAll.
+func albumLess(a, b catalog.Album) bool {
+	if titleA, titleB := strings.ToLower(a.Title), strings.ToLower(b.Title); titleA != titleB {
+		return titleA < titleB
+	}
+	return a.ID.String() < b.ID.String()
+}
+
 // randomAlbum returns a randomly generated Album.
 func randomAlbum() catalog.Album {
 	return catalog.Album{
 		ID:        uuid.New(),
 		Title:     random.String(20 + rand.IntN(20)),
 		Artist:    random.String(20 + rand.IntN(20)),
-		Price:     rand.IntN(100000),
+		Price:     money.New(rand.Int64N(100000), "EUR"),
 		CreatedAt: random.Time(),
 		UpdatedAt: random.Time(),
+		Version:   rand.IntN(100) + 1,
 	}
 }
 
@@ -213,18 +776,33 @@ func randomAlbums(n int) []catalog.Album {
 	return albs
 }
 
+// collectAlbums collects the Albums yielded by seq until it yields an error.
+func collectAlbums(seq iter.Seq2[catalog.Album, error]) ([]catalog.Album, error) {
+	var albs []catalog.Album
+	for alb, err := range seq {
+		if err != nil {
+			return albs, err
+		}
+		albs = append(albs, alb)
+	}
+	return albs, nil
+}
+
 func findAlbum(t *testing.T, db *sql.DB, albID uuid.UUID) catalog.Album {
 	t.Helper()
 
-	query := "SELECT id, title, artist, price, created_at, updated_at FROM album WHERE id = $1"
+	query := "SELECT id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork FROM album WHERE id = $1"
 	row := db.QueryRow(query, albID)
 	var alb catalog.Album
-	err := row.Scan(&alb.ID, &alb.Title, &alb.Artist, &alb.Price, &alb.CreatedAt, &alb.UpdatedAt)
+	err := row.Scan(
+		&alb.ID, &alb.Title, &alb.Artist, &alb.Price.AmountMinor, &alb.Price.Currency, &alb.CreatedAt, &alb.UpdatedAt, &alb.Version,
+		&alb.TenantID, &alb.CreatedBy, &alb.UpdatedBy, &alb.Artwork,
+	)
 	if err != nil {
 		t.Fatalf("Could not find album: %v", err)
 	}
-	alb.CreatedAt = alb.CreatedAt.Local()
-	alb.UpdatedAt = alb.UpdatedAt.Local()
+	alb.CreatedAt = alb.CreatedAt.UTC()
+	alb.UpdatedAt = alb.UpdatedAt.UTC()
 
 	return alb
 }
@@ -232,7 +810,11 @@ func findAlbum(t *testing.T, db *sql.DB, albID uuid.UUID) catalog.Album {
 func insertAlbums(t *testing.T, db *sql.DB, albs ...catalog.Album) {
 	t.Helper()
 
-	query := "INSERT INTO album (id, title, artist, price, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)"
+	query := `
+		INSERT INTO
+			album (id, title, artist, price, currency, created_at, updated_at, version, tenant_id, created_by, updated_by, artwork)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 	stmt, err := db.Prepare(query)
 	if err != nil {
 		t.Fatal(err)
@@ -240,7 +822,10 @@ func insertAlbums(t *testing.T, db *sql.DB, albs ...catalog.Album) {
 	defer stmt.Close()
 
 	for _, alb := range albs {
-		_, err := stmt.Query(alb.ID, alb.Title, alb.Artist, alb.Price, alb.CreatedAt.UTC(), alb.UpdatedAt.UTC())
+		_, err := stmt.Query(
+			alb.ID, alb.Title, alb.Artist, alb.Price.AmountMinor, alb.Price.Currency, alb.CreatedAt.UTC(), alb.UpdatedAt.UTC(), alb.Version,
+			alb.TenantID, alb.CreatedBy, alb.UpdatedBy, alb.Artwork,
+		)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -0,0 +1,410 @@
+// Package storagetest provides a conformance test suite for implementations of
+// catalog.AlbumStorage.
+package storagetest
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/internal/random"
+	"github.com/jhtohru/go-album-catalog/money"
+)
+
+// RunConformanceTests tests that the AlbumStorage implementation returned by
+// newStorage behaves as documented by catalog.AlbumStorage. newStorage is
+// called once per subtest and must return an empty storage each time.
+func RunConformanceTests(t *testing.T, newStorage func() catalog.AlbumStorage) {
+	t.Run("Insert", func(t *testing.T) {
+		t.Run("happy path", func(t *testing.T) {
+			storage := newStorage()
+			alb := randomAlbum()
+
+			err := storage.Insert(context.Background(), alb)
+
+			assert.Nil(t, err)
+			assert.Equal(t, alb, findOne(t, storage, alb.ID))
+		})
+
+		t.Run("album already exists", func(t *testing.T) {
+			storage := newStorage()
+			stored := randomAlbum()
+			insertAlbums(t, storage, stored)
+			alb := randomAlbum()
+			alb.Artist = strings.ToUpper(stored.Artist)
+			alb.Title = strings.ToLower(stored.Title)
+
+			err := storage.Insert(context.Background(), alb)
+
+			assert.ErrorIs(t, err, catalog.ErrAlbumAlreadyExists)
+			_, err = storage.FindOne(context.Background(), alb.ID)
+			assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+		})
+	})
+
+	t.Run("FindAll", func(t *testing.T) {
+		testFindAll(t, newStorage)
+	})
+
+	t.Run("FindOne", func(t *testing.T) {
+		t.Run("album not found", func(t *testing.T) {
+			storage := newStorage()
+
+			alb, err := storage.FindOne(context.Background(), uuid.New())
+
+			assert.Empty(t, alb)
+			assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+		})
+
+		t.Run("happy path", func(t *testing.T) {
+			storage := newStorage()
+			want := randomAlbum()
+			insertAlbums(t, storage, want, randomAlbum())
+
+			alb, err := storage.FindOne(context.Background(), want.ID)
+
+			assert.Nil(t, err)
+			assert.Equal(t, want, alb)
+		})
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		testUpdate(t, newStorage)
+	})
+
+	t.Run("Tenants", func(t *testing.T) {
+		testTenants(t, newStorage)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		t.Run("album not found", func(t *testing.T) {
+			storage := newStorage()
+
+			err := storage.Remove(context.Background(), uuid.New())
+
+			assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+		})
+
+		t.Run("happy path", func(t *testing.T) {
+			storage := newStorage()
+			alb := randomAlbum()
+			kept := randomAlbum()
+			insertAlbums(t, storage, alb, kept)
+
+			err := storage.Remove(context.Background(), alb.ID)
+
+			assert.Nil(t, err)
+			_, err = storage.FindOne(context.Background(), alb.ID)
+			assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+			assert.Equal(t, kept, findOne(t, storage, kept.ID))
+		})
+
+		t.Run("removed twice", func(t *testing.T) {
+			storage := newStorage()
+			alb := randomAlbum()
+			insertAlbums(t, storage, alb)
+			if err := storage.Remove(context.Background(), alb.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			err := storage.Remove(context.Background(), alb.ID)
+
+			assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+		})
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		testCanceled(t, newStorage)
+	})
+}
+
+func testFindAll(t *testing.T, newStorage func() catalog.AlbumStorage) {
+	t.Run("no results from empty storage", func(t *testing.T) {
+		storage := newStorage()
+
+		albs, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, 0, 100)
+
+		assert.Empty(t, albs)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	// fixture returns a storage populated with n albums and the albums in the
+	// order FindAll must return them.
+	fixture := func(n int) (catalog.AlbumStorage, []catalog.Album) {
+		storage := newStorage()
+		albs := randomAlbums(n)
+		insertAlbums(t, storage, albs...)
+		sort.Slice(albs, func(i, j int) bool {
+			return albumLess(albs[i], albs[j])
+		})
+		return storage, albs
+	}
+
+	tests := map[string]struct {
+		offset, limit int
+		wantFrom      int
+		wantTo        int
+	}{
+		"first page":             {offset: 0, limit: 3, wantFrom: 0, wantTo: 3},
+		"middle page":            {offset: 3, limit: 3, wantFrom: 3, wantTo: 6},
+		"last page":              {offset: 6, limit: 4, wantFrom: 6, wantTo: 10},
+		"partial last page":      {offset: 8, limit: 5, wantFrom: 8, wantTo: 10},
+		"limit beyond all":       {offset: 0, limit: 100, wantFrom: 0, wantTo: 10},
+		"single album page":      {offset: 9, limit: 1, wantFrom: 9, wantTo: 10},
+		"offset of the last one": {offset: 9, limit: 10, wantFrom: 9, wantTo: 10},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storage, albs := fixture(10)
+
+			got, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, test.offset, test.limit)
+
+			assert.Nil(t, err)
+			assert.Equal(t, albs[test.wantFrom:test.wantTo], got)
+		})
+	}
+
+	t.Run("no results past the last album", func(t *testing.T) {
+		storage, albs := fixture(10)
+
+		got, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, len(albs), 10)
+
+		assert.Empty(t, got)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	t.Run("identical titles ordered by ID", func(t *testing.T) {
+		storage := newStorage()
+		albs := randomAlbums(5)
+		for i := range albs {
+			albs[i].Title = "Identical Title"
+		}
+		insertAlbums(t, storage, albs...)
+		sort.Slice(albs, func(i, j int) bool {
+			return albumLess(albs[i], albs[j])
+		})
+
+		got, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, 0, len(albs))
+
+		assert.Nil(t, err)
+		assert.Equal(t, albs, got)
+	})
+
+	t.Run("stable ordering", func(t *testing.T) {
+		storage, _ := fixture(10)
+
+		first, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := storage.FindAll(context.Background(), catalog.AlbumFilter{}, 0, 10)
+
+		assert.Nil(t, err)
+		assert.Equal(t, first, second)
+	})
+}
+
+func testUpdate(t *testing.T, newStorage func() catalog.AlbumStorage) {
+	t.Run("album not found", func(t *testing.T) {
+		storage := newStorage()
+
+		err := storage.Update(context.Background(), randomAlbum())
+
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		storage := newStorage()
+		albOutdated := randomAlbum()
+		insertAlbums(t, storage, albOutdated)
+		albUpdated := randomAlbum()
+		albUpdated.ID = albOutdated.ID
+		albUpdated.Version = albOutdated.Version + 1
+
+		err := storage.Update(context.Background(), albUpdated)
+
+		assert.ErrorIs(t, err, catalog.ErrVersionConflict)
+		assert.Equal(t, albOutdated, findOne(t, storage, albOutdated.ID))
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		storage := newStorage()
+		albOutdated := randomAlbum()
+		other := randomAlbum()
+		insertAlbums(t, storage, albOutdated, other)
+		albUpdated := randomAlbum()
+		albUpdated.ID = albOutdated.ID
+		albUpdated.Version = albOutdated.Version
+		want := albUpdated
+		want.Version++
+
+		err := storage.Update(context.Background(), albUpdated)
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, findOne(t, storage, albUpdated.ID))
+		assert.Equal(t, other, findOne(t, storage, other.ID))
+	})
+}
+
+func testTenants(t *testing.T, newStorage func() catalog.AlbumStorage) {
+	acme := catalog.NewTenantContext(context.Background(), "acme")
+	globex := catalog.NewTenantContext(context.Background(), "globex")
+
+	t.Run("isolated catalogs", func(t *testing.T) {
+		storage := newStorage()
+		albAcme := randomAlbum()
+		albAcme.TenantID = "acme"
+		albGlobex := randomAlbum()
+		albGlobex.TenantID = "globex"
+		if err := storage.Insert(acme, albAcme); err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.Insert(globex, albGlobex); err != nil {
+			t.Fatal(err)
+		}
+
+		albs, err := storage.FindAll(acme, catalog.AlbumFilter{}, 0, 10)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []catalog.Album{albAcme}, albs)
+		_, err = storage.FindOne(acme, albGlobex.ID)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+		_, err = storage.FindAll(context.Background(), catalog.AlbumFilter{}, 0, 10)
+		assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+	})
+
+	t.Run("same artist and title", func(t *testing.T) {
+		storage := newStorage()
+		albAcme := randomAlbum()
+		albAcme.TenantID = "acme"
+		albGlobex := randomAlbum()
+		albGlobex.Artist = albAcme.Artist
+		albGlobex.Title = albAcme.Title
+		albGlobex.TenantID = "globex"
+		if err := storage.Insert(acme, albAcme); err != nil {
+			t.Fatal(err)
+		}
+
+		err := storage.Insert(globex, albGlobex)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("album of another tenant not changed", func(t *testing.T) {
+		storage := newStorage()
+		alb := randomAlbum()
+		alb.TenantID = "acme"
+		if err := storage.Insert(acme, alb); err != nil {
+			t.Fatal(err)
+		}
+		albUpdated := randomAlbum()
+		albUpdated.ID = alb.ID
+		albUpdated.Version = alb.Version
+
+		errUpdate := storage.Update(globex, albUpdated)
+		errRemove := storage.Remove(globex, alb.ID)
+
+		assert.ErrorIs(t, errUpdate, catalog.ErrAlbumNotFound)
+		assert.ErrorIs(t, errRemove, catalog.ErrAlbumNotFound)
+		found, err := storage.FindOne(acme, alb.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, alb, found)
+	})
+}
+
+// albumLess reports whether a comes before b in the order Albums are found
+// by FindAll.
+// testCanceled tests that the storage fails the calls whose context is
+// canceled, so that the requests timing out do not wait for it, without
+// changing the albums.
+func testCanceled(t *testing.T, newStorage func() catalog.AlbumStorage) {
+	storage := newStorage()
+	stored := randomAlbum()
+	insertAlbums(t, storage, stored)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	alb := randomAlbum()
+	err := storage.Insert(ctx, alb)
+	assert.ErrorIs(t, err, context.Canceled, "Insert")
+	_, err = storage.FindOne(context.Background(), alb.ID)
+	assert.ErrorIs(t, err, catalog.ErrAlbumNotFound)
+
+	_, err = storage.FindAll(ctx, catalog.AlbumFilter{}, 0, 10)
+	assert.ErrorIs(t, err, context.Canceled, "FindAll")
+
+	_, err = storage.FindOne(ctx, stored.ID)
+	assert.ErrorIs(t, err, context.Canceled, "FindOne")
+
+	updated := stored
+	updated.Title = random.AlbumTitle()
+	err = storage.Update(ctx, updated)
+	assert.ErrorIs(t, err, context.Canceled, "Update")
+
+	err = storage.Remove(ctx, stored.ID)
+	assert.ErrorIs(t, err, context.Canceled, "Remove")
+
+	assert.Equal(t, stored, findOne(t, storage, stored.ID))
+}
+
+func albumLess(a, b catalog.Album) bool {
+	if titleA, titleB := strings.ToLower(a.Title), strings.ToLower(b.Title); titleA != titleB {
+		return titleA < titleB
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+// randomAlbum returns a randomly generated Album.
+func randomAlbum() catalog.Album {
+	return catalog.Album{
+		ID:        uuid.New(),
+		Title:     random.String(20 + rand.IntN(20)),
+		Artist:    random.String(20 + rand.IntN(20)),
+		Price:     money.New(rand.Int64N(100000), randomCurrency()),
+		CreatedAt: random.Time(),
+		UpdatedAt: random.Time(),
+		Version:   rand.IntN(100) + 1,
+	}
+}
+
+// randomCurrency returns one of a few currencies, so that the storage is
+// checked to keep the currency of the prices.
+func randomCurrency() string {
+	currencies := []string{"USD", "EUR", "JPY", "BRL"}
+	return currencies[rand.IntN(len(currencies))]
+}
+
+// randomAlbums returns a slice containing n randomly generated Albums.
+func randomAlbums(n int) []catalog.Album {
+	albs := make([]catalog.Album, n)
+	for i := range albs {
+		albs[i] = randomAlbum()
+	}
+	return albs
+}
+
+func insertAlbums(t *testing.T, storage catalog.AlbumStorage, albs ...catalog.Album) {
+	t.Helper()
+
+	for _, alb := range albs {
+		if err := storage.Insert(context.Background(), alb); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func findOne(t *testing.T, storage catalog.AlbumStorage, albID uuid.UUID) catalog.Album {
+	t.Helper()
+
+	alb, err := storage.FindOne(context.Background(), albID)
+	if err != nil {
+		t.Fatalf("Could not find album: %v", err)
+	}
+	return alb
+}
@@ -0,0 +1,44 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+)
+
+// DefaultTenant is the tenant of the requests not scoped to any, which is the
+// only tenant of single-tenant deployments.
+const DefaultTenant = ""
+
+// tenantKey is the context key of the tenant of a request.
+type tenantKey struct{}
+
+// NewTenantContext returns a copy of ctx scoped to the catalog of tenant.
+// AlbumStorage implementations only operate on the Albums of the tenant of
+// their context.
+func NewTenantContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to, or DefaultTenant if
+// it is not scoped to any.
+func TenantFromContext(ctx context.Context) string {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	if !ok {
+		return DefaultTenant
+	}
+	return tenant
+}
+
+// scopeToTenant returns an http.Handler that passes requests to next scoped
+// to the tenant of their Principal. Unauthenticated requests are passed
+// scoped to DefaultTenant.
+func scopeToTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := auth.FromContext(r.Context()); ok {
+			r = r.WithContext(NewTenantContext(r.Context(), p.Tenant))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
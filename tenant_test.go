@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/auth"
+)
+
+func TestScopeToTenant(t *testing.T) {
+	tests := map[string]struct {
+		principal  *auth.Principal
+		tenantWant string
+	}{
+		"unauthenticated": {
+			tenantWant: DefaultTenant,
+		},
+		"principal without tenant": {
+			principal:  &auth.Principal{Subject: "jtohru"},
+			tenantWant: DefaultTenant,
+		},
+		"principal with tenant": {
+			principal:  &auth.Principal{Subject: "jtohru", Tenant: "acme"},
+			tenantWant: "acme",
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var tenant string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenant = TenantFromContext(r.Context())
+			})
+			req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+			if test.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), *test.principal))
+			}
+
+			scopeToTenant(next).ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, test.tenantWant, tenant)
+		})
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	assert.Equal(t, DefaultTenant, TenantFromContext(context.Background()))
+	assert.Equal(t, "acme", TenantFromContext(NewTenantContext(context.Background(), "acme")))
+}
@@ -0,0 +1,245 @@
+// Package validation validates values by rules, such as the ones of the
+// validate tags of the fields of a struct:
+//
+//	type request struct {
+//		Title string `json:"title" validate:"required,max=200"`
+//		Price int    `json:"price" validate:"gt=0"`
+//	}
+//
+// The rules of a field are separated by commas, and are checked in order
+// until one of them fails, whose problem is reported for the field by its
+// JSON name:
+//
+//   - required: the value is not the zero value, or not empty if it is a
+//     string, slice or map.
+//   - omitempty: if the value is the zero value, the rules after it are not
+//     checked.
+//   - min=N, max=N: the length of a string, in characters, or of a slice or
+//     map, or the number, is at least or at most N.
+//   - gt=N, lt=N: the number is greater or less than N.
+//   - oneof=A B C: the string or number, or every element of the slice, is
+//     one of the space separated values.
+//   - http_url: the string is an absolute http or https URL.
+//
+// Malformed rules are programming errors, so they cause a panic.
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Struct returns the problems of the fields of v, a struct or a pointer to
+// one, by the rules of their validate tags. The problems are keyed by the JSON
+// name of their field, and there is none if v is valid.
+func Struct(v any) map[string]string {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: Struct of %T, which is not a struct", v))
+	}
+	problems := make(map[string]string)
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		rules, ok := field.Tag.Lookup("validate")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if problem := check(rv.Field(i), rules); problem != "" {
+			problems[name] = problem
+		}
+	}
+	return problems
+}
+
+// Value returns the problem of v by rules, written as in a validate tag, or
+// an empty string if v is valid.
+func Value(v any, rules string) string {
+	return check(reflect.ValueOf(v), rules)
+}
+
+// jsonName returns the name of field in its JSON representation.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// check returns the problem of v by rules, or an empty string if v is valid.
+func check(v reflect.Value, rules string) string {
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "omitempty" {
+			if !v.IsValid() || v.IsZero() {
+				return ""
+			}
+			continue
+		}
+		if problem := checkRule(v, name, param); problem != "" {
+			return problem
+		}
+	}
+	return ""
+}
+
+// checkRule returns the problem of v by the rule with name and param, or an
+// empty string if v is valid.
+func checkRule(v reflect.Value, name, param string) string {
+	if name == "required" {
+		return checkRequired(v)
+	}
+	// The other rules are of the values pointed to, and do not apply to the
+	// missing ones.
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch name {
+	case "min", "max":
+		return checkBound(v, name, parseNumber(name, param))
+	case "gt":
+		if n := parseNumber(name, param); number(v, name) <= n {
+			return "is not greater than " + formatNumber(n)
+		}
+	case "lt":
+		if n := parseNumber(name, param); number(v, name) >= n {
+			return "is not less than " + formatNumber(n)
+		}
+	case "oneof":
+		return checkOneOf(v, strings.Fields(param))
+	case "http_url":
+		if u, err := url.Parse(str(v, name)); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "is not an absolute http or https url"
+		}
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q", name))
+	}
+	return ""
+}
+
+// checkRequired returns the problem of v by the required rule.
+func checkRequired(v reflect.Value) string {
+	if !v.IsValid() {
+		return "is missing"
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return "is empty"
+		}
+	default:
+		if v.IsZero() {
+			return "is missing"
+		}
+	}
+	return ""
+}
+
+// checkBound returns the problem of v by the min or max rule, named rule,
+// bounding it to n.
+func checkBound(v reflect.Value, rule string, n float64) string {
+	switch v.Kind() {
+	case reflect.String:
+		length := float64(utf8.RuneCountInString(v.String()))
+		if rule == "min" && length < n {
+			return fmt.Sprintf("is shorter than %s characters", formatNumber(n))
+		}
+		if rule == "max" && length > n {
+			return fmt.Sprintf("is longer than %s characters", formatNumber(n))
+		}
+	case reflect.Slice, reflect.Map:
+		length := float64(v.Len())
+		if rule == "min" && length < n {
+			return fmt.Sprintf("has fewer than %s items", formatNumber(n))
+		}
+		if rule == "max" && length > n {
+			return fmt.Sprintf("has more than %s items", formatNumber(n))
+		}
+	default:
+		x := number(v, rule)
+		if rule == "min" && x < n {
+			return "is less than " + formatNumber(n)
+		}
+		if rule == "max" && x > n {
+			return "is greater than " + formatNumber(n)
+		}
+	}
+	return ""
+}
+
+// checkOneOf returns the problem of v by the oneof rule of values.
+func checkOneOf(v reflect.Value, values []string) string {
+	if v.Kind() == reflect.Slice {
+		for i := range v.Len() {
+			if !slices.Contains(values, format(v.Index(i))) {
+				return "contains a value not one of: " + strings.Join(values, ", ")
+			}
+		}
+		return ""
+	}
+	if !slices.Contains(values, format(v)) {
+		return "is not one of: " + strings.Join(values, ", ")
+	}
+	return ""
+}
+
+// number returns v as a float64, panicking if it is not a number, as rule
+// only applies to numbers.
+func number(v reflect.Value, rule string) float64 {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	case v.CanFloat():
+		return v.Float()
+	}
+	panic(fmt.Sprintf("validation: rule %q of a %s, which is not a number", rule, v.Type()))
+}
+
+// str returns v as a string, panicking if it is not a string, as rule only
+// applies to strings.
+func str(v reflect.Value, rule string) string {
+	if v.Kind() != reflect.String {
+		panic(fmt.Sprintf("validation: rule %q of a %s, which is not a string", rule, v.Type()))
+	}
+	return v.String()
+}
+
+// format returns the string representation of the string or number v.
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return strconv.FormatFloat(number(v, "oneof"), 'f', -1, 64)
+}
+
+// parseNumber parses the param of rule as a number.
+func parseNumber(rule, param string) float64 {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: rule %q with malformed number %q", rule, param))
+	}
+	return n
+}
+
+// formatNumber formats n as in the problems, spelling out zero.
+func formatNumber(n float64) string {
+	if n == 0 {
+		return "zero"
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
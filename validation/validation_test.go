@@ -0,0 +1,91 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/validation"
+)
+
+func TestStruct(t *testing.T) {
+	type album struct {
+		Title    string   `json:"title" validate:"required,max=10"`
+		Artist   string   `json:"artist,omitempty" validate:"required"`
+		Price    int      `json:"price" validate:"gt=0,lt=10000"`
+		Discount *int     `json:"discount" validate:"min=0,max=100"`
+		Tags     []string `json:"tags" validate:"max=2,oneof=rock jazz pop"`
+		Format   string   `json:"format" validate:"omitempty,oneof=cd vinyl"`
+		Website  string   `validate:"omitempty,http_url"`
+		Secret   string   `json:"-" validate:"required"`
+		Notes    string   `json:"notes"`
+	}
+	discount := func(n int) *int { return &n }
+	tests := map[string]struct {
+		v            any
+		problemsWant map[string]string
+	}{
+		"valid": {
+			v:            album{Title: "Nevermind", Artist: "Nirvana", Price: 2999, Tags: []string{"rock"}},
+			problemsWant: map[string]string{},
+		},
+		"valid pointer": {
+			v:            &album{Title: "Nevermind", Artist: "Nirvana", Price: 2999, Discount: discount(10), Format: "cd", Website: "https://nirvana.com"},
+			problemsWant: map[string]string{},
+		},
+		"zero values": {
+			v: album{},
+			problemsWant: map[string]string{
+				"title":  "is empty",
+				"artist": "is empty",
+				"price":  "is not greater than zero",
+			},
+		},
+		"out of bounds": {
+			v: album{
+				Title:    "In Utero (20th Anniversary Remaster)",
+				Artist:   "Nirvana",
+				Price:    10000,
+				Discount: discount(101),
+				Tags:     []string{"rock", "grunge", "alternative"},
+				Format:   "tape",
+				Website:  "ftp://nirvana.com",
+			},
+			problemsWant: map[string]string{
+				"title":    "is longer than 10 characters",
+				"price":    "is not less than 10000",
+				"discount": "is greater than 100",
+				"tags":     "has more than 2 items",
+				"format":   "is not one of: cd, vinyl",
+				"Website":  "is not an absolute http or https url",
+			},
+		},
+		"unknown element": {
+			v:            album{Title: "Nevermind", Artist: "Nirvana", Price: 2999, Tags: []string{"rock", "grunge"}, Discount: discount(-1)},
+			problemsWant: map[string]string{"tags": "contains a value not one of: rock, jazz, pop", "discount": "is less than zero"},
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.problemsWant, validation.Struct(test.v))
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	assert.Equal(t, "", validation.Value("Nevermind", "required,max=200"))
+	assert.Equal(t, "is shorter than 16 characters", validation.Value("secret", "min=16"))
+	assert.Equal(t, "is longer than 3 characters", validation.Value("Ñandú", "max=3"))
+	assert.Equal(t, "", validation.Value("Ñandú", "max=5"))
+	assert.Equal(t, "is greater than 99.99", validation.Value(100.0, "max=99.99"))
+	assert.Equal(t, "", validation.Value(uint(3), "oneof=1 2 3"))
+	assert.Equal(t, "is missing", validation.Value(0, "required"))
+	assert.Equal(t, "has fewer than 1 items", validation.Value(map[string]int{}, "min=1"))
+}
+
+func TestValue_malformedRules(t *testing.T) {
+	assert.Panics(t, func() { validation.Value("Nevermind", "unknown") })
+	assert.Panics(t, func() { validation.Value("Nevermind", "max=two") })
+	assert.Panics(t, func() { validation.Value("Nevermind", "gt=0") })
+	assert.Panics(t, func() { validation.Struct("Nevermind") })
+}
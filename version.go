@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// The build information set at link time, which takes precedence over the
+// build information embedded by the go command. For example:
+//
+//	go build -ldflags "-X github.com/jhtohru/go-album-catalog.buildCommit=$(git rev-parse HEAD)" ./cmd/catalog
+var (
+	buildVersion string
+	buildCommit  string
+	buildTime    string
+)
+
+// BuildInfo describes the build of the running application.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// ReadBuildInfo returns the BuildInfo of the running application, read from
+// the values set at link time or else from the build information embedded by
+// the go command. The fields that are not known are left empty.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Version = bi.Main.Version
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if buildVersion != "" {
+		info.Version = buildVersion
+	}
+	if buildCommit != "" {
+		info.Commit = buildCommit
+	}
+	if buildTime != "" {
+		info.BuildTime = buildTime
+	}
+	return info
+}
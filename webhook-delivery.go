@@ -0,0 +1,204 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/webhook"
+)
+
+// NewWebhookPublisher returns an EventPublisher that queues the delivery of
+// every event into db, once for each webhook subscription of the tenant of its
+// album subscribed to its type. Publishing an event more than once queues its
+// deliveries only once.
+func NewWebhookPublisher(db *sql.DB) EventPublisher {
+	return EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+		alb, err := envelopeAlbum(env)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(env)
+		if err != nil {
+			return fmt.Errorf("encoding %s event: %w", env.Type, err)
+		}
+		query := `
+			INSERT INTO
+				webhook_delivery (subscription_id, event_id, payload)
+			SELECT
+				id, $1, $2
+			FROM
+				webhook_subscription
+			WHERE
+				tenant_id = $3 AND $4 = ANY(event_types)
+			ON CONFLICT (subscription_id, event_id) DO NOTHING`
+		_, err = db.ExecContext(ctx, query, env.ID, payload, alb.TenantID, string(env.Type))
+		return err
+	})
+}
+
+// webhookBatchSize is the maximum number of deliveries attempted by
+// DeliverWebhooks.
+const webhookBatchSize = 20
+
+// webhookMaxAttempts is the number of times a delivery is attempted before it
+// is dead.
+const webhookMaxAttempts = 10
+
+// webhookRetryDelay returns how long to wait before attempting again a delivery
+// that failed attempts times: 30 seconds after the first failure, doubling
+// after every other one, up to an hour.
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= time.Hour {
+			return time.Hour
+		}
+	}
+	return delay
+}
+
+// DeliverWebhooks attempts up to 20 of the due webhook deliveries queued into
+// db, oldest first, by POSTing their events signed with the secret of their
+// subscription through client. The deliveries responded with a 2xx status
+// code are delivered, while the other ones are attempted again later, backing
+// off exponentially, until they run out of attempts and are dead. Dead
+// deliveries are kept, with their last error, to be inspected. It returns the
+// number of deliveries attempted.
+func DeliverWebhooks(ctx context.Context, db *sql.DB, client *http.Client) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	// Skip the deliveries locked by concurrent workers, so that each delivery
+	// is attempted by a single worker at a time.
+	query := `
+		SELECT
+			d.id, d.attempts, d.payload, s.url, s.secret
+		FROM
+			webhook_delivery d
+			JOIN webhook_subscription s ON s.id = d.subscription_id
+		WHERE
+			d.delivered_at IS NULL AND d.dead_at IS NULL AND d.next_attempt_at <= now()
+		ORDER BY
+			d.next_attempt_at ASC, d.id ASC
+		LIMIT
+			$1
+		FOR UPDATE OF d SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	type delivery struct {
+		id       int64
+		attempts int
+		payload  []byte
+		url      string
+		secret   string
+	}
+	var deliveries []delivery
+	for rows.Next() {
+		var d delivery
+		if err := rows.Scan(&d.id, &d.attempts, &d.payload, &d.url, &d.secret); err != nil {
+			return 0, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+	for _, d := range deliveries {
+		attempts := d.attempts + 1
+		deliverErr := deliverWebhook(ctx, client, d.url, []byte(d.secret), d.payload)
+		if deliverErr == nil {
+			query = `
+				UPDATE webhook_delivery
+				SET attempts = $2, delivered_at = now(), last_error = NULL
+				WHERE id = $1`
+			if _, err := tx.ExecContext(ctx, query, d.id, attempts); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		query = `
+			UPDATE webhook_delivery
+			SET
+				attempts = $2,
+				last_error = $3,
+				next_attempt_at = now() + make_interval(secs => $4),
+				dead_at = CASE WHEN $5::boolean THEN now() END
+			WHERE id = $1`
+		delay := webhookRetryDelay(attempts).Seconds()
+		dead := attempts >= webhookMaxAttempts
+		if _, err := tx.ExecContext(ctx, query, d.id, attempts, deliverErr.Error(), delay, dead); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(deliveries), nil
+}
+
+// deliverWebhook POSTs payload signed with secret to url through client,
+// returning an error if it fails or is not responded with a 2xx status code.
+func deliverWebhook(ctx context.Context, client *http.Client, url string, secret, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	webhook.SignRequest(req, secret, time.Now(), payload)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// RunWebhookDelivery delivers the webhooks queued into db through client once
+// every interval until ctx is done, delivering again at once while there are
+// due deliveries left. Failures are reported to onError and do not stop the
+// deliveries.
+func RunWebhookDelivery(
+	ctx context.Context,
+	db *sql.DB,
+	client *http.Client,
+	interval time.Duration,
+	onError func(error),
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				attempted, err := DeliverWebhooks(ctx, db, client)
+				if err != nil {
+					onError(err)
+				}
+				if err != nil || attempted < webhookBatchSize {
+					break
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
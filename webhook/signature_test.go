@@ -0,0 +1,91 @@
+package webhook_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jhtohru/go-album-catalog/webhook"
+)
+
+var (
+	secret  = []byte("whsec_0123456789abcdef")
+	payload = []byte(`{"type":"album.created"}`)
+	now     = time.Date(2024, 8, 26, 12, 0, 0, 0, time.UTC)
+)
+
+func TestSign(t *testing.T) {
+	signature := webhook.Sign(secret, now, payload)
+
+	// printf '1724673600.{"type":"album.created"}' | openssl dgst -sha256 -hmac whsec_0123456789abcdef
+	assert.Equal(t, "sha256=2788962f6d2ea455f7393dbe286f73f9b33a8d217d8e0454c6bf1916c2136b1e", signature)
+}
+
+func TestVerify(t *testing.T) {
+	tests := map[string]struct {
+		timestamp string
+		signature string
+		payload   []byte
+		errWant   error
+	}{
+		"happy path": {
+			timestamp: "1724673600",
+			signature: webhook.Sign(secret, now, payload),
+			payload:   payload,
+		},
+		"tampered payload": {
+			timestamp: "1724673600",
+			signature: webhook.Sign(secret, now, payload),
+			payload:   []byte(`{"type":"album.deleted"}`),
+			errWant:   webhook.ErrInvalidSignature,
+		},
+		"tampered timestamp": {
+			timestamp: "1724673660",
+			signature: webhook.Sign(secret, now, payload),
+			payload:   payload,
+			errWant:   webhook.ErrInvalidSignature,
+		},
+		"other secret": {
+			timestamp: "1724673600",
+			signature: webhook.Sign([]byte("other"), now, payload),
+			payload:   payload,
+			errWant:   webhook.ErrInvalidSignature,
+		},
+		"malformed timestamp": {
+			timestamp: "yesterday",
+			signature: webhook.Sign(secret, now, payload),
+			payload:   payload,
+			errWant:   webhook.ErrInvalidSignature,
+		},
+		"missing signature": {
+			timestamp: "1724673600",
+			payload:   payload,
+			errWant:   webhook.ErrInvalidSignature,
+		},
+		"replayed delivery": {
+			timestamp: "1724673000",
+			signature: webhook.Sign(secret, now.Add(-10*time.Minute), payload),
+			payload:   payload,
+			errWant:   webhook.ErrExpiredTimestamp,
+		},
+	}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := webhook.Verify(secret, test.timestamp, test.signature, test.payload, now, 5*time.Minute)
+
+			assert.ErrorIs(t, err, test.errWant)
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/hooks/catalog", nil)
+
+	webhook.SignRequest(req, secret, now, payload)
+
+	assert.Equal(t, "1724673600", req.Header.Get(webhook.TimestampHeader))
+	assert.Nil(t, webhook.VerifyRequest(req, secret, payload, now.Add(time.Minute), 5*time.Minute))
+}
@@ -0,0 +1,165 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/internal/pgdb"
+)
+
+// WebhookSubscription subscribes a URL to the album change events of some
+// types. The events are delivered to the URL by POST requests signed with the
+// secret of the subscription, as described by the webhook package.
+type WebhookSubscription struct {
+	ID         uuid.UUID     `json:"id"`
+	URL        string        `json:"url"`
+	EventTypes []events.Type `json:"event_types"`
+	// Secret is the secret the deliveries are signed with. It is never
+	// responded.
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// TenantID is the tenant whose album changes are delivered, empty for the
+	// default one.
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// WebhookStorage represents a webhook subscription storage.
+//
+// As an AlbumStorage does, a WebhookStorage keeps separate subscriptions for
+// each tenant, only operating on the subscriptions of the tenant its context
+// is scoped to by NewTenantContext.
+type WebhookStorage interface {
+	// Insert inserts a WebhookSubscription into the storage.
+	Insert(ctx context.Context, sub WebhookSubscription) error
+	// FindAll finds all WebhookSubscriptions in the storage, oldest first.
+	FindAll(ctx context.Context) ([]WebhookSubscription, error)
+	// FindOne finds a single WebhookSubscription in the storage. It returns
+	// ErrWebhookNotFound if there is no WebhookSubscription in the storage
+	// whose ID is equal to id.
+	FindOne(ctx context.Context, id uuid.UUID) (WebhookSubscription, error)
+	// Update updates the URL, event types, secret and update time of the
+	// single WebhookSubscription in the storage whose ID is equal to sub.ID,
+	// returning the updated WebhookSubscription. It returns
+	// ErrWebhookNotFound if there is no such WebhookSubscription.
+	Update(ctx context.Context, sub WebhookSubscription) (WebhookSubscription, error)
+	// Remove removes the single WebhookSubscription in the storage whose ID is
+	// equal to id, along with its pending deliveries. It returns
+	// ErrWebhookNotFound if there is no such WebhookSubscription.
+	Remove(ctx context.Context, id uuid.UUID) error
+}
+
+// ErrWebhookNotFound is returned when the required webhook subscription is not
+// found in the WebhookStorage.
+var ErrWebhookNotFound = errors.New("webhook subscription not found")
+
+type pgWebhookStorage struct {
+	queries *pgdb.Queries
+}
+
+// NewPostgresWebhookStorage returns a new WebhookStorage that uses Postgres to
+// manage data.
+func NewPostgresWebhookStorage(db *sql.DB) WebhookStorage {
+	return &pgWebhookStorage{queries: pgdb.New(db)}
+}
+
+func (s *pgWebhookStorage) Insert(ctx context.Context, sub WebhookSubscription) error {
+	return s.queries.InsertWebhookSubscription(ctx, pgdb.InsertWebhookSubscriptionParams{
+		ID:         sub.ID,
+		TenantID:   TenantFromContext(ctx),
+		Url:        sub.URL,
+		EventTypes: eventTypeStrings(sub.EventTypes),
+		Secret:     sub.Secret,
+		CreatedAt:  sub.CreatedAt,
+		UpdatedAt:  sub.UpdatedAt,
+	})
+}
+
+func (s *pgWebhookStorage) FindAll(ctx context.Context) ([]WebhookSubscription, error) {
+	rows, err := s.queries.FindWebhookSubscriptions(ctx, TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]WebhookSubscription, len(rows))
+	for i, row := range rows {
+		subs[i] = webhookSubscriptionFromRow(row)
+	}
+	return subs, nil
+}
+
+func (s *pgWebhookStorage) FindOne(ctx context.Context, id uuid.UUID) (WebhookSubscription, error) {
+	row, err := s.queries.FindWebhookSubscription(ctx, pgdb.FindWebhookSubscriptionParams{
+		ID:       id,
+		TenantID: TenantFromContext(ctx),
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return WebhookSubscription{}, ErrWebhookNotFound
+	case err != nil:
+		return WebhookSubscription{}, err
+	}
+	return webhookSubscriptionFromRow(row), nil
+}
+
+func (s *pgWebhookStorage) Update(ctx context.Context, sub WebhookSubscription) (WebhookSubscription, error) {
+	row, err := s.queries.UpdateWebhookSubscription(ctx, pgdb.UpdateWebhookSubscriptionParams{
+		Url:        sub.URL,
+		EventTypes: eventTypeStrings(sub.EventTypes),
+		Secret:     sub.Secret,
+		UpdatedAt:  sub.UpdatedAt,
+		ID:         sub.ID,
+		TenantID:   TenantFromContext(ctx),
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return WebhookSubscription{}, ErrWebhookNotFound
+	case err != nil:
+		return WebhookSubscription{}, err
+	}
+	return webhookSubscriptionFromRow(row), nil
+}
+
+func (s *pgWebhookStorage) Remove(ctx context.Context, id uuid.UUID) error {
+	rowsAffected, err := s.queries.RemoveWebhookSubscription(ctx, pgdb.RemoveWebhookSubscriptionParams{
+		ID:       id,
+		TenantID: TenantFromContext(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// webhookSubscriptionFromRow converts row into a WebhookSubscription.
+func webhookSubscriptionFromRow(row pgdb.WebhookSubscription) WebhookSubscription {
+	eventTypes := make([]events.Type, len(row.EventTypes))
+	for i, t := range row.EventTypes {
+		eventTypes[i] = events.Type(t)
+	}
+	return WebhookSubscription{
+		ID:         row.ID,
+		URL:        row.Url,
+		EventTypes: eventTypes,
+		Secret:     row.Secret,
+		CreatedAt:  row.CreatedAt.UTC(),
+		UpdatedAt:  row.UpdatedAt.UTC(),
+		TenantID:   row.TenantID,
+	}
+}
+
+// eventTypeStrings returns the strings of types.
+func eventTypeStrings(types []events.Type) []string {
+	strs := make([]string, len(types))
+	for i, t := range types {
+		strs[i] = string(t)
+	}
+	return strs
+}
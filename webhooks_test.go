@@ -0,0 +1,209 @@
+package catalog_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	catalog "github.com/jhtohru/go-album-catalog"
+	"github.com/jhtohru/go-album-catalog/events"
+	"github.com/jhtohru/go-album-catalog/webhook"
+)
+
+func TestPostgresWebhookStorage(t *testing.T) {
+	t.Parallel()
+
+	db := postgresTest.CreateDBOrFailNow(t)
+	defer db.Close()
+	storage := catalog.NewPostgresWebhookStorage(db)
+	ctx := context.Background()
+	sub := randomWebhookSubscription("https://example.com/hooks")
+
+	err := storage.Insert(ctx, sub)
+
+	assert.Nil(t, err)
+	found, err := storage.FindOne(ctx, sub.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, sub, found)
+	subs, err := storage.FindAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []catalog.WebhookSubscription{sub}, subs)
+
+	// Other tenants do not find the subscription.
+	acmeCtx := catalog.NewTenantContext(ctx, "acme")
+	_, err = storage.FindOne(acmeCtx, sub.ID)
+	assert.ErrorIs(t, err, catalog.ErrWebhookNotFound)
+	subs, err = storage.FindAll(acmeCtx)
+	assert.Nil(t, err)
+	assert.Empty(t, subs)
+
+	updated := sub
+	updated.URL = "https://example.com/new-hooks"
+	updated.EventTypes = []events.Type{events.TypeAlbumDeleted}
+	updated.Secret = "fedcba9876543210"
+	updated.UpdatedAt = sub.UpdatedAt.Add(time.Hour)
+
+	_, err = storage.Update(acmeCtx, updated)
+	assert.ErrorIs(t, err, catalog.ErrWebhookNotFound)
+	found, err = storage.Update(ctx, updated)
+	assert.Nil(t, err)
+	assert.Equal(t, updated, found)
+
+	assert.ErrorIs(t, storage.Remove(acmeCtx, sub.ID), catalog.ErrWebhookNotFound)
+	assert.Nil(t, storage.Remove(ctx, sub.ID))
+	assert.ErrorIs(t, storage.Remove(ctx, sub.ID), catalog.ErrWebhookNotFound)
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("happy path", func(t *testing.T) {
+		db := postgresTest.CreateDBOrFailNow(t)
+		defer db.Close()
+		var (
+			mu       sync.Mutex
+			received []events.Envelope
+		)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload, _ := io.ReadAll(r.Body)
+			if err := webhook.VerifyRequest(r, []byte("0123456789abcdef"), payload, time.Now(), time.Minute); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var env events.Envelope
+			json.Unmarshal(payload, &env)
+			mu.Lock()
+			received = append(received, env)
+			mu.Unlock()
+		}))
+		defer receiver.Close()
+		subs := catalog.NewPostgresWebhookStorage(db)
+		sub := randomWebhookSubscription(receiver.URL)
+		sub.EventTypes = []events.Type{events.TypeAlbumCreated}
+		if err := subs.Insert(context.Background(), sub); err != nil {
+			t.Fatal(err)
+		}
+		otherTenantSub := randomWebhookSubscription(receiver.URL)
+		if err := subs.Insert(catalog.NewTenantContext(context.Background(), "acme"), otherTenantSub); err != nil {
+			t.Fatal(err)
+		}
+		storage := catalog.NewPostgresAlbumStorage(db)
+		alb := randomAlbum()
+		if err := storage.Insert(context.Background(), alb); err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.Remove(context.Background(), alb.ID); err != nil {
+			t.Fatal(err)
+		}
+		// Publishing the events twice queues their deliveries once.
+		publisher := catalog.NewWebhookPublisher(db)
+		publishTwice := catalog.EventPublisherFunc(func(ctx context.Context, env events.Envelope) error {
+			if err := publisher.Publish(ctx, env); err != nil {
+				return err
+			}
+			return publisher.Publish(ctx, env)
+		})
+		if _, err := catalog.RelayOutbox(context.Background(), db, publishTwice); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := catalog.DeliverWebhooks(context.Background(), db, http.DefaultClient)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+		if assert.Len(t, received, 1) {
+			assert.Equal(t, events.TypeAlbumCreated, received[0].Type)
+		}
+		n, err = catalog.DeliverWebhooks(context.Background(), db, http.DefaultClient)
+		assert.Nil(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("retries and dead letters", func(t *testing.T) {
+		db := postgresTest.CreateDBOrFailNow(t)
+		defer db.Close()
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer receiver.Close()
+		sub := randomWebhookSubscription(receiver.URL)
+		if err := catalog.NewPostgresWebhookStorage(db).Insert(context.Background(), sub); err != nil {
+			t.Fatal(err)
+		}
+		insertAlbums(t, db, randomAlbum())
+		if _, err := catalog.RelayOutbox(context.Background(), db, catalog.NewWebhookPublisher(db)); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := catalog.DeliverWebhooks(context.Background(), db, http.DefaultClient)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+		attempts, lastError, dead := webhookDelivery(t, db, sub.ID)
+		assert.Equal(t, 1, attempts)
+		assert.Equal(t, "unexpected status 503 Service Unavailable", lastError)
+		assert.False(t, dead)
+
+		// The failed delivery is not due until it is retried.
+		n, err = catalog.DeliverWebhooks(context.Background(), db, http.DefaultClient)
+		assert.Nil(t, err)
+		assert.Zero(t, n)
+
+		// The delivery is dead once it runs out of attempts.
+		for range 9 {
+			if _, err := db.Exec("UPDATE webhook_delivery SET next_attempt_at = now()"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := catalog.DeliverWebhooks(context.Background(), db, http.DefaultClient); err != nil {
+				t.Fatal(err)
+			}
+		}
+		attempts, _, dead = webhookDelivery(t, db, sub.ID)
+		assert.Equal(t, 10, attempts)
+		assert.True(t, dead)
+		if _, err := db.Exec("UPDATE webhook_delivery SET next_attempt_at = now()"); err != nil {
+			t.Fatal(err)
+		}
+		n, err = catalog.DeliverWebhooks(context.Background(), db, http.DefaultClient)
+		assert.Nil(t, err)
+		assert.Zero(t, n)
+	})
+}
+
+func randomWebhookSubscription(url string) catalog.WebhookSubscription {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	return catalog.WebhookSubscription{
+		ID:         uuid.New(),
+		URL:        url,
+		EventTypes: []events.Type{events.TypeAlbumCreated, events.TypeAlbumUpdated, events.TypeAlbumDeleted},
+		Secret:     "0123456789abcdef",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// webhookDelivery returns the attempts and the last error of the delivery to
+// the subscription subID, and whether it is dead.
+func webhookDelivery(t *testing.T, db *sql.DB, subID uuid.UUID) (int, string, bool) {
+	t.Helper()
+
+	var (
+		attempts  int
+		lastError sql.NullString
+		dead      bool
+	)
+	query := "SELECT attempts, last_error, dead_at IS NOT NULL FROM webhook_delivery WHERE subscription_id = $1"
+	if err := db.QueryRow(query, subID).Scan(&attempts, &lastError, &dead); err != nil {
+		t.Fatal(err)
+	}
+	return attempts, lastError.String, dead
+}